@@ -0,0 +1,115 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"strings"
+
+	"neugram.io/ng/eval/gowrap"
+	"neugram.io/ng/eval/gowrap/genwrap"
+	"neugram.io/ng/gotool"
+)
+
+const buildInterpUsage = `usage: ng build-interp [-o file] [-gomod file] -pkgs path,...
+
+Build-interp builds an ng interpreter with the named Go packages,
+and the packages they import, compiled in. Programs run by it
+import those packages, and the standard library packages built
+into ng, without building plugins or using a Go toolchain.
+
+`
+
+func cmdBuildInterp(args []string) {
+	flags := flag.NewFlagSet("build-interp", flag.ExitOnError)
+	flagPkgs := flags.String("pkgs", "", "comma-separated import paths of the Go packages to compile in")
+	flagO := flags.String("o", "ng", "output file")
+	flagGoMod := flags.String("gomod", "", "go.mod of the module to import Go packages from (default: the module enclosing the current directory)")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, buildInterpUsage)
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+	}
+	var pkgs []string
+	for _, path := range strings.Split(*flagPkgs, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			pkgs = append(pkgs, path)
+		}
+	}
+	if len(pkgs) == 0 {
+		flags.Usage()
+	}
+
+	initModule(*flagGoMod, cwd)
+	err := buildInterp(*flagO, pkgs)
+	gotool.M.Cleanup()
+	if err != nil {
+		exitf("build-interp: %v", err)
+	}
+	os.Exit(0)
+}
+
+// buildInterp builds an interpreter that wraps pkgs into out.
+func buildInterp(out string, pkgs []string) error {
+	// Builtin packages are already wrapped,
+	// but their export data is needed too.
+	wrapped := make(map[string]bool)
+	for path := range gowrap.Pkgs {
+		wrapped[path] = true
+	}
+	files := make(map[string][]byte)
+	for _, pkgPath := range pkgs {
+		paths, err := genwrap.Wrapped(pkgPath, false)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			if wrapped[path] {
+				continue
+			}
+			wrapped[path] = true
+			// Each package is wrapped on its own,
+			// so no package is registered twice.
+			src, err := genwrap.GenGo(path, "main", true)
+			if err != nil {
+				return err
+			}
+			files["wrap_"+strings.NewReplacer("/", "_", ".", "_", "-", "_").Replace(path)+".go"] = src
+		}
+	}
+
+	var paths []string
+	for path := range wrapped {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	buf := new(bytes.Buffer)
+	buf.WriteString("// Generated by ng build-interp, do not edit.\n\n")
+	buf.WriteString("package main\n\nimport \"neugram.io/ng/gotool\"\n\nfunc init() {\n")
+	for _, path := range paths {
+		data, err := gotool.M.ExportData(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "gotool.RegisterExportData(%q, []byte(%q))\n", path, data)
+	}
+	buf.WriteString("}\n")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	files["exportdata_gen.go"] = src
+
+	return gotool.M.BuildInterp(out, files)
+}
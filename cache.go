@@ -0,0 +1,56 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"neugram.io/ng/gotool"
+)
+
+const cacheUsage = `usage: ng cache clean | list
+
+Cache manages the plugins built to import Go packages. They are
+kept in $XDG_CACHE_HOME/ng, or the directory named by $NGCACHE.
+Setting NGCACHE=off disables the cache.
+
+	clean	remove all cached plugins
+	list	print the cached plugins
+`
+
+func cmdCache(args []string) {
+	flags := flag.NewFlagSet("cache", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, cacheUsage)
+		os.Exit(2)
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+	}
+
+	switch flags.Arg(0) {
+	case "clean":
+		if err := gotool.CleanCache(); err != nil {
+			exitf("cache: %v", err)
+		}
+	case "list":
+		entries, err := gotool.ListCache()
+		if err != nil {
+			exitf("cache: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%dK\t%s\t%s\n", e.PkgPath, e.GoVersion, e.Size/1024, e.Created.Format("2006-01-02 15:04"), e.Key[:12])
+		}
+		w.Flush()
+	default:
+		flags.Usage()
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"neugram.io/ng/ngdoc"
+)
+
+const docUsage = `usage: ng doc [-format=text|markdown|html] file.ng [symbol]
+
+Doc prints the documentation of the exported constants, variables,
+functions, types and methods of a Neugram package. A symbol is a
+top-level name or a method in the form Type.Method.
+`
+
+func cmdDoc(args []string) {
+	flags := flag.NewFlagSet("doc", flag.ExitOnError)
+	flagFormat := flags.String("format", "text", "output format: text, markdown or html")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, docUsage)
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+	flags.Parse(args)
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+	}
+	mode, err := ngdoc.ParseMode(*flagFormat)
+	if err != nil {
+		exitf("doc: %v", err)
+	}
+
+	pkg, err := ngdoc.New(flags.Arg(0))
+	if err != nil {
+		exitf("doc: %v", err)
+	}
+	if flags.NArg() == 1 {
+		err = pkg.Write(os.Stdout, mode)
+	} else {
+		symbol := flags.Arg(1)
+		d := pkg.Lookup(symbol)
+		if d == nil {
+			exitf("doc: no symbol %s in package %s", symbol, pkg.Name)
+		}
+		err = d.Write(os.Stdout, mode)
+	}
+	if err != nil {
+		exitf("doc: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eval
+
+import (
+	"fmt"
+	"reflect"
+
+	"neugram.io/ng/format"
+	"neugram.io/ng/frame/memframe"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/tipe"
+	"neugram.io/ng/syntax/token"
+)
+
+// isElemwise reports whether the binary expression e applies an
+// arithmetic operator to each element of a slice, array or table.
+func (p *Program) isElemwise(e *expr.Binary) bool {
+	switch e.Op {
+	case token.Add, token.Sub, token.Mul, token.Div:
+	default:
+		return false
+	}
+	switch tipe.Underlying(p.Types.Type(e)).(type) {
+	case *tipe.Slice, *tipe.Array, *tipe.Table:
+		return true
+	}
+	return false
+}
+
+func (p *Program) isContainer(e expr.Expr) bool {
+	switch tipe.Underlying(p.Types.Type(e)).(type) {
+	case *tipe.Slice, *tipe.Array, *tipe.Table:
+		return true
+	}
+	return false
+}
+
+// evalElemwise evaluates x op y element by element. A scalar operand
+// is broadcast over every element of the other operand. The shapes
+// of two container operands are checked here, as they are not known
+// to the type checker.
+func (p *Program) evalElemwise(e *expr.Binary, x, y reflect.Value) reflect.Value {
+	t := p.Types.Type(e)
+	if tt, isTable := tipe.Underlying(t).(*tipe.Table); isTable {
+		return p.evalTableElemwise(e, tt, x, y)
+	}
+
+	rt := p.toRType(t)
+	elemType := rt.Elem()
+	lc, rc := p.isContainer(e.Left), p.isContainer(e.Right)
+	if !lc {
+		x = convert(x, elemType)
+	}
+	if !rc {
+		y = convert(y, elemType)
+	}
+	n := 0
+	switch {
+	case lc && rc:
+		n = x.Len()
+		if y.Len() != n {
+			panic(interpPanic{fmt.Errorf("invalid operation: %s (mismatched lengths %d and %d)", format.Expr(e), x.Len(), y.Len())})
+		}
+	case lc:
+		n = x.Len()
+	default:
+		n = y.Len()
+	}
+
+	var res reflect.Value
+	if rt.Kind() == reflect.Array {
+		res = reflect.New(rt).Elem()
+	} else {
+		if (lc && x.IsNil()) || (rc && y.IsNil()) {
+			return reflect.Zero(rt)
+		}
+		res = reflect.MakeSlice(rt, n, n)
+	}
+	for i := 0; i < n; i++ {
+		xi, yi := x, y
+		if lc {
+			xi = x.Index(i)
+		}
+		if rc {
+			yi = y.Index(i)
+		}
+		res.Index(i).Set(elemOp(e.Op, xi, yi, elemType))
+	}
+	return res
+}
+
+func (p *Program) evalTableElemwise(e *expr.Binary, tt *tipe.Table, x, y reflect.Value) reflect.Value {
+	elemType := p.toRType(tt.Type)
+	lc, rc := p.isContainer(e.Left), p.isContainer(e.Right)
+	var xt, yt *memframe.Memory
+	if lc {
+		xt, _ = x.Interface().(*memframe.Memory)
+		if xt == nil {
+			xt = memframe.New(0, 0)
+		}
+	} else {
+		x = convert(x, elemType)
+	}
+	if rc {
+		yt, _ = y.Interface().(*memframe.Memory)
+		if yt == nil {
+			yt = memframe.New(0, 0)
+		}
+	} else {
+		y = convert(y, elemType)
+	}
+	if lc && rc && (xt.Width != yt.Width || xt.Height != yt.Height) {
+		panic(interpPanic{fmt.Errorf("invalid operation: %s (mismatched shapes [%d|%d] and [%d|%d])", format.Expr(e), xt.Height, xt.Width, yt.Height, yt.Width)})
+	}
+	shape := xt
+	if !lc {
+		shape = yt
+	}
+
+	res := memframe.New(shape.Width, shape.Height)
+	copy(res.ColName, shape.ColName)
+	for row := 0; row < shape.Height; row++ {
+		for col := 0; col < shape.Width; col++ {
+			xi, yi := x, y
+			if lc {
+				xi = tableElem(xt, col, row, elemType)
+			}
+			if rc {
+				yi = tableElem(yt, col, row, elemType)
+			}
+			res.Data[row*res.Stride+col] = elemOp(e.Op, xi, yi, elemType).Interface()
+		}
+	}
+	return reflect.ValueOf(res)
+}
+
+func elemOp(op token.Token, x, y reflect.Value, elemType reflect.Type) reflect.Value {
+	v, err := binOp(op, x.Interface(), y.Interface())
+	if err != nil {
+		panic(interpPanic{err})
+	}
+	return convert(reflect.ValueOf(v), elemType)
+}
+
+// matMul is the builtin matmul, the matrix product of two tables.
+func matMul(x, y *memframe.Memory) *memframe.Memory {
+	if x == nil {
+		x = memframe.New(0, 0)
+	}
+	if y == nil {
+		y = memframe.New(0, 0)
+	}
+	if x.Width != y.Height {
+		panic(interpPanic{fmt.Errorf("matmul: mismatched shapes [%d|%d] and [%d|%d]", x.Height, x.Width, y.Height, y.Width)})
+	}
+	res := memframe.New(y.Width, x.Height)
+	copy(res.ColName, y.ColName)
+	for row := 0; row < x.Height; row++ {
+		for col := 0; col < y.Width; col++ {
+			var sum interface{}
+			for i := 0; i < x.Width; i++ {
+				a, b := x.Data[row*x.Stride+i], y.Data[i*y.Stride+col]
+				if a == nil || b == nil {
+					continue // unset elements are zero
+				}
+				v, err := binOp(token.Mul, a, b)
+				if err == nil && sum != nil {
+					v, err = binOp(token.Add, sum, v)
+				}
+				if err != nil {
+					panic(interpPanic{err})
+				}
+				sum = v
+			}
+			res.Data[row*res.Stride+col] = sum
+		}
+	}
+	return res
+}
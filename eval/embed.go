@@ -0,0 +1,381 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eval
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"neugram.io/ng/eval/gowrap"
+	"neugram.io/ng/parser"
+	"neugram.io/ng/syntax"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/stmt"
+	"neugram.io/ng/syntax/tipe"
+	"neugram.io/ng/typecheck"
+)
+
+// A Go program embeds Neugram by creating a Program, defining the
+// Go values and packages the Neugram code may use, loading the code,
+// and then calling its functions:
+//
+//	p := eval.New(filename, nil)
+//	if err := p.Define("check", reflect.ValueOf(check)); err != nil {
+//		...
+//	}
+//	err := p.DefinePackage("app", map[string]interface{}{
+//		"Version": version,
+//		"Log":     log.Printf,
+//	})
+//	if err := p.Load(filename); err != nil {
+//		...
+//	}
+//	res, err := p.Call("Validate", cfg)
+
+// Load evaluates the Neugram program in filename in p. If filename
+// is a directory holding the files of a Neugram package, they are
+// evaluated as a package is: their types and functions may be used
+// before they are declared, and their init functions run last.
+//
+// Relative imports are resolved against the path p was created with.
+func (p *Program) Load(filename string) error {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("eval: %v", err)
+	}
+	if !fi.IsDir() {
+		if err := p.evalFile(filename); err != nil {
+			if _, isPanic := err.(Panic); isPanic {
+				return err
+			}
+			return fmt.Errorf("eval: %s:%v", filename, err)
+		}
+		return nil
+	}
+
+	filenames, err := typecheck.PkgFiles(filename)
+	if err != nil {
+		return fmt.Errorf("eval: %v", err)
+	}
+	var files []*syntax.File
+	for _, filename := range filenames {
+		source, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("eval: %v", err)
+		}
+		f, err := parser.New(filename).Parse(source)
+		if err != nil {
+			return fmt.Errorf("eval: %v", err)
+		}
+		files = append(files, f)
+	}
+	if err := p.Types.AddPkg(files); err != nil {
+		return fmt.Errorf("eval: typecheck: %v", err)
+	}
+	return p.evalPkg(files)
+}
+
+// evalPkg evaluates the files of a package checked by AddPkg.
+//
+// The statements run in order, after the imports and type
+// declarations. Functions are declared before any of them run, and
+// take their value as soon as the variables they use are declared,
+// so they may be called from earlier statements and other functions.
+// The init functions run last.
+func (p *Program) evalPkg(files []*syntax.File) (err error) {
+	defer func() {
+		x := recover()
+		if x == nil {
+			return
+		}
+		switch x := x.(type) {
+		case interpPanic:
+			err = x.reason
+		case Panic:
+			err = x
+		default:
+			err = fmt.Errorf("eval: %v", x)
+		}
+	}()
+
+	var stmts []stmt.Stmt
+	for _, f := range files {
+		stmts = append(stmts, f.Stmts...)
+	}
+	for _, s := range stmts {
+		switch s.(type) {
+		case *stmt.Import, *stmt.ImportSet:
+			p.evalStmt(s)
+		}
+	}
+
+	funcs := make(map[*expr.FuncLiteral]reflect.Value) // not yet defined
+	var inits []*expr.FuncLiteral
+	for _, s := range stmts {
+		fn := pkgFunc(s)
+		if fn == nil || len(fn.Type.TypeParams) > 0 {
+			continue
+		}
+		if fn.Name == "init" {
+			inits = append(inits, fn)
+			continue
+		}
+		v := reflect.New(p.toRType(fn.Type)).Elem()
+		funcs[fn] = v
+		p.Cur = &Scope{
+			Parent:   p.Cur,
+			VarName:  fn.Name,
+			Var:      v,
+			Implicit: true,
+		}
+	}
+	for _, s := range stmts {
+		switch s.(type) {
+		case *stmt.TypeDecl, *stmt.TypeDeclSet, *stmt.MethodikDecl:
+			p.evalStmt(s)
+		}
+	}
+	defineFuncs := func() {
+		for fn, v := range funcs {
+			if p.freeVarsDeclared(fn) {
+				v.Set(p.evalFuncLiteral(fn, nil))
+				delete(funcs, fn)
+			}
+		}
+	}
+	defineFuncs()
+
+	for _, s := range stmts {
+		switch s.(type) {
+		case *stmt.Import, *stmt.ImportSet, *stmt.TypeDecl, *stmt.TypeDeclSet, *stmt.MethodikDecl:
+			continue
+		}
+		if fn := pkgFunc(s); fn != nil && (fn.Name == "init" || len(fn.Type.TypeParams) == 0) {
+			if v, ok := funcs[fn]; ok {
+				v.Set(p.evalFuncLiteral(fn, nil))
+				delete(funcs, fn)
+			}
+			continue
+		}
+		p.evalStmt(s)
+		if len(funcs) > 0 {
+			defineFuncs()
+		}
+	}
+	for _, fn := range inits {
+		p.evalFuncLiteral(fn, nil).Call(nil)
+	}
+	return nil
+}
+
+// pkgFunc reports the function declared by the top-level statement s,
+// or nil.
+func pkgFunc(s stmt.Stmt) *expr.FuncLiteral {
+	if s, isSimple := s.(*stmt.Simple); isSimple {
+		if fn, isFunc := s.Expr.(*expr.FuncLiteral); isFunc && fn.Name != "" {
+			return fn
+		}
+	}
+	return nil
+}
+
+// freeVarsDeclared reports whether the free variables of fn are in
+// scope, so that its value can be built.
+func (p *Program) freeVarsDeclared(fn *expr.FuncLiteral) bool {
+	for _, name := range fn.Type.FreeVars {
+		if !p.Cur.Lookup(name).IsValid() {
+			return false
+		}
+	}
+	return true
+}
+
+// Lookup reports the value of the top-level variable, constant,
+// or function name, or the zero Value if there is none.
+// Untyped constants are given their default type.
+func (p *Program) Lookup(name string) reflect.Value {
+	obj := p.Types.Lookup(name)
+	if obj == nil || typecheck.Universe.Objs[name] == obj {
+		return reflect.Value{}
+	}
+	if obj.Kind != typecheck.ObjVar && obj.Kind != typecheck.ObjConst {
+		return reflect.Value{}
+	}
+	v := p.Cur.Lookup(name)
+	if !v.IsValid() {
+		return v
+	}
+	switch x := v.Interface().(type) {
+	case UntypedInt, UntypedFloat, UntypedComplex, UntypedString, UntypedRune, UntypedBool:
+		v = reflect.ValueOf(promoteUntyped(x))
+	}
+	return v
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Call calls the top-level function name with args and reports its
+// results. The arguments are converted to the types of the function
+// parameters. A non-nil error returned as the last result of the
+// function is reported as err, as is a panic.
+func (p *Program) Call(name string, args ...interface{}) (res []interface{}, err error) {
+	fn := p.Lookup(name)
+	if !fn.IsValid() {
+		return nil, fmt.Errorf("eval: %s not defined", name)
+	}
+	if fn.Type() == genericFuncType {
+		return nil, fmt.Errorf("eval: cannot call generic function %s", name)
+	}
+	if fn.Kind() != reflect.Func {
+		return nil, fmt.Errorf("eval: %s is not a function", name)
+	}
+	ft := fn.Type()
+	if n := ft.NumIn(); len(args) != n && !(ft.IsVariadic() && len(args) >= n-1) {
+		return nil, fmt.Errorf("eval: wrong number of arguments to %s: have %d, want %d", name, len(args), ft.NumIn())
+	}
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var t reflect.Type
+		if ft.IsVariadic() && i >= ft.NumIn()-1 {
+			t = ft.In(ft.NumIn() - 1).Elem()
+		} else {
+			t = ft.In(i)
+		}
+		v, err := callArg(arg, t)
+		if err != nil {
+			return nil, fmt.Errorf("eval: argument %d to %s: %v", i, name, err)
+		}
+		in[i] = v
+	}
+
+	defer func() {
+		x := recover()
+		if x == nil {
+			return
+		}
+		res = nil
+		switch x := x.(type) {
+		case interpPanic:
+			err = x.reason
+		case Panic:
+			err = x
+		default:
+			err = fmt.Errorf("eval: %s: %v", name, x)
+		}
+	}()
+	out := fn.Call(in)
+	if n := len(out); n > 0 && ft.Out(n-1) == errorType {
+		if e := out[n-1]; !e.IsNil() {
+			err = e.Interface().(error)
+		}
+		out = out[:n-1]
+	}
+	for _, v := range out {
+		res = append(res, v.Interface())
+	}
+	return res, err
+}
+
+// callArg converts the Go value arg to a parameter of type t.
+// Types that differ only by name, like the struct types of a Go
+// program and those Neugram builds, are converted.
+func callArg(arg interface{}, t reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		switch t.Kind() {
+		case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot use nil as %s", t)
+	}
+	v := reflect.ValueOf(arg)
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if v.Kind() == t.Kind() && v.Type().ConvertibleTo(t) {
+		return v.Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %T as %s", arg, t)
+}
+
+// Define declares name in the universe of p, as a variable holding
+// val. Programs evaluated by p after the call may use it.
+func (p *Program) Define(name string, val reflect.Value) error {
+	t, v, err := p.hostValue(val)
+	if err != nil {
+		return fmt.Errorf("eval: cannot define %s: %v", name, err)
+	}
+	p.Types.Define(name, t)
+	p.defineUniverse(name, v)
+	return nil
+}
+
+// DefinePackage declares name in the universe of p, as a package
+// whose exported members are the Go values in members.
+func (p *Program) DefinePackage(name string, members map[string]interface{}) error {
+	pkgt := &tipe.Package{
+		Path:    name,
+		Exports: make(map[string]tipe.Type),
+	}
+	pkg := &gowrap.Pkg{
+		Exports: make(map[string]reflect.Value),
+	}
+	var names []string
+	for member := range members {
+		names = append(names, member)
+	}
+	sort.Strings(names)
+	for _, member := range names {
+		if !isExported(member) {
+			return fmt.Errorf("eval: cannot define %s.%s: not exported", name, member)
+		}
+		t, v, err := p.hostValue(reflect.ValueOf(members[member]))
+		if err != nil {
+			return fmt.Errorf("eval: cannot define %s.%s: %v", name, member, err)
+		}
+		pkgt.Exports[member] = t
+		pkg.Exports[member] = v
+	}
+	p.Types.DefinePackage(name, pkgt)
+	p.defineUniverse(name, reflect.ValueOf(pkg))
+	return nil
+}
+
+// hostValue reports the Neugram type of the Go value val, and a
+// variable holding val.
+func (p *Program) hostValue(val reflect.Value) (tipe.Type, reflect.Value, error) {
+	if !val.IsValid() {
+		return nil, reflect.Value{}, fmt.Errorf("invalid value")
+	}
+	t := p.reflector.FromRType(val.Type())
+	v := reflect.New(p.toRType(t)).Elem()
+	v.Set(val)
+	return t, v, nil
+}
+
+func isExported(name string) bool {
+	ch, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(ch)
+}
+
+// defineUniverse adds name to the universe scope, under the scope of
+// the main function.
+func (p *Program) defineUniverse(name string, v reflect.Value) {
+	p.Universe = &Scope{
+		Parent:  p.Universe,
+		VarName: name,
+		Var:     v,
+	}
+	for s := p.Cur; s != nil; s = s.Parent {
+		if s.fct == "۰ng-main" {
+			s.Parent = p.Universe
+			return
+		}
+	}
+}
@@ -7,9 +7,12 @@ package eval
 import (
 	"bufio"
 	"fmt"
+	"go/constant"
+	gotypes "go/types"
 	"io/ioutil"
 	"math/big"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"runtime/debug"
@@ -23,6 +26,7 @@ import (
 	_ "neugram.io/ng/eval/gowrap/wrapbuiltin" // registers with gowrap
 	"neugram.io/ng/eval/shell"
 	"neugram.io/ng/format"
+	"neugram.io/ng/frame/memframe"
 	"neugram.io/ng/gengo"
 	"neugram.io/ng/gotool"
 	"neugram.io/ng/internal/bigcplx"
@@ -43,8 +47,6 @@ type Scope struct {
 	// unrolled when block ends.
 	Implicit bool
 
-	Label bool
-
 	fct    string     // function name if this is a function scope
 	defers []deferCtx // LIFO-list of defers to run
 }
@@ -90,6 +92,11 @@ type Program struct {
 	mostRecentLabel string
 
 	typePlugins map[*tipe.Named]string // type to package path TODO lock?
+
+	typeArgs map[*tipe.TypeParam]tipe.Type // type arguments of the generic function being evaluated
+	generics *generics
+
+	iota int64 // value of iota in the const spec being evaluated
 }
 
 type branchType int
@@ -128,14 +135,11 @@ func New(path string, shellState *shell.State) *Program {
 		ShellState:  shellState,
 		reflector:   newReflector(),
 		typePlugins: make(map[*tipe.Named]string),
+		generics:    newGenerics(),
 	}
+	p.reflector.goNamed = p.Types.GoNamed
 	addUniverse := func(name string, val interface{}) {
-		p.Universe = &Scope{
-			Parent:  p.Universe,
-			VarName: name,
-			Var:     reflect.ValueOf(val),
-		}
-		p.Cur.Parent = p.Universe
+		p.defineUniverse(name, reflect.ValueOf(val))
 	}
 	addUniverse("true", true)
 	addUniverse("false", false)
@@ -156,6 +160,9 @@ func New(path string, shellState *shell.State) *Program {
 		if s, ok := c.(UntypedString); ok {
 			return len(s.String)
 		}
+		if t, ok := c.(*memframe.Memory); ok {
+			return tableLen(t)
+		}
 		return reflect.ValueOf(c).Len()
 	})
 	addUniverse("cap", func(c interface{}) int {
@@ -192,6 +199,7 @@ func New(path string, shellState *shell.State) *Program {
 		reflect.ValueOf(m).SetMapIndex(reflect.ValueOf(k), reflect.Value{})
 	})
 	addUniverse("make", builtinMake)
+	addUniverse("matmul", matMul)
 	addUniverse("new", builtinNew)
 	addUniverse("complex", builtinComplex)
 	addUniverse("real", func(v interface{}) interface{} {
@@ -233,12 +241,12 @@ func EvalFile(path string, shellState *shell.State) error {
 		return fmt.Errorf("eval: %v", err)
 	}
 	p := New(path, shellState)
-	return p.evalFile()
+	return p.evalFile(path)
 }
 
-func (p *Program) evalFile() error {
-	prsr := parser.New(p.Path)
-	f, err := os.Open(p.Path)
+func (p *Program) evalFile(filename string) error {
+	prsr := parser.New(filename)
+	f, err := os.Open(filename)
 	if err != nil {
 		return fmt.Errorf("eval: %v", err)
 	}
@@ -449,6 +457,61 @@ func (p *Program) interrupted() bool {
 	}
 }
 
+// rangeBody evaluates one iteration of the body of a range statement
+// with the given label. It reports whether the loop continues.
+// If it does not, res holds the values of any return statement.
+func (p *Program) rangeBody(body stmt.Stmt, label string) (res []reflect.Value, ok bool) {
+	res = p.evalStmt(body)
+	if p.interrupted() {
+		return nil, false
+	}
+	switch p.branchType {
+	case brNone:
+		return nil, true
+	case brBreak:
+		if p.branchLabel == "" || p.branchLabel == label {
+			p.branchType = brNone
+			p.branchLabel = ""
+		}
+		return nil, false
+	case brContinue:
+		if p.branchLabel == "" || p.branchLabel == label {
+			p.branchType = brNone
+			p.branchLabel = ""
+			return nil, true
+		}
+		return nil, false
+	}
+	return res, false
+}
+
+// switchBreak ends a break out of a switch or select statement
+// with the given label.
+func (p *Program) switchBreak(label string) {
+	if p.branchType == brBreak && (p.branchLabel == "" || p.branchLabel == label) {
+		p.branchType = brNone
+		p.branchLabel = ""
+	}
+}
+
+// labelIndex returns the index of the statement labeled label in
+// stmts, or -1.
+func labelIndex(stmts []stmt.Stmt, label string) int {
+	for i, s := range stmts {
+		for {
+			ls, isLabeled := s.(*stmt.Labeled)
+			if !isLabeled {
+				break
+			}
+			if ls.Label == label {
+				return i
+			}
+			s = ls.Stmt
+		}
+	}
+	return -1
+}
+
 var nosig = (<-chan os.Signal)(make(chan os.Signal))
 
 func (p *Program) Eval(s stmt.Stmt, sigint <-chan os.Signal) (res []reflect.Value, err error) {
@@ -518,10 +581,14 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 	p.mostRecentLabel = ""
 	switch s := s.(type) {
 	case *stmt.Const:
-		return p.evalConst(s)
+		return p.evalConst(s, s.Type, s.Values, 0)
 	case *stmt.ConstSet:
-		for _, v := range s.Consts {
-			p.evalConst(v)
+		var last *stmt.Const
+		for i, v := range s.Consts {
+			if v.Type != nil || len(v.Values) > 0 {
+				last = v
+			}
+			p.evalConst(v, last.Type, last.Values, int64(i))
 		}
 		return nil
 	case *stmt.Var:
@@ -542,8 +609,7 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 			} else {
 				types = append(types, t)
 			}
-			// TODO: insert an implicit interface type conversion here
-			vals = append(vals, v...)
+			vals = append(vals, p.implicitConv(rhs, v)...)
 		}
 
 		vars := make([]reflect.Value, len(s.Left))
@@ -552,7 +618,7 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 				if lhs.(*expr.Ident).Name == "_" {
 					continue
 				}
-				t := p.reflector.ToRType(types[i])
+				t := p.toRType(types[i])
 
 				s := &Scope{
 					Parent:   p.Cur,
@@ -580,6 +646,11 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 						continue
 					}
 				}
+				if e, isTableIndex := lhs.(*expr.TableIndex); isTableIndex {
+					// Table elements are not addressable.
+					p.setTableElem(e, vals[i])
+					continue
+				}
 				v := p.evalExprOne(lhs)
 				vars[i] = v
 			}
@@ -595,9 +666,28 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 	case *stmt.Block:
 		p.pushScope()
 		defer p.popScope()
-		for _, s := range s.Stmts {
-			res := p.evalStmt(s)
-			if p.branchType != brNone || p.interrupted() {
+		// scopes records the scope each statement began in, so a
+		// goto to an earlier label drops the variables declared
+		// after it.
+		scopes := make([]*Scope, len(s.Stmts))
+		for i := 0; i < len(s.Stmts); i++ {
+			scopes[i] = p.Cur
+			res := p.evalStmt(s.Stmts[i])
+			if p.interrupted() {
+				return res
+			}
+			if p.branchType == brGoto {
+				if j := labelIndex(s.Stmts, p.branchLabel); j >= 0 {
+					p.branchType = brNone
+					p.branchLabel = ""
+					if j <= i {
+						p.Cur = scopes[j]
+					}
+					i = j - 1
+					continue
+				}
+			}
+			if p.branchType != brNone {
 				return res
 			}
 		}
@@ -616,24 +706,23 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 					break
 				}
 			}
-			p.evalStmt(s.Body)
-			// Note there are three extremely similar loops:
-			//	*stmt.For, *stmt.Range (slice, and map)
+			res := p.evalStmt(s.Body)
+			// Note the similar loop in rangeBody.
 			if p.interrupted() {
 				break
 			}
 			switch p.branchType {
 			default:
-				break loop
+				return res
 			case brNone:
 			case brBreak:
-				if p.branchLabel == mostRecentLabel {
+				if p.branchLabel == "" || p.branchLabel == mostRecentLabel {
 					p.branchType = brNone
 					p.branchLabel = ""
 				}
 				break loop
 			case brContinue:
-				if p.branchLabel == mostRecentLabel {
+				if p.branchLabel == "" || p.branchLabel == mostRecentLabel {
 					p.branchType = brNone
 					p.branchLabel = ""
 					if s.Post != nil {
@@ -681,14 +770,11 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 		ngPkg := strings.HasSuffix(path, ".ng")
 		if ngPkg {
 			var filename string
-			if strings.HasPrefix(path, "./") {
-				filename = filepath.Join(filepath.Dir(p.Path), path)
-				filename, _ = filepath.Abs(filename)
-				path = "rel" + filename
-			} else {
-				panic(Panic{val: fmt.Errorf("TODO: look for .ng file in GOPATH")})
+			var err error
+			path, filename, err = typecheck.NgPkgPath(path, p.Path)
+			if err != nil {
+				panic(Panic{val: err})
 			}
-			path = strings.TrimSuffix(path, ".ng") + "_ng"
 			pkg = gowrap.Pkgs[path]
 			if pkg == nil {
 				adjPkgPath, dir, err := gotool.M.Dir(path)
@@ -715,11 +801,14 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 		} else {
 			pkg = gowrap.Pkgs[path]
 			if pkg == nil {
-				src, err := genwrap.GenGo(path, "main", false)
-				if err != nil {
-					panic(Panic{val: fmt.Errorf("plugin: wrapper gen failed for Go package %q: %v", s.Name, err)})
+				gen := func() ([]byte, error) {
+					src, err := genwrap.GenGo(path, "main", false)
+					if err != nil {
+						return nil, fmt.Errorf("plugin: wrapper gen failed for Go package %q: %v", s.Name, err)
+					}
+					return src, nil
 				}
-				if _, err := gotool.M.Create(path, src); err != nil {
+				if _, err := gotool.M.CreateWrapper(path, genwrap.Version, gen); err != nil {
 					panic(Panic{val: err})
 				}
 				pkg = gowrap.Pkgs[s.Path]
@@ -741,7 +830,7 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 		var key, val reflect.Value
 		if s.Decl {
 			if s.Key != nil {
-				key = reflect.New(p.reflector.ToRType(p.Types.Type(s.Key))).Elem()
+				key = reflect.New(p.toRType(p.Types.Type(s.Key))).Elem()
 				name := s.Key.(*expr.Ident).Name
 				p.Cur = &Scope{
 					Parent:   p.Cur,
@@ -751,7 +840,7 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 				}
 			}
 			if s.Val != nil {
-				val = reflect.New(p.reflector.ToRType(p.Types.Type(s.Val))).Elem()
+				val = reflect.New(p.toRType(p.Types.Type(s.Val))).Elem()
 				name := s.Val.(*expr.Ident).Name
 				p.Cur = &Scope{
 					Parent:   p.Cur,
@@ -769,111 +858,129 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 			}
 		}
 		src := p.evalExprOne(s.Expr)
+		if src.Kind() == reflect.Ptr && src.Type().Elem().Kind() == reflect.Array {
+			src = src.Elem()
+		}
+		if src.Type() == tableType {
+			t := src.Interface().(*memframe.Memory)
+			rowType := p.toRType(tipe.Underlying(p.Types.Type(s.Expr)).(*tipe.Table).Type)
+			for i, n := 0, tableLen(t); i < n; i++ {
+				if key.IsValid() {
+					key.SetInt(int64(i))
+				}
+				if val.IsValid() {
+					val.Set(tableRow(t, i, reflect.SliceOf(rowType)))
+				}
+				if res, ok := p.rangeBody(s.Body, mostRecentLabel); !ok {
+					return res
+				}
+			}
+			return nil
+		}
 		switch src.Kind() {
 		case reflect.Array, reflect.Slice:
 			slen := src.Len()
-		sliceLoop:
 			for i := 0; i < slen; i++ {
-				key.SetInt(int64(i))
-				if val != (reflect.Value{}) {
+				if key.IsValid() {
+					key.SetInt(int64(i))
+				}
+				if val.IsValid() {
 					val.Set(src.Index(i))
 				}
-				p.evalStmt(s.Body)
-				if p.interrupted() {
-					break
+				if res, ok := p.rangeBody(s.Body, mostRecentLabel); !ok {
+					return res
 				}
-				switch p.branchType {
-				default:
-					break sliceLoop
-				case brNone:
-				case brBreak:
-					if p.branchLabel == mostRecentLabel {
-						p.branchType = brNone
-						p.branchLabel = ""
-					}
-					break sliceLoop
-				case brContinue:
-					if p.branchLabel == mostRecentLabel {
-						p.branchType = brNone
-						p.branchLabel = ""
-						continue sliceLoop
-					}
-					break sliceLoop
+			}
+		case reflect.String:
+			for i, r := range src.String() {
+				if key.IsValid() {
+					key.SetInt(int64(i))
+				}
+				if val.IsValid() {
+					val.SetInt(int64(r))
+				}
+				if res, ok := p.rangeBody(s.Body, mostRecentLabel); !ok {
+					return res
+				}
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n := src.Int()
+			for i := int64(0); i < n; i++ {
+				if key.IsValid() {
+					key.SetInt(i)
+				}
+				if res, ok := p.rangeBody(s.Body, mostRecentLabel); !ok {
+					return res
+				}
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n := src.Uint()
+			for i := uint64(0); i < n; i++ {
+				if key.IsValid() {
+					key.SetUint(i)
+				}
+				if res, ok := p.rangeBody(s.Body, mostRecentLabel); !ok {
+					return res
 				}
 			}
 		case reflect.Map:
 			keys := src.MapKeys()
-		mapLoop:
 			for _, k := range keys {
-				if key != (reflect.Value{}) {
+				if key.IsValid() {
 					key.Set(k)
 				}
-				if val != (reflect.Value{}) {
-					v := src.MapIndex(k)
-					val.Set(v)
+				if val.IsValid() {
+					val.Set(src.MapIndex(k))
 				}
-				p.evalStmt(s.Body)
-				if p.interrupted() {
-					break
-				}
-				switch p.branchType {
-				default:
-					break mapLoop
-				case brNone:
-				case brBreak:
-					if p.branchLabel == mostRecentLabel {
-						p.branchType = brNone
-						p.branchLabel = ""
-					}
-					break mapLoop
-				case brContinue:
-					if p.branchLabel == mostRecentLabel {
-						p.branchType = brNone
-						p.branchLabel = ""
-						continue mapLoop
-					}
-					break mapLoop
+				if res, ok := p.rangeBody(s.Body, mostRecentLabel); !ok {
+					return res
 				}
 			}
 		case reflect.Chan:
-		chanLoop:
 			for {
 				v, ok := src.Recv()
 				if !ok {
-					break chanLoop
-				}
-				key.Set(v)
-				p.evalStmt(s.Body)
-				if p.interrupted() {
 					break
 				}
-				switch p.branchType {
-				default:
-					break chanLoop
-				case brNone:
-				case brBreak:
-					if p.branchLabel == mostRecentLabel {
-						p.branchType = brNone
-						p.branchLabel = ""
-					}
-					break chanLoop
-				case brContinue:
-					if p.branchLabel == mostRecentLabel {
-						p.branchType = brNone
-						p.branchLabel = ""
-						continue chanLoop
-					}
-					break chanLoop
+				if key.IsValid() {
+					key.Set(v)
+				}
+				if res, ok := p.rangeBody(s.Body, mostRecentLabel); !ok {
+					return res
 				}
 			}
+		case reflect.Func:
+			// Range over an iterator function. The body of the loop
+			// runs inside yield, which reports whether to continue.
+			var res []reflect.Value
+			done := false
+			yieldType := src.Type().In(0)
+			yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+				if done {
+					panic(interpPanic{fmt.Errorf("range function continued iteration after loop exit")})
+				}
+				if len(args) > 0 && key.IsValid() {
+					key.Set(args[0])
+				}
+				if len(args) > 1 && val.IsValid() {
+					val.Set(args[1])
+				}
+				var ok bool
+				res, ok = p.rangeBody(s.Body, mostRecentLabel)
+				done = !ok
+				return []reflect.Value{reflect.ValueOf(ok).Convert(yieldType.Out(0))}
+			})
+			src.Call([]reflect.Value{yield})
+			done = true
+			return res
 		default:
-			panic(interpPanic{fmt.Errorf("unknown range type: %T", src)})
+			panic(interpPanic{fmt.Errorf("unknown range type: %s", src.Type())})
 		}
 		return nil
 	case *stmt.Return:
 		var res []reflect.Value
 		for _, expr := range s.Exprs {
-			res = append(res, p.evalExpr(expr)...)
+			res = append(res, p.implicitConv(expr, p.evalExpr(expr))...)
 		}
 		p.branchType = brReturn
 		p.branchLabel = ""
@@ -901,11 +1008,15 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 				Implicit: true,
 			}
 			p.Cur = s
+			if gf, isGeneric := res[0].Interface().(*genericFunc); isGeneric {
+				gf.scope = s // instances may call the function recursively
+				return nil   // a generic function has no value until instantiated
+			}
 		}
 		return res
 	case *stmt.Send:
 		ch := p.evalExprOne(s.Chan)
-		v := p.evalExprOne(s.Value)
+		v := p.implicitConv(s.Value, p.evalExpr(s.Value))[0]
 		ch.Send(v)
 		return nil
 	case *stmt.TypeDeclSet:
@@ -914,20 +1025,21 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 		}
 		return nil
 	case *stmt.TypeDecl:
-		if _, isIface := s.Type.Type.(*tipe.Interface); isIface {
-			p.ifaceDecl(s.Type)
+		if len(s.Type.TypeParams) > 0 {
+			return nil // instantiated on use
+		}
+		if iface, isIface := s.Type.Type.(*tipe.Interface); isIface && iface.Union == nil {
+			p.namedTypeDecl(s.Type)
+		} else if p.promotesMethods(s.Type) {
+			p.namedTypeDecl(s.Type)
 		}
 		return nil
 	case *stmt.MethodikDecl:
 		p.methodikDecl(s)
 		return nil
 	case *stmt.Labeled:
-		p.Cur = &Scope{
-			Parent:  p.Cur,
-			VarName: s.Label,
-			Label:   true,
-		}
-		defer p.popScope()
+		// Labels are not scoped: a labeled declaration is
+		// visible to the rest of its block, as in Go.
 		p.mostRecentLabel = s.Label
 		return p.evalStmt(s.Stmt)
 	case *stmt.Branch:
@@ -978,20 +1090,23 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 			}
 			if match || through {
 				through = false
-				p.evalStmt(cse.Body)
+				res := p.evalStmt(cse.Body)
 				switch p.branchType {
 				case brFallthrough:
 					through = true
 					p.branchType = brNone
 					continue loopCases
 				}
-				return nil
+				p.switchBreak(mostRecentLabel)
+				return res
 			}
 		}
 		// no case were triggered.
 		// execute the default one, if any.
 		if !match && dflt != nil {
-			p.evalStmt(dflt.Body)
+			res := p.evalStmt(dflt.Body)
+			p.switchBreak(mostRecentLabel)
+			return res
 		}
 		return nil
 	case *stmt.TypeSwitch:
@@ -1020,16 +1135,20 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 				continue
 			}
 			for _, typ := range cse.Types {
-				rt := p.reflector.ToRType(typ)
+				rt := p.toRType(typ)
 				if t == rt {
-					return p.evalStmt(cse.Body)
+					res := p.evalStmt(cse.Body)
+					p.switchBreak(mostRecentLabel)
+					return res
 				}
 			}
 		}
 		// no case were triggered.
 		// execute the default one, if any.
 		if dflt != nil {
-			return p.evalStmt(dflt.Body)
+			res := p.evalStmt(dflt.Body)
+			p.switchBreak(mostRecentLabel)
+			return res
 		}
 		return nil
 	case *stmt.Select:
@@ -1069,7 +1188,7 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 				cases[i].Dir = reflect.SelectRecv
 			case *stmt.Send:
 				works[i].Chan = p.evalExprOne(cse.Chan)
-				send := p.evalExprOne(cse.Value)
+				send := p.implicitConv(cse.Value, p.evalExpr(cse.Value))[0]
 				cases[i].Dir = reflect.SelectSend
 				cases[i].Chan = works[i].Chan
 				cases[i].Send = send
@@ -1121,8 +1240,9 @@ func (p *Program) evalStmt(s stmt.Stmt) []reflect.Value {
 			panic(interpPanic{fmt.Errorf("invalid select case chan-dir: %v", cases[chosen].Dir)})
 		}
 		cse := &s.Cases[chosen]
-		p.evalStmt(cse.Body)
-		return nil
+		res := p.evalStmt(cse.Body)
+		p.switchBreak(mostRecentLabel)
+		return res
 	}
 	panic(fmt.Sprintf("TODO evalStmt: %s", format.Stmt(s)))
 }
@@ -1135,10 +1255,20 @@ func (p *Program) evalExprOne(e expr.Expr) reflect.Value {
 	return v[0]
 }
 
-func (p *Program) evalConst(s *stmt.Const) []reflect.Value {
+// evalConvOne evaluates e, which must have one value, and converts
+// it as implicitConv does. It is used for the elements of composite
+// literals.
+func (p *Program) evalConvOne(e expr.Expr) reflect.Value {
+	return p.implicitConv(e, []reflect.Value{p.evalExprOne(e)})[0]
+}
+
+// evalConst evaluates the const spec s with the given type and values,
+// which are those of an earlier spec if s repeats it implicitly.
+func (p *Program) evalConst(s *stmt.Const, typ tipe.Type, values []expr.Expr, iota int64) []reflect.Value {
+	p.iota = iota
 	types := make([]tipe.Type, 0, len(s.NameList))
 	vals := make([]reflect.Value, 0, len(s.NameList))
-	for _, rhs := range s.Values {
+	for _, rhs := range values {
 		v := p.evalExpr(rhs)
 		t := p.Types.Type(rhs)
 		if tuple, isTuple := t.(*tipe.Tuple); isTuple {
@@ -1146,13 +1276,12 @@ func (p *Program) evalConst(s *stmt.Const) []reflect.Value {
 		} else {
 			types = append(types, t)
 		}
-		// TODO: insert an implicit interface type conversion here
-		vals = append(vals, v...)
+		vals = append(vals, p.implicitConv(rhs, v)...)
 	}
-	if s.Type != nil {
+	if typ != nil {
 		types = make([]tipe.Type, len(s.NameList))
 		for i := range types {
-			types[i] = s.Type
+			types[i] = typ
 		}
 	}
 
@@ -1161,7 +1290,7 @@ func (p *Program) evalConst(s *stmt.Const) []reflect.Value {
 		if name == "_" {
 			continue
 		}
-		t := p.reflector.ToRType(types[i])
+		t := p.toRType(types[i])
 		s := &Scope{
 			Parent:   p.Cur,
 			VarName:  name,
@@ -1198,8 +1327,7 @@ func (p *Program) evalVar(s *stmt.Var) []reflect.Value {
 		} else {
 			types = append(types, t)
 		}
-		// TODO: insert an implicit interface type conversion here
-		vals = append(vals, v...)
+		vals = append(vals, p.implicitConv(rhs, v)...)
 	}
 	if s.Type != nil {
 		types = make([]tipe.Type, len(s.NameList))
@@ -1213,7 +1341,7 @@ func (p *Program) evalVar(s *stmt.Var) []reflect.Value {
 		if name == "_" {
 			continue
 		}
-		t := p.reflector.ToRType(types[i])
+		t := p.toRType(types[i])
 		s := &Scope{
 			Parent:   p.Cur,
 			VarName:  name,
@@ -1276,6 +1404,66 @@ func promoteUntyped(x interface{}) interface{} {
 	}
 }
 
+// boolValue returns the value of a boolean, typed or untyped.
+func boolValue(v reflect.Value) bool {
+	if b, isUntyped := v.Interface().(UntypedBool); isUntyped {
+		return b.Bool
+	}
+	return v.Bool()
+}
+
+// goConst returns the exact value of the untyped constant name in
+// the Go package pkg, or the zero Value if it is not one. Wrappers
+// hold typed values, which cannot represent all untyped constants.
+func goConst(pkg tipe.Type, name string) reflect.Value {
+	goPkg, _ := pkg.(*tipe.Package).GoPkg.(*gotypes.Package)
+	if goPkg == nil {
+		return reflect.Value{}
+	}
+	c, isConst := goPkg.Scope().Lookup(name).(*gotypes.Const)
+	if !isConst {
+		return reflect.Value{}
+	}
+	basic, _ := c.Type().(*gotypes.Basic)
+	if basic == nil || basic.Info()&gotypes.IsUntyped == 0 {
+		return reflect.Value{}
+	}
+	val := c.Val()
+	switch basic.Kind() {
+	case gotypes.UntypedBool:
+		return reflect.ValueOf(UntypedBool{constant.BoolVal(val)})
+	case gotypes.UntypedString:
+		return reflect.ValueOf(UntypedString{constant.StringVal(val)})
+	case gotypes.UntypedRune:
+		r, _ := constant.Int64Val(val)
+		return reflect.ValueOf(UntypedRune{rune(r)})
+	case gotypes.UntypedInt:
+		i, ok := new(big.Int).SetString(val.ExactString(), 10)
+		if ok {
+			return reflect.ValueOf(UntypedInt{i})
+		}
+	case gotypes.UntypedFloat:
+		return reflect.ValueOf(UntypedFloat{bigFloat(val)})
+	case gotypes.UntypedComplex:
+		return reflect.ValueOf(UntypedComplex{bigFloat(constant.Real(val)), bigFloat(constant.Imag(val))})
+	}
+	return reflect.Value{}
+}
+
+func bigFloat(val constant.Value) *big.Float {
+	switch v := constant.Val(constant.ToFloat(val)).(type) {
+	case int64:
+		return new(big.Float).SetInt64(v)
+	case *big.Int:
+		return new(big.Float).SetInt(v)
+	case *big.Rat:
+		return new(big.Float).SetRat(v)
+	case *big.Float:
+		return new(big.Float).Set(v)
+	}
+	return new(big.Float)
+}
+
 // TODO merge convert func into typeConv func?
 func convert(v reflect.Value, t reflect.Type) reflect.Value {
 	if v.Type() == t {
@@ -1286,6 +1474,8 @@ func convert(v reflect.Value, t reflect.Type) reflect.Value {
 		return v // type conversion
 	case UntypedInt:
 		switch t {
+		case reflect.TypeOf(UntypedRune{}):
+			return reflect.ValueOf(UntypedRune{rune(val.Int64())})
 		case reflect.TypeOf(UntypedFloat{}):
 			res := UntypedFloat{new(big.Float)}
 			res.Float.SetInt(val.Int)
@@ -1323,6 +1513,13 @@ func convert(v reflect.Value, t reflect.Type) reflect.Value {
 			ret.Set(reflect.ValueOf(float64(f)))
 		case reflect.Complex64, reflect.Complex128:
 			ret.SetComplex(complex(float64(f), 0))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			// The typechecker only allows integral constants.
+			i, _ := val.Int64()
+			ret.SetInt(i)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			u, _ := val.Uint64()
+			ret.SetUint(u)
 		default:
 			ret.SetFloat(f)
 		}
@@ -1349,6 +1546,10 @@ func convert(v reflect.Value, t reflect.Type) reflect.Value {
 		}
 		return ret
 	case UntypedRune:
+		switch t {
+		case reflect.TypeOf(UntypedFloat{}), reflect.TypeOf(UntypedComplex{}):
+			return convert(reflect.ValueOf(UntypedInt{big.NewInt(int64(val.Rune))}), t)
+		}
 		ret := reflect.New(t).Elem()
 		r := val.Rune
 		if t.Kind() == reflect.Interface {
@@ -1366,7 +1567,23 @@ func convert(v reflect.Value, t reflect.Type) reflect.Value {
 			ret.SetBool(b)
 		}
 		return ret
+	case bool:
+		// The result of a comparison, an untyped bool.
+		ret := reflect.New(t).Elem()
+		switch {
+		case t == reflect.TypeOf(UntypedBool{}):
+			ret.Set(reflect.ValueOf(UntypedBool{val}))
+		case t.Kind() == reflect.Interface:
+			ret.Set(v)
+		default:
+			ret.SetBool(val)
+		}
+		return ret
 	default:
+		if bt := basicRTypes[t.Kind()]; bt != nil && v.Type() == bt {
+			// The result of an operation on a named type.
+			return v.Convert(t)
+		}
 		ret := reflect.New(t).Elem()
 		ret.Set(v)
 		return ret
@@ -1386,7 +1603,7 @@ func (p *Program) prepCall(e *expr.Call) (fn reflect.Value, args []reflect.Value
 	args = make([]reflect.Value, 0, len(e.Args))
 	i := 0
 	for _, arg := range e.Args {
-		vals := p.evalExpr(arg)
+		vals := p.implicitConv(arg, p.evalExpr(arg))
 		for _, v := range vals {
 			if t := fn.Type(); t.Kind() == reflect.Func && !t.IsVariadic() {
 				// Implicit interface conversion on use.
@@ -1434,29 +1651,23 @@ func (p *Program) evalExpr(e expr.Expr) []reflect.Value {
 		default:
 			v = reflect.ValueOf(val)
 		}
-		t := p.reflector.ToRType(p.Types.Type(e))
+		t := p.toRType(p.Types.Type(e))
 		return []reflect.Value{convert(v, t)}
 	case *expr.Binary:
 		lhs := p.evalExpr(e.Left)
 		switch e.Op {
-		case token.LogicalAnd:
-			v := lhs[0].Interface()
-			if !v.(bool) {
-				return []reflect.Value{reflect.ValueOf(false)}
-			}
-			rhs := p.evalExpr(e.Right)
-			v = rhs[0].Interface()
-			return []reflect.Value{reflect.ValueOf(v)}
-		case token.LogicalOr:
-			v := lhs[0].Interface()
-			if v.(bool) {
-				return []reflect.Value{reflect.ValueOf(true)}
-			}
-			rhs := p.evalExpr(e.Right)
-			v = rhs[0].Interface()
-			return []reflect.Value{reflect.ValueOf(v)}
+		case token.LogicalAnd, token.LogicalOr:
+			v := boolValue(lhs[0])
+			if v == (e.Op == token.LogicalAnd) {
+				v = boolValue(p.evalExprOne(e.Right))
+			}
+			t := p.toRType(p.Types.Type(e))
+			return []reflect.Value{convert(reflect.ValueOf(v), t)}
 		}
 		rhs := p.evalExpr(e.Right)
+		if p.isElemwise(e) {
+			return []reflect.Value{p.evalElemwise(e, lhs[0], rhs[0])}
+		}
 		if e.Op == token.Equal || e.Op == token.NotEqual {
 			lk := lhs[0].Kind()
 			rk := rhs[0].Kind()
@@ -1490,13 +1701,13 @@ func (p *Program) evalExpr(e expr.Expr) []reflect.Value {
 				}
 			}
 		}
-		x := lhs[0].Interface()
-		y := rhs[0].Interface()
+		x := unnamed(lhs[0]).Interface()
+		y := unnamed(rhs[0]).Interface()
 		v, err := binOp(e.Op, x, y)
 		if err != nil {
 			panic(interpPanic{err})
 		}
-		t := p.reflector.ToRType(p.Types.Type(e))
+		t := p.toRType(p.Types.Type(e))
 		return []reflect.Value{convert(reflect.ValueOf(v), t)}
 	case *expr.Call:
 		fn, args := p.prepCall(e)
@@ -1525,19 +1736,19 @@ func (p *Program) evalExpr(e expr.Expr) []reflect.Value {
 		}
 		return res
 	case *expr.CompLiteral:
-		t := p.reflector.ToRType(e.Type)
+		t := p.toRType(e.Type)
 		switch t.Kind() {
 		case reflect.Struct:
 			st := reflect.New(t).Elem()
 			if len(e.Keys) > 0 {
 				for i, elem := range e.Values {
 					name := e.Keys[i].(*expr.Ident).Name
-					v := p.evalExprOne(elem)
+					v := p.evalConvOne(elem)
 					setField(st.FieldByName(name), v)
 				}
 			} else {
 				for i, expr := range e.Values {
-					v := p.evalExprOne(expr)
+					v := p.evalConvOne(expr)
 					setField(st.Field(i), v)
 				}
 			}
@@ -1549,31 +1760,67 @@ func (p *Program) evalExpr(e expr.Expr) []reflect.Value {
 		case reflect.Map:
 			m := reflect.MakeMap(t)
 			for i, kexpr := range e.Keys {
-				k := p.evalExprOne(kexpr)
-				v := p.evalExprOne(e.Values[i])
+				k := p.evalConvOne(kexpr)
+				v := p.evalConvOne(e.Values[i])
 				m.SetMapIndex(k, v)
 			}
 			return []reflect.Value{m}
 		}
 	case *expr.FuncLiteral:
+		if len(e.Type.TypeParams) > 0 {
+			return []reflect.Value{reflect.ValueOf(p.genericFunc(e))}
+		}
 		return []reflect.Value{p.evalFuncLiteral(e, nil)}
 	case *expr.Ident:
 		if e.Name == "nil" { // TODO: make sure it's the Universe nil
-			t := p.reflector.ToRType(p.Types.Type(e))
+			t := p.toRType(p.Types.Type(e))
 			return []reflect.Value{reflect.New(t).Elem()}
 		}
+		obj := p.Types.Ident(e)
+		if obj == typecheck.Universe.Objs["iota"] {
+			v := reflect.ValueOf(UntypedInt{big.NewInt(p.iota)})
+			t := p.toRType(p.Types.Type(e))
+			return []reflect.Value{convert(v, t)}
+		}
 		if v := p.Cur.Lookup(e.Name); v != (reflect.Value{}) {
+			if v.Type() == genericFuncType {
+				v = p.instantiateFunc(v.Interface().(*genericFunc), e)
+			}
+			if obj != nil && obj.Kind == typecheck.ObjConst && typecheck.Universe.Objs[e.Name] != obj {
+				// An untyped constant takes the type
+				// required by its context.
+				v = convert(v, p.toRType(p.Types.Type(e)))
+			}
 			return []reflect.Value{v}
 		}
 		t := p.Types.Type(e)
 		if t != nil {
-			return []reflect.Value{reflect.ValueOf(p.reflector.ToRType(p.Types.Type(e)))}
+			return []reflect.Value{reflect.ValueOf(p.toRType(p.Types.Type(e)))}
 		}
 		panic(interpPanic{fmt.Errorf("eval: undefined identifier: %q", e.Name)})
 	case *expr.Index:
+		if p.Types.TypeArgs(e.Left) != nil {
+			// Explicit instantiation of a generic function,
+			// done when evaluating e.Left.
+			return p.evalExpr(e.Left)
+		}
+		if t, isNamed := p.Types.Type(e).(*tipe.Named); isNamed && t.Origin != nil {
+			if ident, isIdent := e.Left.(*expr.Ident); isIdent && p.Types.Ident(ident).Kind == typecheck.ObjType {
+				return []reflect.Value{reflect.ValueOf(p.toRType(t))}
+			}
+		}
 		container := p.evalExprOne(e.Left)
+		if container.Type() == tableType {
+			return []reflect.Value{p.evalTableRows(e, container.Interface().(*memframe.Memory))}
+		}
 		if len(e.Indicies) != 1 {
-			panic(interpPanic{fmt.Errorf("eval: TODO table slicing")})
+			// A type with an At(i, j int) T method.
+			at := container.MethodByName("At")
+			var args []reflect.Value
+			for _, index := range e.Indicies {
+				args = append(args, p.evalExprOne(index))
+			}
+			return at.Call(args)
 		}
 		if e, isSlice := e.Indicies[0].(*expr.Slice); isSlice {
 			var i, j int
@@ -1618,11 +1865,11 @@ func (p *Program) evalExpr(e expr.Expr) []reflect.Value {
 			panic(interpPanic{fmt.Errorf("eval: *expr.Index unsupported kind: %v", container.Kind())})
 		}
 	case *expr.MapLiteral:
-		t := p.reflector.ToRType(e.Type)
+		t := p.toRType(e.Type)
 		m := reflect.MakeMap(t)
 		for i, kexpr := range e.Keys {
-			k := p.evalExprOne(kexpr)
-			v := p.evalExprOne(e.Values[i])
+			k := p.evalConvOne(kexpr)
+			v := p.evalConvOne(e.Values[i])
 			m.SetMapIndex(k, v)
 		}
 		return []reflect.Value{m}
@@ -1631,6 +1878,19 @@ func (p *Program) evalExpr(e expr.Expr) []reflect.Value {
 		if lhs.Kind() == reflect.Ptr {
 			if pkg, ok := lhs.Interface().(*gowrap.Pkg); ok {
 				name := e.Right.Name
+				if typeArgs := p.Types.TypeArgs(e); typeArgs != nil {
+					path := p.Types.Type(e.Left).(*tipe.Package).Path
+					var targs []tipe.Type
+					for _, targ := range typeArgs {
+						targs = append(targs, tipe.Subst(targ, p.typeArgs))
+					}
+					return []reflect.Value{goInstance(path, name, targs)}
+				}
+				if c := goConst(p.Types.Type(e.Left), name); c != (reflect.Value{}) {
+					// An untyped constant takes the type
+					// required by its context.
+					return []reflect.Value{convert(c, p.toRType(p.Types.Type(e)))}
+				}
 				return []reflect.Value{pkg.Exports[name]}
 			}
 		}
@@ -1648,6 +1908,9 @@ func (p *Program) evalExpr(e expr.Expr) []reflect.Value {
 				v = lhs.FieldByName(e.Right.Name)
 			}
 		}
+		if v == (reflect.Value{}) {
+			v = p.promoted(lhs, p.Types.Type(e.Left), e.Right.Name)
+		}
 		return []reflect.Value{v}
 	case *expr.Shell:
 		p.pushScope()
@@ -1668,13 +1931,17 @@ func (p *Program) evalExpr(e expr.Expr) []reflect.Value {
 		nilerr := reflect.New(errt).Elem()
 		return []reflect.Value{str, nilerr}
 	case *expr.ArrayLiteral:
-		t := p.reflector.ToRType(e.Type)
+		t := p.toRType(e.Type)
 		return p.evalArrayLiteral(t, e.Keys, e.Values)
 	case *expr.SliceLiteral:
-		t := p.reflector.ToRType(e.Type)
+		t := p.toRType(e.Type)
 		return p.evalSliceLiteral(t, e.Keys, e.Values)
+	case *expr.TableLiteral:
+		return []reflect.Value{p.evalTableLiteral(e)}
+	case *expr.TableIndex:
+		return []reflect.Value{p.evalTableIndex(e)}
 	case *expr.Type:
-		t := p.reflector.ToRType(e.Type)
+		t := p.toRType(e.Type)
 		return []reflect.Value{reflect.ValueOf(t)}
 	case *expr.TypeAssert:
 		v := p.evalExprOne(e.Left)
@@ -1682,30 +1949,18 @@ func (p *Program) evalExpr(e expr.Expr) []reflect.Value {
 			// this is coming from a switch x.(type) statement
 			return []reflect.Value{v}
 		}
-		t := p.reflector.ToRType(e.Type)
-		convertible := v.Type().ConvertibleTo(t)
-		if convertible {
-			v = v.Convert(t)
-		} else {
-			if v.CanInterface() {
-				viface := v.Interface()
-				v = reflect.ValueOf(viface)
-				convertible = v.Type().ConvertibleTo(t)
-				if convertible {
-					v = v.Convert(t)
-				}
-			}
-			if !convertible {
-				v = reflect.Zero(t)
-			}
-		}
+		res, ok := p.typeAssert(v, e.Type)
 		if _, commaOK := p.Types.Type(e).(*tipe.Tuple); commaOK {
-			return []reflect.Value{v, reflect.ValueOf(convertible)}
+			return []reflect.Value{res, reflect.ValueOf(ok)}
 		}
-		if !convertible {
-			panic(Panic{val: fmt.Errorf("value of type %s cannot be converted to %s", v.Type(), e.Type)})
+		if !ok {
+			dyn := "nil"
+			if v.IsValid() && !(v.Kind() == reflect.Interface && v.IsNil()) {
+				dyn = reflect.TypeOf(v.Interface()).String()
+			}
+			panic(Panic{val: fmt.Errorf("interface conversion: interface is %s, not %s", dyn, format.Type(e.Type))})
 		}
-		return []reflect.Value{v}
+		return []reflect.Value{res}
 	case *expr.Unary:
 		var v reflect.Value
 		switch e.Op {
@@ -1719,6 +1974,14 @@ func (p *Program) evalExpr(e expr.Expr) []reflect.Value {
 			return []reflect.Value{v.Elem()}
 		case token.Not:
 			v = p.evalExprOne(e.Expr)
+			if v.Kind() == reflect.Interface {
+				// The operand took the type of the context.
+				v = v.Elem()
+			}
+			if _, isUntyped := v.Interface().(UntypedBool); isUntyped {
+				v = reflect.ValueOf(UntypedBool{!boolValue(v)})
+				break
+			}
 			b := v.Bool()
 			if !v.CanAddr() {
 				v = reflect.New(reflect.TypeOf(false)).Elem()
@@ -1757,12 +2020,12 @@ func (p *Program) evalExpr(e expr.Expr) []reflect.Value {
 			res, ok := ch.Recv()
 			switch et := p.Types.Type(e).(type) {
 			case *tipe.Tuple:
-				t := p.reflector.ToRType(et.Elems[0])
+				t := p.toRType(et.Elems[0])
 				return []reflect.Value{convert(res, t), reflect.ValueOf(ok)}
 			}
 			v = res
 		}
-		t := p.reflector.ToRType(p.Types.Type(e))
+		t := p.toRType(p.Types.Type(e))
 		return []reflect.Value{convert(v, t)}
 	}
 	panic(interpPanic{fmt.Errorf("TODO evalExpr(%s), %T", format.Expr(e), e)})
@@ -1800,7 +2063,7 @@ func (p *Program) evalFuncLiteral(e *expr.FuncLiteral, recvt *tipe.Named) reflec
 		params[0] = &tipe.Interface{} // not recvt, breaking cycle
 		funct.Params = &tipe.Tuple{params}
 	}
-	rt := p.reflector.ToRType(&funct)
+	rt := p.toRType(&funct)
 	fn := reflect.MakeFunc(rt, func(args []reflect.Value) (res []reflect.Value) {
 		p := &Program{
 			Universe:    p.Universe,
@@ -1808,6 +2071,8 @@ func (p *Program) evalFuncLiteral(e *expr.FuncLiteral, recvt *tipe.Named) reflec
 			Cur:         s,
 			reflector:   p.reflector,
 			typePlugins: p.typePlugins,
+			typeArgs:    p.typeArgs,
+			generics:    p.generics,
 		}
 		p.pushScope()
 		defer p.popScope()
@@ -1839,7 +2104,7 @@ func (p *Program) evalFuncLiteral(e *expr.FuncLiteral, recvt *tipe.Named) reflec
 			// The int x needs to become an interface{}.
 			res = make([]reflect.Value, len(funct.Results.Elems))
 			for i, et := range funct.Results.Elems {
-				t := p.reflector.ToRType(et)
+				t := p.toRType(et)
 				res[i] = reflect.New(t).Elem()
 			}
 		}
@@ -1877,13 +2142,13 @@ func (p *Program) evalArrayLiteral(t reflect.Type, keys, values []expr.Expr) []r
 	switch len(keys) {
 	case 0:
 		for i, elem := range values {
-			v := p.evalExprOne(elem)
+			v := p.evalConvOne(elem)
 			array.Index(i).Set(v)
 		}
 	default:
 		for i := range keys {
 			k := int(p.evalExprOne(keys[i]).Int())
-			v := p.evalExprOne(values[i])
+			v := p.evalConvOne(values[i])
 			array.Index(k).Set(v)
 		}
 	}
@@ -1895,7 +2160,7 @@ func (p *Program) evalSliceLiteral(t reflect.Type, keys, values []expr.Expr) []r
 	case 0:
 		slice := reflect.MakeSlice(t, len(values), len(values))
 		for i, elem := range values {
-			v := p.evalExprOne(elem)
+			v := p.evalConvOne(elem)
 			slice.Index(i).Set(v)
 		}
 		return []reflect.Value{slice}
@@ -1911,7 +2176,7 @@ func (p *Program) evalSliceLiteral(t reflect.Type, keys, values []expr.Expr) []r
 		}
 		slice := reflect.MakeSlice(t, n, n)
 		for i, elem := range values {
-			v := p.evalExprOne(elem)
+			v := p.evalConvOne(elem)
 			slice.Index(indices[i]).Set(v)
 		}
 		return []reflect.Value{slice}
@@ -1922,6 +2187,10 @@ type reflector struct {
 	mu  sync.RWMutex
 	fwd map[tipe.Type]reflect.Type
 	rev map[reflect.Type]tipe.Type
+
+	// goNamed, if set, reports the type checker's type for the
+	// Go named type pkgPath.name, or nil. See Checker.GoNamed.
+	goNamed func(pkgPath, name string) tipe.Type
 }
 
 func newReflector() *reflector {
@@ -2042,17 +2311,24 @@ func (r *reflector) toRType(t tipe.Type) reflect.Type {
 	case *tipe.Struct:
 		var fields []reflect.StructField
 		for _, f := range t.Fields {
+			ft := r.toRType(f.Type)
 			fields = append(fields, reflect.StructField{
-				Name:      f.Name,
-				Type:      r.toRType(f.Type),
-				Tag:       reflect.StructTag(f.Tag),
-				Anonymous: f.Embedded,
+				Name: f.Name,
+				Type: ft,
+				Tag:  reflect.StructTag(f.Tag),
+				// StructOf cannot promote methods, so an
+				// embedded type with methods is kept as a
+				// plain field. Program.promoted finds its
+				// members.
+				Anonymous: f.Embedded && ft.NumMethod() == 0,
 			})
 		}
 		rtype = reflect.StructOf(fields)
 	case *tipe.Named:
 		if typecheck.IsError(t) {
 			rtype = reflect.TypeOf((*error)(nil)).Elem()
+		} else if t.PkgPath != "" && len(t.TypeArgs) > 0 {
+			rtype = goInstance(t.PkgPath, t.Name, t.TypeArgs).Interface().(reflect.Type)
 		} else if t.PkgPath != "" {
 			path := t.PkgPath
 			v := gowrap.Pkgs[path].Exports[t.Name]
@@ -2072,7 +2348,8 @@ func (r *reflector) toRType(t tipe.Type) reflect.Type {
 		rtype = reflect.SliceOf(r.toRType(t.Elem))
 	case *tipe.Ellipsis:
 		rtype = reflect.SliceOf(r.toRType(t.Elem))
-	// TODO case *Table:
+	case *tipe.Table:
+		rtype = tableType
 	case *tipe.Pointer:
 		rtype = reflect.PtrTo(r.toRType(t.Elem))
 	case *tipe.Chan:
@@ -2102,8 +2379,259 @@ func (r *reflector) toRType(t tipe.Type) reflect.Type {
 	return rtype
 }
 
+// implicitConv converts the value of e to the interface type it
+// flows into, as determined by the typechecker.
+func (p *Program) implicitConv(e expr.Expr, v []reflect.Value) []reflect.Value {
+	t := p.Types.ImplicitConv(e)
+	if t == nil || len(v) != 1 || !v[0].IsValid() {
+		return v
+	}
+	rt := p.toRType(t)
+	if !v[0].Type().AssignableTo(rt) {
+		panic(interpPanic{fmt.Errorf("cannot convert %s (type %s) to %s", format.Expr(e), v[0].Type(), format.Type(t))})
+	}
+	iv := reflect.New(rt).Elem()
+	iv.Set(v[0])
+	return []reflect.Value{iv}
+}
+
+// promoted selects the field or method name of v, which has the
+// Neugram type t, by following the embedded fields that lead to it.
+// It is used when the Go type of v does not embed them itself, as
+// happens for reflect.StructOf types and for embedded types that are
+// printed as their underlying type by gengo.
+func (p *Program) promoted(v reflect.Value, t tipe.Type, name string) reflect.Value {
+	_, index, isMethod, _ := tipe.LookupFieldOrMethod(t, name)
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	if !isMethod {
+		return v
+	}
+	if m := v.MethodByName(name); m.IsValid() {
+		return m
+	}
+	if v.Kind() != reflect.Ptr && v.CanAddr() {
+		return v.Addr().MethodByName(name)
+	}
+	return reflect.Value{}
+}
+
+// typeAssert implements x.(T). For a non-interface T, the assertion
+// holds if the dynamic type of v is identical to T. For an interface
+// T, it holds if v is not nil and its dynamic type implements T.
+func (p *Program) typeAssert(v reflect.Value, t tipe.Type) (reflect.Value, bool) {
+	rt := p.toRType(t)
+	dyn := v
+	for dyn.IsValid() && dyn.Kind() == reflect.Interface {
+		if dyn.IsNil() {
+			dyn = reflect.Value{}
+			break
+		}
+		dyn = dyn.Elem()
+	}
+	if !dyn.IsValid() {
+		return reflect.Zero(rt), false
+	}
+	if rt.Kind() != reflect.Interface {
+		if dyn.Type() != rt {
+			return reflect.Zero(rt), false
+		}
+		return dyn, true
+	}
+	if !dyn.Type().Implements(rt) {
+		return reflect.Zero(rt), false
+	}
+	if iface, isIface := tipe.Underlying(t).(*tipe.Interface); isIface {
+		// Interfaces with methods are represented by interface{},
+		// so check the method set by name.
+		for name := range iface.Methods {
+			if _, ok := dyn.Type().MethodByName(name); !ok {
+				return reflect.Zero(rt), false
+			}
+		}
+	}
+	res := reflect.New(rt).Elem()
+	res.Set(dyn)
+	return res, true
+}
+
+// FromRType reports the Neugram type of values of the Go type rtype.
+//
+// A named Go type becomes a *tipe.Named with the methods of its
+// pointer type, so values of it can be used with those methods as
+// they are in Go. Its Neugram type converts back to rtype, keeping
+// what reflect cannot build, such as methods and unexported fields.
+// An exported type of a package the type checker can import is the
+// type the import gives, so host values mix with imported ones.
 func (r *reflector) FromRType(rtype reflect.Type) tipe.Type {
-	panic("TODO FromRType")
+	r.mu.RLock()
+	t := r.rev[rtype]
+	r.mu.RUnlock()
+
+	if t != nil {
+		return t
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fromRType(rtype)
+}
+
+var basicKinds = map[reflect.Kind]tipe.Type{
+	reflect.Bool:          tipe.Bool,
+	reflect.String:        tipe.String,
+	reflect.Int:           tipe.Int,
+	reflect.Int8:          tipe.Int8,
+	reflect.Int16:         tipe.Int16,
+	reflect.Int32:         tipe.Int32,
+	reflect.Int64:         tipe.Int64,
+	reflect.Uint:          tipe.Uint,
+	reflect.Uint8:         tipe.Uint8,
+	reflect.Uint16:        tipe.Uint16,
+	reflect.Uint32:        tipe.Uint32,
+	reflect.Uint64:        tipe.Uint64,
+	reflect.Uintptr:       tipe.Uintptr,
+	reflect.Float32:       tipe.Float32,
+	reflect.Float64:       tipe.Float64,
+	reflect.Complex64:     tipe.Complex64,
+	reflect.Complex128:    tipe.Complex128,
+	reflect.UnsafePointer: tipe.UnsafePointer,
+}
+
+func (r *reflector) fromRType(rtype reflect.Type) tipe.Type {
+	if t := r.rev[rtype]; t != nil {
+		return t
+	}
+	if rtype == reflect.TypeOf((*error)(nil)).Elem() {
+		return typecheck.Universe.Objs["error"].Type
+	}
+	if rtype.PkgPath() == "" {
+		if t := basicKinds[rtype.Kind()]; t != nil {
+			return t
+		}
+		if rtype.Kind() == reflect.Interface && rtype.NumMethod() == 0 {
+			return tipe.Any
+		}
+		t := r.underlying(rtype)
+		r.rev[rtype] = t
+		r.fwd[t] = rtype
+		return t
+	}
+
+	if r.goNamed != nil && isExported(rtype.Name()) && !strings.Contains(rtype.Name(), "[") {
+		if t := r.goNamed(rtype.PkgPath(), rtype.Name()); t != nil {
+			r.rev[rtype] = t
+			r.fwd[t] = rtype
+			return t
+		}
+	}
+
+	// A named type. It is recorded before its underlying type
+	// and methods are converted, which may refer to it.
+	t := &tipe.Named{
+		Name:    rtype.Name(),
+		PkgName: path.Base(rtype.PkgPath()),
+		PkgPath: rtype.PkgPath(),
+	}
+	r.rev[rtype] = t
+	r.fwd[t] = rtype
+	if rtype.Kind() == reflect.Interface {
+		t.Type = r.underlying(rtype)
+		return t
+	}
+	if basic := basicKinds[rtype.Kind()]; basic != nil {
+		t.Type = basic
+	} else {
+		t.Type = r.underlying(rtype)
+	}
+	ptr := reflect.PtrTo(rtype)
+	for i := 0; i < ptr.NumMethod(); i++ {
+		m := ptr.Method(i)
+		t.MethodNames = append(t.MethodNames, m.Name)
+		t.Methods = append(t.Methods, r.fromRFunc(m.Type, 1))
+		if _, isValue := rtype.MethodByName(m.Name); !isValue {
+			if t.PointerRecv == nil {
+				t.PointerRecv = make(map[string]bool)
+			}
+			t.PointerRecv[m.Name] = true
+		}
+	}
+	return t
+}
+
+// underlying converts the structure of the composite type rtype,
+// ignoring its name.
+func (r *reflector) underlying(rtype reflect.Type) tipe.Type {
+	switch rtype.Kind() {
+	case reflect.Array:
+		return &tipe.Array{
+			Len:  int64(rtype.Len()),
+			Elem: r.fromRType(rtype.Elem()),
+		}
+	case reflect.Slice:
+		return &tipe.Slice{Elem: r.fromRType(rtype.Elem())}
+	case reflect.Ptr:
+		return &tipe.Pointer{Elem: r.fromRType(rtype.Elem())}
+	case reflect.Chan:
+		ch := &tipe.Chan{Elem: r.fromRType(rtype.Elem())}
+		switch rtype.ChanDir() {
+		case reflect.BothDir:
+			ch.Direction = tipe.ChanBoth
+		case reflect.SendDir:
+			ch.Direction = tipe.ChanSend
+		case reflect.RecvDir:
+			ch.Direction = tipe.ChanRecv
+		}
+		return ch
+	case reflect.Map:
+		return &tipe.Map{
+			Key:   r.fromRType(rtype.Key()),
+			Value: r.fromRType(rtype.Elem()),
+		}
+	case reflect.Func:
+		return r.fromRFunc(rtype, 0)
+	case reflect.Struct:
+		s := new(tipe.Struct)
+		for i := 0; i < rtype.NumField(); i++ {
+			f := rtype.Field(i)
+			s.Fields = append(s.Fields, tipe.StructField{
+				Name:     f.Name,
+				Type:     r.fromRType(f.Type),
+				Tag:      tipe.StructTag(f.Tag),
+				Embedded: f.Anonymous,
+			})
+		}
+		return s
+	case reflect.Interface:
+		iface := &tipe.Interface{Methods: make(map[string]*tipe.Func)}
+		for i := 0; i < rtype.NumMethod(); i++ {
+			m := rtype.Method(i)
+			iface.Methods[m.Name] = r.fromRFunc(m.Type, 0)
+		}
+		return iface
+	}
+	panic(fmt.Sprintf("eval: FromRType: unknown kind %s of %s", rtype.Kind(), rtype))
+}
+
+// fromRFunc converts the function type rtype, without its first
+// skip parameters, such as the receiver of a method.
+func (r *reflector) fromRFunc(rtype reflect.Type, skip int) *tipe.Func {
+	fn := &tipe.Func{
+		Params:   &tipe.Tuple{Elems: make([]tipe.Type, rtype.NumIn()-skip)},
+		Results:  &tipe.Tuple{Elems: make([]tipe.Type, rtype.NumOut())},
+		Variadic: rtype.IsVariadic(),
+	}
+	for i := range fn.Params.Elems {
+		fn.Params.Elems[i] = r.fromRType(rtype.In(skip + i))
+	}
+	for i := range fn.Results.Elems {
+		fn.Results.Elems[i] = r.fromRType(rtype.Out(i))
+	}
+	return fn
 }
 
 func isError(t tipe.Type) bool {
@@ -10,10 +10,12 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"neugram.io/ng/eval/environ"
 	"neugram.io/ng/eval/shell"
@@ -21,6 +23,8 @@ import (
 	"neugram.io/ng/gotool"
 	"neugram.io/ng/parser"
 	"neugram.io/ng/syntax/stmt"
+	"neugram.io/ng/syntax/tipe"
+	"neugram.io/ng/syntax/token"
 )
 
 var exprTests = []struct {
@@ -78,6 +82,41 @@ var exprTests = []struct {
 	},
 }
 
+func TestUntypedBinOp(t *testing.T) {
+	// Untyped integers beyond the range of int64 against floats.
+	big70 := UntypedInt{new(big.Int).Lsh(big.NewInt(1), 70)}
+	tests := []struct {
+		op   token.Token
+		x, y interface{}
+		want interface{}
+	}{
+		{token.Greater, big70, UntypedFloat{big.NewFloat(1e20)}, true},
+		{token.Less, big70, UntypedFloat{big.NewFloat(1e21)}, false},
+		{token.LessEqual, big70, UntypedFloat{big.NewFloat(1e22)}, true},
+		{token.Less, UntypedFloat{big.NewFloat(1e20)}, big70, true},
+	}
+	for _, test := range tests {
+		got, err := binOp(test.op, test.x, test.y)
+		if err != nil {
+			t.Errorf("binOp(%s, %v, %v): %v", test.op, test.x, test.y, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("binOp(%s, %v, %v)=%v, want %v", test.op, test.x, test.y, got, test.want)
+		}
+	}
+
+	sum, err := binOp(token.Add, big70, UntypedFloat{big.NewFloat(0.5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := new(big.Float).SetInt(big70.Int)
+	want.Add(want, big.NewFloat(0.5))
+	if got := sum.(UntypedFloat).Float; got.Cmp(want) != 0 {
+		t.Errorf("1<<70 + 0.5 = %v, want %v", got, want)
+	}
+}
+
 func mkBasicProgram() (*Program, error) {
 	p := New("basic", nil)
 	if _, err := p.Eval(mustParse("x := 4"), nil); err != nil {
@@ -143,6 +182,255 @@ func mustParse(src string) stmt.Stmt {
 	return expr
 }
 
+func TestEmbed(t *testing.T) {
+	filename, err := filepath.Abs(filepath.Join("testdata", "embed", "config.ng"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(filename, nil)
+	if err := p.Define("prefix", reflect.ValueOf("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Define("upper", reflect.ValueOf(strings.ToUpper)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Define("cfg", reflect.Value{}); err == nil {
+		t.Error("Define of an invalid value succeeded")
+	}
+	if err := p.Load(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := p.Lookup("Greeting"); got.Interface() != "hello, world" {
+		t.Errorf("Greeting=%v, want %q", got, "hello, world")
+	}
+	if got := p.Lookup("Limit"); got.Interface() != 8080 {
+		t.Errorf("Limit=%v, want 8080", got)
+	}
+	if got := p.Lookup("print"); got.IsValid() {
+		t.Errorf("Lookup of a builtin found %v", got)
+	}
+
+	type config struct {
+		Name string
+		Port int
+	}
+	if _, err := p.Call("Validate", config{"web", 80}); err != nil {
+		t.Errorf("Validate(web): %v", err)
+	}
+	if _, err := p.Call("Validate", config{"db", -1}); err == nil || err.Error() != "db: bad port -1" {
+		t.Errorf("Validate(db) error: %v, want bad port", err)
+	}
+
+	tests := []struct {
+		name string
+		args []interface{}
+		want interface{}
+	}{
+		{"Shout", []interface{}{"hi"}, "HI!"},
+		{"Sum", nil, 0},
+		{"Sum", []interface{}{1, 2, 3}, 6},
+	}
+	for _, test := range tests {
+		res, err := p.Call(test.name, test.args...)
+		if err != nil {
+			t.Errorf("%s%v: %v", test.name, test.args, err)
+			continue
+		}
+		if len(res) != 1 || res[0] != test.want {
+			t.Errorf("%s%v=%v, want %v", test.name, test.args, res, test.want)
+		}
+	}
+
+	if _, err := p.Call("Shout", 1); err == nil {
+		t.Error("Shout(1) succeeded, want argument error")
+	}
+	if _, err := p.Call("Fail"); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("Fail error: %v, want panic", err)
+	}
+	if _, err := p.Call("Missing"); err == nil {
+		t.Error("Missing() succeeded")
+	}
+}
+
+func TestLoadPackage(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "ngpath", "example.com", "multi.ng"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(dir, nil)
+	if err := p.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Lookup("Total"); got.Interface() != 26 {
+		t.Errorf("Total=%v, want 26", got)
+	}
+	res, err := p.Call("Count", []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0] != 11 {
+		t.Errorf("Count([1])=%v, want 11", res)
+	}
+}
+
+func TestDefinePackage(t *testing.T) {
+	p := New("host", nil)
+	var logged []string
+	err := p.DefinePackage("app", map[string]interface{}{
+		"Name": "ng",
+		"Log": func(s string) {
+			logged = append(logged, s)
+		},
+		"Ports": map[string]int{"web": 80},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.DefinePackage("bad", map[string]interface{}{"hidden": 1}); err == nil {
+		t.Error("DefinePackage of an unexported member succeeded")
+	}
+
+	src := `app.Log(app.Name + ":" + strconv.Itoa(app.Ports["web"]))`
+	if _, err := p.Eval(mustParse(`import "strconv"`), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Eval(mustParse(src), nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"ng:80"}; !reflect.DeepEqual(logged, want) {
+		t.Errorf("logged %q, want %q", logged, want)
+	}
+	if _, err := p.Eval(mustParse("app.Missing()"), nil); err == nil {
+		t.Error("app.Missing() succeeded, want typecheck error")
+	}
+}
+
+type testList struct {
+	Value int       `json:"value"`
+	Next  *testList `json:"next,omitempty"`
+}
+
+func (l *testList) Len() int {
+	if l == nil {
+		return 0
+	}
+	return 1 + l.Next.Len()
+}
+
+type testCelsius float64
+
+func (c testCelsius) String() string { return fmt.Sprintf("%.1f°C", float64(c)) }
+
+func TestFromRType(t *testing.T) {
+	r := newReflector()
+	rtypes := []reflect.Type{
+		reflect.TypeOf(testList{}),
+		reflect.TypeOf(testCelsius(0)),
+		reflect.TypeOf((*fmt.Stringer)(nil)).Elem(),
+		reflect.TypeOf((*error)(nil)).Elem(),
+		reflect.TypeOf(struct {
+			A int `ng:"a"`
+			b []string
+		}{}),
+		reflect.TypeOf(func(string, ...interface{}) (int, error) { return 0, nil }),
+		reflect.TypeOf(make(<-chan [4]byte)),
+		reflect.TypeOf(map[string]*testList{}),
+		reflect.TypeOf(new(strings.Builder)),
+	}
+	for _, rtype := range rtypes {
+		typ := r.FromRType(rtype)
+		if got := r.ToRType(typ); got != rtype {
+			t.Errorf("ToRType(FromRType(%s))=%s", rtype, got)
+		}
+		if again := r.FromRType(rtype); again != typ {
+			t.Errorf("FromRType(%s) is not cached", rtype)
+		}
+	}
+
+	list := r.FromRType(reflect.TypeOf(testList{})).(*tipe.Named)
+	if list.Name != "testList" || list.PkgName != "eval" {
+		t.Errorf("testList is named %s.%s", list.PkgName, list.Name)
+	}
+	if want := []string{"Len"}; !reflect.DeepEqual(list.MethodNames, want) {
+		t.Errorf("testList methods %v, want %v", list.MethodNames, want)
+	}
+	fields := list.Type.(*tipe.Struct).Fields
+	if next := fields[1].Type.(*tipe.Pointer).Elem; next != list {
+		t.Errorf("testList.Next is %s, want *testList", format.Type(next))
+	}
+	if tag := fields[0].Tag; tag != `json:"value"` {
+		t.Errorf("testList.Value tag is %q", tag)
+	}
+	stringer := r.FromRType(reflect.TypeOf((*fmt.Stringer)(nil)).Elem()).(*tipe.Named)
+	if iface := stringer.Type.(*tipe.Interface); iface.Methods["String"] == nil {
+		t.Errorf("fmt.Stringer has methods %v", iface.Methods)
+	}
+}
+
+func TestDefineNamed(t *testing.T) {
+	p := New("host", nil)
+	list := &testList{Value: 1, Next: &testList{Value: 2}}
+	if err := p.Define("list", reflect.ValueOf(list)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Define("temp", reflect.ValueOf(testCelsius(21.5))); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{"list.Next.Value + list.Value", 3},
+		{"list.Len()", 2},
+		{"temp.String()", "21.5°C"},
+		{"float64(temp) * 2", 43.0},
+	}
+	for _, test := range tests {
+		res, err := p.Eval(mustParse(test.src), nil)
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+		if got := res[0].Interface(); got != test.want {
+			t.Errorf("%s=%v, want %v", test.src, got, test.want)
+		}
+	}
+	if _, err := p.Eval(mustParse("list.Missing"), nil); err == nil {
+		t.Error("list.Missing succeeded, want typecheck error")
+	}
+}
+
+func TestDefineImported(t *testing.T) {
+	p := New("host", nil)
+	if err := p.Define("d", reflect.ValueOf(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Define("sb", reflect.ValueOf(new(strings.Builder))); err != nil {
+		t.Fatal(err)
+	}
+	stmts := []string{
+		`import "time"`,
+		`import "strings"`,
+		`b := sb`,
+		`b = new(strings.Builder)`,
+		`b.WriteString("x")`,
+	}
+	for _, src := range stmts {
+		if _, err := p.Eval(mustParse(src), nil); err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+	}
+	src := "d + time.Millisecond"
+	res, err := p.Eval(mustParse(src), nil)
+	if err != nil {
+		t.Fatalf("%s: %v", src, err)
+	}
+	if got, want := res[0].Interface(), time.Second+time.Millisecond; got != want {
+		t.Errorf("%s=%v, want %v", src, got, want)
+	}
+}
+
 var errRE = regexp.MustCompile(`ERROR: (.*)`)
 
 func TestPrograms(t *testing.T) {
@@ -161,6 +449,22 @@ func TestPrograms(t *testing.T) {
 	}()
 	defer gotool.M.Cleanup()
 
+	// Build plugins afresh, without touching the user's cache.
+	cacheDir, err := ioutil.TempDir("", "ng-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+	defer os.Setenv("NGCACHE", os.Getenv("NGCACHE"))
+	os.Setenv("NGCACHE", cacheDir)
+
+	ngpath, err := filepath.Abs(filepath.Join("testdata", "ngpath"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("NGPATH", os.Getenv("NGPATH"))
+	os.Setenv("NGPATH", ngpath)
+
 	for _, file := range files {
 		// For the file "testdata/name.ng", name the subtest "name".
 		test := file[len("testdata/") : len(file)-3]
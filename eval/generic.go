@@ -0,0 +1,229 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eval
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"neugram.io/ng/eval/gowrap"
+	"neugram.io/ng/eval/gowrap/genwrap"
+	"neugram.io/ng/format"
+	"neugram.io/ng/gotool"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/stmt"
+	"neugram.io/ng/syntax/tipe"
+)
+
+// generics holds the generic declarations of a program and the
+// methodik types instantiated from them. It is shared by all the
+// Programs evaluating function bodies.
+type generics struct {
+	mu        sync.Mutex
+	methodiks map[*tipe.Named]genericMethodik // generic methodik by origin type
+	built     map[*tipe.Named]bool            // instances with a reflect type
+}
+
+type genericMethodik struct {
+	decl  *stmt.MethodikDecl
+	scope *Scope // scope the methodik was declared in
+}
+
+func newGenerics() *generics {
+	return &generics{
+		methodiks: make(map[*tipe.Named]genericMethodik),
+		built:     make(map[*tipe.Named]bool),
+	}
+}
+
+// genericFunc is the value of a generic function. Each use of it is
+// instantiated with the type arguments found by the typechecker.
+type genericFunc struct {
+	lit      *expr.FuncLiteral
+	scope    *Scope                        // scope the function was declared in
+	typeArgs map[*tipe.TypeParam]tipe.Type // of an enclosing generic function
+
+	mu        sync.Mutex
+	instances map[string]reflect.Value
+}
+
+var genericFuncType = reflect.TypeOf((*genericFunc)(nil))
+
+func (p *Program) genericFunc(e *expr.FuncLiteral) *genericFunc {
+	return &genericFunc{
+		lit:       e,
+		scope:     p.Cur,
+		typeArgs:  p.typeArgs,
+		instances: make(map[string]reflect.Value),
+	}
+}
+
+// instantiateFunc returns the instance of gf named by the expression e.
+func (p *Program) instantiateFunc(gf *genericFunc, e expr.Expr) reflect.Value {
+	targs := p.Types.TypeArgs(e)
+	tparams := gf.lit.Type.TypeParams
+	if len(targs) != len(tparams) {
+		panic(interpPanic{fmt.Errorf("eval: cannot use generic function %s without instantiation", gf.lit.Name)})
+	}
+	m := make(map[*tipe.TypeParam]tipe.Type, len(gf.typeArgs)+len(tparams))
+	for tp, arg := range gf.typeArgs {
+		m[tp] = arg
+	}
+	var key []string
+	for i, tp := range tparams {
+		arg := tipe.Subst(targs[i], p.typeArgs)
+		m[tp] = arg
+		key = append(key, format.Type(arg))
+	}
+	k := strings.Join(key, ", ")
+
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	if fn, ok := gf.instances[k]; ok {
+		return fn
+	}
+	fp := &Program{
+		Universe:    p.Universe,
+		Types:       p.Types,
+		Cur:         gf.scope,
+		reflector:   p.reflector,
+		typePlugins: p.typePlugins,
+		typeArgs:    m,
+		generics:    p.generics,
+	}
+	fn := fp.evalFuncLiteral(gf.lit, nil)
+	gf.instances[k] = fn
+	return fn
+}
+
+// toRType returns the reflect type of t, after substituting the type
+// arguments of the generic function being evaluated.
+func (p *Program) toRType(t tipe.Type) reflect.Type {
+	t = tipe.Subst(t, p.typeArgs)
+	p.generics.mu.Lock()
+	var insts []*tipe.Named
+	if len(p.generics.methodiks) > 0 {
+		insts = p.generics.uninstantiated(t, nil, make(map[tipe.Type]bool))
+	}
+	p.generics.mu.Unlock()
+	for _, inst := range insts {
+		p.generics.mu.Lock()
+		g := p.generics.methodiks[inst.Origin]
+		p.generics.mu.Unlock()
+		p.methodikInstance(inst, g)
+	}
+	return p.reflector.ToRType(t)
+}
+
+// uninstantiated appends to insts the instances of generic methodiks
+// in t that do not yet have a reflect type, and marks them as built.
+// Type arguments come before the instances that use them.
+// It is called with g.mu held.
+func (g *generics) uninstantiated(t tipe.Type, insts []*tipe.Named, seen map[tipe.Type]bool) []*tipe.Named {
+	if t == nil || seen[t] {
+		return insts
+	}
+	seen[t] = true
+	switch t := t.(type) {
+	case *tipe.Named:
+		if t.Origin == nil {
+			return insts
+		}
+		for _, arg := range t.TypeArgs {
+			insts = g.uninstantiated(arg, insts, seen)
+		}
+		insts = g.uninstantiated(t.Type, insts, seen)
+		if _, isMethodik := g.methodiks[t.Origin]; isMethodik && !g.built[t] {
+			g.built[t] = true
+			insts = append(insts, t)
+		}
+	case *tipe.Pointer:
+		insts = g.uninstantiated(t.Elem, insts, seen)
+	case *tipe.Slice:
+		insts = g.uninstantiated(t.Elem, insts, seen)
+	case *tipe.Array:
+		insts = g.uninstantiated(t.Elem, insts, seen)
+	case *tipe.Ellipsis:
+		insts = g.uninstantiated(t.Elem, insts, seen)
+	case *tipe.Chan:
+		insts = g.uninstantiated(t.Elem, insts, seen)
+	case *tipe.Map:
+		insts = g.uninstantiated(t.Key, insts, seen)
+		insts = g.uninstantiated(t.Value, insts, seen)
+	case *tipe.Tuple:
+		if t != nil {
+			for _, elem := range t.Elems {
+				insts = g.uninstantiated(elem, insts, seen)
+			}
+		}
+	case *tipe.Func:
+		if t != nil {
+			insts = g.uninstantiated(t.Params, insts, seen)
+			insts = g.uninstantiated(t.Results, insts, seen)
+		}
+	case *tipe.Struct:
+		for _, sf := range t.Fields {
+			insts = g.uninstantiated(sf.Type, insts, seen)
+		}
+	}
+	return insts
+}
+
+// methodikInstance builds the reflect type for inst, an instance of
+// the generic methodik g.
+func (p *Program) methodikInstance(inst *tipe.Named, g genericMethodik) {
+	m := make(map[*tipe.TypeParam]tipe.Type)
+	for i, tp := range inst.Origin.TypeParams {
+		m[tp] = inst.TypeArgs[i]
+	}
+	var methods []*expr.FuncLiteral
+	for i, lit := range g.decl.Methods {
+		method := *lit
+		method.Type = inst.Methods[i]
+		methods = append(methods, &method)
+	}
+	mp := &Program{
+		Universe:    p.Universe,
+		Types:       p.Types,
+		Cur:         g.scope,
+		reflector:   p.reflector,
+		typePlugins: p.typePlugins,
+		typeArgs:    m,
+		generics:    p.generics,
+	}
+	rtype, err := mp.reflectNamedType(inst, methods)
+	if err != nil {
+		panic(interpPanic{err})
+	}
+	p.reflector.mu.Lock()
+	p.reflector.fwd[inst] = rtype
+	p.reflector.mu.Unlock()
+}
+
+// goInstance returns the instance of the generic Go function or type
+// pkgPath.name with the type arguments targs. Instances are wrapped
+// by plugins, built the first time they are used.
+func goInstance(pkgPath, name string, targs []tipe.Type) reflect.Value {
+	key, err := genwrap.InstanceKey(pkgPath, name, targs)
+	if err != nil {
+		panic(interpPanic{err})
+	}
+	if v, ok := gowrap.Instances[key]; ok {
+		return v
+	}
+	gen := func() ([]byte, error) {
+		return genwrap.GenInstance(pkgPath, name, targs, "main")
+	}
+	if _, err := gotool.M.CreateWrapper(pkgPath, genwrap.Version+" "+key, gen); err != nil {
+		panic(interpPanic{err})
+	}
+	v, ok := gowrap.Instances[key]
+	if !ok {
+		panic(interpPanic{fmt.Errorf("plugin: instance %s missing from plugin", key)})
+	}
+	return v
+}
@@ -7,20 +7,42 @@
 package main
 
 import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/types"
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 	"strings"
 
 	"neugram.io/ng/eval/gowrap/genwrap"
+	"neugram.io/ng/gotool"
 )
 
+// unwrapped are packages that the exported symbols of builtin
+// packages refer to, which are not builtins themselves. Values of
+// their types can be passed around, but the packages themselves
+// have to be imported with a plugin.
+var unwrapped = map[string]bool{
+	"encoding/json/jsontext": true,
+	"encoding/json/v2":       true,
+	"iter":                   true,
+	"reflect":                true,
+	"syscall":                true,
+	"text/template/parse":    true,
+}
+
 func main() {
 	pkgName := os.Args[1]
 
 	// Set skipDeps to true, which means we have to
 	// be careful to include the required dependencies
 	// in our builtins.
+	if err := checkDeps(pkgName); err != nil {
+		log.Fatal(err)
+	}
 	b, err := genwrap.GenGo(pkgName, "wrapbuiltin", true)
 	if err != nil {
 		log.Fatal(err)
@@ -31,3 +53,127 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// checkDeps checks that every package the exported symbols of
+// pkgName refer to is generated before it, by an earlier
+// go:generate line in gowrap.go.
+func checkDeps(pkgName string) error {
+	builtins, err := builtins()
+	if err != nil {
+		return err
+	}
+	pos, ok := builtins[pkgName]
+	if !ok {
+		return fmt.Errorf("genwrap: %s has no go:generate line in gowrap.go", pkgName)
+	}
+	pkg, err := gotool.M.ImportGo(pkgName)
+	if err != nil {
+		return err
+	}
+	for _, dep := range apiDeps(pkg) {
+		if unwrapped[dep] || isInternal(dep) {
+			continue
+		}
+		if i, ok := builtins[dep]; !ok || i >= pos {
+			return fmt.Errorf("genwrap: %s refers to %s, which must be generated before it in gowrap.go", pkgName, dep)
+		}
+	}
+	return nil
+}
+
+// builtins reports the position of each package in the
+// go:generate lines of gowrap.go.
+func builtins() (map[string]int, error) {
+	f, err := os.Open("gowrap.go")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	const prefix = "//go:generate go run genwrap.go "
+	pkgs := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, prefix) {
+			pkgs[strings.TrimSpace(line[len(prefix):])] = len(pkgs)
+		}
+	}
+	return pkgs, scanner.Err()
+}
+
+// apiDeps reports the packages that define the types of
+// the exported symbols of pkg.
+func apiDeps(pkg *types.Package) []string {
+	deps := make(map[string]bool)
+	seen := make(map[types.Type]bool)
+	var walk func(t types.Type)
+	walk = func(t types.Type) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		switch t := t.(type) {
+		case *types.Named:
+			if p := t.Obj().Pkg(); p != nil && p != pkg {
+				deps[p.Path()] = true
+				return
+			}
+			walk(t.Underlying())
+			for i := 0; i < t.NumMethods(); i++ {
+				if m := t.Method(i); m.Exported() {
+					walk(m.Type())
+				}
+			}
+		case *types.Alias:
+			walk(types.Unalias(t))
+		case *types.Pointer:
+			walk(t.Elem())
+		case *types.Slice:
+			walk(t.Elem())
+		case *types.Array:
+			walk(t.Elem())
+		case *types.Chan:
+			walk(t.Elem())
+		case *types.Map:
+			walk(t.Key())
+			walk(t.Elem())
+		case *types.Signature:
+			for i := 0; i < t.Params().Len(); i++ {
+				walk(t.Params().At(i).Type())
+			}
+			for i := 0; i < t.Results().Len(); i++ {
+				walk(t.Results().At(i).Type())
+			}
+		case *types.Struct:
+			for i := 0; i < t.NumFields(); i++ {
+				if f := t.Field(i); f.Exported() {
+					walk(f.Type())
+				}
+			}
+		case *types.Interface:
+			for i := 0; i < t.NumMethods(); i++ {
+				walk(t.Method(i).Type())
+			}
+		}
+	}
+	for _, name := range pkg.Scope().Names() {
+		if ast.IsExported(name) {
+			walk(pkg.Scope().Lookup(name).Type())
+		}
+	}
+	var res []string
+	for dep := range deps {
+		res = append(res, dep)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func isInternal(path string) bool {
+	for _, dir := range strings.Split(path, "/") {
+		if dir == "internal" || dir == "vendor" {
+			return true
+		}
+	}
+	return false
+}
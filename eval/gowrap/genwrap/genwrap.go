@@ -8,15 +8,22 @@ import (
 	"bytes"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/format"
 	"go/types"
 	"log"
+	"math"
 	"strings"
 	"text/template"
 
 	"neugram.io/ng/gotool"
 )
 
+// Version identifies the wrappers GenGo generates. It must be
+// changed whenever they change, as plugins built from them are
+// cached on disk keyed by it.
+const Version = "3"
+
 func quotePkgPath(path string) string {
 	return "wrap_" + strings.NewReplacer(
 		"/", "_",
@@ -25,26 +32,36 @@ func quotePkgPath(path string) string {
 	).Replace(path)
 }
 
-func buildDataPkg(pkgPath string, pkg *types.Package) DataPkg {
+func buildDataPkg(pkgPath string, pkg *types.Package, skip map[string]bool) DataPkg {
 	quotedPkgPath := quotePkgPath(pkgPath)
 	scope := pkg.Scope()
 	exports := map[string]string{}
 	for _, name := range scope.Names() {
-		if !ast.IsExported(name) {
+		if !ast.IsExported(name) || skip[name] {
 			continue
 		}
 		obj := scope.Lookup(name)
-		switch obj.(type) {
+		if isGeneric(obj) {
+			continue // see GenInstance
+		}
+		switch obj := obj.(type) {
 		case *types.TypeName:
-			if _, ok := obj.Type().Underlying().(*types.Interface); ok {
+			if iface, ok := obj.Type().Underlying().(*types.Interface); ok {
+				if !iface.IsMethodSet() {
+					continue // a constraint, such as cmp.Ordered
+				}
 				exports[name] = "reflect.ValueOf(reflect.TypeOf((*" + quotedPkgPath + "." + name + ")(nil)).Elem())"
 			} else {
 				exports[name] = "reflect.ValueOf(reflect.TypeOf(" + quotedPkgPath + "." + name + nilexpr(obj.Type()) + "))"
 			}
 		case *types.Var:
 			exports[name] = "reflect.ValueOf(&" + quotedPkgPath + "." + name + ").Elem()"
-		case *types.Func, *types.Const:
+		case *types.Func:
 			exports[name] = "reflect.ValueOf(" + quotedPkgPath + "." + name + ")"
+		case *types.Const:
+			if export := constExpr(quotedPkgPath+"."+name, obj); export != "" {
+				exports[name] = export
+			}
 		default:
 			log.Printf("genwrap: unexpected obj: %T\n", obj)
 		}
@@ -57,6 +74,54 @@ func buildDataPkg(pkgPath string, pkg *types.Package) DataPkg {
 	}
 }
 
+// isGeneric reports whether obj is a generic function or type.
+func isGeneric(obj types.Object) bool {
+	switch t := obj.Type().(type) {
+	case *types.Signature:
+		return t.TypeParams().Len() > 0
+	case *types.Named:
+		_, isTypeName := obj.(*types.TypeName)
+		return isTypeName && t.TypeParams().Len() > 0
+	case *types.Alias:
+		return t.TypeParams().Len() > 0
+	}
+	return false
+}
+
+// constExpr returns the expression wrapping the constant ref.
+//
+// An untyped constant takes its default type, unless its value
+// overflows it: integers too large for an int are kept as a uint64,
+// and any other constant no Go type holds is left out. Programs
+// using the constant are given its exact value by the typechecker.
+func constExpr(ref string, obj *types.Const) string {
+	basic, isBasic := obj.Type().(*types.Basic)
+	if !isBasic || basic.Info()&types.IsUntyped == 0 {
+		return "reflect.ValueOf(" + ref + ")"
+	}
+	switch v := obj.Val(); v.Kind() {
+	case constant.Int:
+		if _, exact := constant.Int64Val(v); exact {
+			break
+		}
+		if _, exact := constant.Uint64Val(v); exact {
+			return "reflect.ValueOf(uint64(" + ref + "))"
+		}
+		return ""
+	case constant.Float:
+		if f, _ := constant.Float64Val(v); math.IsInf(f, 0) {
+			return ""
+		}
+	case constant.Complex:
+		re, _ := constant.Float64Val(constant.Real(v))
+		im, _ := constant.Float64Val(constant.Imag(v))
+		if math.IsInf(re, 0) || math.IsInf(im, 0) {
+			return ""
+		}
+	}
+	return "reflect.ValueOf(" + ref + ")"
+}
+
 // GenGo generates a wrapper package naemd outPkgName that
 // registers the exported symbols of pkgPath with the global
 // map gopkg.Pkgs.
@@ -64,36 +129,23 @@ func buildDataPkg(pkgPath string, pkg *types.Package) DataPkg {
 // Any other packages that pkgPath depends on for defining its
 // exported symbols are also registered, unless skipDeps is set.
 func GenGo(pkgPath, outPkgName string, skipDeps bool) ([]byte, error) {
-	pkg, err := gotool.M.ImportGo(pkgPath)
+	paths, err := Wrapped(pkgPath, skipDeps)
 	if err != nil {
 		return nil, err
 	}
 	pkgs := make(map[string]DataPkg)
-	pkgs[pkgPath] = buildDataPkg(pkgPath, pkg)
-	imports := []*types.Package{}
-	if !skipDeps {
-		for _, imp := range pkg.Imports() {
-			// Re-import package to get all exported symbols.
-			imppkg, err := gotool.M.ImportGo(imp.Path())
-			if err != nil {
-				return nil, err
-			}
-			imports = append(imports, imppkg)
-		}
-	}
-
-importsLoop:
-	for i := 0; i < len(imports); i++ { // imports grows as we loop
-		path := imports[i].Path()
-		if _, exists := pkgs[path]; exists {
-			continue
+	for _, path := range paths {
+		// Re-import package to get all exported symbols.
+		pkg, err := gotool.M.ImportGo(path)
+		if err != nil {
+			return nil, err
 		}
-		for _, dir := range strings.Split(path, "/") {
-			if dir == "internal" || dir == "vendor" {
-				continue importsLoop
-			}
+		// Leave out names only some builds of Go declare.
+		skip, err := toolchainDecls(path)
+		if err != nil {
+			return nil, err
 		}
-		pkgs[path] = buildDataPkg(path, imports[i])
+		pkgs[path] = buildDataPkg(path, pkg, skip)
 	}
 	data := Data{
 		OutPkgName: outPkgName,
@@ -118,6 +170,31 @@ importsLoop:
 	return res, nil
 }
 
+// Wrapped reports the packages registered by the wrapper GenGo
+// generates for pkgPath: pkgPath itself and, unless skipDeps is
+// set, the packages it imports other than internal and vendored
+// packages.
+func Wrapped(pkgPath string, skipDeps bool) ([]string, error) {
+	pkg, err := gotool.M.ImportGo(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{pkgPath}
+	if skipDeps {
+		return paths, nil
+	}
+importsLoop:
+	for _, imp := range pkg.Imports() {
+		for _, dir := range strings.Split(imp.Path(), "/") {
+			if dir == "internal" || dir == "vendor" {
+				continue importsLoop
+			}
+		}
+		paths = append(paths, imp.Path())
+	}
+	return paths, nil
+}
+
 func nilexpr(t types.Type) string {
 	t = t.Underlying()
 	switch t := t.(type) {
@@ -163,7 +240,7 @@ import (
 	"neugram.io/ng/eval/gowrap"
 
 {{range .Pkgs}}
-	{{.QuotedName}} "{{.Name}}"
+	{{if .Exports}}{{.QuotedName}}{{else}}_{{end}} "{{.Name}}"
 {{end}}
 )
 
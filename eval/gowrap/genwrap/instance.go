@@ -0,0 +1,260 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genwrap
+
+import (
+	"bytes"
+	"fmt"
+	goformat "go/format"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"neugram.io/ng/format"
+	"neugram.io/ng/gotool"
+	"neugram.io/ng/syntax/tipe"
+	"neugram.io/ng/typecheck"
+)
+
+// Generic functions and types cannot be wrapped by GenGo, as only
+// their instances have values. Each instance a program uses is
+// wrapped on its own by GenInstance, and registered with the map
+// gowrap.Instances under the key returned by InstanceKey.
+
+// InstanceKey returns the key that the instance of the generic
+// function or type pkgPath.name with the type arguments targs is
+// registered under in gowrap.Instances. It is the instance in Go
+// syntax, qualified by package path, such as
+//
+//	slices.Index[[]time.Duration, time.Duration]
+func InstanceKey(pkgPath, name string, targs []tipe.Type) (string, error) {
+	return instance(pkgPath, name, targs, func(path string) string { return path })
+}
+
+func instance(pkgPath, name string, targs []tipe.Type, qual func(path string) string) (string, error) {
+	var args []string
+	for _, t := range targs {
+		arg, err := goType(t, qual)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, arg)
+	}
+	return qual(pkgPath) + "." + name + "[" + strings.Join(args, ", ") + "]", nil
+}
+
+// GenInstance generates a wrapper package named outPkgName that
+// registers the instance of the generic function or type
+// pkgPath.name with the type arguments targs.
+//
+// Only Go types can be type arguments, as a type declared by a
+// Neugram program has no Go name.
+func GenInstance(pkgPath, name string, targs []tipe.Type, outPkgName string) ([]byte, error) {
+	pkg, err := gotool.M.ImportGo(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := InstanceKey(pkgPath, name, targs)
+	if err != nil {
+		return nil, err
+	}
+	imports := map[string]bool{pkgPath: true}
+	inst, err := instance(pkgPath, name, targs, func(path string) string {
+		imports[path] = true
+		return quotePkgPath(path)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := instanceData{
+		OutPkgName: outPkgName,
+		Key:        strconv.Quote(key),
+	}
+	switch obj := pkg.Scope().Lookup(name).(type) {
+	case *types.Func:
+		data.Value = "reflect.ValueOf(" + inst + ")"
+	case *types.TypeName:
+		data.Value = "reflect.ValueOf(reflect.TypeOf((*" + inst + ")(nil)).Elem())"
+	default:
+		return nil, fmt.Errorf("genwrap: %s.%s is not a generic function or type (%T)", pkgPath, name, obj)
+	}
+	for path := range imports {
+		data.Imports = append(data.Imports, DataPkg{Name: path, QuotedName: quotePkgPath(path)})
+	}
+	sort.Slice(data.Imports, func(i, j int) bool { return data.Imports[i].Name < data.Imports[j].Name })
+
+	buf := new(bytes.Buffer)
+	if err := instanceTmpl.Execute(buf, data); err != nil {
+		return nil, fmt.Errorf("genwrap: %v", err)
+	}
+	res, err := goformat.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("genwrap: bad generated source for %s: %v\n%s", key, err, buf.Bytes())
+	}
+	return res, nil
+}
+
+// goType returns t in Go syntax. Named types are qualified by qual
+// applied to their package path.
+func goType(t tipe.Type, qual func(path string) string) (string, error) {
+	switch t := t.(type) {
+	case tipe.Basic:
+		switch t {
+		case tipe.Invalid, tipe.Num, tipe.Integer, tipe.Float, tipe.Complex, tipe.UnsafePointer:
+		default:
+			if !strings.HasPrefix(string(t), "untyped") {
+				return string(t), nil
+			}
+		}
+	case *tipe.Alias:
+		if t == tipe.Byte || t == tipe.Rune || t == tipe.Any {
+			return t.Name, nil
+		}
+		return goType(t.Type, qual)
+	case *tipe.Named:
+		if typecheck.IsError(t) {
+			return "error", nil
+		}
+		if t.PkgPath == "" {
+			break // declared in Neugram
+		}
+		if len(t.TypeArgs) == 0 {
+			return qual(t.PkgPath) + "." + t.Name, nil
+		}
+		return instance(t.PkgPath, t.Name, t.TypeArgs, qual)
+	case *tipe.Pointer:
+		elem, err := goType(t.Elem, qual)
+		return "*" + elem, err
+	case *tipe.Slice:
+		elem, err := goType(t.Elem, qual)
+		return "[]" + elem, err
+	case *tipe.Ellipsis:
+		elem, err := goType(t.Elem, qual)
+		return "..." + elem, err
+	case *tipe.Array:
+		elem, err := goType(t.Elem, qual)
+		return fmt.Sprintf("[%d]%s", t.Len, elem), err
+	case *tipe.Map:
+		key, err := goType(t.Key, qual)
+		if err != nil {
+			return "", err
+		}
+		value, err := goType(t.Value, qual)
+		return "map[" + key + "]" + value, err
+	case *tipe.Chan:
+		elem, err := goType(t.Elem, qual)
+		switch t.Direction {
+		case tipe.ChanSend:
+			return "chan<- " + elem, err
+		case tipe.ChanRecv:
+			return "<-chan " + elem, err
+		}
+		return "chan " + elem, err
+	case *tipe.Func:
+		if len(t.TypeParams) > 0 {
+			break
+		}
+		return goFunc("func", t, qual)
+	case *tipe.Struct:
+		var fields []string
+		for _, f := range t.Fields {
+			ft, err := goType(f.Type, qual)
+			if err != nil {
+				return "", err
+			}
+			field := f.Name + " " + ft
+			if f.Embedded {
+				field = ft
+			}
+			if f.Tag != "" {
+				field += " " + strconv.Quote(string(f.Tag))
+			}
+			fields = append(fields, field)
+		}
+		return "struct{" + strings.Join(fields, "; ") + "}", nil
+	case *tipe.Interface:
+		if t.Union != nil {
+			break // only usable as a constraint
+		}
+		var names []string
+		for name := range t.Methods {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var methods []string
+		for _, name := range names {
+			m, err := goFunc(name, t.Methods[name], qual)
+			if err != nil {
+				return "", err
+			}
+			methods = append(methods, m)
+		}
+		return "interface{" + strings.Join(methods, "; ") + "}", nil
+	}
+	return "", fmt.Errorf("genwrap: type %s cannot be a type argument of a Go package", format.Type(t))
+}
+
+// goFunc returns the signature of the function t in Go syntax,
+// starting with prefix.
+func goFunc(prefix string, t *tipe.Func, qual func(path string) string) (string, error) {
+	tuple := func(tuple *tipe.Tuple, variadic bool) (string, error) {
+		if tuple == nil {
+			return "()", nil
+		}
+		var elems []string
+		for i, elem := range tuple.Elems {
+			if variadic && i == len(tuple.Elems)-1 {
+				if s, isSlice := elem.(*tipe.Slice); isSlice {
+					elem = &tipe.Ellipsis{Elem: s.Elem}
+				}
+			}
+			e, err := goType(elem, qual)
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, e)
+		}
+		return "(" + strings.Join(elems, ", ") + ")", nil
+	}
+	params, err := tuple(t.Params, t.Variadic)
+	if err != nil {
+		return "", err
+	}
+	results, err := tuple(t.Results, false)
+	if err != nil {
+		return "", err
+	}
+	return prefix + params + results, nil
+}
+
+type instanceData struct {
+	OutPkgName string
+	Imports    []DataPkg
+	Key        string
+	Value      string
+}
+
+var instanceTmpl = template.Must(template.New("instance").Parse(`
+// Generated file, do not edit.
+
+package {{.OutPkgName}}
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+{{range .Imports}}
+	{{.QuotedName}} "{{.Name}}"{{end}}
+)
+
+func init() {
+	if _, exists := gowrap.Instances[{{.Key}}]; !exists {
+		gowrap.Instances[{{.Key}}] = {{.Value}}
+	}
+}
+`))
@@ -0,0 +1,203 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genwrap
+
+import (
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// toolchainDecls reports the exported names of the standard library
+// package pkgPath that are not declared under every setting of the
+// toolchain build tags its files depend on: the goexperiment tags and
+// the go1.N release tags. Such names, like the options encoding/json
+// declares under goexperiment.jsonv2, exist only in some builds of Go,
+// so a wrapper referring to them does not build with the others.
+//
+// Packages outside the standard library are built by the toolchain
+// that wraps them, and have no such names. They are not looked up
+// at all: build.Default sees neither the GOPATH nor the module
+// gotool builds them in.
+func toolchainDecls(pkgPath string) (map[string]bool, error) {
+	if !inGoroot(pkgPath) {
+		return nil, nil
+	}
+	bp, err := build.Import(pkgPath, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	if !bp.Goroot {
+		return nil, nil
+	}
+
+	fset := token.NewFileSet()
+	files := make(map[string]*ast.File)
+	tags := make(map[string]bool) // toolchain tags of the files
+	var all []string
+	all = append(all, bp.GoFiles...)
+	all = append(all, bp.CgoFiles...)
+	all = append(all, bp.IgnoredGoFiles...)
+	for _, name := range all {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(bp.Dir, name), nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = f
+		for _, x := range buildConstraints(f) {
+			walkTags(x, func(tag string) {
+				if isToolchainTag(tag) {
+					tags[tag] = true
+				}
+			})
+		}
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	// Collect the names declared under each setting of the tags,
+	// and report those missing from any of them.
+	var toggle []string
+	for tag := range tags {
+		toggle = append(toggle, tag)
+	}
+	declared := make(map[string]int)
+	settings := 1 << uint(len(toggle))
+	for set := 0; set < settings; set++ {
+		ctxt := build.Default
+		ctxt.ToolTags = setTags(ctxt.ToolTags, toggle, set)
+		ctxt.ReleaseTags = setTags(ctxt.ReleaseTags, toggle, set)
+		names := make(map[string]bool)
+		for name, f := range files {
+			match, err := ctxt.MatchFile(bp.Dir, name)
+			if err != nil {
+				return nil, err
+			}
+			if match {
+				exportedDecls(f, names)
+			}
+		}
+		for name := range names {
+			declared[name]++
+		}
+	}
+	res := make(map[string]bool)
+	for name, n := range declared {
+		if n < settings {
+			res[name] = true
+		}
+	}
+	return res, nil
+}
+
+// inGoroot reports whether pkgPath is a package of the standard library.
+func inGoroot(pkgPath string) bool {
+	if build.IsLocalImport(pkgPath) || filepath.IsAbs(pkgPath) {
+		return false
+	}
+	fi, err := os.Stat(filepath.Join(build.Default.GOROOT, "src", filepath.FromSlash(pkgPath)))
+	return err == nil && fi.IsDir()
+}
+
+func isToolchainTag(tag string) bool {
+	return strings.HasPrefix(tag, "goexperiment.") || strings.HasPrefix(tag, "go1.")
+}
+
+// setTags returns tags with each of toggle present if its bit
+// is set in set, and absent otherwise. Tags of the toolchain
+// not in toggle are kept.
+func setTags(tags, toggle []string, set int) []string {
+	var res []string
+	for _, tag := range tags {
+		if !isToolchainTag(tag) || indexOf(toggle, tag) < 0 {
+			res = append(res, tag)
+		}
+	}
+	for i, tag := range toggle {
+		if set&(1<<uint(i)) != 0 {
+			res = append(res, tag)
+		}
+	}
+	return res
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// buildConstraints returns the //go:build constraints of f.
+func buildConstraints(f *ast.File) []constraint.Expr {
+	var res []constraint.Expr
+	for _, g := range f.Comments {
+		if g.Pos() >= f.Package {
+			break
+		}
+		for _, c := range g.List {
+			if !constraint.IsGoBuild(c.Text) {
+				continue
+			}
+			if x, err := constraint.Parse(c.Text); err == nil {
+				res = append(res, x)
+			}
+		}
+	}
+	return res
+}
+
+func walkTags(x constraint.Expr, fn func(tag string)) {
+	switch x := x.(type) {
+	case *constraint.TagExpr:
+		fn(x.Tag)
+	case *constraint.NotExpr:
+		walkTags(x.X, fn)
+	case *constraint.AndExpr:
+		walkTags(x.X, fn)
+		walkTags(x.Y, fn)
+	case *constraint.OrExpr:
+		walkTags(x.X, fn)
+		walkTags(x.Y, fn)
+	}
+}
+
+// exportedDecls adds the exported package-level names of f to names.
+func exportedDecls(f *ast.File, names map[string]bool) {
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil && decl.Name.IsExported() {
+				names[decl.Name.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Name.IsExported() {
+						names[spec.Name.Name] = true
+					}
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						if name.IsExported() {
+							names[name.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+}
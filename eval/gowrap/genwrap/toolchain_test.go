@@ -0,0 +1,60 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genwrap
+
+import (
+	"go/build"
+	"testing"
+)
+
+func TestToolchainDecls(t *testing.T) {
+	skip, err := toolchainDecls("encoding/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Marshal is declared both with and without jsonv2.
+	for _, name := range []string{"Marshal", "Unmarshal", "Decoder"} {
+		if skip[name] {
+			t.Errorf("encoding/json.%s skipped", name)
+		}
+	}
+	bp, err := build.Import("encoding/json", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasV2 := false
+	for _, files := range [][]string{bp.GoFiles, bp.IgnoredGoFiles} {
+		for _, name := range files {
+			if name == "v2_options.go" {
+				hasV2 = true
+			}
+		}
+	}
+	if hasV2 && !skip["Options"] {
+		t.Errorf("encoding/json.Options, declared only under goexperiment.jsonv2, not skipped")
+	}
+
+	skip, err = toolchainDecls("strings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skip) != 0 {
+		t.Errorf("strings: skipped %v", skip)
+	}
+}
+
+func TestToolchainDeclsOutsideGoroot(t *testing.T) {
+	// Packages gotool builds in a temporary GOPATH or module
+	// cannot be found by go/build, and are not looked up.
+	for _, path := range []string{"rel/tmp/x/vec_ng", "example.com/nosuchpkg"} {
+		skip, err := toolchainDecls(path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+		if len(skip) != 0 {
+			t.Errorf("%s: skipped %v", path, skip)
+		}
+	}
+}
@@ -2,6 +2,10 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+// Packages are generated in dependency order: every package
+// referred to by the exported symbols of a package is generated
+// before it. genwrap.go checks this.
+
 // L0:
 //go:generate go run genwrap.go errors
 //go:generate go run genwrap.go io
@@ -11,27 +15,44 @@
 
 // L1:
 //go:generate go run genwrap.go math
+//go:generate go run genwrap.go math/rand
+//go:generate go run genwrap.go sort
 //go:generate go run genwrap.go strconv
 //go:generate go run genwrap.go unicode/utf8
 
 // L2:
-// TODO do we want bufio? maybe write our own version.
+//go:generate go run genwrap.go unicode
+//go:generate go run genwrap.go bufio
 //go:generate go run genwrap.go bytes
+//go:generate go run genwrap.go hash
 //go:generate go run genwrap.go path
 //go:generate go run genwrap.go strings
-//go:generate go run genwrap.go unicode
 
 // L3:
+//go:generate go run genwrap.go crypto/md5
+//go:generate go run genwrap.go crypto/sha1
+//go:generate go run genwrap.go crypto/sha256
+//go:generate go run genwrap.go crypto/sha512
 //go:generate go run genwrap.go encoding/base64
 //go:generate go run genwrap.go encoding/binary
+//go:generate go run genwrap.go encoding/csv
+//go:generate go run genwrap.go net/url
+//go:generate go run genwrap.go regexp
 
 // OS:
+//go:generate go run genwrap.go time
+//go:generate go run genwrap.go context
+//go:generate go run genwrap.go io/fs
 //go:generate go run genwrap.go os
-// TODO go:generate go run genwrap.go path/filepath
+//go:generate go run genwrap.go io/ioutil
+//go:generate go run genwrap.go os/exec
+//go:generate go run genwrap.go path/filepath
 
 // L4:
+//go:generate go run genwrap.go compress/gzip
+//go:generate go run genwrap.go encoding/json
 //go:generate go run genwrap.go fmt
-//go:generate go run genwrap.go time
+//go:generate go run genwrap.go text/template
 
 package gowrap // import "neugram.io/ng/eval/gowrap"
 
@@ -39,6 +60,12 @@ import "reflect"
 
 var Pkgs = make(map[string]*Pkg)
 
+// Instances holds the instances of generic Go functions and types,
+// which have no value until instantiated. They are keyed by the
+// instance in Go syntax, qualified by package path, such as
+// "slices.Index[[]int, int]".
+var Instances = make(map[string]reflect.Value)
+
 type Pkg struct {
 	// TODO: ExportData
 	Exports map[string]reflect.Value
@@ -0,0 +1,48 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_bufio "bufio"
+)
+
+var pkg_wrap_bufio = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"ErrAdvanceTooFar":     reflect.ValueOf(&wrap_bufio.ErrAdvanceTooFar).Elem(),
+		"ErrBadReadCount":      reflect.ValueOf(&wrap_bufio.ErrBadReadCount).Elem(),
+		"ErrBufferFull":        reflect.ValueOf(&wrap_bufio.ErrBufferFull).Elem(),
+		"ErrFinalToken":        reflect.ValueOf(&wrap_bufio.ErrFinalToken).Elem(),
+		"ErrInvalidUnreadByte": reflect.ValueOf(&wrap_bufio.ErrInvalidUnreadByte).Elem(),
+		"ErrInvalidUnreadRune": reflect.ValueOf(&wrap_bufio.ErrInvalidUnreadRune).Elem(),
+		"ErrNegativeAdvance":   reflect.ValueOf(&wrap_bufio.ErrNegativeAdvance).Elem(),
+		"ErrNegativeCount":     reflect.ValueOf(&wrap_bufio.ErrNegativeCount).Elem(),
+		"ErrTooLong":           reflect.ValueOf(&wrap_bufio.ErrTooLong).Elem(),
+		"MaxScanTokenSize":     reflect.ValueOf(wrap_bufio.MaxScanTokenSize),
+		"NewReadWriter":        reflect.ValueOf(wrap_bufio.NewReadWriter),
+		"NewReader":            reflect.ValueOf(wrap_bufio.NewReader),
+		"NewReaderSize":        reflect.ValueOf(wrap_bufio.NewReaderSize),
+		"NewScanner":           reflect.ValueOf(wrap_bufio.NewScanner),
+		"NewWriter":            reflect.ValueOf(wrap_bufio.NewWriter),
+		"NewWriterSize":        reflect.ValueOf(wrap_bufio.NewWriterSize),
+		"ReadWriter":           reflect.ValueOf(reflect.TypeOf(wrap_bufio.ReadWriter{})),
+		"Reader":               reflect.ValueOf(reflect.TypeOf(wrap_bufio.Reader{})),
+		"ScanBytes":            reflect.ValueOf(wrap_bufio.ScanBytes),
+		"ScanLines":            reflect.ValueOf(wrap_bufio.ScanLines),
+		"ScanRunes":            reflect.ValueOf(wrap_bufio.ScanRunes),
+		"ScanWords":            reflect.ValueOf(wrap_bufio.ScanWords),
+		"Scanner":              reflect.ValueOf(reflect.TypeOf(wrap_bufio.Scanner{})),
+		"SplitFunc":            reflect.ValueOf(reflect.TypeOf(wrap_bufio.SplitFunc(nil))),
+		"Writer":               reflect.ValueOf(reflect.TypeOf(wrap_bufio.Writer{})),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["bufio"] == nil {
+		gowrap.Pkgs["bufio"] = pkg_wrap_bufio
+	}
+}
@@ -0,0 +1,36 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_compress_gzip "compress/gzip"
+)
+
+var pkg_wrap_compress_gzip = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"BestCompression":    reflect.ValueOf(wrap_compress_gzip.BestCompression),
+		"BestSpeed":          reflect.ValueOf(wrap_compress_gzip.BestSpeed),
+		"DefaultCompression": reflect.ValueOf(wrap_compress_gzip.DefaultCompression),
+		"ErrChecksum":        reflect.ValueOf(&wrap_compress_gzip.ErrChecksum).Elem(),
+		"ErrHeader":          reflect.ValueOf(&wrap_compress_gzip.ErrHeader).Elem(),
+		"Header":             reflect.ValueOf(reflect.TypeOf(wrap_compress_gzip.Header{})),
+		"HuffmanOnly":        reflect.ValueOf(wrap_compress_gzip.HuffmanOnly),
+		"NewReader":          reflect.ValueOf(wrap_compress_gzip.NewReader),
+		"NewWriter":          reflect.ValueOf(wrap_compress_gzip.NewWriter),
+		"NewWriterLevel":     reflect.ValueOf(wrap_compress_gzip.NewWriterLevel),
+		"NoCompression":      reflect.ValueOf(wrap_compress_gzip.NoCompression),
+		"Reader":             reflect.ValueOf(reflect.TypeOf(wrap_compress_gzip.Reader{})),
+		"Writer":             reflect.ValueOf(reflect.TypeOf(wrap_compress_gzip.Writer{})),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["compress/gzip"] == nil {
+		gowrap.Pkgs["compress/gzip"] = pkg_wrap_compress_gzip
+	}
+}
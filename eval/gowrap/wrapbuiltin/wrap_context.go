@@ -0,0 +1,40 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_context "context"
+)
+
+var pkg_wrap_context = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"AfterFunc":         reflect.ValueOf(wrap_context.AfterFunc),
+		"Background":        reflect.ValueOf(wrap_context.Background),
+		"CancelCauseFunc":   reflect.ValueOf(reflect.TypeOf(wrap_context.CancelCauseFunc(nil))),
+		"CancelFunc":        reflect.ValueOf(reflect.TypeOf(wrap_context.CancelFunc(nil))),
+		"Canceled":          reflect.ValueOf(&wrap_context.Canceled).Elem(),
+		"Cause":             reflect.ValueOf(wrap_context.Cause),
+		"Context":           reflect.ValueOf(reflect.TypeOf((*wrap_context.Context)(nil)).Elem()),
+		"DeadlineExceeded":  reflect.ValueOf(&wrap_context.DeadlineExceeded).Elem(),
+		"TODO":              reflect.ValueOf(wrap_context.TODO),
+		"WithCancel":        reflect.ValueOf(wrap_context.WithCancel),
+		"WithCancelCause":   reflect.ValueOf(wrap_context.WithCancelCause),
+		"WithDeadline":      reflect.ValueOf(wrap_context.WithDeadline),
+		"WithDeadlineCause": reflect.ValueOf(wrap_context.WithDeadlineCause),
+		"WithTimeout":       reflect.ValueOf(wrap_context.WithTimeout),
+		"WithTimeoutCause":  reflect.ValueOf(wrap_context.WithTimeoutCause),
+		"WithValue":         reflect.ValueOf(wrap_context.WithValue),
+		"WithoutCancel":     reflect.ValueOf(wrap_context.WithoutCancel),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["context"] == nil {
+		gowrap.Pkgs["context"] = pkg_wrap_context
+	}
+}
@@ -0,0 +1,27 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_crypto_md5 "crypto/md5"
+)
+
+var pkg_wrap_crypto_md5 = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"BlockSize": reflect.ValueOf(wrap_crypto_md5.BlockSize),
+		"New":       reflect.ValueOf(wrap_crypto_md5.New),
+		"Size":      reflect.ValueOf(wrap_crypto_md5.Size),
+		"Sum":       reflect.ValueOf(wrap_crypto_md5.Sum),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["crypto/md5"] == nil {
+		gowrap.Pkgs["crypto/md5"] = pkg_wrap_crypto_md5
+	}
+}
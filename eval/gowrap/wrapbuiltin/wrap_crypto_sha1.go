@@ -0,0 +1,27 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_crypto_sha1 "crypto/sha1"
+)
+
+var pkg_wrap_crypto_sha1 = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"BlockSize": reflect.ValueOf(wrap_crypto_sha1.BlockSize),
+		"New":       reflect.ValueOf(wrap_crypto_sha1.New),
+		"Size":      reflect.ValueOf(wrap_crypto_sha1.Size),
+		"Sum":       reflect.ValueOf(wrap_crypto_sha1.Sum),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["crypto/sha1"] == nil {
+		gowrap.Pkgs["crypto/sha1"] = pkg_wrap_crypto_sha1
+	}
+}
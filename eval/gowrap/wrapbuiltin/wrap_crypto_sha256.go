@@ -0,0 +1,30 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_crypto_sha256 "crypto/sha256"
+)
+
+var pkg_wrap_crypto_sha256 = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"BlockSize": reflect.ValueOf(wrap_crypto_sha256.BlockSize),
+		"New":       reflect.ValueOf(wrap_crypto_sha256.New),
+		"New224":    reflect.ValueOf(wrap_crypto_sha256.New224),
+		"Size":      reflect.ValueOf(wrap_crypto_sha256.Size),
+		"Size224":   reflect.ValueOf(wrap_crypto_sha256.Size224),
+		"Sum224":    reflect.ValueOf(wrap_crypto_sha256.Sum224),
+		"Sum256":    reflect.ValueOf(wrap_crypto_sha256.Sum256),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["crypto/sha256"] == nil {
+		gowrap.Pkgs["crypto/sha256"] = pkg_wrap_crypto_sha256
+	}
+}
@@ -0,0 +1,36 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_crypto_sha512 "crypto/sha512"
+)
+
+var pkg_wrap_crypto_sha512 = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"BlockSize":  reflect.ValueOf(wrap_crypto_sha512.BlockSize),
+		"New":        reflect.ValueOf(wrap_crypto_sha512.New),
+		"New384":     reflect.ValueOf(wrap_crypto_sha512.New384),
+		"New512_224": reflect.ValueOf(wrap_crypto_sha512.New512_224),
+		"New512_256": reflect.ValueOf(wrap_crypto_sha512.New512_256),
+		"Size":       reflect.ValueOf(wrap_crypto_sha512.Size),
+		"Size224":    reflect.ValueOf(wrap_crypto_sha512.Size224),
+		"Size256":    reflect.ValueOf(wrap_crypto_sha512.Size256),
+		"Size384":    reflect.ValueOf(wrap_crypto_sha512.Size384),
+		"Sum384":     reflect.ValueOf(wrap_crypto_sha512.Sum384),
+		"Sum512":     reflect.ValueOf(wrap_crypto_sha512.Sum512),
+		"Sum512_224": reflect.ValueOf(wrap_crypto_sha512.Sum512_224),
+		"Sum512_256": reflect.ValueOf(wrap_crypto_sha512.Sum512_256),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["crypto/sha512"] == nil {
+		gowrap.Pkgs["crypto/sha512"] = pkg_wrap_crypto_sha512
+	}
+}
@@ -0,0 +1,32 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_encoding_csv "encoding/csv"
+)
+
+var pkg_wrap_encoding_csv = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"ErrBareQuote":     reflect.ValueOf(&wrap_encoding_csv.ErrBareQuote).Elem(),
+		"ErrFieldCount":    reflect.ValueOf(&wrap_encoding_csv.ErrFieldCount).Elem(),
+		"ErrQuote":         reflect.ValueOf(&wrap_encoding_csv.ErrQuote).Elem(),
+		"ErrTrailingComma": reflect.ValueOf(&wrap_encoding_csv.ErrTrailingComma).Elem(),
+		"NewReader":        reflect.ValueOf(wrap_encoding_csv.NewReader),
+		"NewWriter":        reflect.ValueOf(wrap_encoding_csv.NewWriter),
+		"ParseError":       reflect.ValueOf(reflect.TypeOf(wrap_encoding_csv.ParseError{})),
+		"Reader":           reflect.ValueOf(reflect.TypeOf(wrap_encoding_csv.Reader{})),
+		"Writer":           reflect.ValueOf(reflect.TypeOf(wrap_encoding_csv.Writer{})),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["encoding/csv"] == nil {
+		gowrap.Pkgs["encoding/csv"] = pkg_wrap_encoding_csv
+	}
+}
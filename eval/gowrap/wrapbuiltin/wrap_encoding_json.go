@@ -0,0 +1,48 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_encoding_json "encoding/json"
+)
+
+var pkg_wrap_encoding_json = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"Compact":               reflect.ValueOf(wrap_encoding_json.Compact),
+		"Decoder":               reflect.ValueOf(reflect.TypeOf(wrap_encoding_json.Decoder{})),
+		"Delim":                 reflect.ValueOf(reflect.TypeOf(wrap_encoding_json.Delim(0))),
+		"Encoder":               reflect.ValueOf(reflect.TypeOf(wrap_encoding_json.Encoder{})),
+		"HTMLEscape":            reflect.ValueOf(wrap_encoding_json.HTMLEscape),
+		"Indent":                reflect.ValueOf(wrap_encoding_json.Indent),
+		"InvalidUTF8Error":      reflect.ValueOf(reflect.TypeOf(wrap_encoding_json.InvalidUTF8Error{})),
+		"InvalidUnmarshalError": reflect.ValueOf(reflect.TypeOf(wrap_encoding_json.InvalidUnmarshalError{})),
+		"Marshal":               reflect.ValueOf(wrap_encoding_json.Marshal),
+		"MarshalIndent":         reflect.ValueOf(wrap_encoding_json.MarshalIndent),
+		"Marshaler":             reflect.ValueOf(reflect.TypeOf((*wrap_encoding_json.Marshaler)(nil)).Elem()),
+		"MarshalerError":        reflect.ValueOf(reflect.TypeOf(wrap_encoding_json.MarshalerError{})),
+		"NewDecoder":            reflect.ValueOf(wrap_encoding_json.NewDecoder),
+		"NewEncoder":            reflect.ValueOf(wrap_encoding_json.NewEncoder),
+		"Number":                reflect.ValueOf(reflect.TypeOf(wrap_encoding_json.Number(""))),
+		"RawMessage":            reflect.ValueOf(reflect.TypeOf(wrap_encoding_json.RawMessage(nil))),
+		"SyntaxError":           reflect.ValueOf(reflect.TypeOf(wrap_encoding_json.SyntaxError{})),
+		"Token":                 reflect.ValueOf(reflect.TypeOf((*wrap_encoding_json.Token)(nil)).Elem()),
+		"Unmarshal":             reflect.ValueOf(wrap_encoding_json.Unmarshal),
+		"UnmarshalFieldError":   reflect.ValueOf(reflect.TypeOf(wrap_encoding_json.UnmarshalFieldError{})),
+		"UnmarshalTypeError":    reflect.ValueOf(reflect.TypeOf(wrap_encoding_json.UnmarshalTypeError{})),
+		"Unmarshaler":           reflect.ValueOf(reflect.TypeOf((*wrap_encoding_json.Unmarshaler)(nil)).Elem()),
+		"UnsupportedTypeError":  reflect.ValueOf(reflect.TypeOf(wrap_encoding_json.UnsupportedTypeError{})),
+		"UnsupportedValueError": reflect.ValueOf(reflect.TypeOf(wrap_encoding_json.UnsupportedValueError{})),
+		"Valid":                 reflect.ValueOf(wrap_encoding_json.Valid),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["encoding/json"] == nil {
+		gowrap.Pkgs["encoding/json"] = pkg_wrap_encoding_json
+	}
+}
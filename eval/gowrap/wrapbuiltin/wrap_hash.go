@@ -0,0 +1,28 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_hash "hash"
+)
+
+var pkg_wrap_hash = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"Cloner": reflect.ValueOf(reflect.TypeOf((*wrap_hash.Cloner)(nil)).Elem()),
+		"Hash":   reflect.ValueOf(reflect.TypeOf((*wrap_hash.Hash)(nil)).Elem()),
+		"Hash32": reflect.ValueOf(reflect.TypeOf((*wrap_hash.Hash32)(nil)).Elem()),
+		"Hash64": reflect.ValueOf(reflect.TypeOf((*wrap_hash.Hash64)(nil)).Elem()),
+		"XOF":    reflect.ValueOf(reflect.TypeOf((*wrap_hash.XOF)(nil)).Elem()),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["hash"] == nil {
+		gowrap.Pkgs["hash"] = pkg_wrap_hash
+	}
+}
@@ -0,0 +1,71 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_io_fs "io/fs"
+)
+
+var pkg_wrap_io_fs = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"DirEntry":           reflect.ValueOf(reflect.TypeOf((*wrap_io_fs.DirEntry)(nil)).Elem()),
+		"ErrClosed":          reflect.ValueOf(&wrap_io_fs.ErrClosed).Elem(),
+		"ErrExist":           reflect.ValueOf(&wrap_io_fs.ErrExist).Elem(),
+		"ErrInvalid":         reflect.ValueOf(&wrap_io_fs.ErrInvalid).Elem(),
+		"ErrNotExist":        reflect.ValueOf(&wrap_io_fs.ErrNotExist).Elem(),
+		"ErrPermission":      reflect.ValueOf(&wrap_io_fs.ErrPermission).Elem(),
+		"FS":                 reflect.ValueOf(reflect.TypeOf((*wrap_io_fs.FS)(nil)).Elem()),
+		"File":               reflect.ValueOf(reflect.TypeOf((*wrap_io_fs.File)(nil)).Elem()),
+		"FileInfo":           reflect.ValueOf(reflect.TypeOf((*wrap_io_fs.FileInfo)(nil)).Elem()),
+		"FileInfoToDirEntry": reflect.ValueOf(wrap_io_fs.FileInfoToDirEntry),
+		"FileMode":           reflect.ValueOf(reflect.TypeOf(wrap_io_fs.FileMode(0))),
+		"FormatDirEntry":     reflect.ValueOf(wrap_io_fs.FormatDirEntry),
+		"FormatFileInfo":     reflect.ValueOf(wrap_io_fs.FormatFileInfo),
+		"Glob":               reflect.ValueOf(wrap_io_fs.Glob),
+		"GlobFS":             reflect.ValueOf(reflect.TypeOf((*wrap_io_fs.GlobFS)(nil)).Elem()),
+		"Lstat":              reflect.ValueOf(wrap_io_fs.Lstat),
+		"ModeAppend":         reflect.ValueOf(wrap_io_fs.ModeAppend),
+		"ModeCharDevice":     reflect.ValueOf(wrap_io_fs.ModeCharDevice),
+		"ModeDevice":         reflect.ValueOf(wrap_io_fs.ModeDevice),
+		"ModeDir":            reflect.ValueOf(wrap_io_fs.ModeDir),
+		"ModeExclusive":      reflect.ValueOf(wrap_io_fs.ModeExclusive),
+		"ModeIrregular":      reflect.ValueOf(wrap_io_fs.ModeIrregular),
+		"ModeNamedPipe":      reflect.ValueOf(wrap_io_fs.ModeNamedPipe),
+		"ModePerm":           reflect.ValueOf(wrap_io_fs.ModePerm),
+		"ModeSetgid":         reflect.ValueOf(wrap_io_fs.ModeSetgid),
+		"ModeSetuid":         reflect.ValueOf(wrap_io_fs.ModeSetuid),
+		"ModeSocket":         reflect.ValueOf(wrap_io_fs.ModeSocket),
+		"ModeSticky":         reflect.ValueOf(wrap_io_fs.ModeSticky),
+		"ModeSymlink":        reflect.ValueOf(wrap_io_fs.ModeSymlink),
+		"ModeTemporary":      reflect.ValueOf(wrap_io_fs.ModeTemporary),
+		"ModeType":           reflect.ValueOf(wrap_io_fs.ModeType),
+		"PathError":          reflect.ValueOf(reflect.TypeOf(wrap_io_fs.PathError{})),
+		"ReadDir":            reflect.ValueOf(wrap_io_fs.ReadDir),
+		"ReadDirFS":          reflect.ValueOf(reflect.TypeOf((*wrap_io_fs.ReadDirFS)(nil)).Elem()),
+		"ReadDirFile":        reflect.ValueOf(reflect.TypeOf((*wrap_io_fs.ReadDirFile)(nil)).Elem()),
+		"ReadFile":           reflect.ValueOf(wrap_io_fs.ReadFile),
+		"ReadFileFS":         reflect.ValueOf(reflect.TypeOf((*wrap_io_fs.ReadFileFS)(nil)).Elem()),
+		"ReadLink":           reflect.ValueOf(wrap_io_fs.ReadLink),
+		"ReadLinkFS":         reflect.ValueOf(reflect.TypeOf((*wrap_io_fs.ReadLinkFS)(nil)).Elem()),
+		"SkipAll":            reflect.ValueOf(&wrap_io_fs.SkipAll).Elem(),
+		"SkipDir":            reflect.ValueOf(&wrap_io_fs.SkipDir).Elem(),
+		"Stat":               reflect.ValueOf(wrap_io_fs.Stat),
+		"StatFS":             reflect.ValueOf(reflect.TypeOf((*wrap_io_fs.StatFS)(nil)).Elem()),
+		"Sub":                reflect.ValueOf(wrap_io_fs.Sub),
+		"SubFS":              reflect.ValueOf(reflect.TypeOf((*wrap_io_fs.SubFS)(nil)).Elem()),
+		"ValidPath":          reflect.ValueOf(wrap_io_fs.ValidPath),
+		"WalkDir":            reflect.ValueOf(wrap_io_fs.WalkDir),
+		"WalkDirFunc":        reflect.ValueOf(reflect.TypeOf(wrap_io_fs.WalkDirFunc(nil))),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["io/fs"] == nil {
+		gowrap.Pkgs["io/fs"] = pkg_wrap_io_fs
+	}
+}
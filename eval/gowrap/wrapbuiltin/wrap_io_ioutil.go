@@ -0,0 +1,31 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_io_ioutil "io/ioutil"
+)
+
+var pkg_wrap_io_ioutil = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"Discard":   reflect.ValueOf(&wrap_io_ioutil.Discard).Elem(),
+		"NopCloser": reflect.ValueOf(wrap_io_ioutil.NopCloser),
+		"ReadAll":   reflect.ValueOf(wrap_io_ioutil.ReadAll),
+		"ReadDir":   reflect.ValueOf(wrap_io_ioutil.ReadDir),
+		"ReadFile":  reflect.ValueOf(wrap_io_ioutil.ReadFile),
+		"TempDir":   reflect.ValueOf(wrap_io_ioutil.TempDir),
+		"TempFile":  reflect.ValueOf(wrap_io_ioutil.TempFile),
+		"WriteFile": reflect.ValueOf(wrap_io_ioutil.WriteFile),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["io/ioutil"] == nil {
+		gowrap.Pkgs["io/ioutil"] = pkg_wrap_io_ioutil
+	}
+}
@@ -0,0 +1,46 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_math_rand "math/rand"
+)
+
+var pkg_wrap_math_rand = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"ExpFloat64":  reflect.ValueOf(wrap_math_rand.ExpFloat64),
+		"Float32":     reflect.ValueOf(wrap_math_rand.Float32),
+		"Float64":     reflect.ValueOf(wrap_math_rand.Float64),
+		"Int":         reflect.ValueOf(wrap_math_rand.Int),
+		"Int31":       reflect.ValueOf(wrap_math_rand.Int31),
+		"Int31n":      reflect.ValueOf(wrap_math_rand.Int31n),
+		"Int63":       reflect.ValueOf(wrap_math_rand.Int63),
+		"Int63n":      reflect.ValueOf(wrap_math_rand.Int63n),
+		"Intn":        reflect.ValueOf(wrap_math_rand.Intn),
+		"New":         reflect.ValueOf(wrap_math_rand.New),
+		"NewSource":   reflect.ValueOf(wrap_math_rand.NewSource),
+		"NewZipf":     reflect.ValueOf(wrap_math_rand.NewZipf),
+		"NormFloat64": reflect.ValueOf(wrap_math_rand.NormFloat64),
+		"Perm":        reflect.ValueOf(wrap_math_rand.Perm),
+		"Rand":        reflect.ValueOf(reflect.TypeOf(wrap_math_rand.Rand{})),
+		"Read":        reflect.ValueOf(wrap_math_rand.Read),
+		"Seed":        reflect.ValueOf(wrap_math_rand.Seed),
+		"Shuffle":     reflect.ValueOf(wrap_math_rand.Shuffle),
+		"Source":      reflect.ValueOf(reflect.TypeOf((*wrap_math_rand.Source)(nil)).Elem()),
+		"Source64":    reflect.ValueOf(reflect.TypeOf((*wrap_math_rand.Source64)(nil)).Elem()),
+		"Uint32":      reflect.ValueOf(wrap_math_rand.Uint32),
+		"Uint64":      reflect.ValueOf(wrap_math_rand.Uint64),
+		"Zipf":        reflect.ValueOf(reflect.TypeOf(wrap_math_rand.Zipf{})),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["math/rand"] == nil {
+		gowrap.Pkgs["math/rand"] = pkg_wrap_math_rand
+	}
+}
@@ -0,0 +1,39 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_net_url "net/url"
+)
+
+var pkg_wrap_net_url = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"Error":            reflect.ValueOf(reflect.TypeOf(wrap_net_url.Error{})),
+		"EscapeError":      reflect.ValueOf(reflect.TypeOf(wrap_net_url.EscapeError(""))),
+		"InvalidHostError": reflect.ValueOf(reflect.TypeOf(wrap_net_url.InvalidHostError(""))),
+		"JoinPath":         reflect.ValueOf(wrap_net_url.JoinPath),
+		"Parse":            reflect.ValueOf(wrap_net_url.Parse),
+		"ParseQuery":       reflect.ValueOf(wrap_net_url.ParseQuery),
+		"ParseRequestURI":  reflect.ValueOf(wrap_net_url.ParseRequestURI),
+		"PathEscape":       reflect.ValueOf(wrap_net_url.PathEscape),
+		"PathUnescape":     reflect.ValueOf(wrap_net_url.PathUnescape),
+		"QueryEscape":      reflect.ValueOf(wrap_net_url.QueryEscape),
+		"QueryUnescape":    reflect.ValueOf(wrap_net_url.QueryUnescape),
+		"URL":              reflect.ValueOf(reflect.TypeOf(wrap_net_url.URL{})),
+		"User":             reflect.ValueOf(wrap_net_url.User),
+		"UserPassword":     reflect.ValueOf(wrap_net_url.UserPassword),
+		"Userinfo":         reflect.ValueOf(reflect.TypeOf(wrap_net_url.Userinfo{})),
+		"Values":           reflect.ValueOf(reflect.TypeOf(wrap_net_url.Values(nil))),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["net/url"] == nil {
+		gowrap.Pkgs["net/url"] = pkg_wrap_net_url
+	}
+}
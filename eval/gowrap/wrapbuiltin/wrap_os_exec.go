@@ -0,0 +1,32 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_os_exec "os/exec"
+)
+
+var pkg_wrap_os_exec = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"Cmd":            reflect.ValueOf(reflect.TypeOf(wrap_os_exec.Cmd{})),
+		"Command":        reflect.ValueOf(wrap_os_exec.Command),
+		"CommandContext": reflect.ValueOf(wrap_os_exec.CommandContext),
+		"ErrDot":         reflect.ValueOf(&wrap_os_exec.ErrDot).Elem(),
+		"ErrNotFound":    reflect.ValueOf(&wrap_os_exec.ErrNotFound).Elem(),
+		"ErrWaitDelay":   reflect.ValueOf(&wrap_os_exec.ErrWaitDelay).Elem(),
+		"Error":          reflect.ValueOf(reflect.TypeOf(wrap_os_exec.Error{})),
+		"ExitError":      reflect.ValueOf(reflect.TypeOf(wrap_os_exec.ExitError{})),
+		"LookPath":       reflect.ValueOf(wrap_os_exec.LookPath),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["os/exec"] == nil {
+		gowrap.Pkgs["os/exec"] = pkg_wrap_os_exec
+	}
+}
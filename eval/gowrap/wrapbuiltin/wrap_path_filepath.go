@@ -0,0 +1,50 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_path_filepath "path/filepath"
+)
+
+var pkg_wrap_path_filepath = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"Abs":           reflect.ValueOf(wrap_path_filepath.Abs),
+		"Base":          reflect.ValueOf(wrap_path_filepath.Base),
+		"Clean":         reflect.ValueOf(wrap_path_filepath.Clean),
+		"Dir":           reflect.ValueOf(wrap_path_filepath.Dir),
+		"ErrBadPattern": reflect.ValueOf(&wrap_path_filepath.ErrBadPattern).Elem(),
+		"EvalSymlinks":  reflect.ValueOf(wrap_path_filepath.EvalSymlinks),
+		"Ext":           reflect.ValueOf(wrap_path_filepath.Ext),
+		"FromSlash":     reflect.ValueOf(wrap_path_filepath.FromSlash),
+		"Glob":          reflect.ValueOf(wrap_path_filepath.Glob),
+		"HasPrefix":     reflect.ValueOf(wrap_path_filepath.HasPrefix),
+		"IsAbs":         reflect.ValueOf(wrap_path_filepath.IsAbs),
+		"IsLocal":       reflect.ValueOf(wrap_path_filepath.IsLocal),
+		"Join":          reflect.ValueOf(wrap_path_filepath.Join),
+		"ListSeparator": reflect.ValueOf(wrap_path_filepath.ListSeparator),
+		"Localize":      reflect.ValueOf(wrap_path_filepath.Localize),
+		"Match":         reflect.ValueOf(wrap_path_filepath.Match),
+		"Rel":           reflect.ValueOf(wrap_path_filepath.Rel),
+		"Separator":     reflect.ValueOf(wrap_path_filepath.Separator),
+		"SkipAll":       reflect.ValueOf(&wrap_path_filepath.SkipAll).Elem(),
+		"SkipDir":       reflect.ValueOf(&wrap_path_filepath.SkipDir).Elem(),
+		"Split":         reflect.ValueOf(wrap_path_filepath.Split),
+		"SplitList":     reflect.ValueOf(wrap_path_filepath.SplitList),
+		"ToSlash":       reflect.ValueOf(wrap_path_filepath.ToSlash),
+		"VolumeName":    reflect.ValueOf(wrap_path_filepath.VolumeName),
+		"Walk":          reflect.ValueOf(wrap_path_filepath.Walk),
+		"WalkDir":       reflect.ValueOf(wrap_path_filepath.WalkDir),
+		"WalkFunc":      reflect.ValueOf(reflect.TypeOf(wrap_path_filepath.WalkFunc(nil))),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["path/filepath"] == nil {
+		gowrap.Pkgs["path/filepath"] = pkg_wrap_path_filepath
+	}
+}
@@ -0,0 +1,32 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_regexp "regexp"
+)
+
+var pkg_wrap_regexp = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"Compile":          reflect.ValueOf(wrap_regexp.Compile),
+		"CompilePOSIX":     reflect.ValueOf(wrap_regexp.CompilePOSIX),
+		"Match":            reflect.ValueOf(wrap_regexp.Match),
+		"MatchReader":      reflect.ValueOf(wrap_regexp.MatchReader),
+		"MatchString":      reflect.ValueOf(wrap_regexp.MatchString),
+		"MustCompile":      reflect.ValueOf(wrap_regexp.MustCompile),
+		"MustCompilePOSIX": reflect.ValueOf(wrap_regexp.MustCompilePOSIX),
+		"QuoteMeta":        reflect.ValueOf(wrap_regexp.QuoteMeta),
+		"Regexp":           reflect.ValueOf(reflect.TypeOf(wrap_regexp.Regexp{})),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["regexp"] == nil {
+		gowrap.Pkgs["regexp"] = pkg_wrap_regexp
+	}
+}
@@ -0,0 +1,39 @@
+// Generated file, do not edit.
+
+package wrapbuiltin
+
+import (
+	"reflect"
+
+	"neugram.io/ng/eval/gowrap"
+
+	wrap_text_template "text/template"
+)
+
+var pkg_wrap_text_template = &gowrap.Pkg{
+	Exports: map[string]reflect.Value{
+
+		"ExecError":        reflect.ValueOf(reflect.TypeOf(wrap_text_template.ExecError{})),
+		"FuncMap":          reflect.ValueOf(reflect.TypeOf(wrap_text_template.FuncMap(nil))),
+		"HTMLEscape":       reflect.ValueOf(wrap_text_template.HTMLEscape),
+		"HTMLEscapeString": reflect.ValueOf(wrap_text_template.HTMLEscapeString),
+		"HTMLEscaper":      reflect.ValueOf(wrap_text_template.HTMLEscaper),
+		"IsTrue":           reflect.ValueOf(wrap_text_template.IsTrue),
+		"JSEscape":         reflect.ValueOf(wrap_text_template.JSEscape),
+		"JSEscapeString":   reflect.ValueOf(wrap_text_template.JSEscapeString),
+		"JSEscaper":        reflect.ValueOf(wrap_text_template.JSEscaper),
+		"Must":             reflect.ValueOf(wrap_text_template.Must),
+		"New":              reflect.ValueOf(wrap_text_template.New),
+		"ParseFS":          reflect.ValueOf(wrap_text_template.ParseFS),
+		"ParseFiles":       reflect.ValueOf(wrap_text_template.ParseFiles),
+		"ParseGlob":        reflect.ValueOf(wrap_text_template.ParseGlob),
+		"Template":         reflect.ValueOf(reflect.TypeOf(wrap_text_template.Template{})),
+		"URLQueryEscaper":  reflect.ValueOf(wrap_text_template.URLQueryEscaper),
+	},
+}
+
+func init() {
+	if gowrap.Pkgs["text/template"] == nil {
+		gowrap.Pkgs["text/template"] = pkg_wrap_text_template
+	}
+}
@@ -21,7 +21,10 @@ import (
 	"neugram.io/ng/syntax/tipe"
 )
 
-func (p *Program) ifaceDecl(t *tipe.Named) {
+// namedTypeDecl builds the reflect type of t in a plugin. It is
+// used for interfaces and for structs that promote methods from
+// their embedded fields, neither of which reflect can build.
+func (p *Program) namedTypeDecl(t *tipe.Named) {
 	// TODO: lock reflector
 	if _, exists := p.reflector.fwd[t]; exists {
 		return
@@ -34,8 +37,36 @@ func (p *Program) ifaceDecl(t *tipe.Named) {
 	p.reflector.fwd[t] = rtype
 }
 
+// promotesMethods reports whether t is a struct with an embedded
+// field that has methods.
+func (p *Program) promotesMethods(t *tipe.Named) bool {
+	st, isStruct := t.Type.(*tipe.Struct)
+	if !isStruct {
+		return false
+	}
+	for _, f := range st.Fields {
+		if !f.Embedded {
+			continue
+		}
+		ft := p.toRType(f.Type)
+		if ft.NumMethod() > 0 {
+			return true
+		}
+		if ft.Kind() != reflect.Ptr && ft.Kind() != reflect.Interface && reflect.PtrTo(ft).NumMethod() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Program) methodikDecl(s *stmt.MethodikDecl) {
 	t := s.Type
+	if len(t.TypeParams) > 0 {
+		p.generics.mu.Lock()
+		p.generics.methodiks[t] = genericMethodik{decl: s, scope: p.Cur}
+		p.generics.mu.Unlock()
+		return
+	}
 	// TODO: lock reflector
 	if _, exists := p.reflector.fwd[t]; exists {
 		return
@@ -60,7 +91,8 @@ func (p *Program) methodikDecl(s *stmt.MethodikDecl) {
 }
 
 func (p *Program) reflectNamedType(t *tipe.Named, methods []*expr.FuncLiteral) (reflect.Type, error) {
-	adjPkgPath, dir, err := gotool.M.Dir(path.Join("methodik", t.Name))
+	name := gengo.TypeName(t)
+	adjPkgPath, dir, err := gotool.M.Dir(path.Join("methodik", name))
 	if err != nil {
 		return nil, err
 	}
@@ -74,21 +106,20 @@ func (p *Program) reflectNamedType(t *tipe.Named, methods []*expr.FuncLiteral) (
 
 	// Do not remove the pkgGo file as future builds that
 	// import this package will need the file to exist.
-	pkgGo := filepath.Join(dir, t.Name+".go")
+	pkgGo := filepath.Join(dir, name+".go")
 	if err := ioutil.WriteFile(pkgGo, pkgb, 0666); err != nil {
 		return nil, err
 	}
-	name := t.Name + "-main"
-	mainGo := filepath.Join(dir, name, t.Name+".go")
+	mainGo := filepath.Join(dir, name+"-main", name+".go")
 	os.Mkdir(filepath.Dir(mainGo), 0775)
 	if err := ioutil.WriteFile(mainGo, mainb, 0666); err != nil {
 		return nil, err
 	}
 	defer os.Remove(mainGo)
 
-	plg, err := gotool.M.Open(path.Join(adjPkgPath, name))
+	plg, err := gotool.M.Open(path.Join(adjPkgPath, name+"-main"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to open plugin %s: %v", name, err)
+		return nil, fmt.Errorf("failed to open plugin %s: %v", name+"-main", err)
 	}
 
 	v, err := plg.Lookup("Zero")
@@ -121,7 +152,7 @@ func (p *Program) evalMethRecv(e *expr.FuncLiteral, recvt *tipe.Named, v reflect
 	// Here we adjust the pointer type to the correct value,
 	// and if the neugram method does not want a pointer, remove
 	// a level of indirection.
-	rt := p.reflector.ToRType(recvt)
+	rt := p.toRType(recvt)
 	arg = reflect.NewAt(rt, ptr)
 	if !e.PointerReceiver {
 		arg = arg.Elem()
@@ -12,6 +12,39 @@ import (
 	"neugram.io/ng/syntax/token"
 )
 
+var basicRTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:       reflect.TypeOf(false),
+	reflect.String:     reflect.TypeOf(""),
+	reflect.Int:        reflect.TypeOf(int(0)),
+	reflect.Int8:       reflect.TypeOf(int8(0)),
+	reflect.Int16:      reflect.TypeOf(int16(0)),
+	reflect.Int32:      reflect.TypeOf(int32(0)),
+	reflect.Int64:      reflect.TypeOf(int64(0)),
+	reflect.Uint:       reflect.TypeOf(uint(0)),
+	reflect.Uint8:      reflect.TypeOf(uint8(0)),
+	reflect.Uint16:     reflect.TypeOf(uint16(0)),
+	reflect.Uint32:     reflect.TypeOf(uint32(0)),
+	reflect.Uint64:     reflect.TypeOf(uint64(0)),
+	reflect.Uintptr:    reflect.TypeOf(uintptr(0)),
+	reflect.Float32:    reflect.TypeOf(float32(0)),
+	reflect.Float64:    reflect.TypeOf(float64(0)),
+	reflect.Complex64:  reflect.TypeOf(complex64(0)),
+	reflect.Complex128: reflect.TypeOf(complex128(0)),
+}
+
+// unnamed converts v, a value of a named type with a basic
+// underlying type such as time.Duration, to the basic type.
+// binOp only operates on basic types.
+func unnamed(v reflect.Value) reflect.Value {
+	if !v.IsValid() {
+		return v
+	}
+	if bt := basicRTypes[v.Kind()]; bt != nil && v.Type() != bt {
+		return v.Convert(bt)
+	}
+	return v
+}
+
 // TODO redo
 func valEq(x, y interface{}) bool {
 	if x == y {
@@ -60,6 +93,16 @@ func valEq(x, y interface{}) bool {
 }
 
 func binOp(op token.Token, x, y interface{}) (interface{}, error) {
+	if xr, isRune := x.(UntypedRune); isRune {
+		if yr, isRune := y.(UntypedRune); isRune {
+			// Untyped rune arithmetic is integer arithmetic.
+			v, err := binOp(op, UntypedInt{big.NewInt(int64(xr.Rune))}, UntypedInt{big.NewInt(int64(yr.Rune))})
+			if i, isInt := v.(UntypedInt); isInt {
+				v = UntypedRune{rune(i.Int64())}
+			}
+			return v, err
+		}
+	}
 	switch op {
 	case token.Add:
 		switch x := x.(type) {
@@ -136,7 +179,7 @@ func binOp(op token.Token, x, y interface{}) (interface{}, error) {
 		case UntypedInt:
 			switch y := y.(type) {
 			case UntypedFloat:
-				z := big.NewFloat(float64(x.Int.Int64()))
+				z := new(big.Float).SetInt(x.Int)
 				return UntypedFloat{z.Add(z, y.Float)}, nil
 			case UntypedInt:
 				z := big.NewInt(0)
@@ -263,14 +306,14 @@ func binOp(op token.Token, x, y interface{}) (interface{}, error) {
 			switch y := y.(type) {
 			case UntypedFloat:
 				z := big.NewFloat(0)
-				xf := big.NewFloat(float64(x.Int.Int64()))
+				xf := new(big.Float).SetInt(x.Int)
 				return UntypedFloat{z.Sub(xf, y.Float)}, nil
 			case UntypedInt:
 				z := big.NewInt(0)
 				return UntypedInt{z.Sub(x.Int, y.Int)}, nil
 			case UntypedComplex:
 				re := big.NewFloat(0)
-				xf := big.NewFloat(float64(x.Int.Int64()))
+				xf := new(big.Float).SetInt(x.Int)
 				im := big.NewFloat(0)
 				return UntypedComplex{re.Sub(xf, y.Real), im.Sub(im, y.Imag)}, nil
 			}
@@ -396,11 +439,11 @@ func binOp(op token.Token, x, y interface{}) (interface{}, error) {
 				return UntypedInt{z.Mul(x.Int, y.Int)}, nil
 			case UntypedFloat:
 				z := big.NewFloat(0)
-				xf := big.NewFloat(float64(x.Int.Int64()))
+				xf := new(big.Float).SetInt(x.Int)
 				return UntypedFloat{z.Mul(xf, y.Float)}, nil
 			case UntypedComplex:
 				re := big.NewFloat(0)
-				xf := big.NewFloat(float64(x.Int.Int64()))
+				xf := new(big.Float).SetInt(x.Int)
 				im := big.NewFloat(0)
 				return UntypedComplex{re.Mul(xf, y.Real), im.Mul(xf, y.Imag)}, nil
 			}
@@ -474,10 +517,10 @@ func binOp(op token.Token, x, y interface{}) (interface{}, error) {
 				return UntypedInt{z.Quo(x.Int, y.Int)}, nil
 			case UntypedFloat:
 				z := big.NewFloat(0)
-				xf := big.NewFloat(float64(x.Int.Int64()))
+				xf := new(big.Float).SetInt(x.Int)
 				return UntypedFloat{z.Quo(xf, y.Float)}, nil
 			case UntypedComplex:
-				xf := big.NewFloat(float64(x.Int.Int64()))
+				xf := new(big.Float).SetInt(x.Int)
 				yre2 := big.NewFloat(0)
 				yre2.Mul(y.Real, y.Real)
 				yim2 := big.NewFloat(0)
@@ -629,6 +672,11 @@ func binOp(op token.Token, x, y interface{}) (interface{}, error) {
 			case float64:
 				return x < y, nil
 			}
+		case string:
+			switch y := y.(type) {
+			case string:
+				return x < y, nil
+			}
 		case *big.Int:
 			switch y := y.(type) {
 			case *big.Int:
@@ -639,6 +687,27 @@ func binOp(op token.Token, x, y interface{}) (interface{}, error) {
 			case *big.Float:
 				return x.Cmp(y) == -1, nil
 			}
+		case UntypedInt:
+			switch y := y.(type) {
+			case UntypedInt:
+				return x.Cmp(y.Int) == -1, nil
+			case UntypedFloat:
+				xf := new(big.Float).SetInt(x.Int)
+				return xf.Cmp(y.Float) == -1, nil
+			}
+		case UntypedFloat:
+			switch y := y.(type) {
+			case UntypedInt:
+				yf := new(big.Float).SetInt(y.Int)
+				return x.Cmp(yf) == -1, nil
+			case UntypedFloat:
+				return x.Cmp(y.Float) == -1, nil
+			}
+		case UntypedString:
+			switch y := y.(type) {
+			case UntypedString:
+				return x.String < y.String, nil
+			}
 		}
 	case token.LessEqual:
 		switch x := x.(type) {
@@ -702,6 +771,11 @@ func binOp(op token.Token, x, y interface{}) (interface{}, error) {
 			case float64:
 				return x <= y, nil
 			}
+		case string:
+			switch y := y.(type) {
+			case string:
+				return x <= y, nil
+			}
 		case *big.Int:
 			switch y := y.(type) {
 			case *big.Int:
@@ -712,23 +786,30 @@ func binOp(op token.Token, x, y interface{}) (interface{}, error) {
 			case *big.Float:
 				return x.Cmp(y) <= 0, nil
 			}
+		case UntypedString:
+			switch y := y.(type) {
+			case UntypedString:
+				return x.String <= y.String, nil
+			}
 		case UntypedInt:
 			switch y := y.(type) {
 			case UntypedInt:
 				return x.Cmp(y.Int) <= 0, nil
 			case UntypedFloat:
-				xf := big.NewFloat(float64(x.Int.Int64()))
+				xf := new(big.Float).SetInt(x.Int)
 				return xf.Cmp(y.Float) <= 0, nil
 			}
 		case UntypedFloat:
 			switch y := y.(type) {
 			case UntypedInt:
-				yf := big.NewFloat(float64(y.Int.Int64()))
+				yf := new(big.Float).SetInt(y.Int)
 				return x.Cmp(yf) <= 0, nil
 			case UntypedFloat:
 				return x.Cmp(y.Float) <= 0, nil
 			}
 		}
+	case token.GreaterEqual:
+		return binOp(token.LessEqual, y, x)
 	case token.Greater:
 		switch x := x.(type) {
 		case int:
@@ -791,6 +872,11 @@ func binOp(op token.Token, x, y interface{}) (interface{}, error) {
 			case float64:
 				return x > y, nil
 			}
+		case string:
+			switch y := y.(type) {
+			case string:
+				return x > y, nil
+			}
 		case *big.Int:
 			switch y := y.(type) {
 			case *big.Int:
@@ -801,6 +887,27 @@ func binOp(op token.Token, x, y interface{}) (interface{}, error) {
 			case *big.Float:
 				return x.Cmp(y) == 1, nil
 			}
+		case UntypedInt:
+			switch y := y.(type) {
+			case UntypedInt:
+				return x.Cmp(y.Int) == 1, nil
+			case UntypedFloat:
+				xf := new(big.Float).SetInt(x.Int)
+				return xf.Cmp(y.Float) == 1, nil
+			}
+		case UntypedFloat:
+			switch y := y.(type) {
+			case UntypedInt:
+				yf := new(big.Float).SetInt(y.Int)
+				return x.Cmp(yf) == 1, nil
+			case UntypedFloat:
+				return x.Cmp(y.Float) == 1, nil
+			}
+		case UntypedString:
+			switch y := y.(type) {
+			case UntypedString:
+				return x.String > y.String, nil
+			}
 		}
 	case token.Pipe:
 		switch x := x.(type) {
@@ -0,0 +1,178 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eval
+
+import (
+	"fmt"
+	"reflect"
+
+	"neugram.io/ng/frame/memframe"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/tipe"
+)
+
+// Tables are evaluated as in-memory frames. Rows and sub-tables
+// are copies: assigning to an element of a sub-table does not
+// change the table it was taken from.
+var tableType = reflect.TypeOf((*memframe.Memory)(nil))
+
+func tableLen(t *memframe.Memory) int {
+	if t == nil {
+		return 0
+	}
+	return t.Height
+}
+
+func (p *Program) evalTableLiteral(e *expr.TableLiteral) reflect.Value {
+	elemType := p.toRType(e.Type.Type)
+	width := len(e.ColNames)
+	if len(e.Rows) > 0 {
+		width = len(e.Rows[0])
+	}
+	t := memframe.New(width, len(e.Rows))
+	for x, name := range e.ColNames {
+		t.ColName[x] = p.evalExprOne(name).String()
+	}
+	for y, row := range e.Rows {
+		for x, elem := range row {
+			v := convert(p.implicitConv(elem, p.evalExpr(elem))[0], elemType)
+			t.Data[y*t.Stride+x] = v.Interface()
+		}
+	}
+	return reflect.ValueOf(t)
+}
+
+// tableElem returns the element of t in column x and row y.
+func tableElem(t *memframe.Memory, x, y int, elemType reflect.Type) reflect.Value {
+	v := reflect.New(elemType).Elem()
+	if elem := t.Data[y*t.Stride+x]; elem != nil {
+		v.Set(reflect.ValueOf(elem))
+	}
+	return v
+}
+
+// tableRow returns a copy of row y of t.
+func tableRow(t *memframe.Memory, y int, rowType reflect.Type) reflect.Value {
+	row := reflect.MakeSlice(rowType, t.Width, t.Width)
+	for x := 0; x < t.Width; x++ {
+		row.Index(x).Set(tableElem(t, x, y, rowType.Elem()))
+	}
+	return row
+}
+
+// subTable returns a copy of the rows y0 to y1 of the columns cols of t.
+func subTable(t *memframe.Memory, cols []int, y0, y1 int) *memframe.Memory {
+	rows := t.Slice(0, t.Width, y0, y1-y0).(*memframe.Memory)
+	return rows.Permute(cols).(*memframe.Memory)
+}
+
+func (p *Program) tableIndex(e expr.Expr, n int, what string) int {
+	i := int(p.evalExprOne(e).Int())
+	if i < 0 || i >= n {
+		panic(interpPanic{fmt.Errorf("table %s index %d out of range [0:%d]", what, i, n)})
+	}
+	return i
+}
+
+func (p *Program) tableSlice(s *expr.Slice, n int, what string) (lo, hi int) {
+	lo, hi = 0, n
+	if s.Low != nil {
+		lo = int(p.evalExprOne(s.Low).Int())
+	}
+	if s.High != nil {
+		hi = int(p.evalExprOne(s.High).Int())
+	}
+	if lo < 0 || lo > hi || hi > n {
+		panic(interpPanic{fmt.Errorf("table %s slice bounds out of range [%d:%d] with length %d", what, lo, hi, n)})
+	}
+	return lo, hi
+}
+
+// evalTableRows evaluates t[i], a row of t, or t[i:j], a table.
+func (p *Program) evalTableRows(e *expr.Index, t *memframe.Memory) reflect.Value {
+	if t == nil {
+		t = memframe.New(0, 0)
+	}
+	if s, isSlice := e.Indicies[0].(*expr.Slice); isSlice {
+		y0, y1 := p.tableSlice(s, t.Height, "row")
+		return reflect.ValueOf(subTable(t, allCols(t), y0, y1))
+	}
+	y := p.tableIndex(e.Indicies[0], t.Height, "row")
+	return tableRow(t, y, p.toRType(p.Types.Type(e)))
+}
+
+// evalTableIndex evaluates t[rows|cols].
+func (p *Program) evalTableIndex(e *expr.TableIndex) reflect.Value {
+	t, cols, y0, y1, isElem := p.tableIndices(e)
+	if isElem {
+		return tableElem(t, cols[0], y0, p.toRType(p.Types.Type(e)))
+	}
+	return reflect.ValueOf(subTable(t, cols, y0, y1))
+}
+
+// setTableElem evaluates t[row|col] = v.
+func (p *Program) setTableElem(e *expr.TableIndex, v reflect.Value) {
+	t, cols, y, _, _ := p.tableIndices(e)
+	t.Data[y*t.Stride+cols[0]] = convert(v, p.toRType(p.Types.Type(e))).Interface()
+}
+
+// tableIndices evaluates the table and the indexes of t[rows|cols].
+// It reports the selected columns, the range of rows y0 to y1, and
+// whether they select a single element.
+func (p *Program) tableIndices(e *expr.TableIndex) (t *memframe.Memory, cols []int, y0, y1 int, isElem bool) {
+	t, _ = p.evalExprOne(e.Left).Interface().(*memframe.Memory)
+	if t == nil {
+		t = memframe.New(0, 0)
+	}
+	isElem = e.Rows != nil && len(e.Cols) == 1
+
+	y0, y1 = 0, t.Height
+	if s, isSlice := e.Rows.(*expr.Slice); isSlice {
+		y0, y1 = p.tableSlice(s, t.Height, "row")
+		isElem = false
+	} else if e.Rows != nil {
+		y0 = p.tableIndex(e.Rows, t.Height, "row")
+		y1 = y0 + 1
+	}
+
+	cols = allCols(t)
+	if len(e.Cols) > 0 {
+		cols = cols[:0]
+	}
+	for _, col := range e.Cols {
+		if s, isSlice := col.(*expr.Slice); isSlice {
+			x0, x1 := p.tableSlice(s, t.Width, "column")
+			for x := x0; x < x1; x++ {
+				cols = append(cols, x)
+			}
+			isElem = false
+			continue
+		}
+		if tipe.Underlying(p.Types.Type(col)) == tipe.String {
+			cols = append(cols, tableColumn(t, p.evalExprOne(col).String()))
+			continue
+		}
+		cols = append(cols, p.tableIndex(col, t.Width, "column"))
+	}
+	return t, cols, y0, y1, isElem
+}
+
+func allCols(t *memframe.Memory) []int {
+	cols := make([]int, t.Width)
+	for x := range cols {
+		cols[x] = x
+	}
+	return cols
+}
+
+// tableColumn returns the index of the column of t called name.
+func tableColumn(t *memframe.Memory, name string) int {
+	for x, colName := range t.ColName {
+		if colName == name {
+			return x
+		}
+	}
+	panic(interpPanic{fmt.Errorf("table has no column %q", name)})
+}
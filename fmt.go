@@ -0,0 +1,145 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"neugram.io/ng/format"
+	"neugram.io/ng/parser"
+)
+
+const fmtUsage = `usage: ng fmt [-w] [-d] [path ...]
+
+Fmt formats Neugram programs. With no paths it formats the
+standard input. Directories are searched for .ng files.
+`
+
+func cmdFmt(args []string) {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	flagW := flags.Bool("w", false, "write result to (source) file instead of stdout")
+	flagD := flags.Bool("d", false, "display diffs instead of rewriting files")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, fmtUsage)
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		if *flagW {
+			exitf("fmt: cannot use -w with standard input")
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			exitf("fmt: %v", err)
+		}
+		if err := fmtSource("<standard input>", src, false, *flagD); err != nil {
+			exitf("fmt: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	status := 0
+	for _, root := range flags.Args() {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || (path != root && !strings.HasSuffix(path, ".ng")) {
+				return nil
+			}
+			src, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return fmtSource(path, src, *flagW, *flagD)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ng fmt: %v\n", err)
+			status = 2
+		}
+	}
+	os.Exit(status)
+}
+
+// fmtSource formats src, the contents of filename.
+// Without write or diff the result is printed to stdout.
+func fmtSource(filename string, src []byte, write, diff bool) error {
+	p := parser.New(filename, parser.ParseComments)
+	f, err := p.Parse(src)
+	state := p.State()
+	p.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	switch state {
+	case parser.StateStmtPartial, parser.StateCmdPartial:
+		return fmt.Errorf("%s: unexpected EOF", filename)
+	}
+	res, err := format.Source(f, src)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	if !write && !diff {
+		_, err := os.Stdout.Write(res)
+		return err
+	}
+	if bytes.Equal(src, res) {
+		return nil
+	}
+	if write {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filename, res, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if diff {
+		d, err := diffSource(filename, src, res)
+		if err != nil {
+			return fmt.Errorf("computing diff: %v", err)
+		}
+		os.Stdout.Write(d)
+	}
+	return nil
+}
+
+// diffSource runs diff -u on the original and formatted source,
+// as gofmt does.
+func diffSource(filename string, src, res []byte) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "ngfmt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	orig := filepath.Join(dir, "orig")
+	formatted := filepath.Join(dir, "formatted")
+	if err := ioutil.WriteFile(orig, src, 0666); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(formatted, res, 0666); err != nil {
+		return nil, err
+	}
+
+	out, err := exec.Command("diff", "-u",
+		"--label", filename+".orig", orig,
+		"--label", filename, formatted).CombinedOutput()
+	if len(out) > 0 {
+		// diff exits with status 1 when the files differ.
+		return out, nil
+	}
+	return nil, err
+}
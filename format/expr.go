@@ -11,6 +11,7 @@ import (
 	"fmt"
 
 	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/src"
 	"neugram.io/ng/syntax/stmt"
 	"neugram.io/ng/syntax/token"
 )
@@ -18,21 +19,44 @@ import (
 type printer struct {
 	buf    *bytes.Buffer
 	indent int
+
+	// Source formatting state, see Source.
+	source   bool           // print canonical source, not debug output
+	src      [][]byte       // lines of the original source
+	comments []*src.Comment // comments not yet printed
+	lastLine int            // last source line printed
+	depth    int            // expression nesting depth, as in gofmt
+	err      error          // first unprintable node
 }
 
 func (p *printer) expr(e expr.Expr) {
 	switch e := e.(type) {
 	case *expr.Binary:
-		WriteExpr(p.buf, e.Left)
+		if p.source {
+			p.binary(e)
+			return
+		}
+		p.expr(e.Left)
 		p.buf.WriteString(e.Op.String())
-		WriteExpr(p.buf, e.Right)
+		p.expr(e.Right)
 	case *expr.Unary:
 		p.buf.WriteString(e.Op.String())
-		WriteExpr(p.buf, e.Expr)
+		if p.source && e.Op == token.Range {
+			p.buf.WriteByte(' ')
+		}
 		if e.Op == token.LeftParen {
+			depth := p.depth
+			if p.depth > 1 {
+				p.depth--
+			}
+			p.expr(e.Expr)
+			p.depth = depth
 			p.buf.WriteByte(')')
+		} else {
+			p.expr(e.Expr)
 		}
 	case *expr.Bad:
+		p.errorf("format: bad expression: %v", e.Error)
 		fmt.Fprintf(p.buf, "bad(%q)", e.Error)
 	case *expr.Slice:
 		if e.Low != nil {
@@ -50,6 +74,10 @@ func (p *printer) expr(e expr.Expr) {
 		p.expr(e.Left)
 		p.buf.WriteString("." + e.Right.Name)
 	case *expr.BasicLiteral:
+		if p.source {
+			p.literal(e)
+			return
+		}
 		p.buf.WriteString(fmt.Sprintf("%v", e.Value))
 	case *expr.FuncLiteral:
 		p.buf.WriteString("func")
@@ -64,6 +92,7 @@ func (p *printer) expr(e expr.Expr) {
 			p.buf.WriteByte(' ')
 			p.buf.WriteString(e.Name)
 		}
+		p.typeParams(e.Type.TypeParams)
 		p.buf.WriteByte('(')
 
 		// Similar to tipeFuncSig, but with parameter names.
@@ -72,11 +101,13 @@ func (p *printer) expr(e expr.Expr) {
 				if i > 0 {
 					p.buf.WriteString(", ")
 				}
+				p.buf.WriteString(name)
+				if i < len(e.ParamsGrouped) && e.ParamsGrouped[i] {
+					continue
+				}
 				if name != "" {
-					p.buf.WriteString(name)
 					p.buf.WriteByte(' ')
 				}
-				// TODO: elide types for equal subsequent params
 				p.tipe(e.Type.Params.Elems[i])
 			}
 		}
@@ -90,11 +121,13 @@ func (p *printer) expr(e expr.Expr) {
 				if i > 0 {
 					p.buf.WriteString(", ")
 				}
+				p.buf.WriteString(name)
+				if i < len(e.ResultsGrouped) && e.ResultsGrouped[i] {
+					continue
+				}
 				if name != "" {
-					p.buf.WriteString(name)
 					p.buf.WriteByte(' ')
 				}
-				// TODO: elide types for equal subsequent params
 				p.tipe(e.Type.Results.Elems[i])
 			}
 			p.buf.WriteString(")")
@@ -107,83 +140,26 @@ func (p *printer) expr(e expr.Expr) {
 	case *expr.CompLiteral:
 		p.tipe(e.Type)
 		p.print("{")
-		if len(e.Keys) > 0 {
-			p.indent++
-			for i, key := range e.Keys {
-				p.newline()
-				p.expr(key)
-				p.print(": ")
-				p.expr(e.Values[i])
-				p.print(",")
-			}
-			p.indent--
-			p.newline()
-		} else if len(e.Values) > 0 {
-			for i, elem := range e.Values {
-				if i > 0 {
-					p.print(", ")
-				}
-				p.expr(elem)
-			}
-		}
+		p.elems(e.Position, e.Keys, e.Values, len(e.Keys) > 0)
 		p.print("}")
 	case *expr.MapLiteral:
 		p.tipe(e.Type)
 		p.print("{")
-		p.indent++
-		for i, key := range e.Keys {
-			p.newline()
-			p.expr(key)
-			p.print(": ")
-			p.expr(e.Values[i])
-			p.print(",")
+		if p.source && len(e.Keys) == 0 {
+			p.print("}")
+			return
 		}
-		p.indent--
-		p.newline()
+		p.elems(e.Position, e.Keys, e.Values, true)
 		p.print("}")
 	case *expr.ArrayLiteral:
 		p.tipe(e.Type)
 		p.print("{")
-		switch len(e.Keys) {
-		case 0:
-			for i, elem := range e.Values {
-				if i > 0 {
-					p.print(", ")
-				}
-				p.expr(elem)
-			}
-		default:
-			for i, elem := range e.Values {
-				if i > 0 {
-					p.print(", ")
-				}
-				p.expr(e.Keys[i])
-				p.print(": ")
-				p.expr(elem)
-			}
-		}
+		p.elems(e.Position, e.Keys, e.Values, false)
 		p.print("}")
 	case *expr.SliceLiteral:
 		p.tipe(e.Type)
 		p.print("{")
-		switch len(e.Keys) {
-		case 0:
-			for i, elem := range e.Values {
-				if i > 0 {
-					p.print(", ")
-				}
-				p.expr(elem)
-			}
-		default:
-			for i, elem := range e.Values {
-				if i > 0 {
-					p.print(", ")
-				}
-				p.expr(e.Keys[i])
-				p.print(": ")
-				p.expr(elem)
-			}
-		}
+		p.elems(e.Position, e.Keys, e.Values, false)
 		p.print("}")
 	case *expr.TableLiteral:
 		p.tipe(e.Type)
@@ -198,20 +174,16 @@ func (p *printer) expr(e expr.Expr) {
 			}
 			p.print("|}")
 		}
-		if len(e.Rows) > 0 {
-			p.print(", ")
-			for i, row := range e.Rows {
-				if i > 0 {
+		for i, row := range e.Rows {
+			if i > 0 || len(e.ColNames) > 0 {
+				p.print(", ")
+			}
+			p.print("{")
+			for j, r := range row {
+				if j > 0 {
 					p.print(", ")
 				}
-				p.print("{")
-				for j, r := range row {
-					if j > 0 {
-						p.print(", ")
-					}
-					p.expr(r)
-				}
-				p.print("}")
+				p.expr(r)
 			}
 			p.print("}")
 		}
@@ -223,12 +195,30 @@ func (p *printer) expr(e expr.Expr) {
 	case *expr.Index:
 		p.expr(e.Left)
 		p.buf.WriteString("[")
+		p.depth++
 		for i, idx := range e.Indicies {
 			if i > 0 {
-				p.buf.WriteString(":")
+				p.buf.WriteString(", ")
 			}
 			p.expr(idx)
 		}
+		p.depth--
+		p.buf.WriteString("]")
+	case *expr.TableIndex:
+		p.expr(e.Left)
+		p.buf.WriteString("[")
+		p.depth++
+		if e.Rows != nil {
+			p.expr(e.Rows)
+		}
+		p.buf.WriteString("|")
+		for i, col := range e.Cols {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			p.expr(col)
+		}
+		p.depth--
 		p.buf.WriteString("]")
 	case *expr.TypeAssert:
 		p.expr(e.Left)
@@ -236,27 +226,40 @@ func (p *printer) expr(e expr.Expr) {
 		if e.Type == nil {
 			p.buf.WriteString("type")
 		} else {
-			WriteType(p.buf, e.Type)
+			p.tipe(e.Type)
 		}
 		p.buf.WriteString(")")
 	case *expr.Call:
-		WriteExpr(p.buf, e.Func)
+		p.expr(e.Func)
 		p.buf.WriteString("(")
+		if len(e.Args) > 1 {
+			p.depth++
+		}
 		for i, arg := range e.Args {
 			if i > 0 {
 				p.buf.WriteString(", ")
 			}
-			WriteExpr(p.buf, arg)
+			p.expr(arg)
+		}
+		if e.Ellipsis {
+			p.buf.WriteString("...")
+		}
+		if len(e.Args) > 1 {
+			p.depth--
 		}
 		p.buf.WriteString(")")
 	case *expr.Shell:
-		if len(e.Cmds) == 1 {
+		multiline, cmdLines := p.shellLayout(e)
+		if len(e.Cmds) == 1 && !multiline {
 			p.buf.WriteString("$$ ")
 			p.expr(e.Cmds[0])
 			p.buf.WriteString(" $$")
 		} else {
 			p.buf.WriteString("$$")
-			for _, cmd := range e.Cmds {
+			for i, cmd := range e.Cmds {
+				if i > 0 && cmdLines != nil && p.blankBetween(cmdLines[i-1], cmdLines[i]) {
+					p.buf.WriteByte('\n')
+				}
 				p.newline()
 				p.expr(cmd)
 			}
@@ -346,6 +349,7 @@ func (p *printer) expr(e expr.Expr) {
 			p.buf.WriteString(r.Filename)
 		}
 	default:
+		p.errorf("format: unknown expr %T", e)
 		p.printf("format: unknown expr %T: ", e)
 		WriteDebug(p.buf, e)
 	}
@@ -359,6 +363,14 @@ func (p *printer) print(args ...interface{}) {
 	fmt.Fprint(p.buf, args...)
 }
 
+// errorf records the first error encountered while printing source.
+// Debug output is best-effort and has no errors.
+func (p *printer) errorf(format string, args ...interface{}) {
+	if p.source && p.err == nil {
+		p.err = fmt.Errorf(format, args...)
+	}
+}
+
 func (p *printer) newline() {
 	p.buf.WriteByte('\n')
 	for i := 0; i < p.indent; i++ {
@@ -378,3 +390,50 @@ func Expr(e expr.Expr) string {
 	WriteExpr(buf, e)
 	return buf.String()
 }
+
+// elems prints the elements of a literal that starts at pos, with
+// their keys if there are any. They are printed one per line if
+// multiline is set or, in source mode, if they were written on
+// lines after the literal's opening brace. A comment on the line of
+// an element stays with that element.
+func (p *printer) elems(pos src.Pos, keys, values []expr.Expr, multiline bool) {
+	elem := func(i int) {
+		if len(keys) > 0 {
+			p.expr(keys[i])
+			p.print(": ")
+		}
+		p.expr(values[i])
+	}
+	if p.source && pos.Line > 0 {
+		for _, v := range values {
+			if first, _ := lines(v); first > int(pos.Line) {
+				multiline = true
+			}
+		}
+	}
+	if !multiline {
+		for i := range values {
+			if i > 0 {
+				p.print(", ")
+			}
+			elem(i)
+		}
+		return
+	}
+	p.indent++
+	for i, v := range values {
+		first, last := lines(v)
+		if len(keys) > 0 {
+			first, _ = lines(keys[i])
+		}
+		p.item(first)
+		elem(i)
+		p.print(",")
+		p.endItem(last)
+	}
+	if p.lastLine > 0 {
+		p.flush(p.lastLine + 1)
+	}
+	p.indent--
+	p.newline()
+}
@@ -24,7 +24,17 @@ $$`,
 
 	"x[:y]",
 	"x[y:z:t]",
+	"t[i|j]",
+	"t[1:|]",
+	"t[:|c, 2:]",
+	"t[i|(a|b)]",
+	"[|]int{{1, 2}, {3, 4}}",
+	"[|]float64{{|x, y|}, {1, 2}}",
+	"a*2+b",
 	"new(int)",
+	"append(x, y...)",
+	"func Map[T, U any](xs []T, f func(T) U) []U {return nil}",
+	"Map[int, string](xs, f)",
 }
 
 var roundTripStmts = []string{
@@ -45,6 +55,17 @@ var roundTripStmts = []string{
 } {
 	func (f) F() int {return f.I}
 }`,
+
+	`type Pair[K comparable, V any] struct {
+	Key K
+	Val V
+}`,
+
+	`methodik Stack[T any] struct {
+	Items []T
+} {
+	func (s) Len() int {return len(s.Items)}
+}`,
 }
 
 func TestRoundTrip(t *testing.T) {
@@ -94,6 +115,10 @@ var typeTests = []string{
 	M2(*int) error
 }`,
 	`struct{}`,
+	`interface {
+	~int | ~int64 | float64
+}`,
+	`func(Pair[int, string]) Stack[float64]`,
 }
 
 func TestTypes(t *testing.T) {
@@ -114,3 +139,99 @@ func TestTypes(t *testing.T) {
 		}
 	}
 }
+
+var sourceTests = []struct {
+	src, want string
+}{
+	{"x:=1+2*3\n", "x := 1 + 2*3\n"},
+	{"y := (a+b)*c\nz := f(a+b*c, d)\n", "y := (a + b) * c\nz := f(a+b*c, d)\n"},
+	{"s := x[i+1]\n", "s := x[i+1]\n"},
+	{"x := 0x1f\nr := 'a'\ns := `raw`\n", "x := 0x1f\nr := 'a'\ns := `raw`\n"},
+	{"x++\ny -= 2\nch<-x\n", "x++\ny -= 2\nch <- x\n"},
+	{"a := 1\n\n\n\nb := 2\n", "a := 1\n\nb := 2\n"},
+	{
+		"// Header.\n\nx := 1 // one\n/* two */\ny := 2\n// end\n",
+		"// Header.\n\nx := 1 // one\n/* two */\ny := 2\n// end\n",
+	},
+	{
+		"func f(x int) int { if x > 0 { // positive\nreturn x }\n// zero\nreturn 0 }\n",
+		"func f(x int) int {\n\tif x > 0 { // positive\n\t\treturn x\n\t}\n\t// zero\n\treturn 0\n}\n",
+	},
+	{
+		"for i := 0; i < 3; i++ {\n}\nfor k, v := range m { print(k, v) }\n",
+		"for i := 0; i < 3; i++ {}\nfor k, v := range m {\n\tprint(k, v)\n}\n",
+	},
+	{
+		"switch x {\ncase 1, 2: // small\n\tf()\ndefault:\n}\n",
+		"switch x {\ncase 1, 2: // small\n\tf()\ndefault:\n}\n",
+	},
+	{
+		"const (\n\tA = iota\n\tB\n)\nvar v int\n",
+		"const (\n\tA = iota\n\tB\n)\nvar v int\n",
+	},
+	{
+		"const (\n\t_ = iota\n\tKB Size = 1 << (10*iota)\n\tMB\n)\n",
+		"const (\n\t_ = iota\n\tKB Size = 1 << (10 * iota)\n\tMB\n)\n",
+	},
+	{"m := map[string]int{}\n", "m := map[string]int{}\n"},
+	{
+		"m := map[string]int{\n\t\"a\": 1,\n\t\"b\": 2, // two\n}\n",
+		"m := map[string]int{\n\t\"a\": 1,\n\t\"b\": 2, // two\n}\n",
+	},
+	{
+		"p := Point{\n\tX: 1, // x\n\tY: 2,\n}\n",
+		"p := Point{\n\tX: 1, // x\n\tY: 2,\n}\n",
+	},
+	{
+		"s := []int{\n\t1, // one\n\t2,\n\t3, // three\n}\nt := []int{1, 2}\n",
+		"s := []int{\n\t1, // one\n\t2,\n\t3, // three\n}\nt := []int{1, 2}\n",
+	},
+	{
+		"if x {\n} else {\n\tf()\n}\nif y {} else {}\n",
+		"if x {\n} else {\n\tf()\n}\nif y {\n} else {\n}\n",
+	},
+	{"func Add(a, b int) (x, y int, err error) { return a, b, nil }\n", "func Add(a, b int) (x, y int, err error) {\n\treturn a, b, nil\n}\n"},
+	{"$$\n  echo a\necho b|wc -l\n$$\n", "$$\necho a\necho b | wc -l\n$$\n"},
+	{"$$ ls $$\n\n$$ pwd $$\n", "$$ ls $$\n\n$$ pwd $$\n"},
+	{"$$\nls\n$$\n", "$$\nls\n$$\n"},
+	{"$$\necho 1\n\n\necho 2 \\\n3\necho 4\n$$\n", "$$\necho 1\n\necho 2 \\\n3\necho 4\n$$\n"},
+	{"x := $$\necho a\n$$\ny := $$ echo b $$\n", "x := $$\necho a\n$$\ny := $$ echo b $$\n"},
+	{
+		"func f() {\n\ty := $$\n\t\techo a\n\n\t\techo b\n\t$$\n\n\tprint(y)\n}\n",
+		"func f() {\n\ty := $$\n\techo a\n\n\techo b\n\t$$\n\n\tprint(y)\n}\n",
+	},
+}
+
+func TestSource(t *testing.T) {
+	for _, test := range sourceTests {
+		f, err := parser.New("test.ng", parser.ParseComments).Parse([]byte(test.src))
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.src, err)
+			continue
+		}
+		got, err := format.Source(f, []byte(test.src))
+		if err != nil {
+			t.Errorf("Source(%q): %v", test.src, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("Source(%q):\n%s\nwant:\n%s", test.src, got, test.want)
+			continue
+		}
+
+		// Formatting must be idempotent.
+		f, err = parser.New("test.ng", parser.ParseComments).Parse(got)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", got, err)
+			continue
+		}
+		again, err := format.Source(f, got)
+		if err != nil {
+			t.Errorf("Source(%q): %v", got, err)
+			continue
+		}
+		if string(again) != string(got) {
+			t.Errorf("Source is not idempotent:\n%s\nthen:\n%s", got, again)
+		}
+	}
+}
@@ -0,0 +1,755 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"neugram.io/ng/syntax"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/src"
+	"neugram.io/ng/syntax/stmt"
+	"neugram.io/ng/syntax/tipe"
+	"neugram.io/ng/syntax/token"
+)
+
+// Source formats a Neugram file in canonical style.
+//
+// The file f must have been parsed from src, which is used to place
+// the comments in f.Comments and to preserve blank lines between
+// statements. Comments are only kept if f was parsed with the
+// parser.ParseComments mode. Source is idempotent: formatting its output again
+// produces the same output.
+//
+// Source reports an error if f contains nodes it cannot print,
+// such as those produced by a parse error.
+func Source(f *syntax.File, source []byte) ([]byte, error) {
+	p := &printer{
+		buf:    new(bytes.Buffer),
+		source: true,
+		src:    bytes.Split(source, []byte("\n")),
+		depth:  1,
+	}
+	for _, g := range f.Comments {
+		p.comments = append(p.comments, g.List...)
+	}
+	if len(p.src) > 0 && bytes.HasPrefix(p.src[0], []byte("#!")) {
+		p.buf.Write(bytes.TrimSpace(p.src[0]))
+		p.lastLine = 1
+	}
+	for i := 0; i < len(f.Stmts); i++ {
+		s := f.Stmts[i]
+		if shellCmd(s) == nil {
+			p.stmtItem(s)
+			continue
+		}
+
+		// Shell commands outside of an expression are parsed
+		// one line at a time. Regroup them into $$ blocks.
+		cmds := []*expr.ShellList{shellCmd(s).Cmds[0]}
+		first := int(s.Pos().Line)
+		last := first
+		if !p.shellDelim(first) {
+			for first > 1 && !p.shellDelim(first-1) {
+				first--
+			}
+			first--
+			for i+1 < len(f.Stmts) && shellCmd(f.Stmts[i+1]) != nil {
+				next := int(f.Stmts[i+1].Pos().Line)
+				if p.shellDelim(next) || p.shellDelimBetween(last, next) {
+					break
+				}
+				i++
+				cmds = append(cmds, shellCmd(f.Stmts[i]).Cmds[0])
+				last = next
+			}
+			last++
+		}
+		p.item(first)
+		p.expr(&expr.Shell{
+			Position: src.Pos{Line: int32(first)},
+			Cmds:     cmds,
+		})
+		p.endItem(last)
+	}
+	p.flush(math.MaxInt32)
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	b := bytes.TrimLeft(p.buf.Bytes(), "\n")
+	if len(b) > 0 {
+		b = append(b, '\n')
+	}
+	return b, nil
+}
+
+// shellCmd returns the shell expression of a top-level shell command.
+// These are statements parsed in shell mode, which have no column.
+func shellCmd(s stmt.Stmt) *expr.Shell {
+	simple, ok := s.(*stmt.Simple)
+	if !ok {
+		return nil
+	}
+	sh, ok := simple.Expr.(*expr.Shell)
+	if !ok || sh.Position.Line == 0 || sh.Position.Column != 0 || len(sh.Cmds) != 1 {
+		return nil
+	}
+	return sh
+}
+
+// shellDelim reports whether the source line starts with $$.
+func (p *printer) shellDelim(line int) bool {
+	if line < 1 || line > len(p.src) {
+		return false
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(p.src[line-1]), []byte("$$"))
+}
+
+func (p *printer) shellDelimBetween(from, to int) bool {
+	for line := from + 1; line < to; line++ {
+		if p.shellDelim(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// shellLayout reports whether the $$ block e was written over
+// several lines in the source, with the opening $$ on a line of
+// its own, and if so the source line of each of its commands.
+// The parser does not record the positions of shell commands, so
+// they are found by reading the lines of the block. If that fails,
+// cmdLines is nil. The block's closing line is marked as printed.
+func (p *printer) shellLayout(e *expr.Shell) (multiline bool, cmdLines []int) {
+	open := int(e.Position.Line)
+	if !p.source || open < 1 || open > len(p.src) {
+		return false, nil
+	}
+	rest := p.src[open-1]
+	if col := int(e.Position.Column); col > 0 && col <= len(rest) {
+		rest = rest[col-1:]
+	}
+	rest = bytes.TrimSpace(rest)
+	if !bytes.HasPrefix(rest, []byte("$$")) || len(bytes.TrimSpace(rest[2:])) > 0 {
+		return false, nil
+	}
+
+	cont := false // previous line ends in a backslash
+	line := open + 1
+	for ; line <= len(p.src); line++ {
+		text := bytes.TrimSpace(p.src[line-1])
+		if bytes.HasPrefix(text, []byte("$$")) {
+			break
+		}
+		if len(text) > 0 && !cont {
+			cmdLines = append(cmdLines, line)
+		}
+		cont = bytes.HasSuffix(text, []byte(`\`))
+	}
+	if line > p.lastLine {
+		p.lastLine = line
+	}
+	if len(cmdLines) != len(e.Cmds) {
+		cmdLines = nil
+	}
+	return true, cmdLines
+}
+
+// lines reports the range of source lines spanned by n,
+// as far as the positions recorded in the syntax tree tell.
+func lines(n syntax.Node) (first, last int) {
+	add := func(line int32) {
+		if line <= 0 {
+			return
+		}
+		if first == 0 || int(line) < first {
+			first = int(line)
+		}
+		if int(line) > last {
+			last = int(line)
+		}
+	}
+	syntax.Walk(n, func(c *syntax.Cursor) bool {
+		switch c.Node.(type) {
+		case nil, *src.CommentGroup:
+			return false // doc comments are printed as comments
+		}
+		add(c.Node.Pos().Line)
+		if b, isBlock := c.Node.(*stmt.Block); isBlock {
+			add(b.Rbrace.Line)
+		}
+		return true
+	}, nil)
+	return first, last
+}
+
+// blankBetween reports whether there is an empty source line
+// strictly between the lines from and to.
+func (p *printer) blankBetween(from, to int) bool {
+	for line := from + 1; line < to && line <= len(p.src); line++ {
+		if line > 0 && len(bytes.TrimSpace(p.src[line-1])) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// item starts a new line for an element of a list, such as a
+// statement in a block, that begins on source line first.
+// Comments preceding the element are printed first, and up to
+// one blank line is kept from the source.
+//
+// After printing the element, call endItem with its last line.
+func (p *printer) item(first int) {
+	if first > 0 {
+		p.flush(first)
+		if p.lastLine > 0 && p.blankBetween(p.lastLine, first) {
+			p.buf.WriteByte('\n')
+		}
+		if first > p.lastLine {
+			p.lastLine = first
+		}
+	}
+	p.newline()
+}
+
+func (p *printer) endItem(last int) {
+	if last > p.lastLine {
+		p.lastLine = last
+	}
+}
+
+// stmtItem prints s as an item of a statement list.
+func (p *printer) stmtItem(s stmt.Stmt) {
+	first, last := lines(s)
+	p.item(first)
+	p.stmt(s)
+	p.endItem(last)
+}
+
+// flush prints the pending comments that appear before line.
+// A comment on the last printed line is kept at the end of it.
+func (p *printer) flush(line int) {
+	for len(p.comments) > 0 && int(p.comments[0].Position.Line) < line {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		cline := int(c.Position.Line)
+		if cline == p.lastLine && p.buf.Len() > 0 {
+			p.buf.WriteByte(' ')
+		} else {
+			if p.lastLine > 0 && p.blankBetween(p.lastLine, cline) {
+				p.buf.WriteByte('\n')
+			}
+			p.newline()
+		}
+		p.buf.WriteString(c.Text)
+		if end := cline + strings.Count(c.Text, "\n"); end > p.lastLine {
+			p.lastLine = end
+		}
+	}
+}
+
+// block prints b. An empty block is printed as {}, or over two
+// lines if multiline is set, as for the branches of an if statement.
+func (p *printer) block(b *stmt.Block, multiline bool) {
+	p.buf.WriteByte('{')
+	start := p.buf.Len()
+	p.indent++
+	depth := p.depth
+	p.depth = 1
+	for _, s := range b.Stmts {
+		p.stmtItem(s)
+	}
+	if b.Rbrace.Line > 0 {
+		p.flush(int(b.Rbrace.Line))
+	}
+	p.depth = depth
+	p.indent--
+	if p.buf.Len() > start || multiline {
+		p.newline()
+	}
+	p.buf.WriteByte('}')
+	if line := int(b.Rbrace.Line); line > p.lastLine {
+		p.lastLine = line
+	}
+}
+
+// caseBody prints the statements of a switch or select case
+// whose clause is on the given line.
+func (p *printer) caseBody(line int, b *stmt.Block) {
+	p.flush(line + 1)
+	if b == nil {
+		return
+	}
+	p.indent++
+	for _, s := range b.Stmts {
+		p.stmtItem(s)
+	}
+	p.indent--
+}
+
+func (p *printer) sourceStmt(s stmt.Stmt) {
+	switch s := s.(type) {
+	case *stmt.Import:
+		if s.Name != "" {
+			fmt.Fprintf(p.buf, "import %s %q", s.Name, s.Path)
+		} else {
+			fmt.Fprintf(p.buf, "import %q", s.Path)
+		}
+	case *stmt.ImportSet:
+		p.buf.WriteString("import (")
+		p.indent++
+		for _, imp := range s.Imports {
+			p.item(int(imp.Position.Line))
+			if imp.Name != "" {
+				fmt.Fprintf(p.buf, "%s %q", imp.Name, imp.Path)
+			} else {
+				fmt.Fprintf(p.buf, "%q", imp.Path)
+			}
+			p.endItem(int(imp.Position.Line))
+		}
+		p.indent--
+		if len(s.Imports) > 0 {
+			p.newline()
+		}
+		p.buf.WriteString(")")
+	case *stmt.TypeDecl:
+		p.buf.WriteString("type ")
+		p.typeSpec(s)
+	case *stmt.TypeDeclSet:
+		p.buf.WriteString("type (")
+		p.indent++
+		for _, t := range s.TypeDecls {
+			first, last := lines(t)
+			p.item(first)
+			p.typeSpec(t)
+			p.endItem(last)
+		}
+		p.indent--
+		if len(s.TypeDecls) > 0 {
+			p.newline()
+		}
+		p.buf.WriteString(")")
+	case *stmt.MethodikDecl:
+		p.buf.WriteString("methodik ")
+		p.buf.WriteString(s.Name)
+		p.typeParams(s.Type.TypeParams)
+		p.buf.WriteString(" ")
+		p.tipe(s.Type.Type)
+		if len(s.Methods) == 0 {
+			p.buf.WriteString(" {}")
+			return
+		}
+		p.buf.WriteString(" {")
+		p.indent++
+		for _, m := range s.Methods {
+			first, last := lines(m)
+			p.item(first)
+			p.expr(m)
+			p.endItem(last)
+		}
+		p.indent--
+		p.newline()
+		p.buf.WriteString("}")
+	case *stmt.Const:
+		p.buf.WriteString("const ")
+		p.valueSpec(s.NameList, s.Type, s.Values)
+	case *stmt.ConstSet:
+		p.buf.WriteString("const (")
+		p.indent++
+		for _, c := range s.Consts {
+			first, last := lines(c)
+			p.item(first)
+			p.valueSpec(c.NameList, c.Type, c.Values)
+			p.endItem(last)
+		}
+		p.indent--
+		if len(s.Consts) > 0 {
+			p.newline()
+		}
+		p.buf.WriteString(")")
+	case *stmt.Var:
+		p.buf.WriteString("var ")
+		p.valueSpec(s.NameList, s.Type, s.Values)
+	case *stmt.VarSet:
+		p.buf.WriteString("var (")
+		p.indent++
+		for _, v := range s.Vars {
+			first, last := lines(v)
+			p.item(first)
+			p.valueSpec(v.NameList, v.Type, v.Values)
+			p.endItem(last)
+		}
+		p.indent--
+		if len(s.Vars) > 0 {
+			p.newline()
+		}
+		p.buf.WriteString(")")
+	case *stmt.Simple:
+		p.expr(s.Expr)
+	case *stmt.Return:
+		p.buf.WriteString("return")
+		if len(s.Exprs) > 0 {
+			p.buf.WriteByte(' ')
+		}
+		p.exprList(s.Exprs)
+	case *stmt.Assign:
+		if op, incDec := incDec(s); incDec {
+			p.expr(s.Left[0])
+			p.buf.WriteString(op)
+			return
+		}
+		if bin := opAssign(s); bin != nil {
+			p.expr(s.Left[0])
+			p.printf(" %s= ", bin.Op)
+			p.expr(bin.Right)
+			return
+		}
+		p.exprList(s.Left)
+		if s.Decl {
+			p.buf.WriteString(" := ")
+		} else {
+			p.buf.WriteString(" = ")
+		}
+		p.exprList(s.Right)
+	case *stmt.Send:
+		p.expr(s.Chan)
+		p.buf.WriteString(" <- ")
+		p.expr(s.Value)
+	case *stmt.Block:
+		p.block(s, false)
+	case *stmt.If:
+		p.buf.WriteString("if ")
+		if s.Init != nil {
+			p.stmt(s.Init)
+			p.buf.WriteString("; ")
+		}
+		p.expr(s.Cond)
+		p.buf.WriteByte(' ')
+		p.block(s.Body.(*stmt.Block), true)
+		if s.Else != nil {
+			p.buf.WriteString(" else ")
+			if b, isBlock := s.Else.(*stmt.Block); isBlock {
+				p.block(b, true)
+			} else {
+				p.stmt(s.Else)
+			}
+		}
+	case *stmt.For:
+		p.buf.WriteString("for ")
+		if s.Init != nil || s.Post != nil {
+			if s.Init != nil {
+				p.stmt(s.Init)
+			}
+			p.buf.WriteString("; ")
+			if s.Cond != nil {
+				p.expr(s.Cond)
+			}
+			p.buf.WriteString("; ")
+			if s.Post != nil {
+				p.stmt(s.Post)
+				p.buf.WriteByte(' ')
+			}
+		} else if s.Cond != nil {
+			p.expr(s.Cond)
+			p.buf.WriteByte(' ')
+		}
+		p.stmt(s.Body)
+	case *stmt.Range:
+		p.buf.WriteString("for ")
+		if s.Key != nil {
+			p.expr(s.Key)
+			if s.Val != nil {
+				p.buf.WriteString(", ")
+				p.expr(s.Val)
+			}
+			if s.Decl {
+				p.buf.WriteString(" := ")
+			} else {
+				p.buf.WriteString(" = ")
+			}
+		}
+		p.buf.WriteString("range ")
+		p.expr(s.Expr)
+		p.buf.WriteByte(' ')
+		p.stmt(s.Body)
+	case *stmt.Switch:
+		p.buf.WriteString("switch ")
+		if s.Init != nil {
+			p.stmt(s.Init)
+			p.buf.WriteString("; ")
+		}
+		if s.Cond != nil {
+			p.expr(s.Cond)
+			p.buf.WriteByte(' ')
+		}
+		p.buf.WriteByte('{')
+		for _, c := range s.Cases {
+			line, _ := lines(c)
+			p.item(line)
+			if c.Default {
+				p.buf.WriteString("default:")
+			} else {
+				p.buf.WriteString("case ")
+				p.exprList(c.Conds)
+				p.buf.WriteByte(':')
+			}
+			p.caseBody(line, c.Body)
+		}
+		p.newline()
+		p.buf.WriteByte('}')
+	case *stmt.TypeSwitch:
+		p.buf.WriteString("switch ")
+		if s.Init != nil {
+			p.stmt(s.Init)
+			p.buf.WriteString("; ")
+		}
+		p.stmt(s.Assign)
+		p.buf.WriteString(" {")
+		for _, c := range s.Cases {
+			line := int(c.Position.Line)
+			p.item(line)
+			if c.Default {
+				p.buf.WriteString("default:")
+			} else {
+				p.buf.WriteString("case ")
+				for i, t := range c.Types {
+					if i > 0 {
+						p.buf.WriteString(", ")
+					}
+					p.tipe(t)
+				}
+				p.buf.WriteByte(':')
+			}
+			p.caseBody(line, c.Body)
+		}
+		p.newline()
+		p.buf.WriteByte('}')
+	case *stmt.Select:
+		p.buf.WriteString("select {")
+		for _, c := range s.Cases {
+			line := int(c.Position.Line)
+			p.item(line)
+			if c.Default {
+				p.buf.WriteString("default:")
+			} else {
+				p.buf.WriteString("case ")
+				p.stmt(c.Stmt)
+				p.buf.WriteByte(':')
+			}
+			p.caseBody(line, c.Body)
+		}
+		p.newline()
+		p.buf.WriteByte('}')
+	case *stmt.Go:
+		p.buf.WriteString("go ")
+		p.expr(s.Call)
+	case *stmt.Defer:
+		p.buf.WriteString("defer ")
+		p.expr(s.Expr)
+	case *stmt.Branch:
+		p.buf.WriteString(s.Type.String())
+		if s.Label != "" {
+			p.buf.WriteByte(' ')
+			p.buf.WriteString(s.Label)
+		}
+	case *stmt.Labeled:
+		// Labels are outdented by one level.
+		if b := p.buf.Bytes(); len(b) > 0 && b[len(b)-1] == '\t' {
+			p.buf.Truncate(len(b) - 1)
+		}
+		p.buf.WriteString(s.Label)
+		p.buf.WriteByte(':')
+		p.newline()
+		p.stmt(s.Stmt)
+	case *stmt.Bad:
+		p.errorf("format: bad statement: %v", s.Error)
+	default:
+		p.errorf("format: unknown stmt %T", s)
+	}
+}
+
+func (p *printer) typeSpec(s *stmt.TypeDecl) {
+	p.buf.WriteString(s.Name)
+	p.typeParams(s.Type.TypeParams)
+	p.buf.WriteByte(' ')
+	p.tipe(s.Type.Type)
+}
+
+func (p *printer) valueSpec(names []string, t tipe.Type, values []expr.Expr) {
+	p.buf.WriteString(strings.Join(names, ", "))
+	if t != nil {
+		p.buf.WriteByte(' ')
+		p.tipe(t)
+	}
+	if len(values) > 0 {
+		p.buf.WriteString(" = ")
+		p.exprList(values)
+	}
+}
+
+func (p *printer) exprList(exprs []expr.Expr) {
+	for i, e := range exprs {
+		if i > 0 {
+			p.buf.WriteString(", ")
+		}
+		p.expr(e)
+	}
+}
+
+// incDec reports whether s was parsed from x++ or x--.
+func incDec(s *stmt.Assign) (string, bool) {
+	bin := opAssign(s)
+	if bin == nil {
+		return "", false
+	}
+	lit, ok := bin.Right.(*expr.BasicLiteral)
+	if !ok || lit.Raw != "" || lit.Position.Line != 0 {
+		return "", false
+	}
+	switch bin.Op {
+	case token.Add:
+		return "++", true
+	case token.Sub:
+		return "--", true
+	}
+	return "", false
+}
+
+// opAssign returns the binary expression of an assignment
+// parsed from an operator assignment such as x += y.
+// The parser shares the left-hand side between the two.
+func opAssign(s *stmt.Assign) *expr.Binary {
+	if s.Decl || len(s.Left) != 1 || len(s.Right) != 1 {
+		return nil
+	}
+	bin, ok := s.Right[0].(*expr.Binary)
+	if !ok || bin.Left != s.Left[0] || bin.Position != s.Position {
+		return nil
+	}
+	return bin
+}
+
+// literal prints a basic literal as it was written in the source.
+func (p *printer) literal(e *expr.BasicLiteral) {
+	if e.Raw != "" {
+		p.buf.WriteString(e.Raw)
+		return
+	}
+	switch v := e.Value.(type) {
+	case string:
+		p.buf.WriteString(strconv.Quote(v))
+	case rune:
+		p.buf.WriteString(strconv.QuoteRune(v))
+	case *big.Float:
+		p.buf.WriteString(v.Text('g', -1))
+	default:
+		fmt.Fprintf(p.buf, "%v", v)
+	}
+}
+
+func (p *printer) tag(tag tipe.StructTag) {
+	if tag == "" {
+		return
+	}
+	p.buf.WriteByte(' ')
+	if strconv.CanBackquote(string(tag)) {
+		p.buf.WriteString("`" + string(tag) + "`")
+	} else {
+		p.buf.WriteString(strconv.Quote(string(tag)))
+	}
+}
+
+// binary prints a binary expression, using spaces around operators
+// to show precedence the way gofmt does.
+func (p *printer) binary(e *expr.Binary) {
+	depth := p.depth
+	prec := e.Op.Precedence()
+	blank := prec < cutoff(e, depth)
+
+	p.depth = depth + diffPrec(e.Left, prec)
+	p.expr(e.Left)
+	if blank {
+		p.buf.WriteByte(' ')
+	}
+	p.buf.WriteString(e.Op.String())
+	if blank {
+		p.buf.WriteByte(' ')
+	}
+	p.depth = depth + 1
+	p.expr(e.Right)
+	p.depth = depth
+}
+
+func cutoff(e *expr.Binary, depth int) int {
+	has4, has5, maxProblem := walkBinary(e)
+	if maxProblem > 0 {
+		return maxProblem + 1
+	}
+	if has4 && has5 {
+		if depth == 1 {
+			return 5
+		}
+		return 4
+	}
+	if depth == 1 {
+		return 6
+	}
+	return 4
+}
+
+func diffPrec(e expr.Expr, prec int) int {
+	x, ok := e.(*expr.Binary)
+	if !ok || prec != x.Op.Precedence() {
+		return 1
+	}
+	return 0
+}
+
+func walkBinary(e *expr.Binary) (has4, has5 bool, maxProblem int) {
+	switch e.Op.Precedence() {
+	case 4:
+		has4 = true
+	case 5:
+		has5 = true
+	}
+
+	if l, ok := e.Left.(*expr.Binary); ok && l.Op.Precedence() >= e.Op.Precedence() {
+		h4, h5, mp := walkBinary(l)
+		has4 = has4 || h4
+		has5 = has5 || h5
+		if maxProblem < mp {
+			maxProblem = mp
+		}
+	}
+
+	switch r := e.Right.(type) {
+	case *expr.Binary:
+		if r.Op.Precedence() > e.Op.Precedence() {
+			h4, h5, mp := walkBinary(r)
+			has4 = has4 || h4
+			has5 = has5 || h5
+			if maxProblem < mp {
+				maxProblem = mp
+			}
+		}
+	case *expr.Unary:
+		switch e.Op.String() + r.Op.String() {
+		case "/*", "&&", "&^":
+			maxProblem = 5
+		case "++", "--":
+			if maxProblem < 4 {
+				maxProblem = 4
+			}
+		}
+	}
+	return has4, has5, maxProblem
+}
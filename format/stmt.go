@@ -12,6 +12,10 @@ import (
 )
 
 func (p *printer) stmt(s stmt.Stmt) {
+	if p.source {
+		p.sourceStmt(s)
+		return
+	}
 	switch s := s.(type) {
 	case *stmt.Import:
 		if s.Name != "" {
@@ -40,11 +44,13 @@ func (p *printer) stmt(s stmt.Stmt) {
 	case *stmt.TypeDecl:
 		p.buf.WriteString("type ")
 		p.buf.WriteString(s.Name)
+		p.typeParams(s.Type.TypeParams)
 		p.buf.WriteString(" ")
 		p.tipe(s.Type.Type)
 	case *stmt.MethodikDecl:
 		p.buf.WriteString("methodik ")
 		p.buf.WriteString(s.Name)
+		p.typeParams(s.Type.TypeParams)
 		p.buf.WriteString(" ")
 		p.tipe(s.Type.Type)
 		if len(s.Methods) == 0 {
@@ -35,6 +35,11 @@ func (p *printer) tipe(t tipe.Type) {
 		}
 		for _, sf := range t.Fields {
 			p.newline()
+			if p.source && sf.Embedded {
+				p.tipe(sf.Type)
+				p.tag(sf.Tag)
+				continue
+			}
 			name := sf.Name
 			if name == "" {
 				name = "*ERROR*No*Name*"
@@ -44,12 +49,18 @@ func (p *printer) tipe(t tipe.Type) {
 				p.buf.WriteByte(' ')
 			}
 			p.tipe(sf.Type)
+			if p.source {
+				p.tag(sf.Tag)
+			}
 		}
 		p.indent--
 		p.newline()
 		p.buf.WriteByte('}')
 	case *tipe.Named:
 		p.buf.WriteString(t.Name)
+		p.typeArgs(t.TypeArgs)
+	case *tipe.TypeParam:
+		p.buf.WriteString(t.Name)
 	case *tipe.Pointer:
 		p.buf.WriteByte('*')
 		p.tipe(t.Elem)
@@ -59,6 +70,7 @@ func (p *printer) tipe(t tipe.Type) {
 			p.buf.WriteByte('.')
 		}
 		p.buf.WriteString(t.Name)
+		p.typeArgs(t.TypeArgs)
 	case *tipe.Array:
 		if t.Ellipsis {
 			p.buf.WriteString("[...]")
@@ -73,12 +85,16 @@ func (p *printer) tipe(t tipe.Type) {
 		p.buf.WriteString("[|]")
 		p.tipe(t.Type)
 	case *tipe.Interface:
-		if len(t.Methods) == 0 {
+		if len(t.Methods) == 0 && len(t.Union) == 0 {
 			p.buf.WriteString("interface{}")
 			return
 		}
 		p.buf.WriteString("interface {")
 		p.indent++
+		if len(t.Union) > 0 {
+			p.newline()
+			p.union(t.Union)
+		}
 		names := make([]string, 0, len(t.Methods))
 		for name := range t.Methods {
 			names = append(names, name)
@@ -109,6 +125,7 @@ func (p *printer) tipe(t tipe.Type) {
 		p.tipe(t.Elem)
 	case *tipe.Func:
 		p.buf.WriteString("func")
+		p.typeParams(t.TypeParams)
 		p.tipeFuncSig(t)
 	case *tipe.Alias:
 		p.buf.WriteString(t.Name)
@@ -125,6 +142,7 @@ func (p *printer) tipe(t tipe.Type) {
 		p.buf.WriteString("...")
 		p.tipe(t.Elem)
 	default:
+		p.errorf("format: unknown type %T", t)
 		p.buf.WriteString("format: unknown type: ")
 		WriteDebug(p.buf, t)
 	}
@@ -158,6 +176,59 @@ func (p *printer) tipeFuncSig(t *tipe.Func) {
 	}
 }
 
+func (p *printer) typeArgs(args []tipe.Type) {
+	if len(args) == 0 {
+		return
+	}
+	p.buf.WriteByte('[')
+	for i, arg := range args {
+		if i > 0 {
+			p.buf.WriteString(", ")
+		}
+		p.tipe(arg)
+	}
+	p.buf.WriteByte(']')
+}
+
+// typeParams prints a type parameter list. Consecutive parameters
+// sharing a constraint are grouped, as in [K, V any].
+func (p *printer) typeParams(tparams []*tipe.TypeParam) {
+	if len(tparams) == 0 {
+		return
+	}
+	p.buf.WriteByte('[')
+	for i, tp := range tparams {
+		if i > 0 {
+			p.buf.WriteString(", ")
+		}
+		p.buf.WriteString(tp.Name)
+		if i+1 < len(tparams) && tparams[i+1].Constraint == tp.Constraint {
+			continue
+		}
+		p.buf.WriteByte(' ')
+		if iface, ok := tp.Constraint.(*tipe.Interface); ok && len(iface.Methods) == 0 && len(iface.Union) > 0 {
+			p.union(iface.Union)
+		} else if tp.Constraint == nil {
+			p.buf.WriteString("any")
+		} else {
+			p.tipe(tp.Constraint)
+		}
+	}
+	p.buf.WriteByte(']')
+}
+
+func (p *printer) union(union []tipe.Term) {
+	for i, term := range union {
+		if i > 0 {
+			p.buf.WriteString(" | ")
+		}
+		if term.Tilde {
+			p.buf.WriteByte('~')
+		}
+		p.tipe(term.Type)
+	}
+}
+
 func WriteType(buf *bytes.Buffer, t tipe.Type) {
 	p := &printer{
 		buf: buf,
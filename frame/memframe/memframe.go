@@ -5,10 +5,12 @@
 package memframe
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
+	"reflect"
 
 	"neugram.io/ng/frame"
 )
@@ -149,6 +151,16 @@ func assign(dst, src interface{}) error {
 	case nil:
 	}
 
+	if dst := reflect.ValueOf(dst); dst.Kind() == reflect.Ptr && src != nil {
+		if dst.IsNil() {
+			return errPtrNil
+		}
+		if src := reflect.ValueOf(src); src.Type().AssignableTo(dst.Elem().Type()) {
+			dst.Elem().Set(src)
+			return nil
+		}
+	}
+
 	return fmt.Errorf("assign: dst:%T, src:%T\n", dst, src)
 }
 
@@ -197,3 +209,55 @@ func (d *Memory) Slice(x, xlen, y, ylen int) frame.Frame {
 		Height:  ylen,
 	}
 }
+
+// Permute copies the columns cols of d into a new frame.
+func (d *Memory) Permute(cols []int) frame.Frame {
+	res := New(len(cols), d.Height)
+	for i, x := range cols {
+		res.ColName[i] = d.ColName[x]
+		for y := 0; y < d.Height; y++ {
+			res.Data[res.offset(i, y)] = d.Data[d.offset(x, y)]
+		}
+	}
+	return res
+}
+
+// String formats d in the style of a table literal.
+func (d *Memory) String() string {
+	buf := new(bytes.Buffer)
+	buf.WriteString("{")
+	named := false
+	for _, name := range d.ColName {
+		if name != "" {
+			named = true
+		}
+	}
+	if named {
+		buf.WriteString("{|")
+		for x, name := range d.ColName {
+			if x > 0 {
+				buf.WriteString(", ")
+			}
+			fmt.Fprintf(buf, "%q", name)
+		}
+		buf.WriteString("|}")
+		if d.Height > 0 {
+			buf.WriteString(", ")
+		}
+	}
+	for y := 0; y < d.Height; y++ {
+		if y > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString("{")
+		for x := 0; x < d.Width; x++ {
+			if x > 0 {
+				buf.WriteString(", ")
+			}
+			fmt.Fprint(buf, d.Data[d.offset(x, y)])
+		}
+		buf.WriteString("}")
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
@@ -9,10 +9,12 @@ package gengo
 import (
 	"bytes"
 	"fmt"
+	"go/constant"
 	goformat "go/format"
 	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -54,9 +56,13 @@ package %s
 `, outGoPkgName)
 
 	usesShell := false
+	unsupported := ""
 	builtins := make(map[string]bool)
 	importPaths := []string{}
 	preFn := func(c *syntax.Cursor) bool {
+		if e, isExpr := c.Node.(expr.Expr); isExpr && unsupported == "" {
+			unsupported = p.unsupported(e)
+		}
 		switch node := c.Node.(type) {
 		case *stmt.Import:
 			importPaths = append(importPaths, node.Path)
@@ -75,7 +81,12 @@ package %s
 		}
 		return true
 	}
-	syntax.Walk(p.pkg.Syntax, preFn, nil)
+	for _, f := range p.pkg.Files {
+		syntax.Walk(f, preFn, nil)
+	}
+	if unsupported != "" {
+		return nil, fmt.Errorf("gengo: %s: %s are not supported", filename, unsupported)
+	}
 
 	// Lift imports to the top-level.
 	importSet := make(map[string]bool)
@@ -147,13 +158,27 @@ package %s
 			if len(n.Methods) > 0 {
 				continue // methodiks are hoisted elsewhere
 			}
-			p.printf("type %s ", obj.Name)
+			p.printf("type %s", obj.Name)
+			p.typeParams(n.TypeParams)
+			p.print(" ")
 			p.tipe(n.Type)
 		case typecheck.ObjVar:
+			if fn, isFunc := obj.Decl.(*expr.FuncLiteral); isFunc && len(fn.Type.TypeParams) > 0 {
+				// Go has no generic function values, so
+				// generic functions are declared here
+				// rather than assigned in init.
+				p.funcLiteral(fn, "")
+				break
+			}
 			p.printf("var %s ", obj.Name)
 			p.tipe(obj.Type)
 		case typecheck.ObjConst:
-			p.printf("const %s = %s", obj.Name, obj.Decl)
+			p.printf("const %s ", obj.Name)
+			if !isUntyped(obj.Type) {
+				p.tipe(obj.Type)
+				p.print(" ")
+			}
+			p.printf("= %s", constLiteral(obj.Decl))
 		}
 		p.newline()
 		p.newline()
@@ -168,7 +193,9 @@ package %s
 		}
 		return true
 	}
-	syntax.Walk(p.pkg.Syntax, preFn, nil)
+	for _, f := range p.pkg.Files {
+		syntax.Walk(f, preFn, nil)
+	}
 	methodiksFlat := make(map[string]*stmt.MethodikDecl)
 	for name, ms := range methodiks {
 		methodiksFlat[name] = ms[0]
@@ -197,24 +224,58 @@ package %s
 		m := methodiksFlat[name]
 		p.printf("// methodik %s", m.Name)
 		p.newline()
-		p.printf("type %s %s", m.Name, format.Type(m.Type.Type))
+		p.printf("type %s", m.Name)
+		p.typeParams(m.Type.TypeParams)
+		p.print(" ")
+		p.tipe(m.Type.Type)
 		p.newline()
 		p.newline()
+		recvTypeName := m.Name
+		if tparams := m.Type.TypeParams; len(tparams) > 0 {
+			names := make([]string, len(tparams))
+			for i, tp := range tparams {
+				names[i] = tp.Name
+			}
+			recvTypeName += "[" + strings.Join(names, ", ") + "]"
+		}
 		for _, method := range m.Methods {
-			p.funcLiteral(method, m.Name)
+			p.funcLiteral(method, recvTypeName)
 			p.newline()
 			p.newline()
 		}
 	}
 
+	// Top-level functions can be used before their declaration,
+	// in any file, so they are assigned before other statements
+	// run. Functions named init run after all of them.
+	var funcs, stmts []stmt.Stmt
+	var inits []*expr.FuncLiteral
+	for _, f := range p.pkg.Files {
+		for _, s := range f.Stmts {
+			switch s := s.(type) {
+			case *stmt.TypeDecl:
+				// handled above
+				continue
+			case *stmt.Simple:
+				if fn, isFunc := s.Expr.(*expr.FuncLiteral); isFunc && fn.Name != "" {
+					switch {
+					case len(fn.Type.TypeParams) > 0:
+						// declared above
+					case fn.Name == "init":
+						inits = append(inits, fn)
+					default:
+						funcs = append(funcs, s)
+					}
+					continue
+				}
+			}
+			stmts = append(stmts, s)
+		}
+	}
+
 	p.print("func init() {")
 	p.indent++
-	for _, s := range p.pkg.Syntax.Stmts {
-		switch s.(type) {
-		case *stmt.TypeDecl:
-			// handled above
-			continue
-		}
+	for _, s := range append(funcs, stmts...) {
 
 		p.newline()
 		p.stmt(s)
@@ -237,6 +298,12 @@ package %s
 	p.indent--
 	p.newline()
 	p.print("}")
+	for _, fn := range inits {
+		p.newline()
+		p.newline()
+		p.print("func init() ")
+		p.stmt(fn.Body.(*stmt.Block))
+	}
 
 	p.printBuiltins(builtins)
 	p.printEliders()
@@ -256,6 +323,24 @@ package %s
 	return res, nil
 }
 
+// unsupported reports the kind of value or operation in e that
+// cannot be generated as Go, or "" if there is none.
+func (p *printer) unsupported(e expr.Expr) string {
+	if _, isTable := tipe.Underlying(p.c.Type(e)).(*tipe.Table); isTable {
+		return "tables"
+	}
+	if e, isBinary := e.(*expr.Binary); isBinary {
+		switch e.Op {
+		case token.Add, token.Sub, token.Mul, token.Div:
+			switch tipe.Underlying(p.c.Type(e)).(type) {
+			case *tipe.Slice, *tipe.Array:
+				return "element-wise operations"
+			}
+		}
+	}
+	return ""
+}
+
 type printer struct {
 	buf    *bytes.Buffer
 	indent int
@@ -269,6 +354,7 @@ type printer struct {
 	typeCur         *tipe.Named
 	typePlugins     map[*tipe.Named]string // plugin pkg path
 	typePluginsUsed map[*tipe.Named]bool
+	goPkgsUsed      map[string]string // Go package path -> name, when c is nil
 }
 
 func (p *printer) printShell() {
@@ -716,6 +802,8 @@ func (p *printer) stmt(s stmt.Stmt) {
 	case *stmt.Import:
 		// lifted to top-level earlier
 	case *stmt.Range:
+		// Go ranges over strings, pointers to arrays, integers
+		// and functions with the same semantics as Neugram.
 		p.print("for ")
 		if s.Key != nil {
 			p.expr(s.Key)
@@ -967,7 +1055,11 @@ func (p *printer) tipe(t tipe.Type) {
 			if name == "" {
 				name = "*ERROR*No*Name*"
 			}
-			if !sf.Embedded {
+			if !sf.Embedded || !p.printsName(sf.Type) {
+				// An embedded type printed as its underlying
+				// type cannot be embedded in Go, so it becomes
+				// a field of the same name. Selectors that use
+				// promotion are resolved by field index.
 				p.print(name)
 				for i := len(name); i <= maxlen; i++ {
 					p.print(" ")
@@ -982,27 +1074,38 @@ func (p *printer) tipe(t tipe.Type) {
 		p.newline()
 		p.print("}")
 	case *tipe.Named:
-		if t.PkgPath != "" && t.PkgPath != p.pkg.Path {
+		if t.PkgPath != "" && p.c == nil {
+			// A standalone type from GenNamedType.
+			p.goPkgsUsed[t.PkgPath] = t.PkgName
+			p.print(t.PkgName)
+			p.print(".")
+			p.print(t.Name)
+			p.typeArgs(t.TypeArgs)
+		} else if t.PkgPath != "" && t.PkgPath != p.pkg.Path {
 			pkg := p.c.Pkg(t.PkgPath)
 			p.print(p.imports[pkg.Type])
 			p.print(".")
 			p.print(t.Name)
-		} else if p.underlying && t.Name != "error" {
+			p.typeArgs(t.TypeArgs)
+		} else if p.underlying && t.Name != "error" && t != tipe.Comparable {
 			if t == p.typeCur {
-				p.print(t.Name)
+				p.print(TypeName(t))
 			} else if pkgpath := p.typePlugins[t]; pkgpath != "" {
 				// This type has methods and previously
 				// declared in a plugin. Use it.
 				p.typePluginsUsed[t] = true
 				p.print(path.Base(pkgpath))
 				p.print(".")
-				p.print(t.Name)
+				p.print(TypeName(t))
 			} else {
 				p.tipe(tipe.Underlying(t))
 			}
 		} else {
 			p.print(t.Name)
+			p.typeArgs(t.TypeArgs)
 		}
+	case *tipe.TypeParam:
+		p.print(t.Name)
 	case *tipe.Pointer:
 		p.print("*")
 		p.tipe(t.Elem)
@@ -1022,12 +1125,16 @@ func (p *printer) tipe(t tipe.Type) {
 		p.print("[]")
 		p.tipe(t.Elem)
 	case *tipe.Interface:
-		if len(t.Methods) == 0 {
+		if len(t.Methods) == 0 && len(t.Union) == 0 {
 			p.print("interface{}")
 			return
 		}
 		p.print("interface {")
 		p.indent++
+		if len(t.Union) > 0 {
+			p.newline()
+			p.union(t.Union)
+		}
 		names := make([]string, 0, len(t.Methods))
 		for name := range t.Methods {
 			names = append(names, name)
@@ -1058,6 +1165,7 @@ func (p *printer) tipe(t tipe.Type) {
 		p.tipe(t.Elem)
 	case *tipe.Func:
 		p.print("func")
+		p.typeParams(t.TypeParams)
 		p.tipeFuncSig(t)
 	case *tipe.Alias:
 		p.print(t.Name)
@@ -1078,6 +1186,74 @@ func (p *printer) tipe(t tipe.Type) {
 	}
 }
 
+func (p *printer) typeArgs(args []tipe.Type) {
+	if len(args) == 0 {
+		return
+	}
+	p.print("[")
+	for i, arg := range args {
+		if i > 0 {
+			p.print(", ")
+		}
+		p.tipe(arg)
+	}
+	p.print("]")
+}
+
+// typeParams prints a type parameter list, grouping consecutive
+// parameters that share a constraint.
+func (p *printer) typeParams(tparams []*tipe.TypeParam) {
+	if len(tparams) == 0 {
+		return
+	}
+	p.print("[")
+	for i, tp := range tparams {
+		if i > 0 {
+			p.print(", ")
+		}
+		p.print(tp.Name)
+		if i+1 < len(tparams) && tparams[i+1].Constraint == tp.Constraint {
+			continue
+		}
+		p.print(" ")
+		if iface, ok := tp.Constraint.(*tipe.Interface); ok && len(iface.Methods) == 0 && len(iface.Union) > 0 {
+			p.union(iface.Union)
+		} else if tp.Constraint == nil {
+			p.print("any")
+		} else {
+			p.tipe(tp.Constraint)
+		}
+	}
+	p.print("]")
+}
+
+func (p *printer) union(union []tipe.Term) {
+	for i, term := range union {
+		if i > 0 {
+			p.print(" | ")
+		}
+		if term.Tilde {
+			p.print("~")
+		}
+		p.tipe(term.Type)
+	}
+}
+
+// printsName reports whether tipe prints t, or the element of
+// a pointer t, as a type name.
+func (p *printer) printsName(t tipe.Type) bool {
+	if ptr, isPtr := t.(*tipe.Pointer); isPtr {
+		t = ptr.Elem
+	}
+	switch t := t.(type) {
+	case *tipe.Named:
+		return t.PkgPath != "" || !p.underlying || t.Name == "error" || t == p.typeCur || p.typePlugins[t] != ""
+	case *tipe.Unresolved:
+		return true
+	}
+	return false
+}
+
 func (p *printer) tipeFuncSig(t *tipe.Func) {
 	p.print("(")
 	if t.Params != nil {
@@ -1113,6 +1289,10 @@ func (p *printer) funcLiteral(e *expr.FuncLiteral, recvTypeName string) {
 			ptr = "*"
 		}
 		p.printf("func (%s %s%s) %s(", e.ReceiverName, ptr, recvTypeName, e.Name)
+	} else if len(e.Type.TypeParams) > 0 {
+		p.printf("func %s", e.Name)
+		p.typeParams(e.Type.TypeParams)
+		p.print("(")
 	} else {
 		p.print("func(")
 	}
@@ -1157,6 +1337,57 @@ func isExported(name string) bool {
 	return unicode.IsUpper(ch)
 }
 
+func isUntyped(t tipe.Type) bool {
+	switch t {
+	case tipe.UntypedBool, tipe.UntypedInteger, tipe.UntypedFloat,
+		tipe.UntypedComplex, tipe.UntypedRune, tipe.UntypedString:
+		return true
+	}
+	return false
+}
+
+// constLiteral formats the value of a constant as a Go literal.
+func constLiteral(decl interface{}) string {
+	v, ok := decl.(constant.Value)
+	if !ok {
+		return fmt.Sprint(decl)
+	}
+	switch v.Kind() {
+	case constant.String:
+		return strconv.Quote(constant.StringVal(v))
+	case constant.Float:
+		if s := v.ExactString(); !strings.Contains(s, "/") {
+			return s
+		}
+	}
+	return v.String()
+}
+
+// TypeName returns the Go identifier GenNamedType uses for t.
+// An instance of a generic type, such as Stack[int], has no
+// Go name of its own, so one is derived from its type arguments.
+func TypeName(t *tipe.Named) string {
+	if len(t.TypeArgs) == 0 {
+		return t.Name
+	}
+	var buf bytes.Buffer
+	buf.WriteString(t.Name)
+	for _, arg := range t.TypeArgs {
+		buf.WriteByte('_')
+		for _, r := range format.Type(arg) {
+			switch {
+			case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+				buf.WriteRune(r)
+			case r == '*':
+				buf.WriteString("ptr")
+			case r == '[':
+				buf.WriteString("_")
+			}
+		}
+	}
+	return buf.String()
+}
+
 func GenNamedType(t *tipe.Named, methods []*expr.FuncLiteral, pkgPath string, typePlugins map[*tipe.Named]string) (pkgb, mainb []byte, err error) {
 	p := &printer{
 		buf:             new(bytes.Buffer),
@@ -1166,13 +1397,15 @@ func GenNamedType(t *tipe.Named, methods []*expr.FuncLiteral, pkgPath string, ty
 		typeCur:         t,
 		typePlugins:     typePlugins,
 		typePluginsUsed: make(map[*tipe.Named]bool),
+		goPkgsUsed:      make(map[string]string),
 	}
 
-	p.printf("var Zero %s", t.Name)
+	name := TypeName(t)
+	p.printf("var Zero %s", name)
 	p.newline()
 	p.newline()
 
-	p.printf("type %s ", t.Name)
+	p.printf("type %s ", name)
 	p.tipe(tipe.Underlying(t.Type))
 
 	for _, mOrig := range methods {
@@ -1193,7 +1426,7 @@ func GenNamedType(t *tipe.Named, methods []*expr.FuncLiteral, pkgPath string, ty
 			}
 		}
 		m.Body = nil
-		p.funcLiteral(m, t.Name)
+		p.funcLiteral(m, name)
 		p.printf(" {")
 		p.indent++
 		p.newline()
@@ -1261,7 +1494,7 @@ func GenNamedType(t *tipe.Named, methods []*expr.FuncLiteral, pkgPath string, ty
 	p.printf("// generated by ng, do not edit")
 	p.newline()
 	p.newline()
-	p.printf("package %s", t.Name)
+	p.printf("package %s", name)
 	p.newline()
 	p.newline()
 
@@ -1272,11 +1505,17 @@ func GenNamedType(t *tipe.Named, methods []*expr.FuncLiteral, pkgPath string, ty
 	for t := range p.typePluginsUsed {
 		imports = append(imports, p.typePlugins[t])
 	}
+	for path := range p.goPkgsUsed {
+		imports = append(imports, path)
+	}
 	sort.Strings(imports)
 	p.printf("import (")
 	p.indent++
 	for _, imp := range imports {
 		p.newline()
+		if name := p.goPkgsUsed[imp]; name != "" && name != path.Base(imp) {
+			p.printf("%s ", name)
+		}
 		p.printf("%q", imp)
 	}
 	p.indent--
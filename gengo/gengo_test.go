@@ -25,6 +25,15 @@ func TestGeneratedPrograms(t *testing.T) {
 		t.Fatal("cannot find testdata")
 	}
 
+	// Programs using features gengo does not translate to Go.
+	unsupported := map[string]string{
+		"table1":    "tables",
+		"table2":    "tables",
+		"table6":    "tables",
+		"elemwise1": "element-wise operations",
+		"elemwise2": "tables",
+	}
+
 	for _, file := range files {
 		file := file
 		test := file[len("../eval/testdata/") : len(file)-3]
@@ -36,6 +45,9 @@ func TestGeneratedPrograms(t *testing.T) {
 			"import4",
 			"import5",
 			"import8",
+			"import10",
+			"import12",
+			"init1_error",
 			"method2",
 			"op1",
 		}
@@ -48,6 +60,15 @@ func TestGeneratedPrograms(t *testing.T) {
 		if donotrun {
 			continue
 		}
+		if what := unsupported[test]; what != "" {
+			t.Run(test, func(t *testing.T) {
+				_, err := gengo.GenGo(file, "main")
+				if want := what + " are not supported"; err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("GenGo error: %v, want %q", err, want)
+				}
+			})
+			continue
+		}
 		t.Run(test, func(t *testing.T) {
 			t.Parallel()
 			res, err := gengo.GenGo(file, "main")
@@ -108,3 +129,51 @@ func TestGeneratedPrograms(t *testing.T) {
 		})
 	}
 }
+
+func TestGeneratedPackage(t *testing.T) {
+	// A package made of the files in a directory, imported from Go.
+	res, err := gengo.GenGo("../eval/testdata/ngpath/example.com/multi.ng", "multi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gopath, err := ioutil.TempDir("", "gengo-pkg-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	files := map[string][]byte{
+		"src/multi/multi.go": res,
+		"src/main/main.go": []byte(`package main
+
+import (
+	"fmt"
+
+	"multi"
+)
+
+func main() {
+	fmt.Println(multi.Total, multi.Count(multi.Numbers{1}))
+}
+`),
+	}
+	for name, contents := range files {
+		filename := filepath.Join(gopath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0775); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, contents, 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command("go", "run", "main")
+	cmd.Dir = gopath
+	cmd.Env = append(os.Environ(), "GOPATH="+gopath, "GO111MODULE=off", "GOFLAGS=")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("failed to run: %v\n%s\n%s", err, out, res)
+	}
+	if got, want := string(out), "26 11\n"; got != want {
+		t.Errorf("output: %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gotool
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"plugin"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Plugins that wrap Go packages are cached on disk, so a program
+// importing a Go package only pays for building its wrapper once.
+//
+// The cache is content-addressed. An entry's key is a hash of the
+// Go version, the package path, the wrapper generator version, and
+// the sum of every module the package is built from. Packages that
+// are not from a versioned module, such as those in a GOPATH or a
+// replacement directory, are hashed by the contents of their files.
+//
+// The cache lives in $XDG_CACHE_HOME/ng, or the platform equivalent.
+// It can be moved by setting $NGCACHE, or disabled with NGCACHE=off.
+
+// CacheEntry describes a cached plugin.
+type CacheEntry struct {
+	Key       string
+	PkgPath   string
+	GoVersion string
+	Generator string
+	Size      int64
+	Created   time.Time
+}
+
+// CacheDir returns the directory of the plugin cache, or "" if the
+// cache is disabled.
+func CacheDir() (string, error) {
+	switch dir := os.Getenv("NGCACHE"); dir {
+	case "off":
+		return "", nil
+	case "":
+	default:
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("gotool: cache: %v", err)
+	}
+	return filepath.Join(dir, "ng"), nil
+}
+
+// CleanCache removes every cached plugin.
+func CleanCache() error {
+	dir, err := CacheDir()
+	if err != nil || dir == "" {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Join(dir, "plugins")); err != nil {
+		return fmt.Errorf("gotool: cache: %v", err)
+	}
+	return nil
+}
+
+// ListCache reports the cached plugins, sorted by package path.
+func ListCache() ([]CacheEntry, error) {
+	dir, err := CacheDir()
+	if err != nil || dir == "" {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "plugins", "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("gotool: cache: %v", err)
+	}
+	var entries []CacheEntry
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("gotool: cache: %v", err)
+		}
+		var e CacheEntry
+		if err := json.Unmarshal(b, &e); err != nil {
+			continue // partially written or from another version
+		}
+		fi, err := os.Stat(strings.TrimSuffix(file, ".json") + ".so")
+		if err != nil {
+			continue
+		}
+		e.Size = fi.Size()
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].PkgPath != entries[j].PkgPath {
+			return entries[i].PkgPath < entries[j].PkgPath
+		}
+		return entries[i].Created.Before(entries[j].Created)
+	})
+	return entries, nil
+}
+
+// CreateWrapper loads a plugin wrapping the Go package pkgPath.
+//
+// If a plugin for pkgPath built by the wrapper generator version
+// genVersion is in the cache, it is loaded. Otherwise gen is called
+// to generate the plugin source, which is built and stored in the
+// cache. The generator may use the Manager, so it is called without
+// holding its lock.
+func (m *Manager) CreateWrapper(pkgPath, genVersion string, gen func() ([]byte, error)) (*plugin.Plugin, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	var key string
+	stale := false
+	if dir != "" {
+		key, err = m.cacheKey(pkgPath, genVersion)
+		if err != nil {
+			return nil, err
+		}
+		filename := filepath.Join(dir, "plugins", key+".so")
+		if _, err := os.Stat(filename); err == nil {
+			plg, err := plugin.Open(filename)
+			if err == nil {
+				return plg, nil
+			}
+			// A corrupt entry, or one built against other Neugram
+			// packages. It is replaced, but this process cannot
+			// open that file again, so it loads the new build
+			// from the temporary directory.
+			stale = true
+		}
+	}
+
+	src, err := gen()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.init(); err != nil {
+		return nil, err
+	}
+	filename, err := m.build(pkgPath, src)
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		entry := CacheEntry{
+			Key:       key,
+			PkgPath:   pkgPath,
+			GoVersion: runtime.Version(),
+			Generator: genVersion,
+			Created:   time.Now(),
+		}
+		// A plugin can only be loaded from one file, so load the
+		// cached copy that later processes will use.
+		if cached, err := storeCache(dir, filename, entry); err == nil && !stale {
+			filename = cached
+		}
+	}
+	plg, err := plugin.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin %s: %v", filepath.Base(filename), err)
+	}
+	return plg, nil
+}
+
+// storeCache copies the plugin filename into the cache.
+func storeCache(dir, filename string, entry CacheEntry) (string, error) {
+	pluginsDir := filepath.Join(dir, "plugins")
+	if err := os.MkdirAll(pluginsDir, 0775); err != nil {
+		return "", err
+	}
+	cached := filepath.Join(pluginsDir, entry.Key+".so")
+	if err := copyFile(cached, filename); err != nil {
+		return "", err
+	}
+	b, err := json.MarshalIndent(entry, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(pluginsDir, entry.Key+".json"), b, 0664); err != nil {
+		return "", err
+	}
+	return cached, nil
+}
+
+// copyFile copies src to dst. The copy is written to a temporary
+// file and renamed, so concurrent processes never see a partial file.
+func copyFile(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := ioutil.TempFile(filepath.Dir(dst), filepath.Base(dst)+".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	if err := os.Rename(out.Name(), dst); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	return nil
+}
+
+// listedPkg is a package, as printed by go list -json.
+type listedPkg struct {
+	ImportPath string
+	Dir        string
+	Standard   bool
+	Module     *listedModule
+	GoFiles    []string
+	CgoFiles   []string
+	CFiles     []string
+	CXXFiles   []string
+	HFiles     []string
+	SFiles     []string
+	SysoFiles  []string
+	EmbedFiles []string
+}
+
+type listedModule struct {
+	Path    string
+	Version string
+	Sum     string
+	Replace *listedModule
+}
+
+// cacheKey computes the cache key of the plugin wrapping pkgPath.
+// Listing the packages it is built from is slow, so the key is
+// computed once per process.
+func (m *Manager) cacheKey(pkgPath, genVersion string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.init(); err != nil {
+		return "", err
+	}
+	memo := pkgPath + " " + genVersion
+	if key, ok := m.cacheKeys[memo]; ok {
+		return key, nil
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "ng plugin cache 1\n")
+	fmt.Fprintf(h, "go %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(h, "package %s\n", pkgPath)
+	fmt.Fprintf(h, "generator %s\n", genVersion)
+	fmt.Fprintf(h, "goflags %s\n", os.Getenv("GOFLAGS"))
+
+	// The plugin is built from the wrapped package and the
+	// Neugram packages the wrapper registers it with.
+	cmd := m.command("list", "-deps", "-json", pkgPath, ngModule+"/eval/gowrap")
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			out = ee.Stderr
+		}
+		return "", fmt.Errorf("gotool: go list %s: %v\n%s", pkgPath, err, out)
+	}
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		pkg := new(listedPkg)
+		if err := dec.Decode(pkg); err != nil {
+			return "", fmt.Errorf("gotool: go list %s: %v", pkgPath, err)
+		}
+		if pkg.Standard {
+			continue // part of the Go version
+		}
+		mod := pkg.Module
+		if mod != nil && mod.Replace != nil {
+			mod = mod.Replace
+		}
+		if mod != nil && mod.Sum != "" {
+			fmt.Fprintf(h, "dep %s %s@%s %s\n", pkg.ImportPath, mod.Path, mod.Version, mod.Sum)
+			continue
+		}
+		// Source positions are part of a built package, so
+		// the same files in another directory are different.
+		fmt.Fprintf(h, "dep %s %s\n", pkg.ImportPath, pkg.Dir)
+		var files []string
+		for _, f := range [][]string{pkg.GoFiles, pkg.CgoFiles, pkg.CFiles, pkg.CXXFiles, pkg.HFiles, pkg.SFiles, pkg.SysoFiles, pkg.EmbedFiles} {
+			files = append(files, f...)
+		}
+		for _, file := range files {
+			if err := hashFile(h, filepath.Join(pkg.Dir, file)); err != nil {
+				return "", err
+			}
+		}
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+	if m.cacheKeys == nil {
+		m.cacheKeys = make(map[string]string)
+	}
+	m.cacheKeys[memo] = key
+	return key, nil
+}
+
+func hashFile(h io.Writer, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("gotool: cache: %v", err)
+	}
+	defer f.Close()
+	fh := sha256.New()
+	if _, err := io.Copy(fh, f); err != nil {
+		return fmt.Errorf("gotool: cache: %v", err)
+	}
+	fmt.Fprintf(h, "file %s %x\n", filepath.Base(filename), fh.Sum(nil))
+	return nil
+}
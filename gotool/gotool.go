@@ -10,9 +10,13 @@
 // Neugram interpreter. It is process-wide because plugins are
 // necessarily so, and so maintaining any finer-grained GOPATHs just
 // lead to confusion and bugs.
+//
+// When Go packages are imported from a module, set with SetModule,
+// the temporary directory is instead a module that requires it.
 package gotool
 
 import (
+	"bytes"
 	"fmt"
 	goimporter "go/importer"
 	gotypes "go/types"
@@ -20,6 +24,7 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"plugin"
 	"runtime"
@@ -38,15 +43,32 @@ var M = new(Manager)
 type Manager struct {
 	mu               sync.Mutex
 	tempdir          string
+	gomod            string            // user's go.mod, "" in GOPATH mode
+	moduleDir        string            // directory whose module is found by init, see SetModuleDir
+	overlay          string            // -overlay file for go.mod files, or ""
+	ngFiles          map[string]string // Neugram package path -> file, see FindNg
+	cacheKeys        map[string]string // package path and generator -> cache key
 	importer         gotypes.Importer
 	importerIsGlobal bool // means we are pre Go 1.10
 }
 
-func (m *Manager) gocmd(args ...string) error {
+func (m *Manager) command(args ...string) *exec.Cmd {
+	// Only the build commands take -overlay; "go mod" rejects it.
+	if m.overlay != "" && (args[0] == "build" || args[0] == "list") {
+		args = append([]string{args[0], "-overlay=" + m.overlay}, args[1:]...)
+	}
 	cmd := exec.Command("go", args...)
 	cmd.Dir = m.tempdir
-	cmd.Env = append(os.Environ(), "GOPATH="+m.gopath())
-	out, err := cmd.CombinedOutput()
+	if m.gomod != "" {
+		cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS="+modFlags(os.Getenv("GOFLAGS")))
+	} else {
+		cmd.Env = append(os.Environ(), "GO111MODULE=off", "GOPATH="+m.gopath())
+	}
+	return cmd
+}
+
+func (m *Manager) gocmd(args ...string) error {
+	out, err := m.command(args...).CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("gotool: %v: %v\n%s", args, err, out)
 	}
@@ -65,6 +87,14 @@ func (m *Manager) init() error {
 	if m.tempdir != "" {
 		return nil
 	}
+	if m.moduleDir != "" {
+		// Without a go tool no plugins can be built,
+		// so GOPATH mode will do.
+		if gomod, err := FindModule(m.moduleDir); err == nil {
+			m.gomod = gomod
+		}
+		m.moduleDir = ""
+	}
 	var err error
 	m.tempdir, err = ioutil.TempDir("", "ng-tmp-")
 	if err != nil {
@@ -73,6 +103,11 @@ func (m *Manager) init() error {
 	if err := os.MkdirAll(filepath.Join(m.tempdir, "src"), 0775); err != nil {
 		return err
 	}
+	if m.gomod != "" {
+		if err := m.writeModule(); err != nil {
+			return err
+		}
+	}
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -91,6 +126,9 @@ func (m *Manager) init() error {
 }
 
 func (m *Manager) importerLookup(path string) (io.ReadCloser, error) {
+	if data := lookupExportData(path); data != nil {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
 	filename := filepath.Join(m.tempdir, path+".a")
 	f, err := os.Open(filename)
 	if os.IsNotExist(err) {
@@ -139,7 +177,19 @@ func (m *Manager) Create(name string, contents []byte) (*plugin.Plugin, error) {
 	if err := m.init(); err != nil {
 		return nil, err
 	}
+	filename, err := m.build(name, contents)
+	if err != nil {
+		return nil, err
+	}
+	plg, err := plugin.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin %s: %v", filepath.Base(filename), err)
+	}
+	return plg, nil
+}
 
+// build builds a single-file plugin and returns its filename.
+func (m *Manager) build(name string, contents []byte) (string, error) {
 	name = strings.Replace(name, "/", "_", -1)
 	name = strings.Replace(name, "\\", "_", -1)
 	var path string
@@ -151,26 +201,21 @@ func (m *Manager) Create(name string, contents []byte) (*plugin.Plugin, error) {
 			if os.IsExist(err) {
 				continue // pick a different name
 			}
-			return nil, err
+			return "", err
 		}
 		_, err = f.Write(contents)
 		f.Close()
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 		break
 	}
 
 	name = filepath.Base(path)
 	if err := m.gocmd("build", "-buildmode=plugin", name); err != nil {
-		return nil, err
-	}
-	pluginName := name[:len(name)-3] + ".so"
-	plg, err := plugin.Open(filepath.Join(m.tempdir, pluginName))
-	if err != nil {
-		return nil, fmt.Errorf("failed to open plugin %s: %v", name, err)
+		return "", err
 	}
-	return plg, nil
+	return filepath.Join(m.tempdir, name[:len(name)-3]+".so"), nil
 }
 
 func (m *Manager) Dir(pkgPath string) (adjPkgPath, dir string, err error) {
@@ -180,10 +225,12 @@ func (m *Manager) Dir(pkgPath string) (adjPkgPath, dir string, err error) {
 	if err := m.init(); err != nil {
 		return "", "", err
 	}
+	return m.dir(pkgPath)
+}
 
-	gopath := m.tempdir
-	adjPkgPath = pkgPath
-	dir = filepath.Join(gopath, "src", adjPkgPath)
+func (m *Manager) dir(pkgPath string) (adjPkgPath, dir string, err error) {
+	adjPkgPath = m.importPath(pkgPath)
+	dir = m.srcDir(adjPkgPath)
 	i := 0
 	for {
 		_, err := os.Stat(dir)
@@ -191,8 +238,8 @@ func (m *Manager) Dir(pkgPath string) (adjPkgPath, dir string, err error) {
 			break
 		}
 		i++
-		adjPkgPath = filepath.Join(fmt.Sprintf("p%d", i), pkgPath)
-		dir = filepath.Join(gopath, "src", adjPkgPath)
+		adjPkgPath = m.importPath(filepath.Join(fmt.Sprintf("p%d", i), pkgPath))
+		dir = m.srcDir(adjPkgPath)
 	}
 	if err := os.MkdirAll(dir, 0775); err != nil {
 		return "", "", err
@@ -200,12 +247,29 @@ func (m *Manager) Dir(pkgPath string) (adjPkgPath, dir string, err error) {
 	return adjPkgPath, dir, nil
 }
 
+// importPath returns the import path of an ephemeral package.
+// In module mode, it is inside the temporary module.
+func (m *Manager) importPath(pkgPath string) string {
+	if m.gomod != "" {
+		return path.Join(tmpModule, filepath.ToSlash(pkgPath))
+	}
+	return pkgPath
+}
+
+// srcDir returns the directory of the ephemeral package pkgPath.
+func (m *Manager) srcDir(pkgPath string) string {
+	if m.gomod != "" {
+		return filepath.Join(m.tempdir, strings.TrimPrefix(pkgPath, tmpModule+"/"))
+	}
+	return filepath.Join(m.tempdir, "src", pkgPath)
+}
+
 func (m *Manager) Open(mainPkgPath string) (*plugin.Plugin, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	pluginName := filepath.Base(mainPkgPath) + ".so"
-	filename := filepath.Join(m.tempdir, "src", mainPkgPath, pluginName)
+	filename := filepath.Join(m.srcDir(mainPkgPath), pluginName)
 
 	if err := m.gocmd("build", "-buildmode=plugin", "-o="+filename, mainPkgPath); err != nil {
 		return nil, err
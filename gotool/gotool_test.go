@@ -0,0 +1,253 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gotool
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0775); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(contents), 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testImportModule(t *testing.T, files map[string]string, path, name string) {
+	dir, err := ioutil.TempDir("", "gotool-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, files)
+
+	m := new(Manager)
+	defer m.Cleanup()
+	if err := m.SetModule(filepath.Join(dir, "mod", "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := m.ImportGo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Scope().Lookup(name) == nil {
+		t.Errorf("%s.%s not found", path, name)
+	}
+}
+
+func TestImportModule(t *testing.T) {
+	testImportModule(t, map[string]string{
+		"mod/go.mod": "module example.com/mod\n\nrequire example.com/dep v1.0.0\n\nreplace example.com/dep => ../dep\n",
+		"mod/p/p.go": "package p\n\nimport \"example.com/dep\"\n\nvar V = dep.V\n",
+		"dep/go.mod": "module example.com/dep\n",
+		"dep/dep.go": "package dep\n\nvar V = 1\n",
+	}, "example.com/mod/p", "V")
+}
+
+func TestSetModuleDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotool-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"mod/go.mod": "module example.com/mod\n",
+		"mod/p/p.go": "package p\n\nvar V = 1\n",
+	})
+	defer os.Setenv("GO111MODULE", os.Getenv("GO111MODULE"))
+	os.Setenv("GO111MODULE", "on")
+
+	m := new(Manager)
+	defer m.Cleanup()
+	m.SetModuleDir(filepath.Join(dir, "mod", "p"))
+	if m.gomod != "" {
+		t.Errorf("module found before first use: %s", m.gomod)
+	}
+	pkg, err := m.ImportGo("example.com/mod/p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Scope().Lookup("V") == nil {
+		t.Errorf("example.com/mod/p.V not found")
+	}
+	if want := filepath.Join(dir, "mod", "go.mod"); m.gomod != want {
+		t.Errorf("module %q, want %q", m.gomod, want)
+	}
+}
+
+func TestImportVendored(t *testing.T) {
+	testImportModule(t, map[string]string{
+		"mod/go.mod":                        "module example.com/mod\n\ngo 1.14\n\nrequire example.com/dep v1.0.0\n",
+		"mod/vendor/modules.txt":            "# example.com/dep v1.0.0\n## explicit\nexample.com/dep\n",
+		"mod/vendor/example.com/dep/dep.go": "package dep\n\nvar V = 1\n",
+		"mod/p/p.go":                        "package p\n\nimport \"example.com/dep\"\n\nvar V = dep.V\n",
+	}, "example.com/mod/p", "V")
+}
+
+func TestCacheKeyMemo(t *testing.T) {
+	m := new(Manager)
+	defer m.Cleanup()
+	key, err := m.cacheKey("unicode/utf8", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The key is not computed again, so no go tool is needed.
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", "")
+	if key2, err := m.cacheKey("unicode/utf8", "test"); err != nil || key2 != key {
+		t.Errorf("second cacheKey = %q, %v, want %q", key2, err, key)
+	}
+	if _, err := m.cacheKey("unicode/utf8", "test2"); err == nil {
+		t.Errorf("cacheKey for another generator did not run the go tool")
+	}
+}
+
+func TestCreateWrapperCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotool-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("NGCACHE", os.Getenv("NGCACHE"))
+	os.Setenv("NGCACHE", dir)
+
+	gens := 0
+	gen := func() ([]byte, error) {
+		gens++
+		return []byte("package main\n\nvar Gens = 1\n"), nil
+	}
+	for i := 0; i < 2; i++ {
+		// Each Manager stands in for a new ng process.
+		m := new(Manager)
+		plg, err := m.CreateWrapper("unicode/utf8", "test", gen)
+		m.Cleanup()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := plg.Lookup("Gens"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if gens != 1 {
+		t.Errorf("wrapper generated %d times, want 1", gens)
+	}
+
+	entries, err := ListCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].PkgPath != "unicode/utf8" || entries[0].Generator != "test" {
+		t.Errorf("ListCache() = %+v, want one entry for unicode/utf8", entries)
+	}
+	if err := CleanCache(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := ListCache(); err != nil || len(entries) != 0 {
+		t.Errorf("after CleanCache, ListCache() = %+v, %v", entries, err)
+	}
+}
+
+func TestRegisterExportData(t *testing.T) {
+	m := new(Manager)
+	defer m.Cleanup()
+	data, err := m.ExportData("container/ring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterExportData("container/ring", data)
+
+	// Importing a registered package does not use the go command.
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", "")
+	m2 := new(Manager)
+	defer m2.Cleanup()
+	pkg, err := m2.ImportGo("container/ring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Scope().Lookup("Ring") == nil {
+		t.Errorf("container/ring.Ring not found")
+	}
+}
+
+func TestFindNg(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotool-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"mod/go.mod":                  "module example.com/mod\n\nrequire example.com/dep v1.0.0\n\nreplace example.com/dep => ../dep\n",
+		"mod/lib/a.ng":                "A := 1\n",
+		"dep/go.mod":                  "module example.com/dep\n",
+		"dep/b.ng":                    "B := 1\n",
+		"ngpath/example.com/dep/b.ng": "B := 2\n",
+	})
+	defer os.Setenv("NGPATH", os.Getenv("NGPATH"))
+	os.Setenv("NGPATH", "")
+
+	m := new(Manager)
+	defer m.Cleanup()
+	if err := m.SetModule(filepath.Join(dir, "mod", "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path, want string
+	}{
+		{"example.com/mod/lib/a.ng", "mod/lib/a.ng"},
+		{"example.com/dep/b.ng", "dep/b.ng"},
+	}
+	for _, test := range tests {
+		got, err := m.FindNg(test.path)
+		if err != nil {
+			t.Errorf("FindNg(%q): %v", test.path, err)
+			continue
+		}
+		if want := filepath.Join(dir, filepath.FromSlash(test.want)); got != want {
+			t.Errorf("FindNg(%q) = %q, want %q", test.path, got, want)
+		}
+	}
+	if got, err := m.FindNg("example.com/mod/lib/missing.ng"); err == nil {
+		t.Errorf("FindNg of a missing file = %q, want error", got)
+	}
+
+	// $NGPATH comes before the modules.
+	os.Setenv("NGPATH", filepath.Join(dir, "ngpath"))
+	m2 := new(Manager)
+	defer m2.Cleanup()
+	if err := m2.SetModule(filepath.Join(dir, "mod", "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := m2.FindNg("example.com/dep/b.ng")
+	if want := filepath.Join(dir, "ngpath", "example.com", "dep", "b.ng"); err != nil || got != want {
+		t.Errorf("FindNg with $NGPATH = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestCommandOverlay(t *testing.T) {
+	m := &Manager{overlay: "overlay.json"}
+	tests := []struct {
+		args, want []string
+	}{
+		{[]string{"build", "x"}, []string{"go", "build", "-overlay=overlay.json", "x"}},
+		{[]string{"list", "-m", "all"}, []string{"go", "list", "-overlay=overlay.json", "-m", "all"}},
+		{[]string{"mod", "download", "-json", "x"}, []string{"go", "mod", "download", "-json", "x"}},
+	}
+	for _, test := range tests {
+		if got := m.command(test.args...).Args; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("command(%q).Args = %q, want %q", test.args, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gotool
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+var exportData struct {
+	mu   sync.Mutex
+	pkgs map[string][]byte
+}
+
+// RegisterExportData registers the gc export data of the package
+// path, as returned by ExportData. ImportGo uses it rather than
+// building the package, so interpreters with Go packages compiled
+// in can type check programs without a Go toolchain.
+func RegisterExportData(path string, data []byte) {
+	exportData.mu.Lock()
+	defer exportData.mu.Unlock()
+	if exportData.pkgs == nil {
+		exportData.pkgs = make(map[string][]byte)
+	}
+	exportData.pkgs[path] = data
+}
+
+func lookupExportData(path string) []byte {
+	exportData.mu.Lock()
+	defer exportData.mu.Unlock()
+	return exportData.pkgs[path]
+}
+
+// ExportData returns the gc export data of the package path.
+//
+// Export data describes every type the package refers to, so
+// it can be imported without the export data of its imports.
+func (m *Manager) ExportData(path string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.init(); err != nil {
+		return nil, err
+	}
+	out, err := m.command("list", "-export", "-f", "{{.Export}}", path).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("gotool: go list -export %s: %v\n%s", path, err, out)
+	}
+	data, err := ioutil.ReadFile(string(bytes.TrimSpace(out)))
+	if err != nil {
+		return nil, fmt.Errorf("gotool: export data of %s: %v", path, err)
+	}
+	return pkgDef(data), nil
+}
+
+// pkgDef trims a package archive to its first member, __.PKGDEF,
+// which holds the export data. The rest is object code.
+func pkgDef(archive []byte) []byte {
+	const magic = "!<arch>\n"
+	const hdrLen = 60
+	if !bytes.HasPrefix(archive, []byte(magic)) || len(archive) < len(magic)+hdrLen {
+		return archive
+	}
+	hdr := archive[len(magic) : len(magic)+hdrLen]
+	if string(bytes.TrimSpace(hdr[:16])) != "__.PKGDEF" {
+		return archive
+	}
+	size, err := strconv.Atoi(string(bytes.TrimSpace(hdr[48:58])))
+	end := len(magic) + hdrLen + size
+	if err != nil || end > len(archive) {
+		return archive
+	}
+	return archive[:end]
+}
+
+// BuildInterp builds the Neugram interpreter, the command
+// neugram.io/ng, into the executable out. The generated files are
+// added to its main package, typically to compile in wrappers of
+// Go packages.
+func (m *Manager) BuildInterp(out string, files map[string][]byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.init(); err != nil {
+		return err
+	}
+	out, err := filepath.Abs(out)
+	if err != nil {
+		return fmt.Errorf("gotool: %v", err)
+	}
+
+	// A main package cannot be imported, so its files are
+	// copied to an ephemeral package.
+	b, err := m.command("list", "-json", ngModule).Output()
+	if err != nil {
+		return fmt.Errorf("gotool: go list %s: %v", ngModule, err)
+	}
+	ng := new(listedPkg)
+	if err := json.Unmarshal(b, ng); err != nil {
+		return fmt.Errorf("gotool: go list %s: %v", ngModule, err)
+	}
+	mainPkgPath, dir, err := m.dir("ng")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range ng.GoFiles {
+		if err := copyFile(filepath.Join(dir, name), filepath.Join(ng.Dir, name)); err != nil {
+			return fmt.Errorf("gotool: %v", err)
+		}
+	}
+	for name, contents := range files {
+		if err := writeFile(filepath.Join(dir, name), contents); err != nil {
+			return err
+		}
+	}
+	return m.gocmd("build", "-o="+out, mainPkgPath)
+}
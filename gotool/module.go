@@ -0,0 +1,371 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gotool
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// tmpModule is the module path of the ephemeral module that holds
+// generated packages when the Manager is in module mode.
+const tmpModule = "ngtmp"
+
+// ngModule is the module path of Neugram. Plugins import its
+// gowrap package, so it must be the source the process was built
+// from.
+const ngModule = "neugram.io/ng"
+
+// FindModule reports the go.mod file of the module that encloses
+// dir, or "" if packages in dir are built in GOPATH mode.
+//
+// It uses the rules of the go tool, so GO111MODULE and GOFLAGS
+// are respected.
+func FindModule(dir string) (string, error) {
+	cmd := exec.Command("go", "env", "GOMOD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("gotool: go env GOMOD: %v", err)
+	}
+	gomod := strings.TrimSpace(string(out))
+	if gomod == os.DevNull {
+		// Module mode, outside of any module.
+		return "", nil
+	}
+	return gomod, nil
+}
+
+// SetModule sets the go.mod file of the module that Go packages are
+// imported from. Generated packages are then built in a temporary
+// module that requires it. An empty gomod selects GOPATH mode.
+func (m *Manager) SetModule(gomod string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if gomod != "" {
+		var err error
+		gomod, err = filepath.Abs(gomod)
+		if err != nil {
+			return fmt.Errorf("gotool: %v", err)
+		}
+	}
+	m.gomod = gomod
+	m.moduleDir = ""
+	m.overlay = ""
+	m.cacheKeys = nil // keys depend on the module
+	if m.tempdir == "" {
+		return nil // module written by init
+	}
+	if gomod == "" {
+		os.Remove(filepath.Join(m.tempdir, "go.mod"))
+		return nil
+	}
+	return m.writeModule()
+}
+
+// SetModuleDir selects the module that encloses dir, as reported by
+// FindModule, as the module Go packages are imported from. As that
+// runs the go tool, the module is found when the Manager is first
+// used. It must be called before then.
+func (m *Manager) SetModuleDir(dir string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.moduleDir = dir
+}
+
+// goMod is a go.mod file, as printed by go mod edit -json.
+type goMod struct {
+	Module struct {
+		Path string
+	}
+	Go      string
+	Require []struct {
+		Path     string
+		Version  string
+		Indirect bool
+	}
+	Replace []struct {
+		Old struct{ Path, Version string }
+		New struct{ Path, Version string }
+	}
+}
+
+// writeModule writes the go.mod of the temporary module.
+//
+// The temporary module requires the user's module, replaced by its
+// directory, along with all of its requirements and replacements.
+// It also replaces Neugram with the source the process was built
+// from, so plugins are built against the same packages as the
+// interpreter.
+//
+// If the user's module is vendored, each vendored module is replaced
+// by its directory in vendor, so nothing needs to be downloaded.
+//
+// Replacement directories without a go.mod, such as vendored modules
+// or a Neugram checkout in a GOPATH, are given one with -overlay.
+func (m *Manager) writeModule() error {
+	userDir := filepath.Dir(m.gomod)
+	cmd := exec.Command("go", "mod", "edit", "-json", m.gomod)
+	cmd.Dir = userDir
+	cmd.Env = append(os.Environ(), "GO111MODULE=on")
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("gotool: reading %s: %v", m.gomod, err)
+	}
+	user := new(goMod)
+	if err := json.Unmarshal(out, user); err != nil {
+		return fmt.Errorf("gotool: reading %s: %v", m.gomod, err)
+	}
+
+	var vendored map[string]string
+	if m.vendored(user) {
+		vendored, err = vendoredModules(filepath.Join(userDir, "vendor", "modules.txt"))
+		if err != nil {
+			return err
+		}
+	}
+
+	// A replacement is either an absolute directory,
+	// or a module path and version. If the directory has
+	// no go.mod, one is made declaring goVersion.
+	type replace struct{ path, new, goVersion string }
+	var requires []string
+	var replaces []replace
+	replaced := make(map[string]bool)
+
+	requires = append(requires, user.Module.Path+" v0.0.0-00010101000000-000000000000")
+	replaces = append(replaces, replace{user.Module.Path, userDir, ""})
+	replaced[user.Module.Path] = true
+
+	if user.Module.Path != ngModule {
+		ngVersion, ngDir, err := ngSource()
+		if err != nil {
+			return err
+		}
+		if ngDir != "" {
+			requires = append(requires, ngModule+" v0.0.0-00010101000000-000000000000")
+			replaces = append(replaces, replace{ngModule, ngDir, ""})
+		} else {
+			requires = append(requires, ngModule+" "+ngVersion)
+		}
+		replaced[ngModule] = true
+	}
+
+	for _, r := range user.Replace {
+		if replaced[r.Old.Path] {
+			continue
+		}
+		if isLocalDir(r.New.Path) {
+			dir := r.New.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(userDir, dir)
+			}
+			replaces = append(replaces, replace{r.Old.Path, dir, ""})
+			replaced[r.Old.Path] = true
+		} else if vendored == nil {
+			// Remote replacements are kept as they are.
+			replaces = append(replaces, replace{r.Old.Path, r.New.Path + " " + r.New.Version, ""})
+			replaced[r.Old.Path] = true
+		}
+	}
+	for _, r := range user.Require {
+		if r.Path == ngModule {
+			continue
+		}
+		if vendored != nil && !replaced[r.Path] {
+			goVersion, ok := vendored[r.Path]
+			if !ok {
+				continue // no packages used from this module
+			}
+			dir := filepath.Join(userDir, "vendor", filepath.FromSlash(r.Path))
+			replaces = append(replaces, replace{r.Path, dir, goVersion})
+			replaced[r.Path] = true
+		}
+		requires = append(requires, r.Path+" "+r.Version)
+	}
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// Generated by neugram for packages imported from %s.\n\n", m.gomod)
+	fmt.Fprintf(buf, "module %s\n", tmpModule)
+	if user.Go != "" {
+		fmt.Fprintf(buf, "\ngo %s\n", user.Go)
+	}
+	fmt.Fprintf(buf, "\nrequire (\n")
+	for _, r := range requires {
+		fmt.Fprintf(buf, "\t%s\n", r)
+	}
+	fmt.Fprintf(buf, ")\n\nreplace (\n")
+	overlay := make(map[string]string)
+	for _, r := range replaces {
+		if filepath.IsAbs(r.new) {
+			fmt.Fprintf(buf, "\t%s => %s\n", r.path, quoteDir(r.new))
+			if _, err := os.Stat(filepath.Join(r.new, "go.mod")); os.IsNotExist(err) {
+				gomod := filepath.Join(m.tempdir, "overlay", fmt.Sprintf("%d.mod", len(overlay)))
+				overlay[filepath.Join(r.new, "go.mod")] = gomod
+				contents := "module " + r.path + "\n"
+				if r.goVersion != "" {
+					contents += "\ngo " + r.goVersion + "\n"
+				}
+				if err := writeFile(gomod, []byte(contents)); err != nil {
+					return err
+				}
+			}
+		} else {
+			fmt.Fprintf(buf, "\t%s => %s\n", r.path, r.new)
+		}
+	}
+	fmt.Fprintf(buf, ")\n")
+	if err := writeFile(filepath.Join(m.tempdir, "go.mod"), buf.Bytes()); err != nil {
+		return err
+	}
+
+	if vendored == nil {
+		gosum, err := ioutil.ReadFile(filepath.Join(userDir, "go.sum"))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("gotool: %v", err)
+		}
+		if err := writeFile(filepath.Join(m.tempdir, "go.sum"), gosum); err != nil {
+			return err
+		}
+	}
+
+	if len(overlay) > 0 {
+		b, err := json.Marshal(struct{ Replace map[string]string }{overlay})
+		if err != nil {
+			return fmt.Errorf("gotool: %v", err)
+		}
+		m.overlay = filepath.Join(m.tempdir, "overlay", "overlay.json")
+		if err := writeFile(m.overlay, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vendored reports whether the user's module is built from its
+// vendor directory, following the rules of the go tool.
+func (m *Manager) vendored(user *goMod) bool {
+	for _, flag := range strings.Fields(os.Getenv("GOFLAGS")) {
+		if strings.HasPrefix(flag, "-mod=") {
+			return flag == "-mod=vendor"
+		}
+	}
+	vendor := filepath.Join(filepath.Dir(m.gomod), "vendor", "modules.txt")
+	if _, err := os.Stat(vendor); err != nil {
+		return false
+	}
+	var major, minor int
+	fmt.Sscanf(user.Go, "%d.%d", &major, &minor)
+	return major > 1 || (major == 1 && minor >= 14)
+}
+
+// vendoredModules reports the modules that have packages in a
+// vendor directory, as listed in its modules.txt, along with the
+// Go version each module declares.
+func vendoredModules(modulesTxt string) (map[string]string, error) {
+	f, err := os.Open(modulesTxt)
+	if err != nil {
+		return nil, fmt.Errorf("gotool: %v", err)
+	}
+	defer f.Close()
+
+	mods := make(map[string]string)
+	var mod, goVersion string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "# "):
+			mod, goVersion = strings.Fields(line[2:])[0], ""
+		case strings.HasPrefix(line, "## "):
+			// Annotations, such as "## explicit; go 1.18".
+			for _, a := range strings.Split(line[3:], ";") {
+				if f := strings.Fields(a); len(f) == 2 && f[0] == "go" {
+					goVersion = f[1]
+				}
+			}
+		case line != "" && mod != "":
+			mods[mod] = goVersion
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("gotool: %v", err)
+	}
+	return mods, nil
+}
+
+// ngSource reports where the Neugram packages linked into this
+// process come from: either a module version, or a source directory.
+func ngSource() (version, dir string, err error) {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		mods := append([]*debug.Module{&info.Main}, info.Deps...)
+		for _, mod := range mods {
+			if mod.Path != ngModule {
+				continue
+			}
+			if mod.Replace != nil {
+				mod = mod.Replace
+			}
+			if filepath.IsAbs(mod.Path) {
+				return "", mod.Path, nil
+			}
+			if mod.Version != "" && mod.Version != "(devel)" {
+				return mod.Version, "", nil
+			}
+		}
+	}
+	_, file, _, ok := runtime.Caller(0)
+	if !ok || !filepath.IsAbs(file) {
+		return "", "", fmt.Errorf("gotool: cannot find the source of %s needed to build plugins in module mode", ngModule)
+	}
+	return "", filepath.Dir(filepath.Dir(file)), nil
+}
+
+func isLocalDir(p string) bool {
+	return filepath.IsAbs(p) || strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../")
+}
+
+// quoteDir quotes a replacement directory for a go.mod file.
+func quoteDir(dir string) string {
+	if strings.ContainsAny(dir, " \t\"'`") {
+		return fmt.Sprintf("%q", dir)
+	}
+	return dir
+}
+
+func writeFile(filename string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0775); err != nil {
+		return fmt.Errorf("gotool: %v", err)
+	}
+	if err := ioutil.WriteFile(filename, data, 0664); err != nil {
+		return fmt.Errorf("gotool: %v", err)
+	}
+	return nil
+}
+
+// modFlags returns GOFLAGS for building in the temporary module.
+// The user's -mod and -modfile flags describe their own module, so
+// they are replaced.
+func modFlags(goflags string) string {
+	var flags []string
+	for _, flag := range strings.Fields(goflags) {
+		if strings.HasPrefix(flag, "-mod=") || strings.HasPrefix(flag, "-modfile=") {
+			continue
+		}
+		flags = append(flags, flag)
+	}
+	return strings.Join(append(flags, "-mod=mod"), " ")
+}
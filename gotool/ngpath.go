@@ -0,0 +1,136 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gotool
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FindNg reports the file of the Neugram package imported by path,
+// such as "example.com/team/ngutil/strings.ng". The file may be a
+// directory of .ng files.
+//
+// The directories listed in $NGPATH are searched first. Then the
+// path is resolved like a Go import path: against the modules of
+// the module Go packages are imported from, or against GOPATH in
+// GOPATH mode. Results are cached for the life of the process.
+func (m *Manager) FindNg(path string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if filename, ok := m.ngFiles[path]; ok {
+		return filename, nil
+	}
+	filename, err := m.findNg(path)
+	if err != nil {
+		return "", err
+	}
+	if m.ngFiles == nil {
+		m.ngFiles = make(map[string]string)
+	}
+	m.ngFiles[path] = filename
+	return filename, nil
+}
+
+func (m *Manager) findNg(path string) (string, error) {
+	slashPath := filepath.ToSlash(path)
+	if strings.HasPrefix(slashPath, "../") || strings.Contains(slashPath, "/../") {
+		return "", fmt.Errorf("gotool: invalid Neugram package path %q", path)
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("NGPATH")) {
+		if dir == "" {
+			continue
+		}
+		if filename := filepath.Join(dir, path); exists(filename) {
+			return filepath.Abs(filename)
+		}
+	}
+
+	if err := m.init(); err != nil {
+		return "", err
+	}
+	if m.gomod == "" {
+		for _, dir := range filepath.SplitList(m.gopath()) {
+			if filename := filepath.Join(dir, "src", path); exists(filename) {
+				return filename, nil
+			}
+		}
+		return "", fmt.Errorf("gotool: cannot find Neugram package %q in $NGPATH or $GOPATH", path)
+	}
+
+	mod, err := m.ngModule(slashPath)
+	if err != nil {
+		return "", err
+	}
+	if mod == nil {
+		return "", fmt.Errorf("gotool: cannot find Neugram package %q in $NGPATH or any module", path)
+	}
+	filename := filepath.Join(mod.Dir, strings.TrimPrefix(slashPath, mod.Path))
+	if !exists(filename) {
+		return "", fmt.Errorf("gotool: module %s has no Neugram package %q", mod.Path, path)
+	}
+	return filename, nil
+}
+
+// ngModule reports the module of the build list that provides path,
+// the module with the longest path that prefixes it, or nil.
+func (m *Manager) ngModule(path string) (*goListModule, error) {
+	out, err := m.command("list", "-m", "-json", "all").Output()
+	if err != nil {
+		return nil, fmt.Errorf("gotool: go list -m all: %v", err)
+	}
+	var mod *goListModule
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		listed := new(goListModule)
+		if err := dec.Decode(listed); err != nil {
+			return nil, fmt.Errorf("gotool: go list -m all: %v", err)
+		}
+		if listed.Path != path && !strings.HasPrefix(path, listed.Path+"/") {
+			continue
+		}
+		if mod == nil || len(listed.Path) > len(mod.Path) {
+			mod = listed
+		}
+	}
+	if mod == nil {
+		return nil, nil
+	}
+	if mod.Dir == "" && mod.Replace != nil {
+		mod.Dir = mod.Replace.Dir
+	}
+	if mod.Dir == "" {
+		// In the build list, but not yet downloaded.
+		out, err := m.command("mod", "download", "-json", mod.Path).Output()
+		if err != nil {
+			return nil, fmt.Errorf("gotool: go mod download %s: %v", mod.Path, err)
+		}
+		downloaded := new(goListModule)
+		if err := json.Unmarshal(out, downloaded); err != nil {
+			return nil, fmt.Errorf("gotool: go mod download %s: %v", mod.Path, err)
+		}
+		mod.Dir = downloaded.Dir
+	}
+	return mod, nil
+}
+
+// goListModule is a module, as printed by go list -m -json.
+type goListModule struct {
+	Path    string
+	Version string
+	Dir     string
+	Replace *goListModule
+}
+
+// exists reports whether filename is a regular file or a directory.
+func exists(filename string) bool {
+	fi, err := os.Stat(filename)
+	return err == nil && (fi.Mode().IsRegular() || fi.IsDir())
+}
@@ -0,0 +1,667 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"neugram.io/ng/format"
+	"neugram.io/ng/parser"
+	"neugram.io/ng/syntax"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/src"
+	"neugram.io/ng/syntax/stmt"
+	"neugram.io/ng/syntax/tipe"
+	"neugram.io/ng/syntax/token"
+	"neugram.io/ng/typecheck"
+)
+
+// A document is an open text document and the result of
+// parsing and type checking it.
+type document struct {
+	uri   string
+	path  string
+	text  []byte
+	lines [][]byte
+
+	file    *syntax.File
+	checker *typecheck.Checker
+	diags   []Diagnostic
+}
+
+func newDocument(uri string, text []byte) *document {
+	doc := &document{
+		uri:   uri,
+		path:  uriToPath(uri),
+		text:  text,
+		lines: bytes.Split(text, []byte("\n")),
+	}
+	doc.check()
+	return doc
+}
+
+// check parses and type checks the document, recording diagnostics.
+//
+// Statements are added to the checker one at a time so that an
+// error can be attributed to the statement that caused it, and
+// so that checking continues past the first error.
+func (doc *document) check() {
+	p := parser.New(doc.path, parser.ParseComments)
+	f, err := p.Parse(doc.text)
+	state := p.State()
+	p.Close()
+	doc.file = f
+
+	switch err := err.(type) {
+	case nil:
+	case parser.Error:
+		doc.diags = append(doc.diags, doc.parseDiag(err))
+	case parser.Errors:
+		for _, err := range err {
+			doc.diags = append(doc.diags, doc.parseDiag(err))
+		}
+	default:
+		doc.diags = append(doc.diags, Diagnostic{
+			Severity: severityError,
+			Source:   "parser",
+			Message:  err.Error(),
+		})
+	}
+	parsed := err == nil
+	switch state {
+	case parser.StateStmtPartial, parser.StateCmdPartial:
+		doc.diags = append(doc.diags, Diagnostic{
+			Range:    doc.endRange(),
+			Severity: severityError,
+			Source:   "parser",
+			Message:  "unexpected EOF",
+		})
+		parsed = false
+	}
+
+	doc.checker = typecheck.New(doc.path)
+	for _, s := range f.Stmts {
+		errs := doc.add(s)
+		if !parsed {
+			// Type errors in a file that does not parse
+			// are mostly noise. Type check anyway, so
+			// hover and completion have something to use.
+			continue
+		}
+		for _, err := range errs {
+			doc.diags = append(doc.diags, Diagnostic{
+				Range:    doc.lineRange(s.Pos()),
+				Severity: severityError,
+				Source:   "typecheck",
+				Message:  err.Error(),
+			})
+		}
+	}
+}
+
+func (doc *document) add(s stmt.Stmt) (errs []error) {
+	defer func() {
+		if x := recover(); x != nil {
+			errs = append(doc.checker.Errs(), fmt.Errorf("internal typecheck error: %v", x))
+		}
+	}()
+	doc.checker.Add(s)
+	return doc.checker.Errs()
+}
+
+func (doc *document) parseDiag(err parser.Error) Diagnostic {
+	pos := toPosition(err.Pos)
+	return Diagnostic{
+		Range:    Range{Start: pos, End: pos},
+		Severity: severityError,
+		Source:   "parser",
+		Message:  err.Msg,
+	}
+}
+
+// lineRange returns the range from pos to the end of its line.
+func (doc *document) lineRange(pos src.Pos) Range {
+	start := toPosition(pos)
+	end := Position{Line: start.Line}
+	if start.Line < len(doc.lines) {
+		end.Character = len(doc.lines[start.Line])
+	}
+	return Range{Start: start, End: end}
+}
+
+// endRange returns the empty range at the end of the last
+// non-blank line of the document.
+func (doc *document) endRange() Range {
+	line := len(doc.lines) - 1
+	for line > 0 && len(bytes.TrimSpace(doc.lines[line])) == 0 {
+		line--
+	}
+	end := Position{Line: line, Character: len(doc.lines[line])}
+	return Range{Start: end, End: end}
+}
+
+func toPosition(pos src.Pos) Position {
+	p := Position{
+		Line:      int(pos.Line) - 1,
+		Character: int(pos.Column) - 1,
+	}
+	if p.Line < 0 {
+		p.Line = 0
+	}
+	if p.Character < 0 {
+		p.Character = 0
+	}
+	return p
+}
+
+func identRange(e *expr.Ident) Range {
+	start := toPosition(e.Position)
+	end := start
+	end.Character += len(e.Name)
+	return Range{Start: start, End: end}
+}
+
+// identAt finds the identifier covering pos.
+// If the identifier is the right side of a selector expression,
+// the selector is returned as well.
+func (doc *document) identAt(pos Position) (ident *expr.Ident, sel *expr.Selector) {
+	if doc.file == nil {
+		return nil, nil
+	}
+	preFn := func(c *syntax.Cursor) bool {
+		if ident != nil {
+			return false
+		}
+		e, ok := c.Node.(*expr.Ident)
+		if !ok {
+			return true
+		}
+		r := identRange(e)
+		if r.Start.Line != pos.Line {
+			return true
+		}
+		if r.Start.Character <= pos.Character && pos.Character <= r.End.Character {
+			ident = e
+			if s, ok := c.Parent.(*expr.Selector); ok && c.Name == "Right" {
+				sel = s
+			}
+		}
+		return true
+	}
+	syntax.Walk(doc.file, preFn, nil)
+	return ident, sel
+}
+
+// obj finds the object an identifier refers to.
+func (doc *document) obj(e *expr.Ident) *typecheck.Obj {
+	if obj := doc.checker.Ident(e); obj != nil {
+		return obj
+	}
+	return doc.checker.Lookup(e.Name)
+}
+
+// pkgMember returns the package and member name a selector
+// refers to, if its left side names an imported package.
+func (doc *document) pkgMember(sel *expr.Selector) (*typecheck.Package, string) {
+	left, ok := sel.Left.(*expr.Ident)
+	if !ok {
+		return nil, ""
+	}
+	obj := doc.obj(left)
+	if obj == nil || obj.Kind != typecheck.ObjPkg {
+		return nil, ""
+	}
+	pkg, _ := obj.Decl.(*typecheck.Package)
+	return pkg, sel.Right.Name
+}
+
+func (doc *document) hover(pos Position) *Hover {
+	ident, sel := doc.identAt(pos)
+	if ident == nil {
+		return nil
+	}
+	var text string
+	if sel != nil {
+		t := doc.checker.Type(sel)
+		if t == nil {
+			return nil
+		}
+		keyword := "field"
+		if pkg, name := doc.pkgMember(sel); pkg != nil {
+			if obj := pkg.GlobalNames[name]; obj != nil {
+				keyword = objKeyword(obj.Kind, obj.Decl)
+			} else if pkg.GoPkg != nil {
+				keyword = goObjKeyword(pkg.GoPkg.Scope().Lookup(name))
+			}
+		} else if _, isFunc := t.(*tipe.Func); isFunc {
+			keyword = "method"
+		}
+		text = objString(keyword, format.Expr(sel), t)
+	} else {
+		obj := doc.obj(ident)
+		if obj == nil {
+			return nil
+		}
+		text = objString(objKeyword(obj.Kind, obj.Decl), ident.Name, obj.Type)
+		if obj.Kind == typecheck.ObjPkg {
+			if pkg, ok := obj.Decl.(*typecheck.Package); ok {
+				text = fmt.Sprintf("package %s (%q)", ident.Name, pkg.Path)
+			}
+		}
+	}
+	r := identRange(ident)
+	return &Hover{
+		Contents: MarkupContent{
+			Kind:  "markdown",
+			Value: "```ng\n" + text + "\n```",
+		},
+		Range: &r,
+	}
+}
+
+func objKeyword(kind typecheck.ObjKind, decl interface{}) string {
+	switch kind {
+	case typecheck.ObjConst:
+		return "const"
+	case typecheck.ObjType:
+		return "type"
+	case typecheck.ObjPkg:
+		return "package"
+	}
+	if _, isFunc := decl.(*expr.FuncLiteral); isFunc {
+		return "func"
+	}
+	return "var"
+}
+
+// objString describes a named object the way it would be declared.
+func objString(keyword, name string, t tipe.Type) string {
+	switch keyword {
+	case "type":
+		if n, ok := t.(*tipe.Named); ok {
+			return "type " + name + " " + format.Type(n.Type)
+		}
+	case "func", "method":
+		if fn, ok := t.(*tipe.Func); ok {
+			return keyword + " " + name + strings.TrimPrefix(format.Type(fn), "func")
+		}
+	}
+	return keyword + " " + name + " " + format.Type(t)
+}
+
+// definition reports the location where the identifier
+// under pos was declared.
+func (doc *document) definition(pos Position) *Location {
+	ident, sel := doc.identAt(pos)
+	if ident == nil {
+		return nil
+	}
+	if sel != nil {
+		pkg, name := doc.pkgMember(sel)
+		if pkg == nil {
+			return nil
+		}
+		if pkg.GoPkg != nil {
+			return goDefinition(pkg.GoPkg.Path(), name)
+		}
+		obj := pkg.GlobalNames[name]
+		if obj == nil {
+			return nil
+		}
+		return doc.declLocation(obj, ngPkgFilename(pkg.Path))
+	}
+	obj := doc.obj(ident)
+	if obj == nil {
+		return nil
+	}
+	if obj.Kind == typecheck.ObjPkg {
+		if pkg, ok := obj.Decl.(*typecheck.Package); ok {
+			if pkg.GoPkg != nil {
+				return goDefinition(pkg.GoPkg.Path(), "")
+			}
+			return &Location{URI: pathToURI(ngPkgFilename(pkg.Path))}
+		}
+	}
+	return doc.declLocation(obj, doc.path)
+}
+
+// declLocation finds the declaration of obj in the file filename.
+func (doc *document) declLocation(obj *typecheck.Obj, filename string) *Location {
+	switch decl := obj.Decl.(type) {
+	case *expr.FuncLiteral, *stmt.TypeDecl, *stmt.MethodikDecl, *stmt.Var, *stmt.Const:
+		pos := toPosition(decl.(syntax.Node).Pos())
+		return &Location{
+			URI:   pathToURI(filename),
+			Range: Range{Start: pos, End: pos},
+		}
+	}
+	if filename != doc.path {
+		return nil
+	}
+
+	// The first identifier referring to obj is its declaration.
+	var decl *expr.Ident
+	preFn := func(c *syntax.Cursor) bool {
+		if decl != nil {
+			return false
+		}
+		if e, ok := c.Node.(*expr.Ident); ok && doc.checker.Ident(e) == obj {
+			decl = e
+		}
+		return true
+	}
+	syntax.Walk(doc.file, preFn, nil)
+	if decl == nil {
+		return nil
+	}
+	return &Location{
+		URI:   doc.uri,
+		Range: identRange(decl),
+	}
+}
+
+// ngPkgFilename reverses the mangling typecheck applies to
+// the path of an imported Neugram package.
+func ngPkgFilename(path string) string {
+	path = strings.TrimPrefix(path, "rel")
+	return strings.TrimSuffix(path, "_ng") + ".ng"
+}
+
+func (doc *document) completion(pos Position) *CompletionList {
+	list := &CompletionList{Items: []CompletionItem{}}
+	if pos.Line >= len(doc.lines) {
+		return list
+	}
+	line := doc.lines[pos.Line]
+	if pos.Character < len(line) {
+		line = line[:pos.Character]
+	}
+	i := len(line)
+	for i > 0 && isIdentByte(line[i-1]) {
+		i--
+	}
+	prefix := string(line[i:])
+
+	seen := make(map[string]bool)
+	add := func(item CompletionItem) {
+		if seen[item.Label] || !strings.HasPrefix(item.Label, prefix) {
+			return
+		}
+		seen[item.Label] = true
+		list.Items = append(list.Items, item)
+	}
+
+	if i > 0 && line[i-1] == '.' {
+		j := i - 1
+		for j > 0 && isIdentByte(line[j-1]) {
+			j--
+		}
+		for _, item := range doc.members(string(line[j:i-1]), pos) {
+			add(item)
+		}
+		sortItems(list.Items)
+		return list
+	}
+
+	// Identifiers already resolved earlier in the file.
+	// This is scope-insensitive, but cheap and usually right.
+	if doc.file != nil {
+		preFn := func(c *syntax.Cursor) bool {
+			e, ok := c.Node.(*expr.Ident)
+			if !ok {
+				return true
+			}
+			if p := toPosition(e.Position); p.Line > pos.Line || (p.Line == pos.Line && p.Character >= pos.Character-len(prefix)) {
+				return true
+			}
+			if obj := doc.checker.Ident(e); obj != nil {
+				add(objItem(e.Name, obj))
+			}
+			return true
+		}
+		syntax.Walk(doc.file, preFn, nil)
+	}
+	for _, s := range doc.file.Stmts {
+		name := topLevelName(s)
+		if name == "" {
+			continue
+		}
+		if obj := doc.checker.Lookup(name); obj != nil {
+			add(objItem(name, obj))
+		}
+	}
+	for name, obj := range typecheck.Universe.Objs {
+		add(objItem(name, obj))
+	}
+	for keyword := range token.Keywords {
+		add(CompletionItem{Label: keyword, Kind: completionKeyword})
+	}
+	sortItems(list.Items)
+	return list
+}
+
+func sortItems(items []CompletionItem) {
+	sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' || b >= 0x80
+}
+
+func objItem(name string, obj *typecheck.Obj) CompletionItem {
+	item := CompletionItem{
+		Label:  name,
+		Detail: format.Type(obj.Type),
+	}
+	switch obj.Kind {
+	case typecheck.ObjVar:
+		item.Kind = completionVariable
+		if _, isFunc := obj.Decl.(*expr.FuncLiteral); isFunc {
+			item.Kind = completionFunction
+		}
+	case typecheck.ObjConst:
+		item.Kind = completionConstant
+	case typecheck.ObjType:
+		item.Kind = completionClass
+	case typecheck.ObjPkg:
+		item.Kind = completionModule
+		item.Detail = ""
+		if pkg, ok := obj.Decl.(*typecheck.Package); ok {
+			item.Detail = "package " + pkg.Path
+		}
+	}
+	return item
+}
+
+// topLevelName reports the name declared by a top-level statement.
+func topLevelName(s stmt.Stmt) string {
+	switch s := s.(type) {
+	case *stmt.TypeDecl:
+		return s.Name
+	case *stmt.MethodikDecl:
+		return s.Name
+	case *stmt.Simple:
+		if fn, ok := s.Expr.(*expr.FuncLiteral); ok {
+			return fn.Name
+		}
+	}
+	return ""
+}
+
+func (doc *document) symbols() []DocumentSymbol {
+	res := []DocumentSymbol{}
+	if doc.file == nil {
+		return res
+	}
+	for _, s := range doc.file.Stmts {
+		res = append(res, doc.stmtSymbols(s)...)
+	}
+	return res
+}
+
+func (doc *document) stmtSymbols(s stmt.Stmt) (res []DocumentSymbol) {
+	sym := func(name string, kind int, pos src.Pos, detail string) DocumentSymbol {
+		r := doc.lineRange(pos)
+		return DocumentSymbol{
+			Name:           name,
+			Detail:         detail,
+			Kind:           kind,
+			Range:          r,
+			SelectionRange: r,
+		}
+	}
+	switch s := s.(type) {
+	case *stmt.Import:
+		return []DocumentSymbol{sym(s.Path, symbolModule, s.Position, s.Name)}
+	case *stmt.ImportSet:
+		for _, imp := range s.Imports {
+			res = append(res, doc.stmtSymbols(imp)...)
+		}
+	case *stmt.TypeDecl:
+		return []DocumentSymbol{sym(s.Name, symbolClass, s.Position, format.Type(s.Type.Type))}
+	case *stmt.TypeDeclSet:
+		for _, t := range s.TypeDecls {
+			res = append(res, doc.stmtSymbols(t)...)
+		}
+	case *stmt.MethodikDecl:
+		m := sym(s.Name, symbolClass, s.Position, "methodik")
+		for _, fn := range s.Methods {
+			m.Children = append(m.Children, sym(fn.Name, symbolMethod, fn.Position, format.Type(fn.Type)))
+		}
+		return []DocumentSymbol{m}
+	case *stmt.Const:
+		for _, name := range s.NameList {
+			res = append(res, sym(name, symbolConstant, s.Position, ""))
+		}
+	case *stmt.ConstSet:
+		for _, c := range s.Consts {
+			res = append(res, doc.stmtSymbols(c)...)
+		}
+	case *stmt.Var:
+		for _, name := range s.NameList {
+			res = append(res, sym(name, symbolVariable, s.Position, ""))
+		}
+	case *stmt.VarSet:
+		for _, v := range s.Vars {
+			res = append(res, doc.stmtSymbols(v)...)
+		}
+	case *stmt.Assign:
+		if !s.Decl {
+			return nil
+		}
+		for _, e := range s.Left {
+			if e, ok := e.(*expr.Ident); ok && e.Name != "_" {
+				res = append(res, sym(e.Name, symbolVariable, e.Position, ""))
+			}
+		}
+	case *stmt.Simple:
+		if fn, ok := s.Expr.(*expr.FuncLiteral); ok && fn.Name != "" {
+			return []DocumentSymbol{sym(fn.Name, symbolFunction, fn.Position, format.Type(fn.Type))}
+		}
+	}
+	return res
+}
+
+// format returns the edits that reformat the document.
+func (doc *document) format() []TextEdit {
+	edits := []TextEdit{}
+	if doc.file == nil || len(doc.diags) > 0 {
+		return edits
+	}
+	out, err := format.Source(doc.file, doc.text)
+	if err != nil || bytes.Equal(out, doc.text) {
+		return edits
+	}
+	end := Position{Line: len(doc.lines)}
+	return append(edits, TextEdit{
+		Range:   Range{Start: Position{}, End: end},
+		NewText: string(out),
+	})
+}
+
+// members lists the completions for a selector whose left side
+// is the identifier name.
+func (doc *document) members(name string, pos Position) (res []CompletionItem) {
+	if name == "" {
+		return nil
+	}
+	var obj *typecheck.Obj
+	preFn := func(c *syntax.Cursor) bool {
+		e, ok := c.Node.(*expr.Ident)
+		if !ok || e.Name != name {
+			return true
+		}
+		if p := toPosition(e.Position); p.Line > pos.Line {
+			return true
+		}
+		if o := doc.checker.Ident(e); o != nil {
+			obj = o // keep the last one
+		}
+		return true
+	}
+	syntax.Walk(doc.file, preFn, nil)
+	if obj == nil {
+		obj = doc.checker.Lookup(name)
+	}
+	if obj == nil {
+		return nil
+	}
+
+	if obj.Kind == typecheck.ObjPkg {
+		pkg, ok := obj.Decl.(*typecheck.Package)
+		if !ok {
+			return nil
+		}
+		for _, obj := range pkg.Globals {
+			if !isExported(obj.Name) {
+				continue
+			}
+			item := objItem(obj.Name, obj)
+			if pkg.GoPkg != nil {
+				switch goObjKeyword(pkg.GoPkg.Scope().Lookup(obj.Name)) {
+				case "func":
+					item.Kind = completionFunction
+				case "type":
+					item.Kind = completionClass
+				}
+			}
+			res = append(res, item)
+		}
+		return res
+	}
+
+	t := obj.Type
+	if p, ok := tipe.Underlying(t).(*tipe.Pointer); ok {
+		t = p.Elem
+	}
+	names, methods := tipe.NewMemory().Methods(&tipe.Pointer{Elem: t})
+	for i, name := range names {
+		res = append(res, CompletionItem{
+			Label:  name,
+			Kind:   completionFunction,
+			Detail: format.Type(methods[i]),
+		})
+	}
+	if st, ok := tipe.Underlying(t).(*tipe.Struct); ok {
+		for _, sf := range st.Fields {
+			res = append(res, CompletionItem{
+				Label:  sf.Name,
+				Kind:   completionField,
+				Detail: format.Type(sf.Type),
+			})
+		}
+	}
+	return res
+}
+
+func isExported(name string) bool {
+	ch, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(ch)
+}
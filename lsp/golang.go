@@ -0,0 +1,93 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"go/ast"
+	"go/build"
+	goparser "go/parser"
+	gotoken "go/token"
+	gotypes "go/types"
+	"path/filepath"
+)
+
+func goObjKeyword(obj gotypes.Object) string {
+	switch obj.(type) {
+	case *gotypes.Const:
+		return "const"
+	case *gotypes.TypeName:
+		return "type"
+	case *gotypes.Func:
+		return "func"
+	default:
+		return "var"
+	}
+}
+
+// goDefinition finds the source of a top-level declaration in a
+// Go package. If name is empty, the location of the package is
+// reported.
+//
+// The typechecker loads Go packages from export data, which has
+// no useful positions, so the package source is parsed instead.
+func goDefinition(pkgPath, name string) *Location {
+	bpkg, err := build.Import(pkgPath, "", 0)
+	if err != nil {
+		return nil
+	}
+	if name == "" {
+		if len(bpkg.GoFiles) == 0 {
+			return nil
+		}
+		return &Location{URI: pathToURI(filepath.Join(bpkg.Dir, bpkg.GoFiles[0]))}
+	}
+
+	fset := gotoken.NewFileSet()
+	for _, filename := range bpkg.GoFiles {
+		f, err := goparser.ParseFile(fset, filepath.Join(bpkg.Dir, filename), nil, 0)
+		if err != nil {
+			continue
+		}
+		if ident := goDecl(f, name); ident != nil {
+			pos := fset.Position(ident.Pos())
+			start := Position{Line: pos.Line - 1, Character: pos.Column - 1}
+			end := start
+			end.Character += len(name)
+			return &Location{
+				URI:   pathToURI(pos.Filename),
+				Range: Range{Start: start, End: end},
+			}
+		}
+	}
+	return nil
+}
+
+// goDecl finds the identifier declaring name at the top level of f.
+func goDecl(f *ast.File, name string) *ast.Ident {
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil && decl.Name.Name == name {
+				return decl.Name
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Name.Name == name {
+						return spec.Name
+					}
+				case *ast.ValueSpec:
+					for _, ident := range spec.Names {
+						if ident.Name == name {
+							return ident
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
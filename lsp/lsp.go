@@ -0,0 +1,293 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lsp implements a Language Server Protocol server for Neugram.
+//
+// The server speaks JSON-RPC 2.0 over a pair of streams, nominally
+// stdin and stdout of the "ng lsp" command. Documents are synchronized
+// in full on every change, parsed with the parser package and type
+// checked with typecheck.Checker. It provides:
+//
+//	diagnostics on open and change
+//	hover, showing types as printed by format.Type
+//	go to definition, for Neugram and Go-imported symbols
+//	completion
+//	document symbols
+//	formatting
+//
+// Specification:
+//
+//	https://microsoft.github.io/language-server-protocol/specification
+//
+// Positions in the protocol are measured in UTF-16 code units. The
+// parser counts bytes. The two agree for ASCII source, which is all
+// the server attempts to handle correctly for now.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var debug = false
+
+// Run serves the Language Server Protocol, reading requests from r
+// and writing responses to w. It returns when the client sends an
+// exit notification, r is closed, or ctx is done.
+func Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	s := &server{
+		in:   bufio.NewReader(r),
+		out:  w,
+		docs: make(map[string]*document),
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		req, err := s.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("lsp: %v", err)
+		}
+		if debug {
+			log.Printf("lsp: <- %s %s", req.Method, req.Params)
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("lsp: exit before shutdown")
+			}
+			return nil
+		}
+		if err := s.handle(req); err != nil {
+			return fmt.Errorf("lsp: %v", err)
+		}
+	}
+}
+
+type server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]*document // URI -> document
+	shutdown bool
+}
+
+// read reads a single JSON-RPC message.
+//
+// A message is a set of HTTP-style headers, of which only
+// Content-Length is interesting, followed by a JSON body.
+func (s *server) read() (*request, error) {
+	hdr, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("reading header: %v", err)
+	}
+	n, err := strconv.Atoi(hdr.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("bad Content-Length: %v", err)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, fmt.Errorf("reading body: %v", err)
+	}
+	req := new(request)
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, fmt.Errorf("bad message: %v", err)
+	}
+	return req, nil
+}
+
+func (s *server) write(msg interface{}) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if debug {
+		log.Printf("lsp: -> %s", b)
+	}
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n", len(b)); err != nil {
+		return err
+	}
+	_, err = s.out.Write(b)
+	return err
+}
+
+func (s *server) notify(method string, params interface{}) error {
+	return s.write(notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+func (s *server) reply(id *json.RawMessage, result interface{}, rerr *responseError) error {
+	resp := response{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   rerr,
+	}
+	if rerr == nil {
+		b, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		resp.Result = b
+	}
+	return s.write(resp)
+}
+
+// handle dispatches a request or notification.
+// An error is only returned if the connection is unusable.
+func (s *server) handle(req *request) error {
+	var (
+		result interface{}
+		rerr   *responseError
+	)
+	switch req.Method {
+	case "initialize":
+		result = InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync: syncFull,
+				HoverProvider:    true,
+				CompletionProvider: &CompletionOptions{
+					TriggerCharacters: []string{"."},
+				},
+				DefinitionProvider:         true,
+				DocumentSymbolProvider:     true,
+				DocumentFormattingProvider: true,
+			},
+		}
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil // notifications have no response
+		}
+		return s.update(params.TextDocument.URI, []byte(params.TextDocument.Text))
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			text := params.ContentChanges[n-1].Text
+			return s.update(params.TextDocument.URI, []byte(text))
+		}
+		return nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if rerr = unmarshalParams(req.Params, &params); rerr == nil {
+			if doc := s.docs[params.TextDocument.URI]; doc != nil {
+				if h := doc.hover(params.Position); h != nil {
+					result = h
+				}
+			}
+		}
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if rerr = unmarshalParams(req.Params, &params); rerr == nil {
+			if doc := s.docs[params.TextDocument.URI]; doc != nil {
+				if loc := doc.definition(params.Position); loc != nil {
+					result = loc
+				}
+			}
+		}
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if rerr = unmarshalParams(req.Params, &params); rerr == nil {
+			if doc := s.docs[params.TextDocument.URI]; doc != nil {
+				result = doc.completion(params.Position)
+			}
+		}
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if rerr = unmarshalParams(req.Params, &params); rerr == nil {
+			if doc := s.docs[params.TextDocument.URI]; doc != nil {
+				result = doc.symbols()
+			}
+		}
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		if rerr = unmarshalParams(req.Params, &params); rerr == nil {
+			if doc := s.docs[params.TextDocument.URI]; doc != nil {
+				result = doc.format()
+			}
+		}
+	default:
+		if req.ID == nil {
+			return nil // unhandled notification, e.g. "initialized"
+		}
+		rerr = &responseError{
+			Code:    codeMethodNotFound,
+			Message: fmt.Sprintf("method not supported: %s", req.Method),
+		}
+	}
+	if req.ID == nil {
+		return nil
+	}
+	return s.reply(req.ID, result, rerr)
+}
+
+func unmarshalParams(params json.RawMessage, v interface{}) *responseError {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{
+			Code:    codeInvalidParams,
+			Message: err.Error(),
+		}
+	}
+	return nil
+}
+
+// update replaces the contents of a document and publishes
+// the resulting diagnostics.
+func (s *server) update(uri string, text []byte) error {
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+	diags := doc.diags
+	if diags == nil {
+		diags = []Diagnostic{} // the protocol requires an array
+	}
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diags,
+	})
+}
+
+// uriToPath converts a file URI into a file path.
+func uriToPath(uri string) string {
+	path := strings.TrimPrefix(uri, "file://")
+	if p, err := url.PathUnescape(path); err == nil {
+		path = p
+	}
+	return path
+}
+
+// pathToURI converts a file path into a file URI.
+func pathToURI(path string) string {
+	u := &url.URL{Scheme: "file", Path: path}
+	return u.String()
+}
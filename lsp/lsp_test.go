@@ -0,0 +1,233 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type client struct {
+	t   *testing.T
+	w   io.Writer
+	r   *bufio.Reader
+	id  int
+	err chan error
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{
+		t:   t,
+		w:   inW,
+		r:   bufio.NewReader(outR),
+		err: make(chan error, 1),
+	}
+	go func() {
+		c.err <- Run(context.Background(), inR, outW)
+		outW.Close()
+	}()
+	return c
+}
+
+func (c *client) send(method string, id *int, params interface{}) {
+	msg := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+	}
+	if id != nil {
+		msg["id"] = *id
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(b), b)
+}
+
+// recv reads the next message from the server.
+func (c *client) recv() map[string]json.RawMessage {
+	hdr, err := textproto.NewReader(c.r).ReadMIMEHeader()
+	if err != nil {
+		c.t.Fatalf("reading header: %v", err)
+	}
+	n, err := strconv.Atoi(hdr.Get("Content-Length"))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(c.r, b); err != nil {
+		c.t.Fatal(err)
+	}
+	msg := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+func (c *client) call(method string, params, result interface{}) {
+	c.id++
+	id := c.id
+	c.send(method, &id, params)
+	msg := c.recv()
+	if msg["error"] != nil {
+		c.t.Fatalf("%s: error: %s", method, msg["error"])
+	}
+	if err := json.Unmarshal(msg["result"], result); err != nil {
+		c.t.Fatalf("%s: bad result %s: %v", method, msg["result"], err)
+	}
+}
+
+func (c *client) open(uri, text string) PublishDiagnosticsParams {
+	c.send("textDocument/didOpen", nil, DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "neugram", Text: text},
+	})
+	msg := c.recv()
+	var diags PublishDiagnosticsParams
+	if err := json.Unmarshal(msg["params"], &diags); err != nil {
+		c.t.Fatal(err)
+	}
+	return diags
+}
+
+func (c *client) close() {
+	var res interface{}
+	c.call("shutdown", nil, &res)
+	c.send("exit", nil, nil)
+	if err := <-c.err; err != nil {
+		c.t.Errorf("Run: %v", err)
+	}
+}
+
+const testSource = `x := 1
+y := x + 2
+type T struct {
+	Name string
+}
+func double(v int) int {
+	return 2*v
+}
+z := double(y)
+`
+
+func TestServer(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	var init InitializeResult
+	c.call("initialize", map[string]interface{}{}, &init)
+	if !init.Capabilities.HoverProvider {
+		t.Errorf("hover not enabled")
+	}
+
+	const uri = "file:///tmp/lsptest/a.ng"
+	diags := c.open(uri, testSource)
+	if len(diags.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics: %+v", diags.Diagnostics)
+	}
+
+	pos := func(line, char int) TextDocumentPositionParams {
+		return TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: char},
+		}
+	}
+
+	var hover Hover
+	c.call("textDocument/hover", pos(1, 5), &hover)
+	if got, want := hover.Contents.Value, "var x int"; !strings.Contains(got, want) {
+		t.Errorf("hover: got %q, want %q", got, want)
+	}
+	c.call("textDocument/hover", pos(8, 6), &hover)
+	if got, want := hover.Contents.Value, "func double(int) int"; !strings.Contains(got, want) {
+		t.Errorf("hover: got %q, want %q", got, want)
+	}
+
+	var loc Location
+	c.call("textDocument/definition", pos(1, 5), &loc)
+	if loc.URI != uri || loc.Range.Start != (Position{0, 0}) {
+		t.Errorf("definition of x: %+v", loc)
+	}
+	c.call("textDocument/definition", pos(8, 6), &loc)
+	if loc.URI != uri || loc.Range.Start.Line != 5 {
+		t.Errorf("definition of double: %+v", loc)
+	}
+
+	var list CompletionList
+	c.call("textDocument/completion", pos(8, 7), &list)
+	found := false
+	for _, item := range list.Items {
+		if item.Label == "double" {
+			found = true
+		}
+		if !strings.HasPrefix(item.Label, "do") {
+			t.Errorf("completion %q does not match prefix", item.Label)
+		}
+	}
+	if !found {
+		t.Errorf("completion: missing double in %+v", list.Items)
+	}
+
+	var syms []DocumentSymbol
+	c.call("textDocument/documentSymbol", DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	}, &syms)
+	var names []string
+	for _, sym := range syms {
+		names = append(names, sym.Name)
+	}
+	if got, want := strings.Join(names, ","), "x,y,T,double,z"; got != want {
+		t.Errorf("symbols: got %s, want %s", got, want)
+	}
+
+	var edits []TextEdit
+	c.call("textDocument/formatting", DocumentFormattingParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	}, &edits)
+	if len(edits) != 1 || !strings.Contains(edits[0].NewText, "\treturn 2 * v\n") {
+		t.Errorf("formatting: got %+v", edits)
+	}
+}
+
+var diagTests = []struct {
+	src  string
+	line int
+}{
+	{"x := 1\ny := x + z\n", 1},
+	{"x := 1\nx := 2\n", 1},
+	{"x := )\n", 0},
+	{"x := 1 +\n", 0},
+	{"x := 1\ny := x +\n\n", 1},
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	var init InitializeResult
+	c.call("initialize", map[string]interface{}{}, &init)
+
+	for i, test := range diagTests {
+		uri := fmt.Sprintf("file:///tmp/lsptest/diag%d.ng", i)
+		diags := c.open(uri, test.src)
+		if len(diags.Diagnostics) == 0 {
+			t.Errorf("%q: no diagnostics", test.src)
+			continue
+		}
+		if got := diags.Diagnostics[0].Range.Start.Line; got != test.line {
+			t.Errorf("%q: diagnostic on line %d, want %d: %+v", test.src, got, test.line, diags.Diagnostics[0])
+		}
+	}
+}
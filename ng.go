@@ -15,7 +15,9 @@ import (
 
 	"neugram.io/ng/eval/shell"
 	"neugram.io/ng/gengo"
+	"neugram.io/ng/gotool"
 	"neugram.io/ng/jupyter"
+	"neugram.io/ng/lsp"
 	"neugram.io/ng/ngcore"
 	"neugram.io/ng/parser"
 )
@@ -43,13 +45,63 @@ func usage() {
 
 Usage:
 	%s
+	ng command [arguments]
+
+Commands:
+	build-interp	build an ng with Go packages compiled in
+	cache	manage the cache of plugins built for Go packages
+	doc	show documentation of a Neugram package
+	fmt	format Neugram source files
+	lsp	run a Language Server Protocol server on stdin and stdout
+	vet	report likely mistakes in Neugram programs
 
 Options:
 `, usageLine)
 	flag.PrintDefaults()
+	fmt.Fprint(os.Stderr, `
+Imports of Neugram packages, such as "example.com/lib/strs.ng", are
+found in the directories listed in $NGPATH, then in the modules of
+the module Go packages are imported from, or in $GOPATH.
+A Neugram package is a .ng file, or a directory named like one that
+holds .ng files, which share the package scope.
+`)
+}
+
+// commands are the subcommands of ng, selected by the first argument
+// when it does not name a file. A program file named like a command
+// is run as a program.
+var commands = map[string]func(args []string){
+	"build-interp": cmdBuildInterp,
+	"cache":        cmdCache,
+	"doc":          cmdDoc,
+	"fmt":          cmdFmt,
+	"lsp":          cmdLSP,
+	"vet":          cmdVet,
+}
+
+func isFile(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && !fi.IsDir()
+}
+
+func cmdLSP(args []string) {
+	if len(args) > 0 {
+		exitf("lsp: unexpected arguments: %v", args)
+	}
+	if err := lsp.Run(context.Background(), os.Stdin, os.Stdout); err != nil {
+		exitf("%v", err)
+	}
+	os.Exit(0)
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd := commands[os.Args[1]]; cmd != nil && !isFile(os.Args[1]) {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
 	shell.Init()
 
 	flagJupyter := flag.String("jupyter", "", "path to jupyter kernel connection file")
@@ -57,6 +109,7 @@ func main() {
 	flagHelp := flag.Bool("h", false, "display help message and exit")
 	flagE := flag.String("e", "", "program passed as a string")
 	flagO := flag.String("o", "", "compile the program to the named file")
+	flagGoMod := flag.String("gomod", "", "go.mod of the module to import Go packages from (default: the module enclosing the program)")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "usage: %s\n", usageLine)
 		os.Exit(1)
@@ -67,6 +120,12 @@ func main() {
 		usage()
 		os.Exit(0)
 	}
+	moduleDir := cwd
+	if args := flag.Args(); len(args) > 0 {
+		moduleDir = filepath.Dir(args[0])
+	}
+	initModule(*flagGoMod, moduleDir)
+
 	if *flagJupyter != "" {
 		err := jupyter.Run(context.Background(), *flagJupyter)
 		if err != nil {
@@ -159,6 +218,22 @@ func init() {
 	}
 }
 
+// initModule selects the Go module that imported Go packages are
+// built in: the go.mod named by the -gomod flag, or else the module
+// enclosing dir. Outside of a module, packages come from GOPATH.
+//
+// Finding the enclosing module runs the go tool, so it is left until
+// a Go package is first needed.
+func initModule(gomod, dir string) {
+	if gomod == "" {
+		gotool.M.SetModuleDir(dir)
+		return
+	}
+	if err := gotool.M.SetModule(gomod); err != nil {
+		exitf("%v", err)
+	}
+}
+
 func initSession(ng *ngcore.Session) {
 	ng.Stdin = os.Stdin
 	ng.Stdout = os.Stdout
@@ -7,8 +7,10 @@ package main_test
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -68,6 +70,59 @@ func TestExitMsg(t *testing.T) {
 	}
 }
 
+func TestCommandNamedScript(t *testing.T) {
+	// A program file named like a command is run, not the command.
+	dir, err := ioutil.TempDir("", "ng-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "fmt"), []byte(`print("script")`+"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	testngAbs, err := filepath.Abs(testng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(testngAbs, "fmt")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("testng fmt failed: %v\n%s", err, out)
+	}
+	if got, want := string(out), "script\n"; got != want {
+		t.Errorf("testng fmt printed %q, want %q", got, want)
+	}
+}
+
+func TestFmtPartial(t *testing.T) {
+	// A file ending in an incomplete statement is not rewritten.
+	dir, err := ioutil.TempDir("", "ng-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "partial.ng")
+	src := "x := 2\ny := 1 +\n"
+	if err := ioutil.WriteFile(filename, []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(testng, "fmt", "-w", filename).CombinedOutput()
+	if err == nil {
+		t.Errorf("testng fmt -w succeeded, want an error")
+	}
+	if !strings.Contains(string(out), "unexpected EOF") {
+		t.Errorf("testng fmt -w output %q does not mention unexpected EOF", out)
+	}
+	got, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != src {
+		t.Errorf("file changed to %q, want %q", got, src)
+	}
+}
+
 func TestGofmt(t *testing.T) {
 	exe, err := exec.LookPath("gofmt")
 
@@ -89,3 +144,28 @@ func TestGofmt(t *testing.T) {
 		t.Errorf("some files were not gofmt'ed:\n%s\n", string(buf.Bytes()))
 	}
 }
+
+func TestBuildInterp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ng-build-interp-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	interp := filepath.Join(dir, "ng"+exeSuffix)
+	out, err := exec.Command(testng, "build-interp", "-pkgs=container/list", "-o", interp).CombinedOutput()
+	if err != nil {
+		t.Fatalf("build-interp failed: %v\n%s", err, out)
+	}
+
+	// Without a go command, the packages must be compiled in.
+	cmd := exec.Command(interp, "-e", `import "container/list"; import "strings"; l := list.New(); l.PushBack(strings.ToUpper("ok")); print(l.Len(), l.Front().Value)`)
+	cmd.Env = append(os.Environ(), "PATH="+dir, "NGCACHE=off")
+	out, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("built interpreter failed: %v\n%s", err, out)
+	}
+	if got, want := strings.TrimSpace(string(out)), "1 OK"; got != want {
+		t.Errorf("built interpreter printed %q, want %q", got, want)
+	}
+}
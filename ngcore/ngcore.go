@@ -33,10 +33,9 @@ import (
 )
 
 type Neugram struct {
-	// TODO: Universe *eval.Scope for session initialization
-
-	mu       sync.Mutex // guards map, not interior of *Session obj
+	mu       sync.Mutex // guards fields, not interior of *Session obj
 	sessions map[string]*Session
+	universe []func(*eval.Program) error // definitions made in each new session
 }
 
 func New() *Neugram {
@@ -86,14 +85,40 @@ type Session struct {
 	neugram *Neugram
 }
 
-func (n *Neugram) NewSession(ctx context.Context, name string, env []string) (*Session, error) {
-	s := n.newSession(ctx, name, env)
+// Define defines name in the universe of each new session, as a
+// variable holding val. See eval.Program.Define.
+func (n *Neugram) Define(name string, val reflect.Value) error {
+	return n.define(func(p *eval.Program) error {
+		return p.Define(name, val)
+	})
+}
+
+// DefinePackage defines name in the universe of each new session,
+// as a package of members. See eval.Program.DefinePackage.
+func (n *Neugram) DefinePackage(name string, members map[string]interface{}) error {
+	return n.define(func(p *eval.Program) error {
+		return p.DefinePackage(name, members)
+	})
+}
+
+func (n *Neugram) define(def func(*eval.Program) error) error {
+	// Report any error now, not when a session is created.
+	if err := def(eval.New("universe", nil)); err != nil {
+		return err
+	}
+	n.mu.Lock()
+	n.universe = append(n.universe, def)
+	n.mu.Unlock()
+	return nil
+}
 
+func (n *Neugram) NewSession(ctx context.Context, name string, env []string) (*Session, error) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 	if n.sessions[name] != nil {
 		return nil, fmt.Errorf("neugram: session %q already exists", name)
 	}
+	s := n.newSession(ctx, name, env)
 	n.sessions[name] = s
 	return s, nil
 }
@@ -104,6 +129,7 @@ func (n *Neugram) GetSession(name string) *Session {
 	return n.sessions[name]
 }
 
+// newSession creates a session. It is called with n.mu held.
 func (n *Neugram) newSession(ctx context.Context, name string, env []string) *Session {
 	// TODO: default shell state
 	shellState := &shell.State{
@@ -121,6 +147,9 @@ func (n *Neugram) newSession(ctx context.Context, name string, env []string) *Se
 		name:        name,
 		neugram:     n,
 	}
+	for _, def := range n.universe {
+		def(s.Program) // checked by define
+	}
 	return s
 }
 
@@ -0,0 +1,43 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ngcore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDefine(t *testing.T) {
+	ng := New()
+	defer ng.Close()
+
+	if err := ng.Define("double", reflect.ValueOf(func(x int) int { return 2 * x })); err != nil {
+		t.Fatal(err)
+	}
+	if err := ng.DefinePackage("host", map[string]interface{}{"Limit": 21}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ng.Define("bad", reflect.Value{}); err == nil {
+		t.Error("Define of an invalid value succeeded")
+	}
+
+	session, err := ng.NewSession(context.Background(), "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	res, err := session.Exec([]byte("double(host.Limit)"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Interface() != 42 {
+		t.Errorf("double(host.Limit)=%v, want 42", res)
+	}
+	if _, err := session.Exec([]byte("bad")); err == nil {
+		t.Error("bad is defined")
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ngdoc extracts documentation from Neugram packages.
+//
+// A Neugram package is a .ng file imported by another program.
+// Its exported functions, types, methodik methods, constants and
+// variables are found by type checking the package. Declarations
+// are rendered by the format package and documented by the comment
+// group immediately preceding them.
+//
+// The first comment group in the file, if it is separated from the
+// first declaration, documents the package.
+package ngdoc
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"neugram.io/ng/format"
+	"neugram.io/ng/syntax"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/src"
+	"neugram.io/ng/syntax/stmt"
+	"neugram.io/ng/typecheck"
+)
+
+// Package is the documentation of a Neugram package.
+type Package struct {
+	Name   string // package name, the file name without .ng
+	Path   string // absolute path of the package file
+	Doc    string // package documentation
+	Consts []*Decl
+	Vars   []*Decl
+	Funcs  []*Decl
+	Types  []*Decl
+}
+
+// Decl is the documentation of an exported declaration.
+type Decl struct {
+	Name    string  // name, Type.Method for methods
+	Kind    string  // "const", "var", "func", "type", or "method"
+	Decl    string  // declaration, as rendered by format
+	Doc     string  // doc comment text
+	Methods []*Decl // exported methods of a methodik type
+}
+
+// New type checks the Neugram package in filename and
+// extracts its documentation.
+func New(filename string) (*Package, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := typecheck.New("").Check(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := pkg.Syntax
+	p := &Package{
+		Name: strings.TrimSuffix(filepath.Base(path), ".ng"),
+		Path: path,
+		Doc:  packageDoc(f),
+	}
+	decls := declIndex(f)
+	for _, obj := range pkg.Globals {
+		if !isExported(obj.Name) {
+			continue
+		}
+		d := &Decl{Name: obj.Name}
+		switch decl := obj.Decl.(type) {
+		case *expr.FuncLiteral:
+			d.Kind = "func"
+			d.Decl = render(&stmt.Simple{Expr: signature(decl)})
+			d.Doc = decl.Doc.Text()
+			p.Funcs = append(p.Funcs, d)
+		case *stmt.TypeDecl:
+			d.Kind = "type"
+			d.Decl = render(&stmt.TypeDecl{Name: decl.Name, Type: decl.Type})
+			d.Doc = declDoc(f, decl.Doc, decls[obj.Name])
+			p.Types = append(p.Types, d)
+		case *stmt.MethodikDecl:
+			d.Kind = "type"
+			m := &stmt.MethodikDecl{Name: decl.Name, Type: decl.Type}
+			for _, fn := range decl.Methods {
+				if !isExported(fn.Name) {
+					continue
+				}
+				m.Methods = append(m.Methods, signature(fn))
+				d.Methods = append(d.Methods, &Decl{
+					Name: decl.Name + "." + fn.Name,
+					Kind: "method",
+					Decl: render(&stmt.Simple{Expr: signature(fn)}),
+					Doc:  fn.Doc.Text(),
+				})
+			}
+			d.Decl = render(m)
+			d.Doc = decl.Doc.Text()
+			p.Types = append(p.Types, d)
+		default:
+			s := decls[obj.Name]
+			if s == nil {
+				continue // declared by a nested statement
+			}
+			switch obj.Kind {
+			case typecheck.ObjConst:
+				d.Kind = "const"
+				c := s.(*stmt.Const)
+				d.Decl = render(constDecl(c, obj))
+				d.Doc = declDoc(f, c.Doc, s)
+				p.Consts = append(p.Consts, d)
+			case typecheck.ObjVar:
+				d.Kind = "var"
+				d.Decl = render(&stmt.Var{NameList: []string{obj.Name}, Type: obj.Type})
+				var doc *src.CommentGroup
+				if v, isVar := s.(*stmt.Var); isVar {
+					doc = v.Doc
+				}
+				d.Doc = declDoc(f, doc, s)
+				p.Vars = append(p.Vars, d)
+			default:
+				continue
+			}
+		}
+	}
+	for _, list := range [][]*Decl{p.Consts, p.Vars, p.Funcs, p.Types} {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	}
+	return p, nil
+}
+
+// Lookup finds the declaration of symbol, which is either the name
+// of a top-level declaration or of a method in the form Type.Method.
+func (p *Package) Lookup(symbol string) *Decl {
+	for _, list := range [][]*Decl{p.Consts, p.Vars, p.Funcs, p.Types} {
+		for _, d := range list {
+			if d.Name == symbol {
+				return d
+			}
+			for _, m := range d.Methods {
+				if m.Name == symbol {
+					return m
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// declIndex maps the names declared by top-level statements in f
+// to the declaring statement, or to the Const spec of a constant.
+func declIndex(f *syntax.File) map[string]stmt.Stmt {
+	decls := make(map[string]stmt.Stmt)
+	for _, s := range f.Stmts {
+		switch s := s.(type) {
+		case *stmt.TypeDecl:
+			decls[s.Name] = s
+		case *stmt.TypeDeclSet:
+			for _, t := range s.TypeDecls {
+				decls[t.Name] = t
+			}
+		case *stmt.Const:
+			for _, name := range s.NameList {
+				decls[name] = s
+			}
+		case *stmt.ConstSet:
+			for _, c := range s.Consts {
+				for _, name := range c.NameList {
+					decls[name] = c
+				}
+			}
+		case *stmt.Var:
+			for _, name := range s.NameList {
+				decls[name] = s
+			}
+		case *stmt.VarSet:
+			for _, v := range s.Vars {
+				for _, name := range v.NameList {
+					decls[name] = v
+				}
+			}
+		case *stmt.Assign:
+			if !s.Decl {
+				continue
+			}
+			for _, e := range s.Left {
+				if ident, ok := e.(*expr.Ident); ok {
+					decls[ident.Name] = s
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// packageDoc returns the text of the first comment group in f,
+// unless it documents the first statement.
+func packageDoc(f *syntax.File) string {
+	if len(f.Comments) == 0 {
+		return ""
+	}
+	g := f.Comments[0]
+	if len(f.Stmts) > 0 {
+		line := f.Stmts[0].Pos().Line
+		if g.Pos().Line > line || g.End().Line+1 >= line {
+			return ""
+		}
+	}
+	return g.Text()
+}
+
+// declDoc returns the doc comment of a declaration. Statements
+// without a Doc field, such as x := 1, are documented by the
+// comment group that ends on the line before them.
+func declDoc(f *syntax.File, doc *src.CommentGroup, s stmt.Stmt) string {
+	if doc != nil || s == nil {
+		return doc.Text()
+	}
+	line := s.Pos().Line
+	if a, isAssign := s.(*stmt.Assign); isAssign && len(a.Left) > 0 {
+		line = a.Left[0].Pos().Line
+	}
+	for _, g := range f.Comments {
+		if g.End().Line+1 == line {
+			return g.Text()
+		}
+	}
+	return ""
+}
+
+// signature returns a copy of fn without its body.
+func signature(fn *expr.FuncLiteral) *expr.FuncLiteral {
+	sig := *fn
+	sig.Doc = nil
+	sig.Body = nil
+	return &sig
+}
+
+// constDecl returns a declaration of the single constant obj
+// declared by c.
+func constDecl(c *stmt.Const, obj *typecheck.Obj) *stmt.Const {
+	d := &stmt.Const{NameList: []string{obj.Name}, Type: c.Type}
+	for i, name := range c.NameList {
+		if name == obj.Name && i < len(c.Values) {
+			d.Values = []expr.Expr{c.Values[i]}
+			return d
+		}
+	}
+	// An implicitly repeated constant. Show its value.
+	d.Values = []expr.Expr{&expr.BasicLiteral{Raw: fmt.Sprint(obj.Decl)}}
+	return d
+}
+
+// render formats a declaration as source.
+func render(s stmt.Stmt) string {
+	b, err := format.Source(&syntax.File{Stmts: []stmt.Stmt{s}}, nil)
+	if err != nil {
+		return format.Stmt(s)
+	}
+	return strings.TrimSuffix(string(b), "\n")
+}
+
+func isExported(name string) bool {
+	ch, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(ch)
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ngdoc_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"neugram.io/ng/ngdoc"
+)
+
+func TestPackage(t *testing.T) {
+	pkg, err := ngdoc.New("testdata/geom.ng")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Name != "geom" {
+		t.Errorf("Name=%q, want geom", pkg.Name)
+	}
+	if want := "Package geom is a small geometry package.\n"; pkg.Doc != want {
+		t.Errorf("Doc=%q, want %q", pkg.Doc, want)
+	}
+
+	tests := []struct {
+		symbol string
+		kind   string
+		decl   string
+		doc    string
+	}{
+		{"Scale", "const", "const Scale = 2", "Scale is the default scale factor.\n"},
+		{"Origin", "const", "const Origin = 0", "Origin is the zero coordinate.\n"},
+		{"Unit", "var", "var Unit Point", "Unit is the unit vector.\n"},
+		{"Add", "func", "func Add(p, q Point) Point", "Add adds two points.\n"},
+		{"Point", "type", "type Point struct {\n\tX int\n\tY int\n}", "Point is a point in the plane.\n"},
+		{"Counter", "type", "methodik Counter int {\n\tfunc (*c) Inc()\n}", "Counter counts.\n"},
+		{"Counter.Inc", "method", "func (*c) Inc()", "Inc increments the counter.\n"},
+	}
+	for _, test := range tests {
+		d := pkg.Lookup(test.symbol)
+		if d == nil {
+			t.Errorf("%s: not found", test.symbol)
+			continue
+		}
+		if d.Kind != test.kind {
+			t.Errorf("%s: Kind=%q, want %q", test.symbol, d.Kind, test.kind)
+		}
+		if d.Decl != test.decl {
+			t.Errorf("%s: Decl=%q, want %q", test.symbol, d.Decl, test.decl)
+		}
+		if d.Doc != test.doc {
+			t.Errorf("%s: Doc=%q, want %q", test.symbol, d.Doc, test.doc)
+		}
+	}
+	for _, symbol := range []string{"hidden", "double", "Counter.value"} {
+		if d := pkg.Lookup(symbol); d != nil {
+			t.Errorf("unexported %s documented: %+v", symbol, d)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	pkg, err := ngdoc.New("testdata/geom.ng")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		mode ngdoc.Mode
+		want []string
+	}{
+		{ngdoc.Text, []string{"package geom\n", "\nFUNCTIONS\n\nfunc Add(p, q Point) Point\n    Add adds two points.\n"}},
+		{ngdoc.Markdown, []string{"# package geom\n", "\n## Types\n", "\n### func Add\n\n```\nfunc Add(p, q Point) Point\n```\n"}},
+		{ngdoc.HTML, []string{"<h1>package geom</h1>", `<div class="decl" id="Counter.Inc">`, "<pre>func Add(p, q Point) Point</pre>\n<p>Add adds two points.\n"}},
+	}
+	for _, test := range tests {
+		buf := new(bytes.Buffer)
+		if err := pkg.Write(buf, test.mode); err != nil {
+			t.Errorf("mode %d: %v", test.mode, err)
+			continue
+		}
+		for _, want := range test.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("mode %d: output missing %q:\n%s", test.mode, want, buf)
+			}
+		}
+	}
+}
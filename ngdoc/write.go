@@ -0,0 +1,164 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ngdoc
+
+import (
+	"bufio"
+	"fmt"
+	godoc "go/doc"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// Mode selects an output format.
+type Mode int
+
+const (
+	Text     Mode = iota // plain text, for terminals
+	Markdown             // GitHub-flavored Markdown
+	HTML                 // an HTML fragment
+)
+
+// ParseMode converts a mode name, "text", "markdown" (or "md")
+// or "html", to a Mode.
+func ParseMode(name string) (Mode, error) {
+	switch name {
+	case "text", "":
+		return Text, nil
+	case "markdown", "md":
+		return Markdown, nil
+	case "html":
+		return HTML, nil
+	}
+	return 0, fmt.Errorf("ngdoc: unknown output mode %q", name)
+}
+
+type section struct {
+	Title string
+	Decls []*Decl
+}
+
+func (p *Package) sections() []section {
+	var sections []section
+	for _, s := range []section{
+		{"Constants", p.Consts},
+		{"Variables", p.Vars},
+		{"Functions", p.Funcs},
+		{"Types", p.Types},
+	} {
+		if len(s.Decls) > 0 {
+			sections = append(sections, s)
+		}
+	}
+	return sections
+}
+
+// Write writes the documentation of the package.
+func (p *Package) Write(w io.Writer, mode Mode) error {
+	switch mode {
+	case Markdown:
+		return writeMarkdown(w, p)
+	case HTML:
+		return htmlTmpl.Execute(w, p)
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "package %s\n", p.Name)
+	if p.Doc != "" {
+		bw.WriteString("\n")
+		godoc.ToText(bw, p.Doc, "", "\t", 80)
+	}
+	for _, s := range p.sections() {
+		fmt.Fprintf(bw, "\n%s\n", strings.ToUpper(s.Title))
+		for _, d := range s.Decls {
+			bw.WriteString("\n")
+			writeText(bw, d)
+		}
+	}
+	return bw.Flush()
+}
+
+// Write writes the documentation of a single declaration.
+func (d *Decl) Write(w io.Writer, mode Mode) error {
+	switch mode {
+	case Markdown:
+		return writeMarkdownDecl(w, d, "##")
+	case HTML:
+		return declTmpl.Execute(w, d)
+	}
+	bw := bufio.NewWriter(w)
+	writeText(bw, d)
+	return bw.Flush()
+}
+
+func writeText(w *bufio.Writer, d *Decl) {
+	w.WriteString(d.Decl)
+	w.WriteString("\n")
+	if d.Doc != "" {
+		godoc.ToText(w, d.Doc, "    ", "\t", 76)
+	}
+	for _, m := range d.Methods {
+		w.WriteString("\n")
+		writeText(w, m)
+	}
+}
+
+func writeMarkdown(w io.Writer, p *Package) error {
+	if _, err := fmt.Fprintf(w, "# package %s\n", p.Name); err != nil {
+		return err
+	}
+	if p.Doc != "" {
+		fmt.Fprintf(w, "\n%s", p.Doc)
+	}
+	for _, s := range p.sections() {
+		fmt.Fprintf(w, "\n## %s\n", s.Title)
+		for _, d := range s.Decls {
+			if err := writeMarkdownDecl(w, d, "###"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeMarkdownDecl(w io.Writer, d *Decl, heading string) error {
+	_, err := fmt.Fprintf(w, "\n%s %s %s\n\n```\n%s\n```\n", heading, d.Kind, d.Name, d.Decl)
+	if err != nil {
+		return err
+	}
+	if d.Doc != "" {
+		fmt.Fprintf(w, "\n%s", d.Doc)
+	}
+	for _, m := range d.Methods {
+		if err := writeMarkdownDecl(w, m, heading+"#"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toHTML(text string) template.HTML {
+	buf := new(strings.Builder)
+	godoc.ToHTML(buf, text, nil)
+	return template.HTML(buf.String())
+}
+
+var htmlFuncs = template.FuncMap{
+	"doc":      toHTML,
+	"sections": (*Package).sections,
+}
+
+const declHTML = `{{define "decl"}}<div class="decl" id="{{.Name}}">
+<h3>{{.Kind}} {{.Name}}</h3>
+<pre>{{.Decl}}</pre>
+{{with .Doc}}{{doc .}}{{end}}{{range .Methods}}{{template "decl" .}}{{end}}</div>
+{{end}}`
+
+var declTmpl = template.Must(template.New("single").Funcs(htmlFuncs).Parse(declHTML + `{{template "decl" .}}`))
+
+var htmlTmpl = template.Must(template.New("package").Funcs(htmlFuncs).Parse(declHTML + `<h1>package {{.Name}}</h1>
+{{with .Doc}}{{doc .}}{{end}}{{range sections .}}
+<h2>{{.Title}}</h2>
+{{range .Decls}}{{template "decl" .}}{{end}}{{end}}`))
@@ -0,0 +1,681 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ngvet reports suspicious constructs in Neugram programs.
+//
+// The program is first type checked. A walk of its syntax tree then
+// looks for mistakes that are legal Neugram but probably unintended:
+//
+//	unused       unused local variables and imports
+//	shadow       local declarations that shadow another local
+//	shellerr     $$ commands whose error is assigned to _
+//	unreachable  statements following a return or panic
+//	printf       format strings that do not match their arguments
+//	copylocks    values of sync types copied by value
+//	loopclosure  loop variables captured by goroutine func literals
+package ngvet
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"neugram.io/ng/format"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/src"
+	"neugram.io/ng/syntax/stmt"
+	"neugram.io/ng/syntax/tipe"
+	"neugram.io/ng/syntax/token"
+	"neugram.io/ng/typecheck"
+)
+
+// A Diagnostic is a problem found in a program.
+type Diagnostic struct {
+	Filename string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Check    string `json:"check"` // name of the check, e.g. "printf"
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.Filename, d.Line, d.Column, d.Message)
+}
+
+// Check type checks the Neugram program in filename and reports
+// any suspicious constructs it contains, ordered by position.
+func Check(filename string) ([]Diagnostic, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	c := typecheck.New("")
+	pkg, err := c.Check(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &vetter{
+		filename: filename,
+		types:    c,
+		scope:    &scope{vars: make(map[string]*variable)},
+	}
+	for _, s := range pkg.Syntax.Stmts {
+		v.importDecl(s, pkg)
+	}
+	v.stmts(pkg.Syntax.Stmts)
+
+	sort.SliceStable(v.diags, func(i, j int) bool {
+		di, dj := v.diags[i], v.diags[j]
+		if di.Line != dj.Line {
+			return di.Line < dj.Line
+		}
+		return di.Column < dj.Column
+	})
+	return v.diags, nil
+}
+
+type vetter struct {
+	filename string
+	types    *typecheck.Checker
+	scope    *scope
+	goFunc   *scope // scope enclosing the innermost "go func() {...}()"
+	diags    []Diagnostic
+}
+
+// A scope is a block of variable declarations.
+// The outermost scope holds the globals of the program.
+type scope struct {
+	parent *scope
+	vars   map[string]*variable
+	depth  int
+}
+
+type variable struct {
+	name   string
+	pos    src.Pos
+	scope  *scope
+	used   bool
+	silent bool // never reported as unused: globals, parameters, etc.
+	loop   bool // declared by a for or range statement
+}
+
+func (v *vetter) errorf(pos src.Pos, check, format string, args ...interface{}) {
+	v.diags = append(v.diags, Diagnostic{
+		Filename: v.filename,
+		Line:     int(pos.Line),
+		Column:   int(pos.Column),
+		Check:    check,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *vetter) pushScope() {
+	v.scope = &scope{
+		parent: v.scope,
+		vars:   make(map[string]*variable),
+		depth:  v.scope.depth + 1,
+	}
+}
+
+func (v *vetter) popScope() {
+	var unused []*variable
+	for _, vr := range v.scope.vars {
+		if !vr.used && !vr.silent {
+			unused = append(unused, vr)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool { return unused[i].name < unused[j].name })
+	for _, vr := range unused {
+		v.errorf(vr.pos, "unused", "%s declared and not used", vr.name)
+	}
+	v.scope = v.scope.parent
+}
+
+func (v *vetter) lookup(name string) *variable {
+	for s := v.scope; s != nil; s = s.parent {
+		if vr := s.vars[name]; vr != nil {
+			return vr
+		}
+	}
+	return nil
+}
+
+// declare adds a variable to the current scope. Unless it is
+// silent, a variable declared in a nested scope that shadows
+// another local variable is reported.
+func (v *vetter) declare(name string, pos src.Pos, silent bool) *variable {
+	if name == "_" || name == "" {
+		return nil
+	}
+	if v.scope.vars[name] != nil {
+		return v.scope.vars[name] // redeclared by :=
+	}
+	if prev := v.lookup(name); prev != nil && !silent && prev.scope.depth > 0 {
+		v.errorf(pos, "shadow", "declaration of %q shadows declaration at line %d", name, prev.pos.Line)
+	}
+	vr := &variable{
+		name:   name,
+		pos:    pos,
+		scope:  v.scope,
+		silent: silent || v.scope.depth == 0,
+	}
+	v.scope.vars[name] = vr
+	return vr
+}
+
+func (v *vetter) use(e *expr.Ident) {
+	vr := v.lookup(e.Name)
+	if vr == nil {
+		return
+	}
+	vr.used = true
+	if vr.loop && v.goFunc != nil && vr.scope.depth <= v.goFunc.depth {
+		v.errorf(e.Position, "loopclosure", "loop variable %s captured by func literal", e.Name)
+		vr.loop = false // report each variable once
+	}
+}
+
+// importDecl reports the unused imports of pkg.
+func (v *vetter) importDecl(s stmt.Stmt, pkg *typecheck.Package) {
+	var imports []*stmt.Import
+	switch s := s.(type) {
+	case *stmt.Import:
+		imports = append(imports, s)
+	case *stmt.ImportSet:
+		imports = s.Imports
+	}
+	for _, imp := range imports {
+		if imp.Name == "_" {
+			continue
+		}
+		if obj := pkg.GlobalNames[imp.Name]; obj != nil && obj.Kind == typecheck.ObjPkg && !obj.Used {
+			v.errorf(imp.Position, "unused", "%q imported and not used", imp.Path)
+		}
+	}
+}
+
+// stmts checks a statement list, reporting the first statement
+// that cannot be reached.
+func (v *vetter) stmts(list []stmt.Stmt) {
+	reported := false
+	for i, s := range list {
+		if !reported && i > 0 && v.terminates(list[i-1]) {
+			if _, isLabeled := s.(*stmt.Labeled); !isLabeled {
+				v.errorf(stmtPos(s), "unreachable", "unreachable code")
+				reported = true
+			}
+		}
+		v.stmt(s)
+	}
+}
+
+// terminates reports whether s is a return statement or a call
+// of the builtin panic.
+func (v *vetter) terminates(s stmt.Stmt) bool {
+	switch s := s.(type) {
+	case *stmt.Return:
+		return true
+	case *stmt.Simple:
+		call, isCall := s.Expr.(*expr.Call)
+		if !isCall {
+			return false
+		}
+		fn, isIdent := call.Func.(*expr.Ident)
+		return isIdent && fn.Name == "panic" && v.lookup("panic") == nil &&
+			v.types.Ident(fn) == typecheck.Universe.Objs["panic"]
+	}
+	return false
+}
+
+func (v *vetter) stmt(s stmt.Stmt) {
+	switch s := s.(type) {
+	case *stmt.Import, *stmt.ImportSet, *stmt.TypeDecl, *stmt.TypeDeclSet, *stmt.Branch:
+	case *stmt.Const:
+		for _, e := range s.Values {
+			v.expr(e)
+		}
+		for _, name := range s.NameList {
+			v.declare(name, s.Position, true)
+		}
+	case *stmt.ConstSet:
+		for _, c := range s.Consts {
+			v.stmt(c)
+		}
+	case *stmt.Var:
+		for _, e := range s.Values {
+			v.expr(e)
+		}
+		if len(s.NameList) == 2 && s.NameList[1] == "_" {
+			v.shellErr(s.Values, s.Position)
+		}
+		for i, name := range s.NameList {
+			if i < len(s.Values) {
+				v.copyLock(s.Values[i], "variable declaration copies lock value to %s", name)
+			}
+			v.declare(name, s.Position, false)
+		}
+	case *stmt.VarSet:
+		for _, vs := range s.Vars {
+			v.stmt(vs)
+		}
+	case *stmt.Assign:
+		v.assign(s, false)
+	case *stmt.Block:
+		v.pushScope()
+		v.stmts(s.Stmts)
+		v.popScope()
+	case *stmt.If:
+		if s.Init != nil {
+			v.pushScope()
+			defer v.popScope()
+			v.stmt(s.Init)
+		}
+		v.expr(s.Cond)
+		v.stmt(s.Body)
+		if s.Else != nil {
+			v.stmt(s.Else)
+		}
+	case *stmt.For:
+		v.pushScope()
+		defer v.popScope()
+		if s.Init != nil {
+			if a, isAssign := s.Init.(*stmt.Assign); isAssign {
+				v.assign(a, true)
+			} else {
+				v.stmt(s.Init)
+			}
+		}
+		if s.Cond != nil {
+			v.expr(s.Cond)
+		}
+		if s.Post != nil {
+			v.stmt(s.Post)
+		}
+		v.stmt(s.Body)
+	case *stmt.Range:
+		v.expr(s.Expr)
+		v.pushScope()
+		defer v.popScope()
+		if s.Decl {
+			for _, e := range []expr.Expr{s.Key, s.Val} {
+				if ident, isIdent := e.(*expr.Ident); isIdent {
+					if vr := v.declare(ident.Name, ident.Position, false); vr != nil {
+						vr.loop = true
+					}
+				}
+			}
+		} else {
+			for _, e := range []expr.Expr{s.Key, s.Val} {
+				v.lhs(e)
+			}
+		}
+		if s.Val != nil {
+			if t := lockPath(v.elemType(s.Expr)); t != "" {
+				v.errorf(exprPos(s.Val), "copylocks", "range var %s copies lock: %s", format.Expr(s.Val), t)
+			}
+		}
+		v.stmt(s.Body)
+	case *stmt.Switch:
+		if s.Init != nil {
+			v.pushScope()
+			defer v.popScope()
+			v.stmt(s.Init)
+		}
+		if s.Cond != nil {
+			v.expr(s.Cond)
+		}
+		for _, c := range s.Cases {
+			for _, e := range c.Conds {
+				v.expr(e)
+			}
+			v.stmt(c.Body)
+		}
+	case *stmt.TypeSwitch:
+		v.pushScope()
+		defer v.popScope()
+		if s.Init != nil {
+			v.stmt(s.Init)
+		}
+		v.stmt(s.Assign)
+		for _, c := range s.Cases {
+			v.stmt(c.Body)
+		}
+	case *stmt.Select:
+		for _, c := range s.Cases {
+			v.pushScope()
+			if c.Stmt != nil {
+				v.stmt(c.Stmt)
+			}
+			v.stmt(c.Body)
+			v.popScope()
+		}
+	case *stmt.Labeled:
+		v.stmt(s.Stmt)
+	case *stmt.Go:
+		v.call(s.Call, true)
+	case *stmt.Defer:
+		v.expr(s.Expr)
+	case *stmt.Return:
+		for _, e := range s.Exprs {
+			v.expr(e)
+			v.copyLock(e, "return copies lock value")
+		}
+	case *stmt.Send:
+		v.expr(s.Chan)
+		v.expr(s.Value)
+	case *stmt.Simple:
+		if fn, isFunc := s.Expr.(*expr.FuncLiteral); isFunc && fn.Name != "" {
+			v.declare(fn.Name, fn.Position, true)
+		}
+		v.expr(s.Expr)
+	case *stmt.MethodikDecl:
+		for _, m := range s.Methods {
+			v.funcLit(m)
+		}
+	}
+}
+
+// assign checks an assignment. If loop is set, the variables
+// declared by the assignment belong to a for statement.
+func (v *vetter) assign(s *stmt.Assign, loop bool) {
+	for _, e := range s.Right {
+		v.expr(e)
+	}
+	if len(s.Left) == 2 {
+		if blank, isIdent := s.Left[1].(*expr.Ident); isIdent && blank.Name == "_" {
+			v.shellErr(s.Right, blank.Position)
+		}
+	}
+	for i, lhs := range s.Left {
+		if len(s.Right) == len(s.Left) {
+			v.copyLock(s.Right[i], "assignment copies lock value to %s", format.Expr(lhs))
+		}
+		ident, isIdent := lhs.(*expr.Ident)
+		if !s.Decl || !isIdent {
+			v.lhs(lhs)
+			continue
+		}
+		if isSelfCopy(s, ident) {
+			// x := x deliberately shadows x.
+			v.declare(ident.Name, ident.Position, true)
+			continue
+		}
+		if vr := v.declare(ident.Name, ident.Position, false); vr != nil && loop {
+			vr.loop = true
+		}
+	}
+}
+
+func isSelfCopy(s *stmt.Assign, ident *expr.Ident) bool {
+	for _, e := range s.Right {
+		if r, isIdent := e.(*expr.Ident); isIdent && r.Name == ident.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// lhs checks the left-hand side of an assignment.
+// Assigning to a variable does not use it.
+func (v *vetter) lhs(e expr.Expr) {
+	if e == nil {
+		return
+	}
+	if _, isIdent := e.(*expr.Ident); isIdent {
+		return
+	}
+	v.expr(e)
+}
+
+// shellErr reports a $$ command whose error result, at pos,
+// is assigned to _.
+func (v *vetter) shellErr(right []expr.Expr, pos src.Pos) {
+	if len(right) != 1 {
+		return
+	}
+	if _, isShell := right[0].(*expr.Shell); isShell {
+		v.errorf(pos, "shellerr", "error result of $$ command assigned to _")
+	}
+}
+
+func (v *vetter) call(e *expr.Call, isGo bool) {
+	if fn, isFunc := e.Func.(*expr.FuncLiteral); isFunc && isGo {
+		outer := v.goFunc
+		v.goFunc = v.scope
+		v.expr(fn)
+		v.goFunc = outer
+	} else {
+		v.expr(e.Func)
+	}
+	for _, arg := range e.Args {
+		v.expr(arg)
+		v.copyLock(arg, "call of %s copies lock value", format.Expr(e.Func))
+	}
+	v.printf(e)
+}
+
+func (v *vetter) funcLit(fn *expr.FuncLiteral) {
+	if v.goFunc == nil {
+		// Loop variables of enclosing functions are not
+		// reported unless this is a goroutine.
+		defer func(vars []*variable) {
+			for _, vr := range vars {
+				vr.loop = true
+			}
+		}(v.hideLoopVars())
+	}
+
+	v.pushScope()
+	defer v.popScope()
+	if fn.ReceiverName != "" {
+		v.declare(fn.ReceiverName, fn.Position, true)
+	}
+	if fn.Type != nil && fn.Type.Params != nil {
+		for i, name := range fn.ParamNames {
+			if i < len(fn.Type.Params.Elems) {
+				if t := lockPath(fn.Type.Params.Elems[i]); t != "" {
+					v.errorf(fn.Position, "copylocks", "%s passes lock by value: %s", funcName(fn), t)
+				}
+			}
+			v.declare(name, fn.Position, true)
+		}
+	}
+	for _, name := range fn.ResultNames {
+		v.declare(name, fn.Position, true)
+	}
+	if body, ok := fn.Body.(*stmt.Block); ok && body != nil {
+		v.stmts(body.Stmts)
+	}
+}
+
+// hideLoopVars clears and returns the loop variables in scope.
+func (v *vetter) hideLoopVars() (vars []*variable) {
+	for s := v.scope; s != nil; s = s.parent {
+		for _, vr := range s.vars {
+			if vr.loop {
+				vr.loop = false
+				vars = append(vars, vr)
+			}
+		}
+	}
+	return vars
+}
+
+func funcName(fn *expr.FuncLiteral) string {
+	if fn.Name == "" {
+		return "func"
+	}
+	return fn.Name
+}
+
+func (v *vetter) expr(e expr.Expr) {
+	switch e := e.(type) {
+	case *expr.Ident:
+		v.use(e)
+	case *expr.Binary:
+		v.expr(e.Left)
+		v.expr(e.Right)
+	case *expr.Unary:
+		v.expr(e.Expr)
+	case *expr.Selector:
+		v.expr(e.Left)
+	case *expr.Slice:
+		v.expr(e.Low)
+		v.expr(e.High)
+		v.expr(e.Max)
+	case *expr.Index:
+		v.expr(e.Left)
+		for _, i := range e.Indicies {
+			v.expr(i)
+		}
+	case *expr.TableIndex:
+		v.expr(e.Left)
+		if e.Rows != nil {
+			v.expr(e.Rows)
+		}
+		for _, c := range e.Cols {
+			v.expr(c)
+		}
+	case *expr.TypeAssert:
+		v.expr(e.Left)
+	case *expr.FuncLiteral:
+		v.funcLit(e)
+	case *expr.CompLiteral:
+		for _, val := range e.Values {
+			v.expr(val)
+			v.copyLock(val, "literal copies lock value from %s", format.Expr(val))
+		}
+	case *expr.MapLiteral:
+		v.exprs(e.Keys)
+		v.exprs(e.Values)
+	case *expr.ArrayLiteral:
+		v.exprs(e.Keys)
+		v.exprs(e.Values)
+	case *expr.SliceLiteral:
+		v.exprs(e.Keys)
+		v.exprs(e.Values)
+	case *expr.TableLiteral:
+		v.exprs(e.ColNames)
+		for _, row := range e.Rows {
+			v.exprs(row)
+		}
+	case *expr.Call:
+		v.call(e, false)
+	case *expr.Shell:
+		for _, name := range e.FreeVars {
+			v.use(&expr.Ident{Position: e.Position, Name: name})
+		}
+	}
+}
+
+// stmtPos returns the position of the first token of s.
+func stmtPos(s stmt.Stmt) src.Pos {
+	switch s := s.(type) {
+	case *stmt.Simple:
+		return exprPos(s.Expr)
+	case *stmt.Assign:
+		if len(s.Left) > 0 {
+			return exprPos(s.Left[0])
+		}
+	}
+	return s.Pos()
+}
+
+// exprPos returns the position of the first token of e.
+// The position of some expressions is that of their operator.
+func exprPos(e expr.Expr) src.Pos {
+	switch e := e.(type) {
+	case *expr.Call:
+		return exprPos(e.Func)
+	case *expr.Selector:
+		return exprPos(e.Left)
+	case *expr.Index:
+		return exprPos(e.Left)
+	case *expr.TableIndex:
+		return exprPos(e.Left)
+	case *expr.TypeAssert:
+		return exprPos(e.Left)
+	case *expr.Binary:
+		return exprPos(e.Left)
+	}
+	return e.Pos()
+}
+
+func (v *vetter) exprs(list []expr.Expr) {
+	for _, e := range list {
+		v.expr(e)
+	}
+}
+
+// copyLock reports e if it copies a value containing a sync type.
+// Values that are constructed in place, such as composite literals
+// or function results, are not copies.
+func (v *vetter) copyLock(e expr.Expr, format string, args ...interface{}) {
+	switch e := e.(type) {
+	case *expr.Ident, *expr.Selector, *expr.Index:
+	case *expr.Unary:
+		if e.Op != token.Mul {
+			return
+		}
+	default:
+		return
+	}
+	if t := lockPath(v.types.Type(e)); t != "" {
+		v.errorf(exprPos(e), "copylocks", format+": %s", append(args, t)...)
+	}
+}
+
+// elemType returns the type of the values ranged over by e.
+func (v *vetter) elemType(e expr.Expr) tipe.Type {
+	switch t := tipe.Underlying(v.types.Type(e)).(type) {
+	case *tipe.Array:
+		return t.Elem
+	case *tipe.Slice:
+		return t.Elem
+	case *tipe.Map:
+		return t.Value
+	}
+	return nil
+}
+
+// syncTypes are the types in package sync that must not be copied.
+var syncTypes = map[string]bool{
+	"Cond":      true,
+	"Map":       true,
+	"Mutex":     true,
+	"Once":      true,
+	"Pool":      true,
+	"RWMutex":   true,
+	"WaitGroup": true,
+}
+
+// lockPath returns the name of the sync type contained by
+// value in t, or "" if t contains none.
+func lockPath(t tipe.Type) string {
+	return lockPathSeen(t, make(map[tipe.Type]bool))
+}
+
+func lockPathSeen(t tipe.Type, seen map[tipe.Type]bool) string {
+	if t == nil || seen[t] {
+		return ""
+	}
+	seen[t] = true
+	switch t := tipe.Unalias(t).(type) {
+	case *tipe.Named:
+		if t.PkgPath == "sync" && syncTypes[t.Name] {
+			return "sync." + t.Name
+		}
+		return lockPathSeen(t.Type, seen)
+	case *tipe.Struct:
+		for _, f := range t.Fields {
+			if p := lockPathSeen(f.Type, seen); p != "" {
+				return p
+			}
+		}
+	case *tipe.Array:
+		return lockPathSeen(t.Elem, seen)
+	}
+	return ""
+}
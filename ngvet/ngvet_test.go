@@ -0,0 +1,54 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ngvet_test
+
+import (
+	"strings"
+	"testing"
+
+	"neugram.io/ng/ngvet"
+)
+
+func TestCheck(t *testing.T) {
+	diags, err := ngvet.Check("testdata/vet.ng")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`testdata/vet.ng:2:2: "sort" imported and not used`,
+		`testdata/vet.ng:8:2: x declared and not used`,
+		`testdata/vet.ng:15:3: declaration of "n" shadows declaration at line 13`,
+		`testdata/vet.ng:21:6: error result of $$ command assigned to _`,
+		`testdata/vet.ng:26:2: unreachable code`,
+		`testdata/vet.ng:30:2: printf format %s reads arg #2, but call has 1 args`,
+		`testdata/vet.ng:31:17: printf format %d has arg s of wrong type string`,
+		`testdata/vet.ng:32:2: printf call needs 2 args but has 3 args`,
+		`testdata/vet.ng:33:2: printf format %z has unknown verb z`,
+		`testdata/vet.ng:45:1: locks passes lock by value: sync.Mutex`,
+		`testdata/vet.ng:47:9: assignment copies lock value to mu2: sync.Mutex`,
+		`testdata/vet.ng:58:10: loop variable v captured by func literal`,
+	}
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.String())
+	}
+	for i := 0; i < len(got) || i < len(want); i++ {
+		switch {
+		case i >= len(got):
+			t.Errorf("missing diagnostic: %s", want[i])
+		case i >= len(want):
+			t.Errorf("unexpected diagnostic: %s", got[i])
+		case got[i] != want[i]:
+			t.Errorf("diagnostic %d:\n got: %s\nwant: %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCheckPartial(t *testing.T) {
+	_, err := ngvet.Check("testdata/partial.ng")
+	if err == nil || !strings.Contains(err.Error(), "unexpected EOF") {
+		t.Errorf("Check error: %v, want unexpected EOF", err)
+	}
+}
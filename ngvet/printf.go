@@ -0,0 +1,220 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ngvet
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"neugram.io/ng/format"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/tipe"
+	"neugram.io/ng/typecheck"
+)
+
+// fmtFuncs maps the printf-like functions of package fmt
+// to the index of their format argument.
+var fmtFuncs = map[string]int{
+	"Errorf":  0,
+	"Fprintf": 1,
+	"Printf":  0,
+	"Sprintf": 0,
+}
+
+// builtinFuncs are the printf-like Neugram builtins.
+var builtinFuncs = map[string]int{
+	"errorf": 0,
+	"printf": 0,
+}
+
+// printfFunc returns the name of the printf-like function called by
+// e and the index of its format argument. The name is "" if e does
+// not call such a function.
+func (v *vetter) printfFunc(e *expr.Call) (string, int) {
+	switch fn := e.Func.(type) {
+	case *expr.Ident:
+		if i, ok := builtinFuncs[fn.Name]; ok && v.types.Ident(fn) == typecheck.Universe.Objs[fn.Name] {
+			return fn.Name, i
+		}
+	case *expr.Selector:
+		pkgIdent, isIdent := fn.Left.(*expr.Ident)
+		if !isIdent {
+			return "", 0
+		}
+		obj := v.types.Ident(pkgIdent)
+		if obj == nil || obj.Kind != typecheck.ObjPkg {
+			return "", 0
+		}
+		if pkg, ok := obj.Decl.(*typecheck.Package); !ok || pkg.Path != "fmt" {
+			return "", 0
+		}
+		if i, ok := fmtFuncs[fn.Right.Name]; ok {
+			return "fmt." + fn.Right.Name, i
+		}
+	}
+	return "", 0
+}
+
+// printf checks the format string and arguments of a call to a
+// printf-like function.
+func (v *vetter) printf(e *expr.Call) {
+	name, fmtIndex := v.printfFunc(e)
+	if name == "" || e.Ellipsis || fmtIndex >= len(e.Args) {
+		return
+	}
+	lit, isLit := e.Args[fmtIndex].(*expr.BasicLiteral)
+	if !isLit {
+		return
+	}
+	fmtStr, isStr := lit.Value.(string)
+	if !isStr {
+		return
+	}
+	args := e.Args[fmtIndex+1:]
+
+	argNum := 0
+	indexed := false
+	for i := 0; i < len(fmtStr); {
+		if fmtStr[i] != '%' {
+			i++
+			continue
+		}
+		start := i
+		i++
+		if i < len(fmtStr) && fmtStr[i] == '%' {
+			i++
+			continue
+		}
+		for i < len(fmtStr) && strings.IndexByte("+-# 0", fmtStr[i]) >= 0 {
+			i++
+		}
+		// Width and precision, each possibly preceded by an
+		// explicit argument index.
+		for _, prec := range []bool{false, true} {
+			if prec {
+				if i >= len(fmtStr) || fmtStr[i] != '.' {
+					break
+				}
+				i++
+			}
+			if n, w, ok := argIndex(fmtStr[i:]); ok {
+				argNum = n
+				indexed = true
+				i += w
+			}
+			if i < len(fmtStr) && fmtStr[i] == '*' {
+				i++
+				if argNum >= len(args) {
+					v.errorf(exprPos(e), "printf", "%s format %s reads arg #%d, but call has %d args", name, fmtStr[start:i], argNum+1, len(args))
+					return
+				}
+				argNum++
+				continue
+			}
+			for i < len(fmtStr) && '0' <= fmtStr[i] && fmtStr[i] <= '9' {
+				i++
+			}
+		}
+		if n, w, ok := argIndex(fmtStr[i:]); ok {
+			argNum = n
+			indexed = true
+			i += w
+		}
+		if i >= len(fmtStr) {
+			v.errorf(exprPos(e), "printf", "%s format %s is missing verb at end of string", name, fmtStr[start:])
+			return
+		}
+		verb, w := utf8.DecodeRuneInString(fmtStr[i:])
+		i += w
+		directive := fmtStr[start:i]
+		if !strings.ContainsRune("bcdefgopqstvxEFGOTUX", verb) {
+			v.errorf(exprPos(e), "printf", "%s format %s has unknown verb %c", name, directive, verb)
+			return
+		}
+		if argNum >= len(args) {
+			v.errorf(exprPos(e), "printf", "%s format %s reads arg #%d, but call has %d args", name, directive, argNum+1, len(args))
+			return
+		}
+		arg := args[argNum]
+		if !verbMatches(verb, v.types.Type(arg)) {
+			v.errorf(exprPos(arg), "printf", "%s format %s has arg %s of wrong type %s", name, directive, format.Expr(arg), format.Type(v.types.Type(arg)))
+		}
+		argNum++
+	}
+	if !indexed && argNum != len(args) {
+		v.errorf(exprPos(e), "printf", "%s call needs %d args but has %d args", name, argNum, len(args))
+	}
+}
+
+// argIndex parses an explicit argument index, such as [2], at the
+// start of s. It returns the zero-based index and the width of the
+// index in bytes.
+func argIndex(s string) (index, width int, ok bool) {
+	if len(s) < 3 || s[0] != '[' {
+		return 0, 0, false
+	}
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return 0, 0, false
+	}
+	n, err := strconv.Atoi(s[1:end])
+	if err != nil || n < 1 {
+		return 0, 0, false
+	}
+	return n - 1, end + 1, true
+}
+
+// verbMatches reports whether an argument of type t may be printed
+// with verb. Only basic types are checked: named types may have
+// String or Format methods.
+func verbMatches(verb rune, t tipe.Type) bool {
+	b, isBasic := tipe.Unalias(t).(tipe.Basic)
+	if !isBasic {
+		return true
+	}
+	switch verb {
+	case 'v', 'T':
+		return true
+	case 't':
+		return b == tipe.Bool || b == tipe.UntypedBool
+	case 'c', 'U':
+		return isInteger(b)
+	case 'd', 'o', 'O':
+		return isInteger(b)
+	case 'b', 'x', 'X':
+		return isInteger(b) || isFloat(b) || isString(b)
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		return isFloat(b)
+	case 's', 'q':
+		return isString(b) || (verb == 'q' && isInteger(b))
+	case 'p':
+		return false
+	}
+	return true
+}
+
+func isInteger(b tipe.Basic) bool {
+	switch b {
+	case tipe.Int, tipe.Int8, tipe.Int16, tipe.Int32, tipe.Int64,
+		tipe.Uint, tipe.Uint8, tipe.Uint16, tipe.Uint32, tipe.Uint64, tipe.Uintptr,
+		tipe.Integer, tipe.UntypedInteger, tipe.UntypedRune:
+		return true
+	}
+	return false
+}
+
+func isFloat(b tipe.Basic) bool {
+	switch b {
+	case tipe.Float32, tipe.Float64, tipe.Complex64, tipe.Complex128,
+		tipe.Float, tipe.Complex, tipe.UntypedFloat, tipe.UntypedComplex:
+		return true
+	}
+	return false
+}
+
+func isString(b tipe.Basic) bool {
+	return b == tipe.String || b == tipe.UntypedString
+}
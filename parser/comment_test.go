@@ -0,0 +1,119 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser_test
+
+import (
+	"reflect"
+	"testing"
+
+	"neugram.io/ng/parser"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/src"
+	"neugram.io/ng/syntax/stmt"
+)
+
+const commentSrc = `// Header comment.
+
+// F does nothing.
+// Really.
+func F() {
+	x := 1 // trailing
+	// inside
+	print(x)
+}
+
+/* T is a type. */
+type T int
+
+// Not a doc comment.
+
+const C = 1
+
+var (
+	// V is a variable.
+	V = 2
+)
+
+// M is a methodik.
+methodik M struct{} {
+	// Get gets.
+	func (m) Get() int { return 0 }
+}
+`
+
+func TestComments(t *testing.T) {
+	f, err := parser.New("comments.ng").Parse([]byte(commentSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Comments != nil {
+		t.Errorf("comments collected without ParseComments: %v", f.Comments)
+	}
+
+	f, err = parser.New("comments.ng", parser.ParseComments).Parse([]byte(commentSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	var lines []int32
+	for _, g := range f.Comments {
+		texts = append(texts, g.Text())
+		lines = append(lines, g.Pos().Line)
+	}
+	wantTexts := []string{
+		"Header comment.\n",
+		"F does nothing.\nReally.\n",
+		"trailing\n",
+		"inside\n",
+		" T is a type.\n",
+		"Not a doc comment.\n",
+		"V is a variable.\n",
+		"M is a methodik.\n",
+		"Get gets.\n",
+	}
+	if !reflect.DeepEqual(texts, wantTexts) {
+		t.Errorf("comment groups:\n%q\nwant:\n%q", texts, wantTexts)
+	}
+	wantLines := []int32{1, 3, 6, 7, 11, 14, 19, 23, 25}
+	if !reflect.DeepEqual(lines, wantLines) {
+		t.Errorf("comment group lines: %v, want %v", lines, wantLines)
+	}
+
+	docs := make(map[string]*src.CommentGroup)
+	for _, s := range f.Stmts {
+		switch s := s.(type) {
+		case *stmt.Simple:
+			fn := s.Expr.(*expr.FuncLiteral)
+			docs[fn.Name] = fn.Doc
+		case *stmt.TypeDecl:
+			docs[s.Name] = s.Doc
+		case *stmt.Const:
+			docs[s.NameList[0]] = s.Doc
+		case *stmt.VarSet:
+			docs[s.Vars[0].NameList[0]] = s.Vars[0].Doc
+		case *stmt.MethodikDecl:
+			docs[s.Name] = s.Doc
+			docs[s.Methods[0].Name] = s.Methods[0].Doc
+		}
+	}
+	wantDocs := map[string]string{
+		"F":   "F does nothing.\nReally.\n",
+		"T":   " T is a type.\n",
+		"C":   "",
+		"V":   "V is a variable.\n",
+		"M":   "M is a methodik.\n",
+		"Get": "Get gets.\n",
+	}
+	for name, want := range wantDocs {
+		g, ok := docs[name]
+		if !ok {
+			t.Errorf("%s: declaration not found", name)
+			continue
+		}
+		if got := g.Text(); got != want {
+			t.Errorf("%s: doc %q, want %q", name, got, want)
+		}
+	}
+}
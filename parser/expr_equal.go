@@ -257,6 +257,21 @@ func EqualExpr(x, y expr.Expr) bool {
 			return false
 		}
 		return equalExprs(x.Indicies, y.Indicies)
+	case *expr.TableIndex:
+		y, ok := y.(*expr.TableIndex)
+		if !ok {
+			return false
+		}
+		if x == nil || y == nil {
+			return x == nil && y == nil
+		}
+		if !EqualExpr(x.Left, y.Left) {
+			return false
+		}
+		if !EqualExpr(x.Rows, y.Rows) {
+			return false
+		}
+		return equalExprs(x.Cols, y.Cols)
 	case *expr.TypeAssert:
 		y, ok := y.(*expr.TypeAssert)
 		if !ok {
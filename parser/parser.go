@@ -22,11 +22,23 @@ import (
 	"neugram.io/ng/syntax/token"
 )
 
-func New(filename string) *Parser {
+// A Mode is a set of flags controlling optional parser behavior.
+type Mode uint
+
+const (
+	// ParseComments collects the comments in a file into
+	// File.Comments, and attaches doc comments to declarations.
+	ParseComments Mode = 1 << iota
+)
+
+func New(filename string, mode ...Mode) *Parser {
 	p := &Parser{
 		filename: filename,
 		s:        newScanner(),
 	}
+	for _, m := range mode {
+		p.mode |= m
+	}
 	go p.work()
 	<-p.s.needSrc
 	return p
@@ -46,6 +58,10 @@ const (
 // If non-nil, the returned error is either of type Error or Errors.
 func (p *Parser) Parse(source []byte) (*syntax.File, error) {
 	f := &syntax.File{Filename: p.filename}
+	defer func() {
+		f.Comments = p.comments
+		p.comments = nil
+	}()
 	var errs Errors
 	scanner := bufio.NewScanner(bytes.NewReader(source))
 	for i := 0; scanner.Scan(); i++ {
@@ -54,15 +70,23 @@ func (p *Parser) Parse(source []byte) (*syntax.File, error) {
 			p.s.Line++
 			continue
 		}
-		res := p.ParseLine(b)
+		res := p.parseLine(b)
 		if len(res.Stmts) > 0 {
 			f.Stmts = append(f.Stmts, res.Stmts...)
 		}
+		// Shell commands are not positioned by the shell parser.
+		// Use the line they end on.
+		cmdPos := func(cmd *expr.ShellList) src.Pos {
+			if cmd.Pos().Line != 0 {
+				return cmd.Pos()
+			}
+			return src.Pos{Filename: p.filename, Line: int32(i + 1)}
+		}
 		if len(res.Cmds) == 1 {
 			s := &stmt.Simple{
-				Position: res.Cmds[0].Pos(),
+				Position: cmdPos(res.Cmds[0]),
 				Expr: &expr.Shell{
-					Position: res.Cmds[0].Pos(),
+					Position: cmdPos(res.Cmds[0]),
 					Cmds:     res.Cmds,
 				},
 			}
@@ -73,9 +97,9 @@ func (p *Parser) Parse(source []byte) (*syntax.File, error) {
 			}
 			for _, cmd := range res.Cmds {
 				simple := &stmt.Simple{
-					Position: cmd.Pos(),
+					Position: cmdPos(cmd),
 					Expr: &expr.Shell{
-						Position: cmd.Pos(),
+						Position: cmdPos(cmd),
 						Cmds:     []*expr.ShellList{cmd},
 					},
 				}
@@ -104,7 +128,19 @@ func (p *Parser) Parse(source []byte) (*syntax.File, error) {
 	return f, nil
 }
 
+// State reports the state of the parser after the input given to it
+// so far. After Parse, StateStmtPartial or StateCmdPartial means the
+// source ends in an incomplete statement.
+func (p *Parser) State() ParserState {
+	return p.res.State
+}
+
 func (p *Parser) ParseLine(line []byte) Result {
+	p.comments = nil // only kept when parsing a file
+	return p.parseLine(line)
+}
+
+func (p *Parser) parseLine(line []byte) Result {
 	p.s.addSrc <- append(line, '\n') // TODO: skip the append?
 	<-p.s.needSrc
 	r := p.res
@@ -117,8 +153,12 @@ type Parser struct {
 
 	res Result
 
+	mode        Mode
 	interactive bool
-	noCompLit   bool // to resolve composite literal parsing
+	noCompLit   bool                // to resolve composite literal parsing
+	inIndex     bool                // '|' separates table rows and columns
+	comments    []*src.CommentGroup // comments seen by Parse
+	lead        *src.CommentGroup   // comment group ending just before the current token
 	s           *Scanner
 }
 
@@ -208,9 +248,33 @@ func ParseStmt(src []byte) (stmt stmt.Stmt, err error) {
 }
 
 func (p *Parser) next() {
+	prevLine := p.s.tokLine
 	p.s.Next()
-	if p.s.Token == token.Comment {
-		p.next()
+	p.lead = nil
+	if p.s.Token != token.Comment {
+		return
+	}
+
+	// Group adjacent comments. A comment on the same line as
+	// the previous token starts a group of its own.
+	var g *src.CommentGroup
+	for p.s.Token == token.Comment {
+		if p.mode&ParseComments != 0 {
+			c := &src.Comment{
+				Position: p.tokPos(),
+				Text:     p.s.Literal.(string),
+			}
+			if g == nil || c.Position.Line > g.End().Line+1 ||
+				(g.Pos().Line == prevLine && c.Position.Line != prevLine) {
+				g = &src.CommentGroup{}
+				p.comments = append(p.comments, g)
+			}
+			g.List = append(g.List, c)
+		}
+		p.s.Next()
+	}
+	if g != nil && g.Pos().Line != prevLine && g.End().Line+1 >= p.s.tokLine {
+		p.lead = g
 	}
 }
 
@@ -232,6 +296,15 @@ func (p *Parser) pos() src.Pos {
 	}
 }
 
+// tokPos returns the position of the first byte of the current token.
+func (p *Parser) tokPos() src.Pos {
+	return src.Pos{
+		Filename: p.filename,
+		Line:     p.s.tokLine,
+		Column:   p.s.tokCol,
+	}
+}
+
 func (p *Parser) parseExpr() expr.Expr {
 	return p.parseBinaryExpr(1)
 }
@@ -241,7 +314,7 @@ func (p *Parser) parseBinaryExpr(minPrec int) expr.Expr {
 	for prec := p.s.Token.Precedence(); prec >= minPrec; prec-- {
 		for {
 			op := p.s.Token
-			if op.Precedence() != prec {
+			if op.Precedence() != prec || (op == token.Pipe && p.inIndex) {
 				break
 			}
 			pos := p.pos()
@@ -363,6 +436,8 @@ func (p *Parser) parsePrimaryExpr() expr.Expr {
 			p.next()
 			var args []expr.Expr
 			var ellipsis bool
+			origInIndex := p.inIndex
+			p.inIndex = false
 			for p.s.Token != token.RightParen && p.s.r > 0 && !ellipsis {
 				args = append(args, p.parseExpr())
 				if p.s.Token == token.Ellipsis {
@@ -374,6 +449,7 @@ func (p *Parser) parsePrimaryExpr() expr.Expr {
 				}
 				p.next()
 			}
+			p.inIndex = origInIndex
 			p.expect(token.RightParen)
 			p.next()
 
@@ -417,6 +493,8 @@ func (p *Parser) parsePrimaryExpr() expr.Expr {
 			} else if t := maybePackageType(x); t != nil {
 				t := &expr.Type{Position: pos, Type: t}
 				x = t
+			} else if t := maybeGenericType(x); t != nil {
+				x = &expr.Type{Position: pos, Type: t}
 			}
 		default:
 			return x
@@ -441,6 +519,62 @@ func maybePackageType(x expr.Expr) *tipe.Unresolved {
 	}
 }
 
+// maybeGenericType reports the instantiated generic type named by an
+// index expression such as List[int] or pkg.Set[string].
+func maybeGenericType(x expr.Expr) *tipe.Unresolved {
+	index, isIndex := x.(*expr.Index)
+	if !isIndex {
+		return nil
+	}
+	var t *tipe.Unresolved
+	switch left := index.Left.(type) {
+	case *expr.Ident:
+		t = &tipe.Unresolved{Name: left.Name}
+	case *expr.Selector:
+		t = maybePackageType(left)
+	}
+	if t == nil {
+		return nil
+	}
+	for _, arg := range index.Indicies {
+		argt := exprToType(arg)
+		if argt == nil {
+			return nil
+		}
+		t.TypeArgs = append(t.TypeArgs, argt)
+	}
+	return t
+}
+
+// exprToType converts an expression that names a type, such as the
+// type arguments of an index expression, into that type.
+func exprToType(x expr.Expr) tipe.Type {
+	switch x := x.(type) {
+	case *expr.Ident:
+		if x.Name == "num" {
+			return tipe.Num
+		}
+		return &tipe.Unresolved{Name: x.Name}
+	case *expr.Type:
+		return x.Type
+	case *expr.Selector:
+		if t := maybePackageType(x); t != nil {
+			return t
+		}
+	case *expr.Index:
+		if t := maybeGenericType(x); t != nil {
+			return t
+		}
+	case *expr.Unary:
+		if x.Op == token.Mul {
+			if elem := exprToType(x.Expr); elem != nil {
+				return &tipe.Pointer{Elem: elem}
+			}
+		}
+	}
+	return nil
+}
+
 func (p *Parser) parseTypeAssert(lhs expr.Expr) expr.Expr {
 	pos := p.pos()
 	p.expect(token.LeftParen)
@@ -467,74 +601,115 @@ func (p *Parser) parseTypeAssert(lhs expr.Expr) expr.Expr {
 
 func (p *Parser) parseIndex(lhs expr.Expr) expr.Expr {
 	p.expect(token.LeftBracket)
-
-	res := &expr.Index{
-		Position: p.pos(),
-		Left:     lhs,
-	}
-
+	pos := p.pos()
 	p.next()
 
-	for p.s.Token != 0 && p.s.Token != token.RightBracket {
-		if len(res.Indicies) != 0 {
+	origInIndex := p.inIndex
+	p.inIndex = true
+	defer func() { p.inIndex = origInIndex }()
+
+	var indicies []expr.Expr
+	for p.s.Token != 0 && p.s.Token != token.RightBracket && p.s.Token != token.Pipe {
+		if len(indicies) != 0 {
 			if !p.expect(token.Comma) {
 				break
 			}
 			p.next()
 		}
+		indicies = append(indicies, p.parseIndexElem())
+	}
 
-		if p.s.Token == token.Colon {
-			slice := &expr.Slice{Position: p.pos()}
-			// [:expr]
-			p.next()
-			if p.s.Token == token.RightBracket || p.s.Token == token.Comma {
-				res.Indicies = append(res.Indicies, slice)
-				continue
-			}
-			slice.High = p.parseExpr()
-			res.Indicies = append(res.Indicies, slice)
-			continue
+	if p.s.Token == token.Pipe {
+		// [rows|cols]
+		res := &expr.TableIndex{
+			Position: pos,
+			Left:     lhs,
 		}
-
-		e := p.parseExpr()
-		if p.s.Token == token.RightBracket || p.s.Token == token.Comma {
-			// [expr]
-			res.Indicies = append(res.Indicies, e)
-			continue
+		switch len(indicies) {
+		case 0:
+		case 1:
+			res.Rows = indicies[0]
+		default:
+			p.errorf("table index has %d row indexes", len(indicies))
 		}
-		p.expect(token.Colon)
-		colonPos := p.pos()
 		p.next()
-		if p.s.Token == token.RightBracket || p.s.Token == token.Comma {
-			// [expr:]
-			res.Indicies = append(res.Indicies, &expr.Slice{Position: colonPos, Low: e})
-			continue
-		}
-		high := p.parseExpr()
-		if p.s.Token == token.RightBracket || p.s.Token == token.Comma {
-			// [expr:high]
-			res.Indicies = append(res.Indicies, &expr.Slice{
-				Position: colonPos,
-				Low:      e,
-				High:     high,
-			})
-			continue
+		for p.s.Token != 0 && p.s.Token != token.RightBracket {
+			if len(res.Cols) != 0 {
+				if !p.expect(token.Comma) {
+					break
+				}
+				p.next()
+			}
+			res.Cols = append(res.Cols, p.parseIndexElem())
 		}
-		p.expect(token.Colon)
+		p.expect(token.RightBracket)
+		p.next()
+		return res
+	}
+
+	p.expect(token.RightBracket)
+	p.next()
+	return &expr.Index{
+		Position: pos,
+		Left:     lhs,
+		Indicies: indicies,
+	}
+}
+
+// endOfIndex reports whether the current token ends an index element.
+func (p *Parser) endOfIndex() bool {
+	switch p.s.Token {
+	case token.RightBracket, token.Comma, token.Pipe:
+		return true
+	}
+	return false
+}
+
+// parseIndexElem parses an index or a slice, as in [expr] or
+// [low:high:max].
+func (p *Parser) parseIndexElem() expr.Expr {
+	if p.s.Token == token.Colon {
+		slice := &expr.Slice{Position: p.pos()}
+		// [:expr]
 		p.next()
-		max := p.parseExpr()
-		// [expr:high:max]
-		res.Indicies = append(res.Indicies, &expr.Slice{
+		if p.endOfIndex() {
+			return slice
+		}
+		slice.High = p.parseExpr()
+		return slice
+	}
+
+	e := p.parseExpr()
+	if p.endOfIndex() {
+		// [expr]
+		return e
+	}
+	p.expect(token.Colon)
+	colonPos := p.pos()
+	p.next()
+	if p.endOfIndex() {
+		// [expr:]
+		return &expr.Slice{Position: colonPos, Low: e}
+	}
+	high := p.parseExpr()
+	if p.endOfIndex() {
+		// [expr:high]
+		return &expr.Slice{
 			Position: colonPos,
 			Low:      e,
 			High:     high,
-			Max:      max,
-		})
-
+		}
 	}
-	p.expect(token.RightBracket)
+	p.expect(token.Colon)
 	p.next()
-	return res
+	max := p.parseExpr()
+	// [expr:high:max]
+	return &expr.Slice{
+		Position: colonPos,
+		Low:      e,
+		High:     high,
+		Max:      max,
+	}
 }
 
 func (p *Parser) parseRange() (r expr.Range) {
@@ -571,7 +746,27 @@ func (p *Parser) maybeParseParamType() (t tipe.Type) {
 
 func (p *Parser) parseParam() (name string, t tipe.Type) {
 	// Scan what may be a type, or may be a parameter name.
-	first := p.maybeParseParamType()
+	var first tipe.Type
+	if p.s.Token == token.Ident {
+		first = p.parseTypeName(p.parseIdent())
+		if p.s.Token == token.LeftBracket {
+			p.next()
+			switch p.s.Token {
+			case token.RightBracket, token.Int, token.Ellipsis, token.Pipe:
+				// A parameter name followed by an array, slice,
+				// or table type.
+				name = typeAsName(first)
+				t = p.parseBracketType()
+				if p.s.Token == token.Comma {
+					p.next()
+				}
+				return name, t
+			}
+			first = p.parseTypeArgs(first)
+		}
+	} else {
+		first = p.maybeParseParamType()
+	}
 	if n := typeAsName(first); n != "" && p.s.Token > 0 && p.s.Token != token.Comma && p.s.Token != token.RightParen {
 		// Looks like a type may follow. Treat first as a name.
 		name = n
@@ -589,13 +784,15 @@ func (p *Parser) parseParam() (name string, t tipe.Type) {
 }
 
 func typeAsName(t tipe.Type) string {
-	if u, ok := t.(*tipe.Unresolved); ok && u.Package == "" {
+	if u, ok := t.(*tipe.Unresolved); ok && u.Package == "" && u.TypeArgs == nil {
 		return u.Name
 	}
 	return ""
 }
 
-func (p *Parser) parseParamTuple() (names []string, params *tipe.Tuple) {
+// parseParamTuple parses a parameter list. If a parameter is named
+// without a type, as a in "a, b int", grouped reports it.
+func (p *Parser) parseParamTuple() (names []string, grouped []bool, params *tipe.Tuple) {
 	params = &tipe.Tuple{}
 	for p.s.Token > 0 && p.s.Token != token.RightParen {
 		name, t := p.parseParam()
@@ -618,7 +815,7 @@ func (p *Parser) parseParamTuple() (names []string, params *tipe.Tuple) {
 				names[i] = typeAsName(params.Elems[i])
 				if names[i] == "" {
 					p.error("function signature mixes named and unnamed arguments")
-					return nil, &tipe.Tuple{}
+					return nil, nil, &tipe.Tuple{}
 				}
 				params.Elems[i] = nil
 			} else {
@@ -626,17 +823,21 @@ func (p *Parser) parseParamTuple() (names []string, params *tipe.Tuple) {
 				t := params.Elems[i]
 				for j := i - 1; j >= 0 && params.Elems[j] == nil; j-- {
 					params.Elems[j] = t
+					if grouped == nil {
+						grouped = make([]bool, len(names))
+					}
+					grouped[j] = true
 				}
 			}
 		}
 		for _, t := range params.Elems {
 			if t == nil {
 				p.error("function signature mixes named and unnamed arguments")
-				return nil, &tipe.Tuple{}
+				return nil, nil, &tipe.Tuple{}
 			}
 		}
 	}
-	return names, params
+	return names, grouped, params
 }
 
 func (p *Parser) parseMethodik(name string) *stmt.MethodikDecl {
@@ -645,9 +846,9 @@ func (p *Parser) parseMethodik(name string) *stmt.MethodikDecl {
 		Type: &tipe.Named{
 			// TODO Spec
 			Name: name,
-			Type: p.parseType(),
 		},
 	}
+	c.Type.TypeParams, c.Type.Type = p.parseTypeParamsAndType()
 	p.expect(token.LeftBrace)
 	p.next()
 
@@ -673,6 +874,30 @@ func (p *Parser) parseMethodik(name string) *stmt.MethodikDecl {
 	return c
 }
 
+// parseTypeParamsAndType parses the optional type parameters and
+// the underlying type in a type or methodik declaration. The
+// declaration
+//
+//	type T[E any] []E
+//
+// is distinguished from an array or slice type by the name that
+// starts a type parameter list.
+func (p *Parser) parseTypeParamsAndType() ([]*tipe.TypeParam, tipe.Type) {
+	if p.s.Token != token.LeftBracket {
+		return nil, p.parseType()
+	}
+	p.next()
+	if p.s.Token != token.Ident {
+		t := p.parseBracketType()
+		if t == nil {
+			p.errorf("expected type , got %s", p.s.Token)
+		}
+		return nil, t
+	}
+	tparams := p.parseTypeParams()
+	return tparams, p.parseType()
+}
+
 func (p *Parser) parseType() tipe.Type {
 	t := p.maybeParseType()
 	if t == nil {
@@ -681,54 +906,165 @@ func (p *Parser) parseType() tipe.Type {
 	return t
 }
 
-func (p *Parser) maybeParseType() tipe.Type {
-	switch p.s.Token {
-	case token.Ident:
-		ident := p.parseIdent()
-		if p.s.Token == token.Period {
-			p.next()
-			sel := p.parseIdent()
-			return &tipe.Unresolved{
-				Package: ident.Name,
-				Name:    sel.Name,
-			}
+func unpointer(t tipe.Type) tipe.Type {
+	if ptr, isPtr := t.(*tipe.Pointer); isPtr {
+		return ptr.Elem
+	}
+	return t
+}
+
+// parseTypeName parses the rest of a type name beginning with ident,
+// such as pkg.T.
+func (p *Parser) parseTypeName(ident *expr.Ident) tipe.Type {
+	if p.s.Token == token.Period {
+		p.next()
+		return &tipe.Unresolved{
+			Package: ident.Name,
+			Name:    p.parseIdent().Name,
 		}
-		// It is an error to declare a variable with the name of a
-		// type parameter, so we can resolve it immediately.
-		if ident.Name == "num" {
-			return tipe.Num
+	}
+	// It is an error to declare a variable with the name of a
+	// type parameter, so we can resolve it immediately.
+	if ident.Name == "num" {
+		return tipe.Num
+	}
+	return &tipe.Unresolved{Name: ident.Name}
+}
+
+// parseTypeArgs parses the type arguments of an instantiated generic
+// type, such as List[int], after the opening bracket.
+func (p *Parser) parseTypeArgs(t tipe.Type) tipe.Type {
+	u, ok := t.(*tipe.Unresolved)
+	if !ok {
+		p.errorf("%s is not a generic type", format.Type(t))
+		u = &tipe.Unresolved{}
+	}
+	u = &tipe.Unresolved{Package: u.Package, Name: u.Name}
+	for p.s.Token > 0 && p.s.Token != token.RightBracket {
+		u.TypeArgs = append(u.TypeArgs, p.parseType())
+		if p.s.Token != token.Comma {
+			break
 		}
-		return &tipe.Unresolved{Name: ident.Name}
-	case token.LeftBracket:
 		p.next()
-		table := false
-		if p.s.Token == token.Pipe {
-			table = true
-			p.next()
+	}
+	p.expect(token.RightBracket)
+	p.next()
+	return u
+}
+
+// parseTypeParams parses the type parameter list of a generic
+// function or type, such as [K comparable, V any], after the
+// opening bracket.
+func (p *Parser) parseTypeParams() (tparams []*tipe.TypeParam) {
+	var pending []*tipe.TypeParam
+	for p.s.Token > 0 && p.s.Token != token.RightBracket {
+		if p.s.Token != token.Ident {
+			p.errorf("expected type parameter name, got %s", p.s.Token)
+			break
 		}
-		switch p.s.Token {
-		case token.RightBracket:
+		tp := &tipe.TypeParam{Name: p.parseIdent().Name}
+		tparams = append(tparams, tp)
+		pending = append(pending, tp)
+		if p.s.Token == token.Comma {
 			p.next()
-			if table {
-				return &tipe.Table{Type: p.parseType()}
-			} else {
-				return &tipe.Slice{Elem: p.parseType()}
+			continue
+		}
+		constraint := p.parseConstraint()
+		for _, tp := range pending {
+			tp.Constraint = constraint
+		}
+		pending = nil
+		if p.s.Token != token.Comma {
+			break
+		}
+		p.next()
+	}
+	if len(pending) > 0 {
+		p.errorf("missing constraint for type parameter %s", pending[0].Name)
+	}
+	p.expect(token.RightBracket)
+	p.next()
+	return tparams
+}
+
+// parseConstraint parses a type parameter constraint. It is either
+// an interface type, or a union of type terms such as ~int | string
+// that stands for an interface with that type set.
+func (p *Parser) parseConstraint() tipe.Type {
+	union := p.parseUnion(nil)
+	if len(union) == 1 && !union[0].Tilde {
+		return union[0].Type
+	}
+	return &tipe.Interface{Methods: map[string]*tipe.Func{}, Union: union}
+}
+
+// parseUnion parses the terms of a union. If first is not nil, it is
+// the already parsed type of the first term.
+func (p *Parser) parseUnion(first tipe.Type) (union []tipe.Term) {
+	for {
+		var term tipe.Term
+		if first != nil {
+			term.Type, first = first, nil
+		} else {
+			if p.s.Token == token.Tilde {
+				term.Tilde = true
+				p.next()
 			}
-		case token.Int:
-			sz := p.s.Literal.(*big.Int).Int64()
-			p.next()
-			p.expect(token.RightBracket)
-			p.next()
-			return &tipe.Array{Len: sz, Elem: p.parseType()}
-		case token.Ellipsis:
-			p.next()
-			p.expect(token.RightBracket)
+			term.Type = p.parseType()
+		}
+		union = append(union, term)
+		if p.s.Token != token.Pipe {
+			return union
+		}
+		p.next()
+	}
+}
+
+// parseBracketType parses an array, slice, or table type after the
+// opening bracket.
+func (p *Parser) parseBracketType() tipe.Type {
+	table := false
+	if p.s.Token == token.Pipe {
+		table = true
+		p.next()
+	}
+	switch p.s.Token {
+	case token.RightBracket:
+		p.next()
+		if table {
+			return &tipe.Table{Type: p.parseType()}
+		} else {
+			return &tipe.Slice{Elem: p.parseType()}
+		}
+	case token.Int:
+		sz := p.s.Literal.(*big.Int).Int64()
+		p.next()
+		p.expect(token.RightBracket)
+		p.next()
+		return &tipe.Array{Len: sz, Elem: p.parseType()}
+	case token.Ellipsis:
+		p.next()
+		p.expect(token.RightBracket)
+		p.next()
+		return &tipe.Array{Elem: p.parseType(), Ellipsis: true}
+	default:
+		p.errorf("invalid token=%v in type declaration", p.s.Token)
+		return nil
+	}
+}
+
+func (p *Parser) maybeParseType() tipe.Type {
+	switch p.s.Token {
+	case token.Ident:
+		t := p.parseTypeName(p.parseIdent())
+		if p.s.Token == token.LeftBracket {
 			p.next()
-			return &tipe.Array{Elem: p.parseType(), Ellipsis: true}
-		default:
-			p.errorf("invalid token=%v in type declaration", p.s.Token)
-			return nil
+			t = p.parseTypeArgs(t)
 		}
+		return t
+	case token.LeftBracket:
+		p.next()
+		return p.parseBracketType()
 	case token.Mul:
 		p.next()
 		return &tipe.Pointer{Elem: p.parseType()}
@@ -751,12 +1087,21 @@ func (p *Parser) maybeParseType() tipe.Type {
 			}
 			if p.s.Token != token.Comma {
 				switch p.s.Token {
-				case token.RightBrace, token.Semicolon:
-					// embedded type field
+				case token.RightBrace, token.Semicolon, token.String, token.Period:
+					// embedded type field: T, *T, pkg.T or *pkg.T
 					if n != "" {
-						t = &tipe.Unresolved{Name: n}
+						u := &tipe.Unresolved{Name: n}
+						if p.s.Token == token.Period {
+							p.next()
+							u.Package = n
+							u.Name = p.parseIdent().Name
+						}
+						t = u
+					}
+					if u, ok := unpointer(t).(*tipe.Unresolved); ok {
+						n = u.Name
 					} else {
-						n = t.(*tipe.Pointer).Elem.(*tipe.Unresolved).Name
+						p.errorf("embedded field type %s must be a type name", format.Type(t))
 					}
 					embed = true
 				default:
@@ -809,11 +1154,31 @@ func (p *Parser) maybeParseType() tipe.Type {
 		p.next()
 		iface := &tipe.Interface{Methods: make(map[string]*tipe.Func)}
 		for p.s.Token > 0 && p.s.Token != token.RightBrace {
-			f := p.parseFuncType(false)
-			// TODO: we are throwing away a lot of information
-			// not technically part of the type but that we want
-			// in the AST for pretty printing. Recover it.
-			iface.Methods[f.Name] = f.Type
+			var first tipe.Type
+			if p.s.Token == token.Ident {
+				ident := p.parseIdent()
+				if p.s.Token == token.LeftParen {
+					f := &expr.FuncLiteral{Name: ident.Name, Type: &tipe.Func{}}
+					p.parseSignature(f)
+					// TODO: we are throwing away a lot of information
+					// not technically part of the type but that we want
+					// in the AST for pretty printing. Recover it.
+					iface.Methods[f.Name] = f.Type
+					if p.s.Token == token.Semicolon {
+						p.next()
+					}
+					continue
+				}
+				first = p.parseTypeName(ident)
+				if p.s.Token == token.LeftBracket {
+					p.next()
+					first = p.parseTypeArgs(first)
+				}
+			}
+			if iface.Union != nil {
+				p.errorf("interface has more than one type set line")
+			}
+			iface.Union = p.parseUnion(first)
 			if p.s.Token == token.Semicolon {
 				p.next()
 			}
@@ -1127,11 +1492,11 @@ func (p *Parser) parseStmt() stmt.Stmt {
 		p.expectSemi()
 		return s
 	case token.Const:
-		pos := p.pos()
+		pos, doc := p.pos(), p.lead
 		p.next()
 		if p.s.Token == token.LeftParen {
 			p.next()
-			s := &stmt.ConstSet{Position: pos}
+			s := &stmt.ConstSet{Position: pos, Doc: doc}
 			for p.s.Token > 0 && p.s.Token != token.RightParen {
 				s.Consts = append(s.Consts, p.parseConst())
 				if p.s.Token == token.Semicolon {
@@ -1145,14 +1510,15 @@ func (p *Parser) parseStmt() stmt.Stmt {
 		}
 		s := p.parseConst()
 		s.Position = pos
+		s.Doc = doc
 		p.expectSemi()
 		return s
 	case token.Var:
-		pos := p.pos()
+		pos, doc := p.pos(), p.lead
 		p.next()
 		if p.s.Token == token.LeftParen {
 			p.next()
-			s := &stmt.VarSet{Position: pos}
+			s := &stmt.VarSet{Position: pos, Doc: doc}
 			for p.s.Token > 0 && p.s.Token != token.RightParen {
 				s.Vars = append(s.Vars, p.parseVar())
 				if p.s.Token == token.Semicolon {
@@ -1166,21 +1532,23 @@ func (p *Parser) parseStmt() stmt.Stmt {
 		}
 		s := p.parseVar()
 		s.Position = pos
+		s.Doc = doc
 		p.expectSemi()
 		return s
 	case token.Methodik:
-		pos := p.pos()
+		pos, doc := p.pos(), p.lead
 		p.next()
 		m := p.parseMethodik(p.parseIdent().Name)
 		m.Position = pos
+		m.Doc = doc
 		p.expectSemi()
 		return m
 	case token.Type:
-		pos := p.pos()
+		pos, doc := p.pos(), p.lead
 		p.next()
 		if p.s.Token == token.LeftParen {
 			p.next()
-			s := &stmt.TypeDeclSet{Position: pos}
+			s := &stmt.TypeDeclSet{Position: pos, Doc: doc}
 			for p.s.Token > 0 && p.s.Token != token.RightParen {
 				s.TypeDecls = append(s.TypeDecls, p.parseTypeDecl())
 				if p.s.Token == token.Semicolon {
@@ -1194,6 +1562,7 @@ func (p *Parser) parseStmt() stmt.Stmt {
 		}
 		s := p.parseTypeDecl()
 		s.Position = pos
+		s.Doc = doc
 		p.expectSemi()
 		return s
 	case token.Import:
@@ -1234,6 +1603,7 @@ func (p *Parser) parseStmt() stmt.Stmt {
 }
 
 func (p *Parser) parseImport() (s *stmt.Import) {
+	pos := p.pos()
 	name := ""
 	if p.s.Token == token.Ident {
 		name = p.s.Literal.(string)
@@ -1245,8 +1615,9 @@ func (p *Parser) parseImport() (s *stmt.Import) {
 	}
 	path := p.s.Literal.(string)
 	s = &stmt.Import{
-		Name: name,
-		Path: path[1 : len(path)-1],
+		Position: pos,
+		Name:     name,
+		Path:     path[1 : len(path)-1],
 	}
 	p.next()
 	return s
@@ -1286,6 +1657,7 @@ func (p *Parser) parseGo() stmt.Stmt {
 func (p *Parser) parseConst() *stmt.Const {
 	s := &stmt.Const{
 		Position: p.pos(),
+		Doc:      p.lead,
 	}
 items:
 	for {
@@ -1334,6 +1706,7 @@ items:
 func (p *Parser) parseVar() *stmt.Var {
 	s := &stmt.Var{
 		Position: p.pos(),
+		Doc:      p.lead,
 	}
 items:
 	for {
@@ -1370,12 +1743,12 @@ items:
 
 func (p *Parser) parseTypeDecl() *stmt.TypeDecl {
 	pos := p.pos()
-	t := &tipe.Named{
-		Name: p.parseIdent().Name,
-		Type: p.parseType(),
-	}
+	doc := p.lead
+	t := &tipe.Named{Name: p.parseIdent().Name}
+	t.TypeParams, t.Type = p.parseTypeParamsAndType()
 	s := &stmt.TypeDecl{
 		Position: pos,
+		Doc:      doc,
 		Name:     t.Name,
 		Type:     t,
 	}
@@ -1544,6 +1917,10 @@ func (p *Parser) parseSwitchHeader() (stmt.Stmt, stmt.Stmt, bool) {
 		switch p.s.Token {
 		case token.Semicolon:
 			p.next()
+			if p.s.Token == token.LeftBrace {
+				// switch x := foo(); { ... }
+				break
+			}
 			s2 = p.parseSimpleStmt()
 			switch s2 := s2.(type) {
 			default:
@@ -1614,7 +1991,7 @@ func (p *Parser) parseExprSwitch(s *stmt.Switch) {
 	for i, cse := range s.Cases {
 		lastCase := i == len(s.Cases)-1
 		for j, e := range cse.Body.Stmts {
-			// TODO: detect fallthrough statements in non-top-level statements
+			// Nested fallthrough statements are rejected by the typechecker.
 			lastStmt := j == len(cse.Body.Stmts)-1
 			switch e := e.(type) {
 			case *stmt.Branch:
@@ -1659,7 +2036,7 @@ func (p *Parser) parseTypeSwitch(s *stmt.TypeSwitch) {
 		p.next()
 		c.Body = &stmt.Block{Stmts: p.parseStmts()}
 		for _, e := range c.Body.Stmts {
-			// TODO: detect fallthrough statements in non-top-level statements
+			// Nested fallthrough statements are rejected by the typechecker.
 			switch e := e.(type) {
 			case *stmt.Branch:
 				if e.Type == token.Fallthrough {
@@ -1681,6 +2058,7 @@ func (p *Parser) parseBlock() stmt.Stmt {
 	p.next()
 	s := &stmt.Block{Stmts: p.parseStmts()}
 	p.expect(token.RightBrace)
+	s.Rbrace = p.tokPos()
 	p.next()
 	return s
 }
@@ -1719,14 +2097,24 @@ func (p *Parser) parseFuncType(method bool) *expr.FuncLiteral {
 
 	if p.s.Token == token.Ident {
 		f.Name = p.parseIdent().Name
+		if !method && p.s.Token == token.LeftBracket {
+			p.next()
+			f.Type.TypeParams = p.parseTypeParams()
+		}
 	} else if method {
 		p.errorf("class method missing name")
 	}
 
+	p.parseSignature(f)
+	return f
+}
+
+// parseSignature parses the parameters and results of a function.
+func (p *Parser) parseSignature(f *expr.FuncLiteral) {
 	p.expect(token.LeftParen)
 	p.next()
 	if p.s.Token != token.RightParen {
-		f.ParamNames, f.Type.Params = p.parseParamTuple()
+		f.ParamNames, f.ParamsGrouped, f.Type.Params = p.parseParamTuple()
 		if params := f.Type.Params; len(params.Elems) > 0 {
 			last := params.Elems[len(params.Elems)-1]
 			if _, variadic := last.(*tipe.Ellipsis); variadic {
@@ -1743,7 +2131,7 @@ func (p *Parser) parseFuncType(method bool) *expr.FuncLiteral {
 		p.expect(token.LeftParen)
 		p.next()
 		if p.s.Token != token.RightParen {
-			f.ResultNames, f.Type.Results = p.parseParamTuple()
+			f.ResultNames, f.ResultsGrouped, f.Type.Results = p.parseParamTuple()
 		}
 		p.expect(token.RightParen)
 		p.next()
@@ -1754,15 +2142,16 @@ func (p *Parser) parseFuncType(method bool) *expr.FuncLiteral {
 			f.Type.Results = &tipe.Tuple{Elems: []tipe.Type{typ}}
 		}
 	}
-	return f
 }
 
 func (p *Parser) parseFunc(method bool) *expr.FuncLiteral {
 	p.expect(token.Func)
 	funcPos := p.pos()
+	doc := p.lead
 	p.next()
 	f := p.parseFuncType(method)
 	f.Position = funcPos
+	f.Doc = doc
 	if p.s.Token != token.LeftBrace {
 		p.next()
 		p.errorf("missing function body")
@@ -1781,6 +2170,7 @@ func (p *Parser) parseOperand() expr.Expr {
 		x := &expr.BasicLiteral{
 			Position: p.pos(),
 			Value:    p.s.Literal,
+			Raw:      p.s.raw(),
 		}
 		p.next()
 		return x
@@ -1788,6 +2178,7 @@ func (p *Parser) parseOperand() expr.Expr {
 		x := &expr.BasicLiteral{
 			Position: p.pos(),
 			Value:    p.s.Literal,
+			Raw:      p.s.raw(),
 		}
 		p.next()
 		return x
@@ -1796,18 +2187,19 @@ func (p *Parser) parseOperand() expr.Expr {
 		x := &expr.BasicLiteral{
 			Position: p.pos(),
 			Value:    s,
+			Raw:      p.s.Literal.(string),
 		}
 		p.next()
 		return x
 	case token.LeftParen:
-		origNoCompLit := p.noCompLit
+		origNoCompLit, origInIndex := p.noCompLit, p.inIndex
 		pos := p.pos()
-		p.noCompLit = false
+		p.noCompLit, p.inIndex = false, false
 		p.next()
 		ex := p.parseExpr() // TODO or a type?
 		p.expect(token.RightParen)
 		p.next()
-		p.noCompLit = origNoCompLit
+		p.noCompLit, p.inIndex = origNoCompLit, origInIndex
 		return &expr.Unary{
 			Position: pos,
 			Op:       token.LeftParen, Expr: ex,
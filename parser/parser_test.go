@@ -289,14 +289,29 @@ var parserTests = []parserTest{
 	{"x[:,:]", &expr.Index{Left: &expr.Ident{Name: "x"}, Indicies: []expr.Expr{&expr.Slice{}, &expr.Slice{}}}},
 	{"x[1:,:3]", &expr.Index{Left: &expr.Ident{Name: "x"}, Indicies: []expr.Expr{&expr.Slice{Low: basic(1)}, &expr.Slice{High: basic(3)}}}},
 	{"x[1:3,5:7]", &expr.Index{Left: &expr.Ident{Name: "x"}, Indicies: []expr.Expr{&expr.Slice{Low: basic(1), High: basic(3)}, &expr.Slice{Low: basic(5), High: basic(7)}}}},
-	/* TODO
-	{`x["C1"|"C2"]`, &expr.TableIndex{Expr: &expr.Ident{Name: "x"}, ColNames: []string{"C1", "C2"}}},
-	{`x["C1",1:]`, &expr.TableIndex{
-		Expr:     &expr.Ident{Name: "x"},
-		ColNames: []string{"C1"},
-		Rows:     expr.Range{Start: &expr.BasicLiteral{Value:big.NewInt(1)}},
+	{"x[1|2]", &expr.TableIndex{Left: &expr.Ident{Name: "x"}, Rows: basic(1), Cols: []expr.Expr{basic(2)}}},
+	{"x[1:3|]", &expr.TableIndex{Left: &expr.Ident{Name: "x"}, Rows: &expr.Slice{Low: basic(1), High: basic(3)}}},
+	{"x[:|1:]", &expr.TableIndex{Left: &expr.Ident{Name: "x"}, Rows: &expr.Slice{}, Cols: []expr.Expr{&expr.Slice{Low: basic(1)}}}},
+	{`x[|"C1", "C2"]`, &expr.TableIndex{Left: &expr.Ident{Name: "x"}, Cols: []expr.Expr{basic("C1"), basic("C2")}}},
+	{"x[i+1|(a|b)]", &expr.TableIndex{
+		Left: &expr.Ident{Name: "x"},
+		Rows: &expr.Binary{Op: token.Add, Left: &expr.Ident{Name: "i"}, Right: basic(1)},
+		Cols: []expr.Expr{&expr.Unary{Op: token.LeftParen, Expr: &expr.Binary{
+			Op:    token.Pipe,
+			Left:  &expr.Ident{Name: "a"},
+			Right: &expr.Ident{Name: "b"},
+		}}},
+	}},
+	{"x[f(a|b)]", &expr.Index{
+		Left: &expr.Ident{Name: "x"},
+		Indicies: []expr.Expr{&expr.Call{Func: &expr.Ident{Name: "f"}, Args: []expr.Expr{&expr.Binary{
+			Op:    token.Pipe,
+			Left:  &expr.Ident{Name: "a"},
+			Right: &expr.Ident{Name: "b"},
+		}}}},
 	}},
-	/*{"[|]num{}", &expr.TableLiteral{Type: &tipe.Table{tipe.Num}}},
+	/* TODO
+	{"[|]num{}", &expr.TableLiteral{Type: &tipe.Table{tipe.Num}}},
 	{"[|]num{{0, 1, 2}}", &expr.TableLiteral{
 		Type: &tipe.Table{tipe.Num},
 		Rows: [][]expr.Expr{{basic(0), basic(1), basic(2)}},
@@ -860,6 +875,23 @@ var stmtTests = []stmtTest{
 			Name: "T",
 		},
 	}},
+	{"type T struct { sync.Mutex; *io.Reader }", &stmt.TypeDecl{
+		Name: "T",
+		Type: &tipe.Named{
+			Type: &tipe.Struct{Fields: []tipe.StructField{
+				{Name: "Mutex", Type: &tipe.Unresolved{Package: "sync", Name: "Mutex"}, Embedded: true},
+				{Name: "Reader", Type: &tipe.Pointer{Elem: &tipe.Unresolved{Package: "io", Name: "Reader"}}, Embedded: true},
+			}},
+			Name: "T",
+		},
+	}},
+	{"type T struct { S `json` }", &stmt.TypeDecl{
+		Name: "T",
+		Type: &tipe.Named{
+			Type: &tipe.Struct{Fields: []tipe.StructField{{Name: "S", Type: &tipe.Unresolved{Name: "S"}, Tag: "json", Embedded: true}}},
+			Name: "T",
+		},
+	}},
 	{"type T struct { A string `json` }", &stmt.TypeDecl{
 		Name: "T",
 		Type: &tipe.Named{
@@ -1020,6 +1052,36 @@ var stmtTests = []stmtTest{
 			}},
 		},
 	},
+	{
+		`func Max[T int | float64](a, b T) T { return a }`,
+		&stmt.Simple{Expr: &expr.FuncLiteral{
+			Name: "Max",
+			Type: &tipe.Func{
+				TypeParams: []*tipe.TypeParam{{
+					Name: "T",
+					Constraint: &tipe.Interface{
+						Methods: map[string]*tipe.Func{},
+						Union: []tipe.Term{
+							{Type: &tipe.Unresolved{Name: "int"}},
+							{Type: tfloat64},
+						},
+					},
+				}},
+				Params:  &tipe.Tuple{Elems: []tipe.Type{&tipe.Unresolved{Name: "T"}, &tipe.Unresolved{Name: "T"}}},
+				Results: &tipe.Tuple{Elems: []tipe.Type{&tipe.Unresolved{Name: "T"}}},
+			},
+			ParamNames: []string{"a", "b"},
+			Body: &stmt.Block{Stmts: []stmt.Stmt{
+				&stmt.Return{Exprs: []expr.Expr{&expr.Ident{Name: "a"}}},
+			}},
+		}},
+	},
+	{"Pair[string, int]{}", &stmt.Simple{Expr: &expr.CompLiteral{
+		Type: &tipe.Unresolved{Name: "Pair", TypeArgs: []tipe.Type{
+			&tipe.Unresolved{Name: "string"},
+			&tipe.Unresolved{Name: "int"},
+		}},
+	}}},
 	{"S{ X: 7 }", &stmt.Simple{Expr: &expr.CompLiteral{
 		Type:   &tipe.Unresolved{Name: "S"},
 		Keys:   []expr.Expr{&expr.Ident{Name: "X"}},
@@ -1200,6 +1262,24 @@ var stmtTests = []stmtTest{
 			},
 		},
 	},
+	{`switch v, ok := m["a"]; {
+	}`,
+		&stmt.Switch{
+			Init: &stmt.Assign{
+				Decl: true,
+				Left: []expr.Expr{
+					&expr.Ident{Name: "v"},
+					&expr.Ident{Name: "ok"},
+				},
+				Right: []expr.Expr{
+					&expr.Index{
+						Left:     &expr.Ident{Name: "m"},
+						Indicies: []expr.Expr{&expr.BasicLiteral{Value: "a"}},
+					},
+				},
+			},
+		},
+	},
 	{
 		"switch v.(type) {}",
 		&stmt.TypeSwitch{
@@ -35,6 +35,11 @@ type Scanner struct {
 	Literal   interface{} // string, *big.Int, *big.Float
 	lastWidth int16
 
+	// Start of the current token
+	tokOff  int
+	tokLine int32
+	tokCol  int16
+
 	// Scanner state
 	src          []byte
 	r            rune
@@ -365,6 +370,14 @@ func (s *Scanner) scanComment() string {
 	return string(lit)
 }
 
+// raw returns the source text of the current token.
+func (s *Scanner) raw() string {
+	if s.tokOff > s.Offset || s.Offset > len(s.src) {
+		return ""
+	}
+	return string(s.src[s.tokOff:s.Offset])
+}
+
 func (s *Scanner) nextInShell() {
 	if s.exitingShell {
 		if s.r != '$' {
@@ -466,6 +479,9 @@ func (s *Scanner) Next() {
 	}()*/
 	s.skipWhitespace()
 	//fmt.Printf("Next: s.r=%v (%s) s.off=%d\n", s.r, string(s.r), s.off)
+	s.tokOff = s.Offset
+	s.tokLine = s.Line
+	s.tokCol = s.Column + 1
 
 	wasSemi := s.semi
 	s.semi = false
@@ -700,6 +716,8 @@ func (s *Scanner) Next() {
 		default:
 			s.Token = token.Not
 		}
+	case '~':
+		s.Token = token.Tilde
 	default:
 		s.Token = token.Unknown
 		s.Literal = string(r)
@@ -140,6 +140,7 @@ var srcposWant = &syntax.File{
 											Column:   int16(9),
 										},
 										Value: big.NewInt(41),
+										Raw:   "41",
 									},
 									Right: &expr.BasicLiteral{
 										Position: src.Pos{
@@ -148,6 +149,7 @@ var srcposWant = &syntax.File{
 											Column:   int16(13),
 										},
 										Value: big.NewInt(1),
+										Raw:   "1",
 									},
 								},
 							},
@@ -182,6 +184,11 @@ var srcposWant = &syntax.File{
 								},
 							},
 						},
+						Rbrace: src.Pos{
+							Filename: "srctest.ng",
+							Line:     int32(5),
+							Column:   int16(1),
+						},
 					},
 				},
 			},
@@ -280,6 +287,11 @@ var srcposWant = &syntax.File{
 						},
 					},
 				},
+				Rbrace: src.Pos{
+					Filename: "srctest.ng",
+					Line:     int32(8),
+					Column:   int16(1),
+				},
 			},
 		},
 	},
@@ -53,6 +53,16 @@ type Index struct {
 	Indicies []Expr
 }
 
+// TableIndex indexes or slices a table, as in t[rows|cols].
+// Rows is an index or a *Slice, and is nil to select all rows.
+// Each column is an index, a column name, or a *Slice.
+type TableIndex struct {
+	Position src.Pos
+	Left     Expr
+	Rows     Expr
+	Cols     []Expr
+}
+
 type TypeAssert struct {
 	Position src.Pos
 	Left     Expr
@@ -62,16 +72,20 @@ type TypeAssert struct {
 type BasicLiteral struct {
 	Position src.Pos
 	Value    interface{} // string, *big.Int, *big.Float
+	Raw      string      // literal as written in the source, if parsed
 }
 
 type FuncLiteral struct {
 	Position        src.Pos
-	Name            string // may be empty
-	ReceiverName    string // if non-empty, this is a method
+	Doc             *src.CommentGroup // associated documentation; or nil
+	Name            string            // may be empty
+	ReceiverName    string            // if non-empty, this is a method
 	PointerReceiver bool
 	Type            *tipe.Func
 	ParamNames      []string
 	ResultNames     []string
+	ParamsGrouped   []bool      // [i] is set if the type of param i is that of i+1, as a in "a, b int"
+	ResultsGrouped  []bool      // as ParamsGrouped, for the results
 	Body            interface{} // *stmt.Block, breaking the package import cycle
 }
 
@@ -215,6 +229,7 @@ func (e *Type) expr()           {}
 func (e *Ident) expr()          {}
 func (e *Call) expr()           {}
 func (e *Index) expr()          {}
+func (e *TableIndex) expr()     {}
 func (e *TypeAssert) expr()     {}
 func (e *ShellList) expr()      {}
 func (e *ShellAndOr) expr()     {}
@@ -242,6 +257,7 @@ func (e *Ident) Pos() src.Pos          { return e.Position }
 func (e *Call) Pos() src.Pos           { return e.Position }
 func (e *Range) Pos() src.Pos          { return e.Position }
 func (e *Index) Pos() src.Pos          { return e.Position }
+func (e *TableIndex) Pos() src.Pos     { return e.Position }
 func (e *TypeAssert) Pos() src.Pos     { return e.Position }
 func (e *ShellList) Pos() src.Pos      { return e.Position }
 func (e *ShellAndOr) Pos() src.Pos     { return e.Position }
@@ -2,10 +2,14 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package src provides source code position tracking.
+// Package src provides source code position tracking and
+// the comments found in a source file.
 package src
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Pos is a position in a source file.
 type Pos struct {
@@ -24,3 +28,59 @@ func (p Pos) String() string {
 		return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
 	}
 }
+
+// A Comment is a single // or /* */ comment.
+type Comment struct {
+	Position Pos
+	Text     string // comment text, including the comment markers
+}
+
+// A CommentGroup is a sequence of comments with no blank
+// lines or other tokens between them.
+type CommentGroup struct {
+	List []*Comment
+}
+
+func (c *Comment) Pos() Pos { return c.Position }
+
+func (g *CommentGroup) Pos() Pos { return g.List[0].Position }
+
+// End returns the position of the last line of the group.
+func (g *CommentGroup) End() Pos {
+	last := g.List[len(g.List)-1]
+	end := last.Position
+	end.Line += int32(strings.Count(last.Text, "\n"))
+	return end
+}
+
+// Text returns the text of the comment group without the comment
+// markers, leading space on // comments, or leading and trailing
+// blank lines. Lines are separated and terminated by newlines.
+func (g *CommentGroup) Text() string {
+	if g == nil {
+		return ""
+	}
+	var lines []string
+	for _, c := range g.List {
+		text := c.Text
+		if strings.HasPrefix(text, "//") {
+			text = strings.TrimPrefix(text[2:], " ")
+			lines = append(lines, text)
+			continue
+		}
+		text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, strings.TrimRight(line, " \t"))
+		}
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -30,17 +30,20 @@ type ImportSet struct {
 
 type TypeDecl struct {
 	Position src.Pos
+	Doc      *src.CommentGroup // associated documentation; or nil
 	Name     string
 	Type     *tipe.Named
 }
 
 type TypeDeclSet struct {
 	Position  src.Pos
+	Doc       *src.CommentGroup // associated documentation; or nil
 	TypeDecls []*TypeDecl
 }
 
 type MethodikDecl struct {
 	Position src.Pos
+	Doc      *src.CommentGroup // associated documentation; or nil
 	Name     string
 	Type     *tipe.Named
 	Methods  []*expr.FuncLiteral
@@ -50,6 +53,7 @@ type MethodikDecl struct {
 
 type Const struct {
 	Position src.Pos
+	Doc      *src.CommentGroup // associated documentation; or nil
 	NameList []string
 	Type     tipe.Type
 	Values   []expr.Expr
@@ -57,16 +61,19 @@ type Const struct {
 
 type ConstSet struct {
 	Position src.Pos
+	Doc      *src.CommentGroup // associated documentation; or nil
 	Consts   []*Const
 }
 
 type VarSet struct {
 	Position src.Pos
+	Doc      *src.CommentGroup // associated documentation; or nil
 	Vars     []*Var
 }
 
 type Var struct {
 	Position src.Pos
+	Doc      *src.CommentGroup // associated documentation; or nil
 	NameList []string
 	Type     tipe.Type
 	Values   []expr.Expr
@@ -82,6 +89,7 @@ type Assign struct {
 type Block struct {
 	Position src.Pos
 	Stmts    []Stmt
+	Rbrace   src.Pos // position of the closing brace, if any
 }
 
 type If struct {
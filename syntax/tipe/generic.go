@@ -0,0 +1,269 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tipe
+
+import "sync"
+
+// Union returns the type set terms of the constraint of t. It is
+// nil if the constraint permits all types.
+func (t *TypeParam) Union() []Term {
+	if iface, isIface := Underlying(t.Constraint).(*Interface); isIface {
+		return iface.Union
+	}
+	return nil
+}
+
+// AllTerms reports whether the type set of t is restricted by a
+// union whose every term satisfies pred.
+func (t *TypeParam) AllTerms(pred func(Type) bool) bool {
+	union := t.Union()
+	if len(union) == 0 {
+		return false
+	}
+	for _, term := range union {
+		if !pred(term.Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// Satisfies reports whether the type t is in the type set of the
+// union terms. It does not consider methods.
+func Satisfies(t Type, union []Term) bool {
+	if len(union) == 0 {
+		return true
+	}
+	if tp, isParam := Unalias(t).(*TypeParam); isParam {
+		return tp.AllTerms(func(t Type) bool { return Satisfies(t, union) })
+	}
+	for _, term := range union {
+		if Equal(t, term.Type) {
+			return true
+		}
+		if term.Tilde && Equal(Underlying(t), Underlying(term.Type)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGeneric reports whether t is a generic function or named type
+// that must be instantiated before use.
+func IsGeneric(t Type) bool {
+	switch t := t.(type) {
+	case *Func:
+		return len(t.TypeParams) > 0
+	case *Named:
+		return len(t.TypeParams) > 0 && t.Origin == nil
+	}
+	return false
+}
+
+var instMu sync.Mutex
+
+// Instantiate returns the instance of the generic named type n with
+// the type arguments args. Identical instantiations return the same
+// *Named, so recursive generic types terminate. Instantiating n
+// with its own type parameters returns n.
+func Instantiate(n *Named, args []Type) *Named {
+	own := len(args) == len(n.TypeParams)
+	for i := 0; own && i < len(args); i++ {
+		own = args[i] == Type(n.TypeParams[i])
+	}
+	if own {
+		return n
+	}
+	instMu.Lock()
+	for _, inst := range n.instances {
+		same := true
+		for i, arg := range inst.TypeArgs {
+			if !Equal(arg, args[i]) {
+				same = false
+				break
+			}
+		}
+		if same {
+			instMu.Unlock()
+			return inst
+		}
+	}
+	inst := &Named{
+		Spec:        n.Spec,
+		PkgName:     n.PkgName,
+		PkgPath:     n.PkgPath,
+		Name:        n.Name,
+		MethodNames: n.MethodNames,
+		PointerRecv: n.PointerRecv,
+		Origin:      n,
+		TypeArgs:    args,
+	}
+	n.instances = append(n.instances, inst)
+	instMu.Unlock()
+
+	m := make(map[*TypeParam]Type, len(args))
+	for i, tp := range n.TypeParams {
+		m[tp] = args[i]
+	}
+	inst.Type = Subst(n.Type, m)
+	for _, f := range n.Methods {
+		inst.Methods = append(inst.Methods, Subst(f, m).(*Func))
+	}
+	return inst
+}
+
+// Subst returns t with each type parameter that is a key of m
+// replaced by its type argument. Parts of t that do not refer to the
+// type parameters are shared, not copied.
+func Subst(t Type, m map[*TypeParam]Type) Type {
+	if len(m) == 0 || t == nil {
+		return t
+	}
+	s := substituter{m: m, seen: make(map[Type]bool)}
+	return s.subst(t)
+}
+
+type substituter struct {
+	m    map[*TypeParam]Type
+	seen map[Type]bool // named types being walked, to stop cycles
+}
+
+func (s *substituter) subst(t Type) Type {
+	switch t := t.(type) {
+	case *TypeParam:
+		if arg, ok := s.m[t]; ok {
+			return arg
+		}
+		return t
+	case *Named:
+		if t.Origin == nil {
+			// A generic type used inside its own declaration,
+			// such as the receiver of a method, stands for
+			// the instance with its own type parameters.
+			var args []Type
+			for _, tp := range t.TypeParams {
+				arg, ok := s.m[tp]
+				if !ok {
+					return t
+				}
+				args = append(args, arg)
+			}
+			if args == nil {
+				return t
+			}
+			return Instantiate(t, args)
+		}
+		var args []Type
+		changed := false
+		for _, arg := range t.TypeArgs {
+			a := s.subst(arg)
+			changed = changed || a != arg
+			args = append(args, a)
+		}
+		if !changed {
+			return t
+		}
+		return Instantiate(t.Origin, args)
+	case *Func:
+		if t == nil {
+			return t
+		}
+		var tparams []*TypeParam
+		for _, tp := range t.TypeParams {
+			if _, ok := s.m[tp]; !ok {
+				tparams = append(tparams, tp)
+			}
+		}
+		params := s.subst(t.Params).(*Tuple)
+		results := s.subst(t.Results).(*Tuple)
+		if params == t.Params && results == t.Results && len(tparams) == len(t.TypeParams) {
+			return t
+		}
+		return &Func{
+			Spec:       t.Spec,
+			TypeParams: tparams,
+			Params:     params,
+			Results:    results,
+			Variadic:   t.Variadic,
+			FreeVars:   t.FreeVars,
+			FreeMdik:   t.FreeMdik,
+		}
+	case *Tuple:
+		if t == nil {
+			return t
+		}
+		var elems []Type
+		changed := false
+		for _, elem := range t.Elems {
+			e := s.subst(elem)
+			changed = changed || e != elem
+			elems = append(elems, e)
+		}
+		if !changed {
+			return t
+		}
+		return &Tuple{Elems: elems}
+	case *Struct:
+		var fields []StructField
+		changed := false
+		for _, sf := range t.Fields {
+			ft := s.subst(sf.Type)
+			changed = changed || ft != sf.Type
+			sf.Type = ft
+			fields = append(fields, sf)
+		}
+		if !changed {
+			return t
+		}
+		return &Struct{Spec: t.Spec, Fields: fields}
+	case *Interface:
+		changed := false
+		methods := make(map[string]*Func, len(t.Methods))
+		for name, f := range t.Methods {
+			methods[name] = s.subst(f).(*Func)
+			changed = changed || methods[name] != f
+		}
+		var union []Term
+		for _, term := range t.Union {
+			tt := s.subst(term.Type)
+			changed = changed || tt != term.Type
+			union = append(union, Term{Tilde: term.Tilde, Type: tt})
+		}
+		if !changed {
+			return t
+		}
+		return &Interface{Methods: methods, Union: union}
+	case *Pointer:
+		if elem := s.subst(t.Elem); elem != t.Elem {
+			return &Pointer{Elem: elem}
+		}
+	case *Slice:
+		if elem := s.subst(t.Elem); elem != t.Elem {
+			return &Slice{Elem: elem}
+		}
+	case *Array:
+		if elem := s.subst(t.Elem); elem != t.Elem {
+			return &Array{Len: t.Len, Elem: elem, Ellipsis: t.Ellipsis}
+		}
+	case *Ellipsis:
+		if elem := s.subst(t.Elem); elem != t.Elem {
+			return &Ellipsis{Elem: elem}
+		}
+	case *Table:
+		if elem := s.subst(t.Type); elem != t.Type {
+			return &Table{Type: elem}
+		}
+	case *Chan:
+		if elem := s.subst(t.Elem); elem != t.Elem {
+			return &Chan{Direction: t.Direction, Elem: elem}
+		}
+	case *Map:
+		key, value := s.subst(t.Key), s.subst(t.Value)
+		if key != t.Key || value != t.Value {
+			return &Map{Key: key, Value: value}
+		}
+	}
+	return t
+}
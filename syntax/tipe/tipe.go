@@ -18,12 +18,13 @@ type Type interface {
 }
 
 type Func struct {
-	Spec     Specialization
-	Params   *Tuple
-	Results  *Tuple
-	Variadic bool // last value of Params is a slice
-	FreeVars []string
-	FreeMdik []*Named
+	Spec       Specialization
+	TypeParams []*TypeParam // type parameters of a generic function
+	Params     *Tuple
+	Results    *Tuple
+	Variadic   bool // last value of Params is a slice
+	FreeVars   []string
+	FreeMdik   []*Named
 }
 
 type Struct struct {
@@ -53,6 +54,10 @@ type StructTag string
 //
 // As in Go, a named type has an underlying type.
 // A named type can also have methods associated with it.
+//
+// A generic named type has TypeParams. Each instance of it, such as
+// List[int], is a distinct Named with Origin set to the generic type
+// and TypeArgs holding the type arguments.
 type Named struct {
 	// TODO: need to track the definition package so the evaluator can
 	// extract the mscope from the right place. Is this the only
@@ -66,6 +71,24 @@ type Named struct {
 
 	MethodNames []string
 	Methods     []*Func
+	PointerRecv map[string]bool // methods with pointer receivers
+
+	TypeParams []*TypeParam
+	Origin     *Named
+	TypeArgs   []Type
+
+	instances []*Named // instances of a generic type, see Instantiate
+}
+
+// TypeParam is a type parameter of a generic function or named type,
+// such as T in:
+//
+//	func Map[T, U any](xs []T, f func(T) U) []U
+//
+// Type parameters are compared by identity.
+type TypeParam struct {
+	Name       string
+	Constraint Type // constraint interface; nil means any
 }
 
 type Ellipsis struct {
@@ -120,6 +143,14 @@ type Package struct {
 
 type Interface struct {
 	Methods map[string]*Func
+	Union   []Term // type set of a constraint, such as ~int | string; nil means all types
+}
+
+// Term is a term of the union in a constraint interface. A term
+// with Tilde set stands for all types whose underlying type is Type.
+type Term struct {
+	Tilde bool
+	Type  Type
 }
 
 type Alias struct {
@@ -130,8 +161,44 @@ type Alias struct {
 var (
 	Byte = &Alias{Name: "byte", Type: Uint8}
 	Rune = &Alias{Name: "rune", Type: Int32}
+	Any  = &Alias{Name: "any", Type: &Interface{Methods: map[string]*Func{}}}
+
+	// Comparable is the predeclared constraint satisfied by
+	// all types that support == and !=.
+	Comparable = &Named{Name: "comparable", Type: &Interface{Methods: map[string]*Func{}}}
+
+	// Error is the predeclared error interface.
+	Error = &Named{
+		Name: "error",
+		Type: &Interface{
+			Methods: map[string]*Func{
+				"Error": {Results: &Tuple{Elems: []Type{String}}},
+			},
+		},
+	}
 )
 
+// TableMethods is the method set of tables. A table is evaluated as
+// a two-dimensional frame, so it satisfies the frame.Frame interface.
+var TableMethods = map[string]*Func{
+	"Cols": {
+		Params:  &Tuple{},
+		Results: &Tuple{Elems: []Type{&Slice{Elem: String}}},
+	},
+	"Get": {
+		Params: &Tuple{Elems: []Type{
+			Int, Int,
+			&Slice{Elem: &Interface{Methods: map[string]*Func{}}},
+		}},
+		Results:  &Tuple{Elems: []Type{Error}},
+		Variadic: true,
+	},
+	"Len": {
+		Params:  &Tuple{},
+		Results: &Tuple{Elems: []Type{Int, Error}},
+	},
+}
+
 // Specialization carries any type specialization data particular to this type.
 //
 // *Func, *Struct, *Named can be parameterized over the name num, which can
@@ -202,6 +269,7 @@ const (
 	Imag        Builtin = "builtin imag"
 	Len         Builtin = "builtin len"
 	Make        Builtin = "builtin make"
+	MatMul      Builtin = "builtin matmul"
 	New         Builtin = "builtin new"
 	Panic       Builtin = "builtin panic"
 	Real        Builtin = "builtin real"
@@ -210,8 +278,9 @@ const (
 )
 
 type Unresolved struct {
-	Package string
-	Name    string
+	Package  string
+	Name     string
+	TypeArgs []Type // type arguments of an instantiated generic type
 }
 
 var (
@@ -232,6 +301,7 @@ var (
 	_ = Type((*Interface)(nil))
 	_ = Type((*Alias)(nil))
 	_ = Type((*Unresolved)(nil))
+	_ = Type((*TypeParam)(nil))
 )
 
 func (t Basic) tipe()       {}
@@ -251,9 +321,13 @@ func (t *Package) tipe()    {}
 func (t *Interface) tipe()  {}
 func (t *Alias) tipe()      {}
 func (t *Unresolved) tipe() {}
+func (t *TypeParam) tipe()  {}
 
 func IsNumeric(t Type) bool {
 	t = Unalias(t)
+	if tp, isParam := t.(*TypeParam); isParam {
+		return tp.AllTerms(IsNumeric)
+	}
 	b, ok := Underlying(t).(Basic)
 	if !ok {
 		return false
@@ -358,6 +432,23 @@ type equaler struct {
 	matchUnresolved bool
 }
 
+// equalTypeParams reports whether two type parameter lists match.
+// Outside of parse trees a type parameter is identical only to
+// itself, which the types using it already check.
+func (eq *equaler) equalTypeParams(x, y []*TypeParam) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	if eq.matchUnresolved {
+		for i := range x {
+			if !eq.equal(x[i], y[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func (eq *equaler) equal(x, y Type) bool {
 	x, y = Unalias(x), Unalias(y)
 	if x == y {
@@ -395,6 +486,9 @@ func (eq *equaler) equal(x, y Type) bool {
 		if x.Spec != y.Spec {
 			return false
 		}
+		if !eq.equalTypeParams(x.TypeParams, y.TypeParams) {
+			return false
+		}
 		if !eq.equal(x.Params, y.Params) {
 			return false
 		}
@@ -442,6 +536,20 @@ func (eq *equaler) equal(x, y Type) bool {
 		if x.Spec != y.Spec {
 			return false
 		}
+		if x.Origin != nil || y.Origin != nil {
+			if x.Origin != y.Origin || len(x.TypeArgs) != len(y.TypeArgs) {
+				return false
+			}
+			for i := range x.TypeArgs {
+				if !eq.equal(x.TypeArgs[i], y.TypeArgs[i]) {
+					return false
+				}
+			}
+			return true
+		}
+		if !eq.equalTypeParams(x.TypeParams, y.TypeParams) {
+			return false
+		}
 		if !eq.equal(x.Type, y.Type) {
 			return false
 		}
@@ -551,7 +659,7 @@ func (eq *equaler) equal(x, y Type) bool {
 		if x == nil || y == nil {
 			return false
 		}
-		if len(x.Methods) != len(y.Methods) {
+		if len(x.Methods) != len(y.Methods) || len(x.Union) != len(y.Union) {
 			return false
 		}
 		for xn, xt := range x.Methods {
@@ -563,7 +671,19 @@ func (eq *equaler) equal(x, y Type) bool {
 				return false
 			}
 		}
+		for i := range x.Union {
+			if x.Union[i].Tilde != y.Union[i].Tilde || !eq.equal(x.Union[i].Type, y.Union[i].Type) {
+				return false
+			}
+		}
 		return true
+	case *TypeParam:
+		if eq.matchUnresolved {
+			// Parsed type parameters are compared by declaration.
+			y, ok := y.(*TypeParam)
+			return ok && x.Name == y.Name && eq.equal(x.Constraint, y.Constraint)
+		}
+		return false // type parameters are identical only to themselves
 	case *Pointer:
 		y, ok := y.(*Pointer)
 		if !ok {
@@ -608,9 +728,14 @@ func (eq *equaler) equal(x, y Type) bool {
 		if x == nil || y == nil {
 			return false
 		}
-		if x.Name != y.Name {
+		if x.Name != y.Name || len(x.TypeArgs) != len(y.TypeArgs) {
 			return false
 		}
+		for i := range x.TypeArgs {
+			if !eq.equal(x.TypeArgs[i], y.TypeArgs[i]) {
+				return false
+			}
+		}
 		return true
 	}
 	panic(fmt.Sprintf("tipe.Equal TODO %T\n", x))
@@ -660,7 +785,7 @@ func (m *Memory) Methods(t Type) ([]string, []Type) { // TODO: ([]string, []*Fun
 		return names, m.methods[t]
 	}
 	methodset := make(map[string]Type)
-	methods(t, methodset, 0)
+	methods(t, methodset)
 
 	for name := range methodset {
 		names = append(names, name)
@@ -687,27 +812,193 @@ func (m *Memory) Method(t Type, name string) *Func {
 	return nil
 }
 
-func methods(t Type, methodset map[string]Type, pointersRemoved int) {
+// methods adds the method set of t, including methods promoted
+// through embedded fields, to methodset.
+func methods(t Type, methodset map[string]Type) {
+	names := make(map[string]bool)
+	methodNames(t, names, make(map[*Named]bool))
+	for name := range names {
+		if PointerMethod(t, name) {
+			continue
+		}
+		if mt, _, isMethod, _ := LookupFieldOrMethod(t, name); isMethod {
+			methodset[name] = mt
+		}
+	}
+}
+
+// PointerMethod reports whether t, which is not a pointer type, has
+// a method called name with a pointer receiver. Such a method is not
+// in the method set of t, unless it is promoted through an embedded
+// pointer.
+func PointerMethod(t Type, name string) bool {
 	t = Unalias(t)
-	switch t := t.(type) {
-	case *Pointer:
-		if pointersRemoved < 1 {
-			methods(t.Elem, methodset, pointersRemoved+1)
+	if _, isPtr := t.(*Pointer); isPtr {
+		return false
+	}
+	_, index, isMethod, _ := LookupFieldOrMethod(t, name)
+	if !isMethod {
+		return false
+	}
+	for _, i := range index {
+		t = Unalias(Underlying(t).(*Struct).Fields[i].Type)
+		if _, isPtr := t.(*Pointer); isPtr {
+			return false
+		}
+	}
+	named, isNamed := t.(*Named)
+	return isNamed && named.PointerRecv[name]
+}
+
+// methodNames collects the names of all methods that may be
+// promoted to t.
+func methodNames(t Type, names map[string]bool, seen map[*Named]bool) {
+	t = Unalias(t)
+	if ptr, isPtr := t.(*Pointer); isPtr {
+		t = Unalias(ptr.Elem)
+	}
+	if named, isNamed := t.(*Named); isNamed {
+		if seen[named] {
+			return
 		}
+		seen[named] = true
+		for _, name := range named.MethodNames {
+			names[name] = true
+		}
+		t = Underlying(named)
+	}
+	switch t := t.(type) {
 	case *Interface:
-		for name, typ := range t.Methods {
-			if methodset[name] != nil {
-				continue
+		for name := range t.Methods {
+			names[name] = true
+		}
+	case *Table:
+		for name := range TableMethods {
+			names[name] = true
+		}
+	case *Struct:
+		for _, sf := range t.Fields {
+			if sf.Embedded {
+				methodNames(sf.Type, names, seen)
 			}
-			methodset[name] = typ
 		}
-	case *Named:
-		for i, name := range t.MethodNames {
-			if methodset[name] != nil {
+	}
+}
+
+// embeddedType is a type reached through embedded fields during
+// a LookupFieldOrMethod search.
+type embeddedType struct {
+	typ       Type
+	index     []int // field indices leading to typ
+	multiples bool  // typ is reachable by more than one path at this depth
+}
+
+// LookupFieldOrMethod looks up the field or method called name in t,
+// following the Go rules for promotion through embedded fields: a
+// member found at a shallower depth hides members of the same name
+// further down, and two members found at the same depth make the
+// selector ambiguous.
+//
+// The index is the sequence of field indices leading to the member.
+// For a field, its last entry is the field itself. For a method, it
+// leads to the embedded value the method is called on.
+//
+// If t has no such member, mt is nil. If the selector is ambiguous,
+// mt is nil and ambiguous is true.
+func LookupFieldOrMethod(t Type, name string) (mt Type, index []int, isMethod, ambiguous bool) {
+	t = Unalias(t)
+	if ptr, isPtr := t.(*Pointer); isPtr {
+		t = ptr.Elem
+	}
+	current := []embeddedType{{typ: t}}
+	seen := make(map[*Named]bool)
+	for len(current) > 0 {
+		var next []embeddedType
+		found := 0
+		for _, e := range current {
+			typ := Unalias(e.typ)
+			if named, isNamed := typ.(*Named); isNamed {
+				if seen[named] {
+					continue
+				}
+				seen[named] = true
+				for i, mname := range named.MethodNames {
+					if mname == name {
+						found++
+						if e.multiples {
+							found++
+						}
+						mt, index, isMethod = named.Methods[i], e.index, true
+					}
+				}
+				typ = Underlying(named)
+			}
+			switch typ := typ.(type) {
+			case *Interface:
+				if m := typ.Methods[name]; m != nil {
+					found++
+					if e.multiples {
+						found++
+					}
+					mt, index, isMethod = m, e.index, true
+				}
+			case *Table:
+				if m := TableMethods[name]; m != nil {
+					return m, e.index, true, false
+				}
+			case *Struct:
+				for i, sf := range typ.Fields {
+					fieldIndex := append(append([]int(nil), e.index...), i)
+					if sf.Name == name {
+						found++
+						if e.multiples {
+							found++
+						}
+						mt, index, isMethod = sf.Type, fieldIndex, false
+					}
+					if sf.Embedded {
+						next = append(next, embeddedType{
+							typ:       unpointer(sf.Type),
+							index:     fieldIndex,
+							multiples: e.multiples,
+						})
+					}
+				}
+			}
+		}
+		switch {
+		case found == 1:
+			return mt, index, isMethod, false
+		case found > 1:
+			return nil, nil, false, true
+		}
+		current = consolidateMultiples(next)
+	}
+	return nil, nil, false, false
+}
+
+// consolidateMultiples merges the entries of list that refer to the
+// same named type, marking them as reachable by multiple paths.
+func consolidateMultiples(list []embeddedType) []embeddedType {
+	res := list[:0]
+	pos := make(map[*Named]int)
+	for _, e := range list {
+		if named, isNamed := e.typ.(*Named); isNamed {
+			if i, exists := pos[named]; exists {
+				res[i].multiples = true
 				continue
 			}
-			methodset[name] = t.Methods[i]
+			pos[named] = len(res)
 		}
-		methods(t.Type, methodset, pointersRemoved)
+		res = append(res, e)
 	}
+	return res
+}
+
+func unpointer(t Type) Type {
+	t = Unalias(t)
+	if ptr, isPtr := t.(*Pointer); isPtr {
+		return Unalias(ptr.Elem)
+	}
+	return t
 }
@@ -53,6 +53,7 @@ const (
 	TwoLess      // <<
 	ChanOp       // <-
 	Ellipsis     // ...
+	Tilde        // ~
 
 	// Statement Operators
 
@@ -154,6 +155,7 @@ var tokens = map[string]Token{
 	"<<":           TwoLess,
 	"<-":           ChanOp,
 	"...":          Ellipsis,
+	"~":            Tilde,
 	"++":           Inc,
 	"--":           Dec,
 	"+=":           AddAssign,
@@ -9,6 +9,7 @@ import (
 	"reflect"
 
 	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/src"
 	"neugram.io/ng/syntax/stmt"
 )
 
@@ -19,6 +20,9 @@ import (
 //
 // If a postFn is provided it is called for each node after its children
 // are traversed. TODO: If a postFn returns false traversal ends.
+//
+// Doc comments attached to declarations are visited as *src.CommentGroup
+// nodes. The comments in File.Comments are not visited separately.
 func Walk(root Node, preFn, postFn WalkFunc) (result Node) {
 	type rootNode struct {
 		Node
@@ -92,24 +96,30 @@ func (w *walker) walk(parent, node Node, fieldName string, iter *iterator) {
 		w.walkSlice(node, "Imports")
 
 	case *stmt.TypeDecl:
-		// done
+		w.walk(node, node.Doc, "Doc", nil)
 
 	case *stmt.TypeDeclSet:
+		w.walk(node, node.Doc, "Doc", nil)
 		w.walkSlice(node, "TypeDecls")
 
 	case *stmt.MethodikDecl:
+		w.walk(node, node.Doc, "Doc", nil)
 		w.walkSlice(node, "Methods")
 
 	case *stmt.Const:
+		w.walk(node, node.Doc, "Doc", nil)
 		w.walkSlice(node, "Values")
 
 	case *stmt.ConstSet:
+		w.walk(node, node.Doc, "Doc", nil)
 		w.walkSlice(node, "Consts")
 
 	case *stmt.Var:
+		w.walk(node, node.Doc, "Doc", nil)
 		w.walkSlice(node, "Values")
 
 	case *stmt.VarSet:
+		w.walk(node, node.Doc, "Doc", nil)
 		w.walkSlice(node, "Vars")
 
 	case *stmt.Assign:
@@ -206,12 +216,18 @@ func (w *walker) walk(parent, node Node, fieldName string, iter *iterator) {
 		w.walk(node, node.Left, "Left", nil)
 		w.walkSlice(node, "Indicies")
 
+	case *expr.TableIndex:
+		w.walk(node, node.Left, "Left", nil)
+		w.walk(node, node.Rows, "Rows", nil)
+		w.walkSlice(node, "Cols")
+
 	case *expr.TypeAssert:
 		w.walk(node, node.Left, "Left", nil)
 
 	case *expr.BasicLiteral:
 
 	case *expr.FuncLiteral:
+		w.walk(node, node.Doc, "Doc", nil)
 		if body, isStmt := node.Body.(*stmt.Block); isStmt {
 			w.walk(node, body, "Body", nil)
 		}
@@ -273,6 +289,11 @@ func (w *walker) walk(parent, node Node, fieldName string, iter *iterator) {
 	case *expr.Shell:
 		w.walkSlice(node, "Cmds")
 
+	case *src.CommentGroup:
+		w.walkSlice(node, "List")
+
+	case *src.Comment:
+
 	default:
 		panic(fmt.Sprintf("syntax.Walk: unknown node (type %T)", node))
 	}
@@ -12,6 +12,7 @@ import (
 
 	"neugram.io/ng/parser"
 	"neugram.io/ng/syntax"
+	"neugram.io/ng/syntax/src"
 )
 
 func TestWalk(t *testing.T) {
@@ -67,3 +68,36 @@ func TestWalk(t *testing.T) {
 		})
 	}
 }
+
+func TestWalkComments(t *testing.T) {
+	source := `// Double doubles x.
+// It is documented.
+func Double(x int) int { return 2 * x }
+
+// T is a type.
+type T int
+`
+	p := parser.New("comments.ng", parser.ParseComments)
+	f, err := p.Parse([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, comments := 0, 0
+	preFn := func(c *syntax.Cursor) bool {
+		switch c.Node.(type) {
+		case *src.CommentGroup:
+			groups++
+			if c.Name != "Doc" {
+				t.Errorf("comment group visited as %q, want Doc", c.Name)
+			}
+		case *src.Comment:
+			comments++
+		}
+		return true
+	}
+	syntax.Walk(f, preFn, nil)
+	if groups != 2 || comments != 3 {
+		t.Errorf("Walk visited %d comment groups and %d comments, want 2 and 3", groups, comments)
+	}
+}
@@ -12,22 +12,12 @@ import (
 
 var Universe = &Scope{Objs: universeObjs}
 
-var errorType = &tipe.Named{
-	Name: "error",
-	Type: &tipe.Interface{
-		Methods: map[string]*tipe.Func{
-			"Error": {
-				Results: &tipe.Tuple{
-					Elems: []tipe.Type{tipe.String},
-				},
-			},
-		},
-	},
-}
+var errorType = tipe.Error
 
 var universeObjs = map[string]*Obj{
 	"true":  {Kind: ObjConst, Type: tipe.UntypedBool, Decl: constant.MakeBool(true)},
 	"false": {Kind: ObjConst, Type: tipe.UntypedBool, Decl: constant.MakeBool(false)},
+	"iota":  {Kind: ObjConst, Type: tipe.UntypedInteger}, // value set by Checker.checkConst
 	"nil":   {Kind: ObjVar, Type: tipe.UntypedNil},
 	"env":   {Kind: ObjVar, Type: &tipe.Map{Key: tipe.String, Value: tipe.String}},
 	"alias": {Kind: ObjVar, Type: &tipe.Map{Key: tipe.String, Value: tipe.String}},
@@ -72,6 +62,7 @@ var universeObjs = map[string]*Obj{
 	"delete":  {Kind: ObjVar, Type: tipe.Delete},
 	"len":     {Kind: ObjVar, Type: tipe.Len},
 	"make":    {Kind: ObjVar, Type: tipe.Make},
+	"matmul":  {Kind: ObjVar, Type: tipe.MatMul},
 	"new":     {Kind: ObjVar, Type: tipe.New},
 	"panic":   {Kind: ObjVar, Type: tipe.Panic},
 	"recover": {Kind: ObjVar, Type: tipe.Recover},
@@ -109,4 +100,6 @@ func init() {
 	}
 	Universe.Objs["byte"] = &Obj{Kind: ObjType, Type: tipe.Byte}
 	Universe.Objs["rune"] = &Obj{Kind: ObjType, Type: tipe.Rune}
+	Universe.Objs["any"] = &Obj{Kind: ObjType, Type: tipe.Any}
+	Universe.Objs["comparable"] = &Obj{Kind: ObjType, Type: tipe.Comparable}
 }
@@ -0,0 +1,179 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package typecheck
+
+import (
+	"strconv"
+
+	"neugram.io/ng/format"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/tipe"
+	"neugram.io/ng/syntax/token"
+)
+
+// elemType reports the element type of t if arithmetic on t is
+// applied element-wise, that is if t is a slice, array or table.
+func elemType(t tipe.Type) (elem tipe.Type, ok bool) {
+	switch t := tipe.Underlying(t).(type) {
+	case *tipe.Slice:
+		return t.Elem, true
+	case *tipe.Array:
+		return t.Elem, true
+	case *tipe.Table:
+		return t.Type, true
+	}
+	return nil, false
+}
+
+func isElemwiseOp(op token.Token) bool {
+	switch op {
+	case token.Add, token.Sub, token.Mul, token.Div:
+		return true
+	}
+	return false
+}
+
+// exprElemwise checks a binary arithmetic operation on slices,
+// arrays or tables of a numeric type. If both operands are
+// containers they must have the same type and shape. A scalar
+// operand is broadcast over every element of the other.
+func (c *Checker) exprElemwise(e *expr.Binary, left, right partial) partial {
+	invalid := partial{mode: modeInvalid, expr: e}
+	_, lok := elemType(left.typ)
+	_, rok := elemType(right.typ)
+
+	container, containerExpr := left, e.Left
+	switch {
+	case lok && rok:
+		if !tipe.Equal(left.typ, right.typ) {
+			c.errorfmt("inoperable types %s and %s", left.typ, right.typ)
+			return invalid
+		}
+		if ls, rs := shape(e.Left), shape(e.Right); ls != nil && rs != nil && !sameShape(ls, rs) {
+			c.errorfmt("invalid operation: %s (mismatched shapes %s and %s)", format.Expr(e), shapeString(ls), shapeString(rs))
+			return invalid
+		}
+	case lok:
+		c.broadcast(e, &right, left.typ)
+	case rok:
+		container, containerExpr = right, e.Right
+		c.broadcast(e, &left, right.typ)
+	}
+	if left.mode == modeInvalid || right.mode == modeInvalid {
+		return invalid
+	}
+
+	elem, _ := elemType(container.typ)
+	if !tipe.IsNumeric(elem) {
+		c.errorfmt("invalid operation: operator %s not defined on %s (type %s)", e.Op, format.Expr(containerExpr), container.typ)
+		return invalid
+	}
+	return partial{mode: modeVar, typ: container.typ, expr: e}
+}
+
+// broadcast checks that the scalar operand p can be applied to every
+// element of a container of type t.
+func (c *Checker) broadcast(e *expr.Binary, p *partial, t tipe.Type) {
+	elem, _ := elemType(t)
+	c.constrainUntyped(p, elem)
+	if p.mode == modeInvalid {
+		return
+	}
+	if !tipe.Equal(p.typ, elem) {
+		c.errorfmt("invalid operation: %s (cannot broadcast %s over %s)", format.Expr(e), p.typ, t)
+		p.mode = modeInvalid
+	}
+}
+
+// exprMatMul checks the builtin matmul(x, y), the matrix product of
+// two numeric tables. The width of x must match the height of y.
+func (c *Checker) exprMatMul(e *expr.Call) (p partial) {
+	p.expr = e
+	if len(e.Args) != 2 {
+		p.mode = modeInvalid
+		c.errorfmt("matmul takes exactly 2 arguments, got %d", len(e.Args))
+		return p
+	}
+	x, y := c.expr(e.Args[0]), c.expr(e.Args[1])
+	if x.mode == modeInvalid || y.mode == modeInvalid {
+		p.mode = modeInvalid
+		return p
+	}
+	t, isTable := tipe.Underlying(x.typ).(*tipe.Table)
+	if !isTable || !tipe.IsNumeric(t.Type) {
+		p.mode = modeInvalid
+		c.errorfmt("argument to matmul must be a numeric table, got %s (type %s)", format.Expr(e.Args[0]), x.typ)
+		return p
+	}
+	if !tipe.Equal(x.typ, y.typ) {
+		p.mode = modeInvalid
+		c.errorfmt("invalid operation: %s (mismatched types %s and %s)", format.Expr(e), x.typ, y.typ)
+		return p
+	}
+	if xs, ys := shape(e.Args[0]), shape(e.Args[1]); xs != nil && ys != nil && xs[1] != ys[0] {
+		p.mode = modeInvalid
+		c.errorfmt("invalid operation: %s (mismatched shapes %s and %s)", format.Expr(e), shapeString(xs), shapeString(ys))
+		return p
+	}
+	p.mode = modeVar
+	p.typ = x.typ
+	return p
+}
+
+// shape reports the dimensions of e when they are known before
+// evaluation, that is for literals and arithmetic on literals.
+// Tables are reported as rows, then columns. The length of an
+// array is part of its type, so arrays are not reported.
+func shape(e expr.Expr) []int {
+	switch e := e.(type) {
+	case *expr.SliceLiteral:
+		if len(e.Keys) > 0 {
+			return nil
+		}
+		return []int{len(e.Values)}
+	case *expr.TableLiteral:
+		width := len(e.ColNames)
+		if len(e.Rows) > 0 {
+			width = len(e.Rows[0])
+		}
+		return []int{len(e.Rows), width}
+	case *expr.Unary:
+		if e.Op == token.LeftParen {
+			return shape(e.Expr)
+		}
+	case *expr.Binary:
+		if !isElemwiseOp(e.Op) {
+			return nil
+		}
+		if s := shape(e.Left); s != nil {
+			return s
+		}
+		return shape(e.Right)
+	}
+	return nil
+}
+
+func sameShape(s1, s2 []int) bool {
+	if len(s1) != len(s2) {
+		return false
+	}
+	for i := range s1 {
+		if s1[i] != s2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func shapeString(s []int) string {
+	str := "["
+	for i, n := range s {
+		if i > 0 {
+			str += "|"
+		}
+		str += strconv.Itoa(n)
+	}
+	return str + "]"
+}
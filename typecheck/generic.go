@@ -0,0 +1,407 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package typecheck
+
+import (
+	"strings"
+
+	"neugram.io/ng/format"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/tipe"
+)
+
+// declareTypeParams adds the type parameters to the current scope
+// and resolves their constraints. Constraints may refer to any of
+// the type parameters, as in [S ~[]E, E any].
+func (c *Checker) declareTypeParams(tparams []*tipe.TypeParam) {
+	for _, tp := range tparams {
+		c.addObj(&Obj{
+			Name: tp.Name,
+			Kind: ObjType,
+			Type: tp,
+		})
+	}
+	for _, tp := range tparams {
+		if tp.Constraint == nil {
+			tp.Constraint = tipe.Any
+			continue
+		}
+		tp.Constraint, _ = c.resolve(tp.Constraint)
+		if _, isIface := tipe.Underlying(tipe.Unalias(tp.Constraint)).(*tipe.Interface); !isIface {
+			c.errorfmt("cannot use %s as constraint of type parameter %s", format.Type(tp.Constraint), tp.Name)
+			tp.Constraint = tipe.Any
+		}
+	}
+}
+
+// satisfies reports whether the type argument t satisfies the
+// constraint of the type parameter tp.
+func (c *Checker) satisfies(t tipe.Type, tp *tipe.TypeParam) bool {
+	constraint := tipe.Unalias(tp.Constraint)
+	if constraint == tipe.Comparable {
+		if !isComparable(t) {
+			c.errorfmt("%s does not satisfy comparable", c.typeString(t))
+			return false
+		}
+		return true
+	}
+	iface, _ := tipe.Underlying(constraint).(*tipe.Interface)
+	if iface == nil {
+		return true
+	}
+	if !tipe.Satisfies(t, iface.Union) {
+		var terms []string
+		for _, term := range iface.Union {
+			str := format.Type(term.Type)
+			if term.Tilde {
+				str = "~" + str
+			}
+			terms = append(terms, str)
+		}
+		union := strings.Join(terms, " | ")
+		name := c.typeString(constraint)
+		if _, isNamed := constraint.(*tipe.Named); !isNamed {
+			name = union
+		}
+		c.errorfmt("%s does not satisfy %s (%s missing in %s)", c.typeString(t), name, c.typeString(t), union)
+		return false
+	}
+	if tp, isParam := t.(*tipe.TypeParam); isParam {
+		t = tp.Constraint
+	}
+	if name, wrongType, ptrRecv := c.missingMethod(t, iface); name != "" {
+		reason := "missing method " + name
+		switch {
+		case wrongType:
+			reason = "wrong type for method " + name
+		case ptrRecv:
+			reason = "method " + name + " has pointer receiver"
+		}
+		c.errorfmt("%s does not satisfy %s (%s)", c.typeString(t), c.typeString(constraint), reason)
+		return false
+	}
+	return true
+}
+
+// instantiate checks the type arguments for the generic named type n
+// and returns the instance.
+func (c *Checker) instantiate(n *tipe.Named, args []tipe.Type) *tipe.Named {
+	if len(n.TypeParams) == 0 || n.Origin != nil {
+		c.errorfmt("%s is not a generic type", n.Name)
+		return nil
+	}
+	if len(args) != len(n.TypeParams) {
+		c.errorfmt("wrong number of type arguments for %s: have %d, want %d", n.Name, len(args), len(n.TypeParams))
+		return nil
+	}
+	m := make(map[*tipe.TypeParam]tipe.Type)
+	for i, tp := range n.TypeParams {
+		m[tp] = args[i]
+	}
+	for i, tp := range n.TypeParams {
+		constrained := &tipe.TypeParam{Name: tp.Name, Constraint: tipe.Subst(tp.Constraint, m)}
+		if !c.satisfies(args[i], constrained) {
+			return nil
+		}
+	}
+	return tipe.Instantiate(n, args)
+}
+
+// instantiateFunc substitutes the leading type parameters of the
+// generic function f with the type arguments args.
+func (c *Checker) instantiateFunc(f *tipe.Func, args []tipe.Type) *tipe.Func {
+	if len(args) > len(f.TypeParams) {
+		c.errorfmt("got %d type arguments but %s has %d type parameters", len(args), format.Type(f), len(f.TypeParams))
+		return nil
+	}
+	m := make(map[*tipe.TypeParam]tipe.Type)
+	for i, arg := range args {
+		m[f.TypeParams[i]] = arg
+	}
+	for i, arg := range args {
+		tp := f.TypeParams[i]
+		constrained := &tipe.TypeParam{Name: tp.Name, Constraint: tipe.Subst(tp.Constraint, m)}
+		if !c.satisfies(arg, constrained) {
+			return nil
+		}
+	}
+	return tipe.Subst(f, m).(*tipe.Func)
+}
+
+// recordTypeArgs records type arguments for the generic function
+// named by e, unwrapping any explicit instantiation such as F[int].
+// Inferred arguments follow the explicit ones, so they are
+// recorded at the offset of the first inferred parameter.
+func (c *Checker) recordTypeArgs(e expr.Expr, offset int, args []tipe.Type) {
+	for {
+		index, isIndex := e.(*expr.Index)
+		if !isIndex {
+			break
+		}
+		e = index.Left
+	}
+	prev := c.typeArgs[e]
+	if len(prev) > offset {
+		prev = prev[:offset]
+	}
+	c.typeArgs[e] = append(prev[:len(prev):len(prev)], args...)
+}
+
+// infer infers the type arguments of the generic function f from the
+// arguments of a call, and returns the instantiated signature.
+func (c *Checker) infer(e *expr.Call, f *tipe.Func, args []partial) *tipe.Func {
+	m := make(map[*tipe.TypeParam]tipe.Type)
+	for _, tp := range f.TypeParams {
+		m[tp] = nil
+	}
+	var params []tipe.Type
+	if f.Params != nil {
+		params = f.Params.Elems
+	}
+	paramType := func(i int) tipe.Type {
+		if i < len(params) {
+			t := params[i]
+			if ell, isEllipsis := t.(*tipe.Ellipsis); isEllipsis {
+				if e.Ellipsis {
+					return &tipe.Slice{Elem: ell.Elem}
+				}
+				return ell.Elem
+			}
+			return t
+		}
+		if f.Variadic && len(params) > 0 && !e.Ellipsis {
+			if ell, isEllipsis := params[len(params)-1].(*tipe.Ellipsis); isEllipsis {
+				return ell.Elem
+			}
+		}
+		return nil
+	}
+
+	// Typed arguments first, then the untyped constants fill in
+	// whatever type parameters remain with their default type.
+	for i, arg := range args {
+		pt := paramType(i)
+		if pt == nil || arg.typ == nil || isUntyped(arg.typ) {
+			continue
+		}
+		if !unify(pt, arg.typ, m) {
+			c.errorfmt("type %s of %s does not match %s", c.typeString(arg.typ), format.Expr(arg.expr), format.Type(pt))
+			return nil
+		}
+	}
+	inferCore(f.TypeParams, m)
+	for i, arg := range args {
+		tp, isParam := paramType(i).(*tipe.TypeParam)
+		if !isParam || !isUntyped(arg.typ) || tipe.IsUntypedNil(arg.typ) {
+			continue
+		}
+		if have, known := m[tp]; known && have == nil {
+			m[tp] = defaultType(arg.typ)
+		}
+	}
+	inferCore(f.TypeParams, m)
+
+	var targs []tipe.Type
+	for _, tp := range f.TypeParams {
+		if m[tp] == nil {
+			c.errorfmt("cannot infer %s in call to %s", tp.Name, format.Expr(e.Func))
+			return nil
+		}
+		targs = append(targs, m[tp])
+	}
+	inst := c.instantiateFunc(f, targs)
+	if inst != nil {
+		offset := 0
+		if index, isIndex := e.Func.(*expr.Index); isIndex {
+			offset = len(index.Indicies)
+		}
+		c.recordTypeArgs(e.Func, offset, targs)
+	}
+	return inst
+}
+
+// inferCore infers type parameters from the core types of the
+// constraints of other inferred type parameters. For example, in
+//
+//	func Sum[S ~[]E, E any](s S) E
+//
+// inferring S as []int means E is int.
+func inferCore(tparams []*tipe.TypeParam, m map[*tipe.TypeParam]tipe.Type) {
+	for changed := true; changed; {
+		changed = false
+		for _, tp := range tparams {
+			union := tp.Union()
+			if m[tp] == nil || len(union) != 1 {
+				continue
+			}
+			before := 0
+			for _, t := range m {
+				if t != nil {
+					before++
+				}
+			}
+			target := m[tp]
+			if union[0].Tilde {
+				target = tipe.Underlying(target)
+			}
+			unify(union[0].Type, target, m)
+			after := 0
+			for _, t := range m {
+				if t != nil {
+					after++
+				}
+			}
+			changed = changed || after > before
+		}
+	}
+}
+
+// unify matches the parameter type param against the argument type
+// arg, recording the type arguments of any type parameters it finds
+// in m. It reports false if the types cannot match.
+func unify(param, arg tipe.Type, m map[*tipe.TypeParam]tipe.Type) bool {
+	if tp, isParam := param.(*tipe.TypeParam); isParam {
+		have, inferring := m[tp]
+		if !inferring {
+			return tipe.Equal(param, arg)
+		}
+		if have == nil {
+			m[tp] = arg
+			return true
+		}
+		return tipe.Equal(have, arg)
+	}
+	if n, isNamed := param.(*tipe.Named); isNamed {
+		an, argNamed := arg.(*tipe.Named)
+		if n.Origin == nil || !argNamed || an.Origin != n.Origin {
+			return tipe.Equal(param, arg)
+		}
+		for i := range n.TypeArgs {
+			if !unify(n.TypeArgs[i], an.TypeArgs[i], m) {
+				return false
+			}
+		}
+		return true
+	}
+	// Unnamed parameter types match the underlying type of a
+	// named argument, as in assignability.
+	arg = tipe.Unalias(arg)
+	if _, isNamed := arg.(*tipe.Named); isNamed {
+		arg = tipe.Underlying(arg)
+	}
+	switch param := param.(type) {
+	case *tipe.Pointer:
+		a, ok := arg.(*tipe.Pointer)
+		return ok && unify(param.Elem, a.Elem, m)
+	case *tipe.Slice:
+		a, ok := arg.(*tipe.Slice)
+		return ok && unify(param.Elem, a.Elem, m)
+	case *tipe.Array:
+		a, ok := arg.(*tipe.Array)
+		return ok && a.Len == param.Len && unify(param.Elem, a.Elem, m)
+	case *tipe.Ellipsis:
+		a, ok := arg.(*tipe.Ellipsis)
+		return ok && unify(param.Elem, a.Elem, m)
+	case *tipe.Chan:
+		a, ok := arg.(*tipe.Chan)
+		return ok && unify(param.Elem, a.Elem, m)
+	case *tipe.Map:
+		a, ok := arg.(*tipe.Map)
+		return ok && unify(param.Key, a.Key, m) && unify(param.Value, a.Value, m)
+	case *tipe.Tuple:
+		a, ok := arg.(*tipe.Tuple)
+		if !ok || param == nil || a == nil {
+			return ok && (param == nil || len(param.Elems) == 0) && (a == nil || len(a.Elems) == 0)
+		}
+		if len(param.Elems) != len(a.Elems) {
+			return false
+		}
+		for i := range param.Elems {
+			if !unify(param.Elems[i], a.Elems[i], m) {
+				return false
+			}
+		}
+		return true
+	case *tipe.Func:
+		a, ok := arg.(*tipe.Func)
+		return ok && param.Variadic == a.Variadic && unify(param.Params, a.Params, m) && unify(param.Results, a.Results, m)
+	}
+	return tipe.Equal(param, arg) || tipe.Equal(tipe.Underlying(param), arg)
+}
+
+// coreType returns the underlying type of t. For a type parameter
+// whose type set has a single underlying type, such as ~[]E, it is
+// that type.
+func coreType(t tipe.Type) tipe.Type {
+	tp, isParam := tipe.Unalias(t).(*tipe.TypeParam)
+	if !isParam {
+		return tipe.Underlying(t)
+	}
+	union := tp.Union()
+	if len(union) == 0 {
+		return tp
+	}
+	core := tipe.Underlying(union[0].Type)
+	for _, term := range union[1:] {
+		if !tipe.Equal(core, tipe.Underlying(term.Type)) {
+			return tp
+		}
+	}
+	return core
+}
+
+// TypeArgs reports the type arguments that the generic function
+// named by e is instantiated with, either explicitly or by inference.
+func (c *Checker) TypeArgs(e expr.Expr) []tipe.Type {
+	c.mu.Lock()
+	args := c.typeArgs[e]
+	c.mu.Unlock()
+	return args
+}
+
+// exprInstantiate checks the explicit instantiation of a generic
+// function or type, such as Map[int, string] or List[int].
+func (c *Checker) exprInstantiate(e *expr.Index, left partial) (p partial) {
+	p.expr = e
+	var args []tipe.Type
+	for _, index := range e.Indicies {
+		t := c.exprType(index)
+		if t == nil {
+			p.mode = modeInvalid
+			return p
+		}
+		args = append(args, t)
+	}
+	switch t := left.typ.(type) {
+	case *tipe.Named:
+		if left.mode != modeTypeExpr {
+			break
+		}
+		inst := c.instantiate(t, args)
+		if inst == nil {
+			p.mode = modeInvalid
+			return p
+		}
+		p.mode = modeTypeExpr
+		p.typ = inst
+		return p
+	case *tipe.Func:
+		inst := c.instantiateFunc(t, args)
+		if inst == nil {
+			p.mode = modeInvalid
+			return p
+		}
+		c.recordTypeArgs(e, 0, args)
+		if len(inst.TypeParams) == 0 {
+			c.types[e.Left] = inst
+		}
+		p.mode = modeVar
+		p.typ = inst
+		return p
+	}
+	p.mode = modeInvalid
+	c.errorfmt("cannot index %s (type %s)", format.Expr(e.Left), c.typeString(left.typ))
+	return p
+}
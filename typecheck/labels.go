@@ -0,0 +1,267 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package typecheck
+
+import (
+	"sort"
+
+	"neugram.io/ng/syntax/stmt"
+	"neugram.io/ng/syntax/token"
+)
+
+// labelChecker validates the labels and branch statements of a
+// function body. Function literals inside the body are not walked,
+// they are checked when the typechecker reaches them.
+type labelChecker struct {
+	c            *Checker
+	topLevel     bool // checking a top-level statement, where goto is invalid
+	labels       map[string]*labelDecl
+	gotos        []gotoStmt
+	fallthroughs map[*stmt.Branch]bool // fallthrough statements ending a case
+}
+
+type labelDecl struct {
+	stmt  *stmt.Labeled
+	block *stmt.Block // block containing the labeled statement
+	index int         // index of the labeled statement in block
+	used  bool
+}
+
+type gotoStmt struct {
+	branch *stmt.Branch
+	// index holds, for the block of the goto and each of its
+	// enclosing blocks, the index of the statement that contains
+	// the goto.
+	index map[*stmt.Block]int
+}
+
+// branchTarget is a statement that break or continue may refer to.
+type branchTarget struct {
+	label string
+	loop  bool // for and range statements, targets of continue
+}
+
+// checkLabels checks the branch statements of body. Labels are
+// scoped to a function body, or to a single top-level statement.
+// Top-level statements are run one at a time, so goto can only be
+// used in function bodies.
+func (c *Checker) checkLabels(body *stmt.Block, topLevel bool) {
+	l := &labelChecker{
+		c:            c,
+		topLevel:     topLevel,
+		labels:       make(map[string]*labelDecl),
+		fallthroughs: make(map[*stmt.Branch]bool),
+	}
+	l.declare(body)
+	l.block(body, nil, nil)
+	for _, g := range l.gotos {
+		l.checkGoto(g)
+	}
+	var unused []string
+	for name, decl := range l.labels {
+		if !decl.used {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	for _, name := range unused {
+		if topLevel {
+			c.errorfmt("label %s defined and not used (goto is only allowed in function bodies)", name)
+			continue
+		}
+		c.errorfmt("label %s defined and not used", name)
+	}
+}
+
+// declare records the labels declared in b and its nested blocks.
+func (l *labelChecker) declare(b *stmt.Block) {
+	for i, s := range b.Stmts {
+		for {
+			ls, isLabeled := s.(*stmt.Labeled)
+			if !isLabeled {
+				break
+			}
+			if l.labels[ls.Label] != nil {
+				l.c.errorfmt("label %s already defined", ls.Label)
+			} else {
+				l.labels[ls.Label] = &labelDecl{stmt: ls, block: b, index: i}
+			}
+			s = ls.Stmt
+		}
+		for _, inner := range innerBlocks(s) {
+			l.declare(inner)
+		}
+	}
+}
+
+// innerBlocks returns the blocks directly nested in s.
+func innerBlocks(s stmt.Stmt) (blocks []*stmt.Block) {
+	switch s := s.(type) {
+	case *stmt.Block:
+		blocks = append(blocks, s)
+	case *stmt.If:
+		blocks = append(blocks, s.Body.(*stmt.Block))
+		if s.Else != nil {
+			blocks = append(blocks, innerBlocks(s.Else)...)
+		}
+	case *stmt.For:
+		blocks = append(blocks, s.Body.(*stmt.Block))
+	case *stmt.Range:
+		blocks = append(blocks, s.Body.(*stmt.Block))
+	case *stmt.Switch:
+		for _, cse := range s.Cases {
+			blocks = append(blocks, cse.Body)
+		}
+	case *stmt.TypeSwitch:
+		for _, cse := range s.Cases {
+			blocks = append(blocks, cse.Body)
+		}
+	case *stmt.Select:
+		for _, cse := range s.Cases {
+			blocks = append(blocks, cse.Body)
+		}
+	case *stmt.Labeled:
+		return innerBlocks(s.Stmt)
+	}
+	return blocks
+}
+
+// block checks the branch statements in b. The targets are the
+// enclosing statements that break and continue can refer to, and
+// index is the position of b in each of its enclosing blocks.
+func (l *labelChecker) block(b *stmt.Block, targets []branchTarget, index map[*stmt.Block]int) {
+	for i, s := range b.Stmts {
+		idx := make(map[*stmt.Block]int, len(index)+1)
+		for blk, n := range index {
+			idx[blk] = n
+		}
+		idx[b] = i
+		l.stmt(s, "", targets, idx)
+	}
+}
+
+func (l *labelChecker) stmt(s stmt.Stmt, label string, targets []branchTarget, index map[*stmt.Block]int) {
+	switch s := s.(type) {
+	case *stmt.Labeled:
+		l.stmt(s.Stmt, s.Label, targets, index)
+	case *stmt.Block:
+		l.block(s, targets, index)
+	case *stmt.If:
+		l.block(s.Body.(*stmt.Block), targets, index)
+		if s.Else != nil {
+			l.stmt(s.Else, "", targets, index)
+		}
+	case *stmt.For:
+		l.block(s.Body.(*stmt.Block), append(targets, branchTarget{label: label, loop: true}), index)
+	case *stmt.Range:
+		l.block(s.Body.(*stmt.Block), append(targets, branchTarget{label: label, loop: true}), index)
+	case *stmt.Switch:
+		targets = append(targets, branchTarget{label: label})
+		for i, cse := range s.Cases {
+			l.caseBody(cse.Body, i < len(s.Cases)-1, targets, index)
+		}
+	case *stmt.TypeSwitch:
+		targets = append(targets, branchTarget{label: label})
+		for _, cse := range s.Cases {
+			l.caseBody(cse.Body, false, targets, index)
+		}
+	case *stmt.Select:
+		targets = append(targets, branchTarget{label: label})
+		for _, cse := range s.Cases {
+			l.block(cse.Body, targets, index)
+		}
+	case *stmt.Branch:
+		l.branch(s, targets, index)
+	}
+}
+
+// caseBody checks the body of a switch case. A fallthrough is only
+// valid as the final statement of a case that is not the last one.
+func (l *labelChecker) caseBody(body *stmt.Block, canFallthrough bool, targets []branchTarget, index map[*stmt.Block]int) {
+	if n := len(body.Stmts); canFallthrough && n > 0 {
+		if br, isBranch := body.Stmts[n-1].(*stmt.Branch); isBranch {
+			l.fallthroughs[br] = true
+		}
+	}
+	l.block(body, targets, index)
+}
+
+func (l *labelChecker) branch(s *stmt.Branch, targets []branchTarget, index map[*stmt.Block]int) {
+	switch s.Type {
+	case token.Goto:
+		if l.topLevel {
+			l.c.errorfmt("goto %s outside function body", s.Label)
+			return
+		}
+		l.gotos = append(l.gotos, gotoStmt{branch: s, index: index})
+	case token.Fallthrough:
+		if !l.fallthroughs[s] {
+			l.c.errorfmt("fallthrough statement out of place")
+		}
+	case token.Break, token.Continue:
+		if s.Label == "" {
+			for i := len(targets) - 1; i >= 0; i-- {
+				if s.Type == token.Break || targets[i].loop {
+					return
+				}
+			}
+			if s.Type == token.Break {
+				l.c.errorfmt("break is not in a loop, switch, or select")
+			} else {
+				l.c.errorfmt("continue is not in a loop")
+			}
+			return
+		}
+		decl := l.labels[s.Label]
+		if decl == nil {
+			l.c.errorfmt("label %s not defined", s.Label)
+			return
+		}
+		decl.used = true
+		for _, t := range targets {
+			if t.label == s.Label && (s.Type == token.Break || t.loop) {
+				return
+			}
+		}
+		l.c.errorfmt("invalid %s label %s", s.Type, s.Label)
+	}
+}
+
+func (l *labelChecker) checkGoto(g gotoStmt) {
+	name := g.branch.Label
+	decl := l.labels[name]
+	if decl == nil {
+		l.c.errorfmt("label %s not defined", name)
+		return
+	}
+	decl.used = true
+
+	// The label must be in the block of the goto, or in one
+	// of the blocks enclosing it.
+	from, ok := g.index[decl.block]
+	if !ok {
+		l.c.errorfmt("goto %s jumps into block", name)
+		return
+	}
+	for i := from + 1; i < decl.index; i++ {
+		if line, isDecl := varDeclLine(decl.block.Stmts[i]); isDecl {
+			l.c.errorfmt("goto %s jumps over variable declaration at line %d", name, line)
+			return
+		}
+	}
+}
+
+// varDeclLine reports whether s declares variables and its line.
+func varDeclLine(s stmt.Stmt) (int32, bool) {
+	switch s := s.(type) {
+	case *stmt.Var, *stmt.VarSet:
+		return s.Pos().Line, true
+	case *stmt.Assign:
+		return s.Position.Line, s.Decl
+	case *stmt.Labeled:
+		return varDeclLine(s.Stmt)
+	}
+	return 0, false
+}
@@ -0,0 +1,227 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package typecheck
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"neugram.io/ng/parser"
+	"neugram.io/ng/syntax"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/stmt"
+	"neugram.io/ng/syntax/tipe"
+)
+
+// A Neugram package is a .ng file, or a directory whose name ends
+// in .ng holding several .ng files that share the package scope.
+//
+// Unlike the statements of a program, which are checked one at a
+// time, the top-level types and functions of a package are declared
+// before any of its statements are checked, so they can be used
+// before their declaration, in any file. Variables and constants are
+// still declared in order, file by file, as that is the order in
+// which the package's statements run.
+//
+// The functions named init are not declared. They run after all of
+// the top-level statements of the package, in the order of the files,
+// and are checked last.
+
+// PkgFiles reports the files of the Neugram package in filename,
+// sorted by name. Files starting with "." or "_" are ignored.
+func PkgFiles(filename string) ([]string, error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{filename}, nil
+	}
+	infos, err := ioutil.ReadDir(filename)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, ".ng") || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			continue
+		}
+		files = append(files, filepath.Join(filename, name))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .ng files in %s", filename)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// checkPkg checks the files of the package c.curPkg.
+// The file of a single file package is parsed as path.
+func (c *Checker) checkPkg(path string, filenames []string) error {
+	var files []*syntax.File
+	for _, filename := range filenames {
+		source, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		name := filename
+		if len(filenames) == 1 {
+			name = path
+		}
+		p := parser.New(name, parser.ParseComments)
+		f, err := p.Parse(source)
+		state := p.State()
+		p.Close()
+		if err != nil {
+			return err
+		}
+		switch state {
+		case parser.StateStmtPartial, parser.StateCmdPartial:
+			return fmt.Errorf("%s: unexpected EOF", filename)
+		}
+		files = append(files, f)
+	}
+	c.curPkg.Syntax = files[0]
+	c.curPkg.Files = files
+	return c.checkFiles(files)
+}
+
+// AddPkg checks files, the parsed files of a Neugram package, in the
+// current scope. Unlike the statements passed to Add, they are checked
+// as a package: their types and functions are declared first.
+// It is used to load a package into a program.
+func (c *Checker) AddPkg(files []*syntax.File) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs = c.errs[:0]
+	c.curPkg.Syntax = files[0]
+	c.curPkg.Files = files
+	return c.checkFiles(files)
+}
+
+// checkFiles checks the statements of the files of a package,
+// as described at the top of this file.
+func (c *Checker) checkFiles(files []*syntax.File) error {
+	var stmts []stmt.Stmt
+	for _, f := range files {
+		stmts = append(stmts, f.Stmts...)
+	}
+	c.declare(stmts)
+	if len(c.errs) > 0 {
+		return c.errs[0]
+	}
+	var inits []stmt.Stmt
+	for _, s := range stmts {
+		switch s := s.(type) {
+		case *stmt.Import, *stmt.ImportSet:
+			continue // checked by declare
+		case *stmt.Simple:
+			if fn, isFunc := s.Expr.(*expr.FuncLiteral); isFunc && fn.Name == "init" {
+				inits = append(inits, s)
+				continue
+			}
+		}
+		c.checkLabels(&stmt.Block{Stmts: []stmt.Stmt{s}}, true)
+		c.stmt(s, nil, nil)
+		if len(c.errs) > 0 {
+			return c.errs[0]
+		}
+	}
+	for _, s := range inits {
+		c.stmt(s, nil, nil)
+		if len(c.errs) > 0 {
+			return c.errs[0]
+		}
+	}
+	return nil
+}
+
+// declare checks the imports of a package, and declares its
+// top-level types and functions.
+func (c *Checker) declare(stmts []stmt.Stmt) {
+	for _, s := range stmts {
+		switch s := s.(type) {
+		case *stmt.Import, *stmt.ImportSet:
+			c.stmt(s, nil, nil)
+		}
+	}
+
+	var types []*tipe.Named
+	declType := func(name string, t *tipe.Named, decl interface{}) {
+		c.addObj(&Obj{
+			Name: name,
+			Kind: ObjType,
+			Type: t,
+			Decl: decl,
+		})
+		types = append(types, t)
+	}
+	for _, s := range stmts {
+		switch s := s.(type) {
+		case *stmt.TypeDecl:
+			declType(s.Name, s.Type, s)
+		case *stmt.TypeDeclSet:
+			for _, s := range s.TypeDecls {
+				declType(s.Name, s.Type, s)
+			}
+		case *stmt.MethodikDecl:
+			pointerRecvs(s.Type)
+			declType(s.Name, s.Type, s)
+		}
+	}
+	for _, t := range types {
+		c.resolve(t)
+	}
+
+	for _, s := range stmts {
+		s, isSimple := s.(*stmt.Simple)
+		if !isSimple {
+			continue
+		}
+		fn, isFunc := s.Expr.(*expr.FuncLiteral)
+		if !isFunc || fn.Name == "" || fn.Name == "init" {
+			continue
+		}
+		c.pushScope()
+		c.declareTypeParams(fn.Type.TypeParams)
+		c.resolve(fn.Type)
+		c.popScope()
+		c.addObj(&Obj{
+			Name: fn.Name,
+			Kind: ObjVar,
+			Type: fn.Type,
+			Decl: fn,
+		})
+	}
+}
+
+// declared reports whether the top-level name was declared by decl
+// before its statement was checked, see declare.
+func (c *Checker) declared(name string, decl interface{}) bool {
+	obj := c.cur.Objs[name]
+	return c.cur.Parent == Universe && obj != nil && obj.Decl == decl
+}
+
+// checkInit checks the declaration of an init function.
+func (c *Checker) checkInit(fn *expr.FuncLiteral) {
+	if c.curPkg.Files == nil {
+		// The statements of a program run as they are
+		// checked, so there is no after for init to run.
+		c.errorfmt("func init is only allowed in packages")
+		return
+	}
+	if c.cur.Parent != Universe {
+		c.errorfmt("func init must be declared at the top level")
+		return
+	}
+	if len(fn.Type.TypeParams) > 0 || len(fn.ParamNames) > 0 || (fn.Type.Results != nil && len(fn.Type.Results.Elems) > 0) {
+		c.errorfmt("func init must have no arguments and no return values")
+	}
+}
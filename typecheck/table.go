@@ -0,0 +1,139 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package typecheck
+
+import (
+	"neugram.io/ng/format"
+	"neugram.io/ng/syntax/expr"
+	"neugram.io/ng/syntax/tipe"
+)
+
+// indexTable checks t[i], which selects row i of a table as a slice,
+// and t[i:j], which selects a range of rows as a table.
+func (c *Checker) indexTable(e *expr.Index, typ tipe.Type, t *tipe.Table) (p partial) {
+	p.expr = e
+	if len(e.Indicies) != 1 {
+		p.mode = modeInvalid
+		c.errorfmt("cannot index table %s with %d indexes, use %s[rows|cols]", e.Left, len(e.Indicies), e.Left)
+		return p
+	}
+	if !c.tableIndexElem(e.Indicies[0], false) {
+		p.mode = modeInvalid
+		return p
+	}
+	p.mode = modeVar
+	if _, isSlice := e.Indicies[0].(*expr.Slice); isSlice {
+		p.typ = typ
+	} else {
+		p.typ = &tipe.Slice{Elem: t.Type}
+	}
+	return p
+}
+
+// exprTableIndex checks t[rows|cols]. Selecting a single row and a
+// single column yields an element, otherwise the result is a table.
+func (c *Checker) exprTableIndex(e *expr.TableIndex) (p partial) {
+	p.expr = e
+	left := c.expr(e.Left)
+	if left.mode == modeInvalid {
+		return left
+	}
+	t, isTable := tipe.Underlying(left.typ).(*tipe.Table)
+	if !isTable {
+		p.mode = modeInvalid
+		if e.Rows != nil && len(e.Cols) == 1 {
+			// Inside brackets a | separates rows and columns.
+			c.errorfmt("cannot table index %s (type %s), use (%s|%s) for bitwise or", e.Left, left.typ, e.Rows, e.Cols[0])
+		} else {
+			c.errorfmt("cannot table index %s (type %s)", e.Left, left.typ)
+		}
+		return p
+	}
+
+	isElem := e.Rows != nil && len(e.Cols) == 1
+	if e.Rows != nil {
+		if !c.tableIndexElem(e.Rows, false) {
+			p.mode = modeInvalid
+			return p
+		}
+		if _, isSlice := e.Rows.(*expr.Slice); isSlice {
+			isElem = false
+		}
+	}
+	for _, col := range e.Cols {
+		if !c.tableIndexElem(col, true) {
+			p.mode = modeInvalid
+			return p
+		}
+		if _, isSlice := col.(*expr.Slice); isSlice {
+			isElem = false
+		}
+	}
+
+	p.mode = modeVar
+	if isElem {
+		p.typ = t.Type
+	} else {
+		p.typ = left.typ
+	}
+	return p
+}
+
+// tableIndexElem checks a row or column index of a table, which may
+// be a slice. Columns may also be selected by name.
+func (c *Checker) tableIndexElem(e expr.Expr, isCol bool) bool {
+	if s, isSlice := e.(*expr.Slice); isSlice {
+		if s.Max != nil {
+			c.errorfmt("3-index slice of table")
+			return false
+		}
+		for _, e := range []expr.Expr{s.Low, s.High} {
+			if e == nil {
+				continue
+			}
+			p := c.expr(e)
+			c.assign(&p, tipe.Int)
+			if p.mode == modeInvalid {
+				return false
+			}
+		}
+		return true
+	}
+	p := c.expr(e)
+	if p.mode == modeInvalid {
+		return false
+	}
+	if isCol && isString(p.typ) {
+		c.assign(&p, tipe.String)
+	} else {
+		c.assign(&p, tipe.Int)
+	}
+	return p.mode != modeInvalid
+}
+
+// checkTableAssign reports whether lhs, if it indexes a table, can
+// be assigned to. Rows and sub-tables are copies, so only a single
+// element t[row|col] can be.
+func (c *Checker) checkTableAssign(lhs expr.Expr) bool {
+	var left expr.Expr
+	switch e := lhs.(type) {
+	case *expr.Index:
+		left = e.Left
+	case *expr.TableIndex:
+		left = e.Left
+	default:
+		return true
+	}
+	if _, isTable := tipe.Underlying(c.types[left]).(*tipe.Table); !isTable {
+		return true
+	}
+	if e, isTableIndex := lhs.(*expr.TableIndex); isTableIndex {
+		if _, isTable := tipe.Underlying(c.types[e]).(*tipe.Table); !isTable {
+			return true
+		}
+	}
+	c.errorfmt("cannot assign to %s (only a single table element t[row|col] can be assigned)", format.Expr(lhs))
+	return false
+}
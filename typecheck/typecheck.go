@@ -10,7 +10,7 @@ import (
 	"go/constant"
 	gotoken "go/token"
 	gotypes "go/types"
-	"io/ioutil"
+	"math"
 	"math/big"
 	"path/filepath"
 	"sort"
@@ -37,7 +37,9 @@ type Checker struct {
 	mu            *sync.Mutex
 	types         map[expr.Expr]tipe.Type      // computed type for each expression
 	consts        map[expr.Expr]constant.Value // component constant for const expressions
+	convs         map[expr.Expr]tipe.Type      // interface type each implicitly converted value flows into
 	idents        map[*expr.Ident]*Obj         // map of idents to the Obj they represent
+	typeArgs      map[expr.Expr][]tipe.Type    // type arguments of each instantiated generic func
 	pkgs          map[string]*Package          // (ng abs file path or go import path) -> pkg
 	goTypes       map[gotypes.Type]tipe.Type   // cache for the fromGoType method
 	goTypesToFill map[gotypes.Type]tipe.Type
@@ -45,6 +47,7 @@ type Checker struct {
 	importWalk    []string // in-process pkgs, used to detect cycles
 	memory        *tipe.Memory
 	resolveWalked map[*tipe.Named]bool
+	iota          constant.Value // value of iota in the const spec being checked, or nil
 
 	cur    *Scope
 	curPkg *Package
@@ -65,7 +68,9 @@ func New(initPkg string) *Checker {
 		types:         make(map[expr.Expr]tipe.Type),
 		ImportGo:      gotool.M.ImportGo,
 		consts:        make(map[expr.Expr]constant.Value),
+		convs:         make(map[expr.Expr]tipe.Type),
 		idents:        make(map[*expr.Ident]*Obj),
+		typeArgs:      make(map[expr.Expr][]tipe.Type),
 		pkgs:          make(map[string]*Package),
 		goTypes:       make(map[gotypes.Type]tipe.Type),
 		goTypesToFill: make(map[gotypes.Type]tipe.Type),
@@ -147,12 +152,22 @@ func IsError(t tipe.Type) bool {
 func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe.Type {
 	switch s := s.(type) {
 	case *stmt.ConstSet:
-		for _, v := range s.Consts {
-			c.checkConst(v)
+		// A spec with neither a type nor values repeats the
+		// type and values of the last spec that had them.
+		var last *stmt.Const
+		for i, v := range s.Consts {
+			if v.Type != nil || len(v.Values) > 0 {
+				last = v
+			}
+			if last == nil {
+				c.errorfmt("missing init expr for const declaration")
+				return nil
+			}
+			c.checkConst(v, last.Type, last.Values, int64(i))
 		}
 		return nil
 	case *stmt.Const:
-		return c.checkConst(s)
+		return c.checkConst(s, s.Type, s.Values, 0)
 	case *stmt.VarSet:
 		for _, v := range s.Vars {
 			c.checkVar(v)
@@ -167,6 +182,10 @@ func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe
 			if p.mode == modeInvalid {
 				return nil
 			}
+			if _, isFunc := p.typ.(*tipe.Func); isFunc && tipe.IsGeneric(p.typ) {
+				c.errorfmt("cannot use generic function %s without instantiation", format.Expr(rhs))
+				return nil
+			}
 			if tuple, isTuple := p.typ.(*tipe.Tuple); isTuple {
 				if len(s.Right) > 1 {
 					c.errorfmt("multiple value %s in single-value context", rhs)
@@ -243,6 +262,9 @@ func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe
 					continue
 				}
 				lhsP := c.expr(lhs)
+				if lhsP.mode == modeInvalid || !c.checkTableAssign(lhs) {
+					return nil
+				}
 				c.assign(&p, lhsP.typ)
 			}
 		}
@@ -262,7 +284,9 @@ func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe
 		}
 		if p.mode == modeFunc {
 			fn := p.expr.(*expr.FuncLiteral)
-			if fn.Name != "" {
+			if fn.Name == "init" {
+				c.checkInit(fn)
+			} else if fn.Name != "" && !c.declared(fn.Name, fn) {
 				c.addObj(&Obj{
 					Name: fn.Name,
 					Kind: ObjVar,
@@ -291,7 +315,8 @@ func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe
 			defer c.popScope()
 			c.stmt(s.Init, retType, retNames)
 		}
-		c.expr(s.Cond)
+		cond := c.expr(s.Cond)
+		c.constrainUntyped(&cond, tipe.Bool)
 		c.stmt(s.Body, retType, retNames)
 		if s.Else != nil {
 			c.stmt(s.Else, retType, retNames)
@@ -305,7 +330,8 @@ func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe
 			c.stmt(s.Init, retType, retNames)
 		}
 		if s.Cond != nil {
-			c.expr(s.Cond)
+			cond := c.expr(s.Cond)
+			c.constrainUntyped(&cond, tipe.Bool)
 		}
 		if s.Post != nil {
 			c.stmt(s.Post, retType, retNames)
@@ -318,21 +344,72 @@ func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe
 		defer c.popScope()
 
 		p := c.expr(s.Expr)
+		if p.mode == modeInvalid {
+			return nil
+		}
+		if isUntyped(p.typ) {
+			c.constrainUntyped(&p, defaultType(p.typ))
+		}
 		var kt, vt tipe.Type
-		switch t := p.typ.(type) {
+		nvars := 2 // number of iteration variables permitted
+		switch t := coreType(p.typ).(type) {
 		case *tipe.Array:
 			kt = tipe.Int
 			vt = t.Elem
 		case *tipe.Slice:
 			kt = tipe.Int
 			vt = t.Elem
+		case *tipe.Table:
+			// Ranging over a table yields its rows.
+			kt = tipe.Int
+			vt = &tipe.Slice{Elem: t.Type}
 		case *tipe.Map:
 			kt = t.Key
 			vt = t.Value
 		case *tipe.Chan:
 			kt = t.Elem
+			nvars = 1
+		case *tipe.Pointer:
+			a, isArray := tipe.Underlying(t.Elem).(*tipe.Array)
+			if !isArray {
+				c.errorfmt("cannot range over %s (type %s)", format.Expr(s.Expr), format.Type(p.typ))
+				return nil
+			}
+			kt = tipe.Int
+			vt = a.Elem
+		case *tipe.Func:
+			params, ok := rangeFuncParams(t)
+			if !ok {
+				c.errorfmt("cannot range over %s (type %s): func must be func(yield func(...) bool)", format.Expr(s.Expr), format.Type(p.typ))
+				return nil
+			}
+			nvars = len(params)
+			if nvars > 0 {
+				kt = params[0]
+			}
+			if nvars > 1 {
+				vt = params[1]
+			}
 		default:
-			c.errorfmt("TODO range over non-slice: %T", t)
+			switch {
+			case isString(t):
+				kt = tipe.Int
+				vt = tipe.Rune
+			case isInteger(t):
+				kt = p.typ
+				nvars = 1
+			default:
+				c.errorfmt("cannot range over %s (type %s)", format.Expr(s.Expr), format.Type(p.typ))
+				return nil
+			}
+		}
+		switch {
+		case s.Val != nil && nvars < 2:
+			c.errorfmt("range over %s permits only one iteration variable", format.Expr(s.Expr))
+			return nil
+		case s.Key != nil && nvars < 1:
+			c.errorfmt("range over %s permits no iteration variables", format.Expr(s.Expr))
+			return nil
 		}
 		if s.Decl {
 			if _, exists := c.types[s.Key]; s.Key != nil && !exists {
@@ -373,12 +450,14 @@ func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe
 		return nil
 
 	case *stmt.TypeDecl:
-		c.addObj(&Obj{
-			Name: s.Name,
-			Kind: ObjType,
-			Type: s.Type,
-			Decl: s,
-		})
+		if !c.declared(s.Name, s) {
+			c.addObj(&Obj{
+				Name: s.Name,
+				Kind: ObjType,
+				Type: s.Type,
+				Decl: s,
+			})
+		}
 		t, _ := c.resolve(s.Type)
 		if t.(*tipe.Named) != s.Type {
 			panic(fmt.Sprintf("resolve changed type decl: %s", s.Type.Name))
@@ -392,12 +471,15 @@ func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe
 		return nil
 
 	case *stmt.MethodikDecl:
-		c.addObj(&Obj{
-			Name: s.Name,
-			Kind: ObjType,
-			Type: s.Type,
-			Decl: s,
-		})
+		pointerRecvs(s.Type)
+		if !c.declared(s.Name, s) {
+			c.addObj(&Obj{
+				Name: s.Name,
+				Kind: ObjType,
+				Type: s.Type,
+				Decl: s,
+			})
+		}
 		t, _ := c.resolve(s.Type)
 		if t.(*tipe.Named) != s.Type {
 			panic(fmt.Sprintf("resolve changed methodik decl: %s", s.Type.Name))
@@ -408,6 +490,12 @@ func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe
 			usesNum = usesNum || tipe.UsesNum(f)
 		}
 
+		if len(s.Type.TypeParams) > 0 {
+			c.pushScope()
+			for _, tp := range s.Type.TypeParams {
+				c.addObj(&Obj{Name: tp.Name, Kind: ObjType, Type: tp})
+			}
+		}
 		for _, m := range s.Methods {
 			c.pushScope()
 			st := tipe.Type(s.Type)
@@ -440,6 +528,10 @@ func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe
 			}
 		}
 
+		if len(s.Type.TypeParams) > 0 {
+			c.popScope()
+		}
+
 		if usesNum {
 			s.Type.Spec.Num = tipe.Num
 		}
@@ -511,7 +603,13 @@ func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe
 
 		for i := range want {
 			if !c.assignable(want[i], got[i]) {
-				c.errorfmt("cannot use %s as %s in return argument", got[i], want[i])
+				if !c.errorNotImplements(got[i], want[i], "return argument") {
+					c.errorfmt("cannot use %s as %s in return argument", got[i], want[i])
+				}
+				continue
+			}
+			if len(partials) == len(want) {
+				c.markConv(partials[i].expr, got[i], want[i])
 			}
 		}
 		return nil
@@ -546,14 +644,17 @@ func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe
 		if p.mode == modeInvalid {
 			return nil
 		}
+		valType := p.typ
 		c.convert(&p, cht.Elem)
 		if p.mode == modeInvalid {
 			c.errorfmt("cannot send %s to %s", p.typ, cht)
+			return nil
 		}
+		c.markConv(p.expr, valType, cht.Elem)
 		return nil
 
 	case *stmt.Branch:
-		// TODO: make sure the branch is valid
+		// Validated by checkLabels.
 		return nil
 
 	case *stmt.Labeled:
@@ -741,47 +842,37 @@ func (c *Checker) stmt(s stmt.Stmt, retType *tipe.Tuple, retNames []string) tipe
 	}
 }
 
-func (c *Checker) checkConst(s *stmt.Const) tipe.Type {
-	if s.Type != nil {
-		if t, ok := c.resolve(s.Type); ok {
-			s.Type = t
+// checkConst checks the const spec s, whose type and values are
+// typ and values. They differ from those of s when s is an implicit
+// repetition of an earlier spec in a const group. The value of iota
+// is the index of s in its group.
+func (c *Checker) checkConst(s *stmt.Const, typ tipe.Type, values []expr.Expr, iota int64) tipe.Type {
+	if typ != nil {
+		if t, ok := c.resolve(typ); ok {
+			typ = t
+		}
+		if s.Type != nil {
+			s.Type = typ
 		}
 	}
+	c.iota = constant.MakeInt64(iota)
+	defer func() { c.iota = nil }()
+
 	var partials []partial
-	for _, rhs := range s.Values {
+	for _, rhs := range values {
 		p := c.exprNoElide(rhs)
 		if p.mode == modeInvalid {
 			return nil
 		}
-		if tuple, isTuple := p.typ.(*tipe.Tuple); isTuple {
-			if len(s.Values) > 1 {
-				c.errorfmt("multiple value %s in single-value context", rhs)
-				return nil
-			}
-			for _, t := range tuple.Elems {
-				partials = append(partials, partial{
-					mode: modeConst,
-					typ:  t,
-				})
-			}
-			continue
+		if p.mode != modeConst {
+			c.errorfmt("const initializer %s is not a constant", format.Expr(rhs))
+			return nil
 		}
-		partials = append(partials, c.exprNoElide(rhs))
+		partials = append(partials, p)
 	}
-	if len(s.Values) == 1 && len(s.NameList) == 2 && len(s.NameList) == len(partials)+1 {
-		partials = c.checkCommaOK(s.Values[0], partials)
-	}
-
-	if len(s.Values) == 1 && len(s.NameList) == len(partials)-1 && IsError(partials[len(partials)-1].typ) {
-		markElideError(s.Values[0])
-		partials = partials[:len(partials)-1]
-	}
-
 	if len(s.NameList) != len(partials) {
-		if s.Type == nil || len(partials) != 0 {
-			c.errorfmt("arity mismatch, left %d != right %d", len(s.NameList), len(partials))
-			return nil
-		}
+		c.errorfmt("arity mismatch, left %d != right %d", len(s.NameList), len(partials))
+		return nil
 	}
 
 	// make sure none of the lhs have been previously declared.
@@ -796,37 +887,36 @@ func (c *Checker) checkConst(s *stmt.Const) tipe.Type {
 	}
 
 	for i, name := range s.NameList {
-		if name == "_" {
-			continue
-		}
-		var typ tipe.Type
-		if len(partials) > i {
-			p := partials[i]
+		p := partials[i]
+		if typ != nil {
 			if isUntyped(p.typ) {
-				if s.Type != nil {
-					c.constrainUntyped(&p, s.Type)
+				c.constrainUntyped(&p, typ)
+				if p.mode == modeInvalid {
+					return nil
 				}
 			}
-			typ = p.typ
-
-			if s.Type != nil && !c.assignable(s.Type, p.typ) {
+			if !c.assignable(typ, p.typ) {
+				if c.errorNotImplements(p.typ, typ, "assignment") {
+					return nil
+				}
 				switch len(s.NameList) {
 				case 1:
-					c.errorfmt("cannot use %v (type %v) as type %v in assignment", format.Expr(s.Values[i]), format.Type(p.typ), format.Type(s.Type))
+					c.errorfmt("cannot use %v (type %v) as type %v in assignment", format.Expr(values[i]), format.Type(p.typ), format.Type(typ))
 				default:
-					c.errorfmt("cannot assign %v to %s (type %v) in multiple assignment", format.Type(p.typ), name, format.Type(s.Type))
+					c.errorfmt("cannot assign %v to %s (type %v) in multiple assignment", format.Type(p.typ), name, format.Type(typ))
 				}
 				return nil
 			}
+			p.typ = typ
 		}
-		if s.Type != nil {
-			typ = s.Type
+		if name == "_" {
+			continue
 		}
 		c.addObj(&Obj{
 			Name: name,
 			Kind: ObjConst,
-			Type: typ,
-			Decl: c.consts[s.Values[i]],
+			Type: p.typ,
+			Decl: p.val,
 		})
 	}
 	return nil
@@ -844,6 +934,10 @@ func (c *Checker) checkVar(s *stmt.Var) tipe.Type {
 		if p.mode == modeInvalid {
 			return nil
 		}
+		if _, isFunc := p.typ.(*tipe.Func); isFunc && tipe.IsGeneric(p.typ) {
+			c.errorfmt("cannot use generic function %s without instantiation", format.Expr(rhs))
+			return nil
+		}
 		if tuple, isTuple := p.typ.(*tipe.Tuple); isTuple {
 			if len(s.Values) > 1 {
 				c.errorfmt("multiple value %s in single-value context", rhs)
@@ -903,6 +997,9 @@ func (c *Checker) checkVar(s *stmt.Var) tipe.Type {
 			typ = p.typ
 
 			if s.Type != nil && !c.assignable(s.Type, p.typ) {
+				if c.errorNotImplements(p.typ, s.Type, "assignment") {
+					return nil
+				}
 				switch len(s.NameList) {
 				case 1:
 					c.errorfmt("cannot use %v (type %v) as type %v in assignment", format.Expr(s.Values[i]), format.Type(p.typ), format.Type(s.Type))
@@ -911,6 +1008,9 @@ func (c *Checker) checkVar(s *stmt.Var) tipe.Type {
 				}
 				return nil
 			}
+			if s.Type != nil {
+				c.markConv(p.expr, p.typ, s.Type)
+			}
 		}
 		if s.Type != nil {
 			typ = s.Type
@@ -929,7 +1029,7 @@ func (c *Checker) checkCommaOK(e expr.Expr, partials []partial) []partial {
 	switch e := e.(type) {
 	case *expr.Index:
 		// v, ok = m[key]
-		switch typ := c.types[e.Left].(type) {
+		switch typ := tipe.Underlying(c.types[e.Left]).(type) {
 		case *tipe.Map:
 			// the general case for a map-index is to only typecheck
 			// for returning the map-value.
@@ -1061,7 +1161,17 @@ func (c *Checker) fromGoType(t gotypes.Type) (res tipe.Type) {
 		if t.Obj().Id() == goErrorID {
 			return Universe.Objs["error"].Type
 		}
+		if t.Obj().Pkg() == nil && t.Obj().Name() == "comparable" {
+			return tipe.Comparable
+		}
 		return new(tipe.Named)
+	case *gotypes.Alias:
+		if t.Obj().Pkg() == nil && t.Obj().Name() == "any" {
+			return tipe.Any
+		}
+		return c.fromGoType(gotypes.Unalias(t))
+	case *gotypes.TypeParam:
+		return new(tipe.TypeParam)
 	case *gotypes.Array:
 		return &tipe.Array{}
 	case *gotypes.Slice:
@@ -1086,7 +1196,7 @@ func (c *Checker) fillGoType(res tipe.Type, t gotypes.Type) {
 	switch t := t.(type) {
 	case *gotypes.Basic:
 	case *gotypes.Named:
-		if t.Obj().Id() == goErrorID {
+		if t.Obj().Id() == goErrorID || t.Obj().Pkg() == nil {
 			return
 		}
 		base := c.fromGoType(t.Underlying())
@@ -1097,10 +1207,37 @@ func (c *Checker) fillGoType(res tipe.Type, t gotypes.Type) {
 			PkgName: t.Obj().Pkg().Name(),
 			PkgPath: t.Obj().Pkg().Path(),
 		}
+		if targs := t.TypeArgs(); targs.Len() > 0 {
+			// An instance of a generic Go type. go/types has
+			// already substituted the underlying type and the
+			// methods, so only the origin needs recording.
+			mdik.Origin = c.fromGoType(t.Origin()).(*tipe.Named)
+			for i := 0; i < targs.Len(); i++ {
+				mdik.TypeArgs = append(mdik.TypeArgs, c.fromGoType(targs.At(i)))
+			}
+		} else {
+			mdik.TypeParams = c.fromGoTypeParams(t.TypeParams())
+		}
 		for i := 0; i < t.NumMethods(); i++ {
 			m := t.Method(i)
 			mdik.MethodNames = append(mdik.MethodNames, m.Name())
-			mdik.Methods = append(mdik.Methods, c.fromGoType(m.Type()).(*tipe.Func))
+			if rtps := m.Type().(*gotypes.Signature).RecvTypeParams(); rtps.Len() == len(mdik.TypeParams) {
+				// The methods of a generic type declare
+				// type parameters of their own with the
+				// receiver. They stand for the type's,
+				// which instances substitute.
+				for j := 0; j < rtps.Len(); j++ {
+					c.goTypes[rtps.At(j)] = mdik.TypeParams[j]
+				}
+			}
+			fn := c.fromGoType(m.Type()).(*tipe.Func)
+			mdik.Methods = append(mdik.Methods, fn)
+			if _, isPtr := m.Type().(*gotypes.Signature).Recv().Type().(*gotypes.Pointer); isPtr {
+				if mdik.PointerRecv == nil {
+					mdik.PointerRecv = make(map[string]bool)
+				}
+				mdik.PointerRecv[m.Name()] = true
+			}
 		}
 	case *gotypes.Array:
 		a := res.(*tipe.Array)
@@ -1141,20 +1278,43 @@ func (c *Checker) fillGoType(res tipe.Type, t gotypes.Type) {
 	case *gotypes.Pointer:
 		elem := c.fromGoType(t.Elem())
 		res.(*tipe.Pointer).Elem = elem
+	case *gotypes.TypeParam:
+		*res.(*tipe.TypeParam) = tipe.TypeParam{
+			Name:       t.Obj().Name(),
+			Constraint: c.fromGoType(t.Constraint()),
+		}
 	case *gotypes.Interface:
 		mthds := make(map[string]*tipe.Func)
 		for i := 0; i < t.NumMethods(); i++ {
 			m := t.Method(i)
 			mthds[m.Name()] = c.fromGoType(m.Type()).(*tipe.Func)
 		}
-		res.(*tipe.Interface).Methods = mthds
+		iface := res.(*tipe.Interface)
+		iface.Methods = mthds
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			// Type set terms, as in constraints like cmp.Ordered.
+			// Embedded interfaces contribute their methods above.
+			switch e := t.EmbeddedType(i).(type) {
+			case *gotypes.Union:
+				for j := 0; j < e.Len(); j++ {
+					term := e.Term(j)
+					iface.Union = append(iface.Union, tipe.Term{
+						Tilde: term.Tilde(),
+						Type:  c.fromGoType(term.Type()),
+					})
+				}
+			case *gotypes.Basic, *gotypes.Slice, *gotypes.Map, *gotypes.Pointer, *gotypes.Chan, *gotypes.Array, *gotypes.Signature:
+				iface.Union = append(iface.Union, tipe.Term{Type: c.fromGoType(e)})
+			}
+		}
 	case *gotypes.Signature:
 		p := t.Params()
 		r := t.Results()
 		fn := tipe.Func{
-			Params:   &tipe.Tuple{Elems: make([]tipe.Type, p.Len())},
-			Results:  &tipe.Tuple{Elems: make([]tipe.Type, r.Len())},
-			Variadic: t.Variadic(),
+			TypeParams: c.fromGoTypeParams(t.TypeParams()),
+			Params:     &tipe.Tuple{Elems: make([]tipe.Type, p.Len())},
+			Results:    &tipe.Tuple{Elems: make([]tipe.Type, r.Len())},
+			Variadic:   t.Variadic(),
 		}
 		for i := 0; i < p.Len(); i++ {
 			fn.Params.Elems[i] = c.fromGoType(p.At(i).Type())
@@ -1166,6 +1326,17 @@ func (c *Checker) fillGoType(res tipe.Type, t gotypes.Type) {
 	}
 }
 
+func (c *Checker) fromGoTypeParams(list *gotypes.TypeParamList) []*tipe.TypeParam {
+	if list.Len() == 0 {
+		return nil
+	}
+	params := make([]*tipe.TypeParam, list.Len())
+	for i := range params {
+		params[i] = c.fromGoType(list.At(i)).(*tipe.TypeParam)
+	}
+	return params
+}
+
 func (c *Checker) goPkg(path string) (*Package, error) {
 	if pkg := c.pkgs[path]; pkg != nil {
 		return pkg, nil
@@ -1224,29 +1395,44 @@ func (c *Checker) Pkg(path string) *Package {
 	return c.pkgs[path]
 }
 
-func (c *Checker) ngPkg(path string) (*Package, error) {
-	var filename string
+// NgPkgPath resolves the import of the Neugram package path, a file
+// or directory ending in ".ng", by the file importer. It reports the file, and
+// the package path the type checker and evaluator know it by, which
+// is derived from the absolute filename.
+//
+// A path starting with "./" is relative to the directory of the
+// importer. Any other path is found by gotool.M.FindNg, which
+// searches $NGPATH and the module roots, or GOPATH.
+func NgPkgPath(path, importer string) (pkgPath, filename string, err error) {
 	if strings.HasPrefix(path, "./") {
-		filename = filepath.Join(filepath.Dir(c.importWalk[len(c.importWalk)-1]), path)
-		var err error
-		filename, err = filepath.Abs(filename)
-		if err != nil {
-			return nil, fmt.Errorf("ng package import: %v", err)
-		}
-		path = "rel" + filename
+		filename = filepath.Join(filepath.Dir(importer), path)
 	} else if filepath.IsAbs(path) {
 		filename = path
 	} else {
-		return nil, fmt.Errorf("TODO: look for .ng package in GOPATH")
+		filename, err = gotool.M.FindNg(path)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	filename, err = filepath.Abs(filename)
+	if err != nil {
+		return "", "", err
+	}
+	return "rel" + strings.TrimSuffix(filename, ".ng") + "_ng", filename, nil
+}
+
+func (c *Checker) ngPkg(path string) (*Package, error) {
+	path, filename, err := NgPkgPath(path, c.importWalk[len(c.importWalk)-1])
+	if err != nil {
+		return nil, fmt.Errorf("ng package import: %v", err)
 	}
-	path = strings.TrimSuffix(path, ".ng") + "_ng"
 	for i, p := range c.importWalk {
 		if p == filename {
 			cycle := c.importWalk[i]
 			for _, p := range c.importWalk[i+1:] {
-				cycle += "-> " + p
+				cycle += " -> " + p
 			}
-			cycle += "-> " + path
+			cycle += " -> " + filename
 			return nil, fmt.Errorf("package import cycle: %s", cycle)
 		}
 	}
@@ -1254,7 +1440,7 @@ func (c *Checker) ngPkg(path string) (*Package, error) {
 		return pkg, nil
 	}
 
-	source, err := ioutil.ReadFile(filename)
+	filenames, err := PkgFiles(filename)
 	if err != nil {
 		return nil, fmt.Errorf("ng package import: %v", err)
 	}
@@ -1279,7 +1465,7 @@ func (c *Checker) ngPkg(path string) (*Package, error) {
 		},
 		GlobalNames: make(map[string]*Obj),
 	}
-	if err := c.parseFile(path, source); err != nil {
+	if err := c.checkPkg(path, filenames); err != nil {
 		return nil, fmt.Errorf("ng import parse: %v", err)
 	}
 	for _, t := range c.curPkg.Type.Exports {
@@ -1298,24 +1484,6 @@ func isExported(name string) bool {
 	return unicode.IsUpper(ch)
 }
 
-func (c *Checker) parseFile(filename string, source []byte) error {
-	p := parser.New(filename)
-	f, err := p.Parse(source)
-	if err != nil {
-		return err
-	}
-	c.curPkg.Syntax = f
-
-	for _, s := range f.Stmts {
-		c.stmt(s, nil, nil)
-		if len(c.errs) > 0 {
-			return c.errs[0]
-		}
-	}
-
-	return nil
-}
-
 func (c *Checker) checkImport(s *stmt.Import) {
 	if strings.HasPrefix(s.Path, "/") {
 		c.errorfmt("imports do not support absolute paths: %q", s.Path)
@@ -1387,6 +1555,18 @@ func (c *Checker) exprType(e expr.Expr) tipe.Type {
 	return nil
 }
 
+// pointerRecvs records that every method of the methodik t has a
+// pointer receiver. The evaluator builds them that way: a method
+// declared with a value receiver is given the value its receiver
+// points to, and may modify it, which a copy held in an interface
+// could not provide.
+func pointerRecvs(t *tipe.Named) {
+	t.PointerRecv = make(map[string]bool)
+	for _, name := range t.MethodNames {
+		t.PointerRecv[name] = true
+	}
+}
+
 func (c *Checker) resolve(t tipe.Type) (ret tipe.Type, resolved bool) {
 	switch t := t.(type) {
 	case *tipe.Func:
@@ -1404,6 +1584,11 @@ func (c *Checker) resolve(t tipe.Type) (ret tipe.Type, resolved bool) {
 			resolved = resolved && r1
 		}
 		t.Methods = m
+		for i, term := range t.Union {
+			tt, r1 := c.resolve(term.Type)
+			t.Union[i].Type = tt
+			resolved = resolved && r1
+		}
 		return t, resolved
 	case *tipe.Map:
 		var r1, r2 bool
@@ -1415,6 +1600,11 @@ func (c *Checker) resolve(t tipe.Type) (ret tipe.Type, resolved bool) {
 			return t, true
 		}
 		c.resolveWalked[t] = true
+		if len(t.TypeParams) > 0 && t.Origin == nil {
+			c.pushScope()
+			defer c.popScope()
+			c.declareTypeParams(t.TypeParams)
+		}
 		t.Type, resolved = c.resolve(t.Type)
 		for i, f := range t.Methods {
 			f, r1 := c.resolve(f)
@@ -1465,6 +1655,30 @@ func (c *Checker) resolve(t tipe.Type) (ret tipe.Type, resolved bool) {
 		}
 		return t, resolved
 	case *tipe.Unresolved:
+		if t.TypeArgs != nil {
+			origin, resolved := c.resolve(&tipe.Unresolved{Package: t.Package, Name: t.Name})
+			if !resolved {
+				return t, false
+			}
+			n, isNamed := origin.(*tipe.Named)
+			if !isNamed {
+				c.errorfmt("%s is not a generic type", format.Type(origin))
+				return t, false
+			}
+			var args []tipe.Type
+			for _, arg := range t.TypeArgs {
+				arg, resolved := c.resolve(arg)
+				if !resolved {
+					return t, false
+				}
+				args = append(args, arg)
+			}
+			inst := c.instantiate(n, args)
+			if inst == nil {
+				return t, false
+			}
+			return inst, true
+		}
 		if t.Package != "" {
 			res := c.lookupPkgType(t.Package, t.Name)
 			if res == nil {
@@ -1499,6 +1713,7 @@ func (c *Checker) lookupPkgType(pkgName, sel string) tipe.Type {
 		c.errorfmt("%s is not a packacge", pkgName)
 		return nil
 	}
+	obj.Used = true
 	pkg := obj.Decl.(*Package)
 	res := pkg.GlobalNames[sel]
 	if res == nil || !isExported(sel) {
@@ -1735,7 +1950,7 @@ func (c *Checker) exprBuiltinCall(e *expr.Call) partial {
 		}
 		arg0 := c.expr(e.Args[0])
 		switch t := tipe.Underlying(arg0.typ).(type) {
-		case *tipe.Array, *tipe.Slice, *tipe.Map, *tipe.Chan:
+		case *tipe.Array, *tipe.Slice, *tipe.Map, *tipe.Chan, *tipe.Table:
 			return p
 		case tipe.Basic:
 			switch t {
@@ -1798,6 +2013,8 @@ func (c *Checker) exprBuiltinCall(e *expr.Call) partial {
 			c.errorfmt("make argument must be a slice, map, or channel")
 		}
 		return p
+	case tipe.MatMul:
+		return c.exprMatMul(e)
 	case tipe.New:
 		if len(e.Args) != 1 {
 			p.mode = modeInvalid
@@ -1898,7 +2115,40 @@ func (c *Checker) exprPartialCall(e *expr.Call) partial {
 
 	p.mode = modeVar
 	p.expr = e
-	funct := tipe.Underlying(p.typ).(*tipe.Func)
+	funct, isFunc := coreType(p.typ).(*tipe.Func)
+	if !isFunc {
+		p.mode = modeInvalid
+		c.errorfmt("cannot call non-function %s (type %s)", format.Expr(e.Func), c.typeString(p.typ))
+		return p
+	}
+
+	// When we have exactly one argument, the Go spec allows this
+	// to be treated as multiple arguments in a few cases, such as
+	// when calling f(g()) and g returns multiple values. Handle this.
+	// Comma-ok expressions are single-valued here: the spec only
+	// gives them a second value in assignments and initializations.
+	unpacked, ok := c.unpackExprs(hintNone, e.Args...)
+	if !ok {
+		p.mode = modeInvalid
+		return p
+	}
+
+	if len(funct.TypeParams) > 0 {
+		funct = c.infer(e, funct, unpacked)
+		if funct == nil {
+			p.mode = modeInvalid
+			return p
+		}
+		for x := e.Func; x != nil; {
+			c.types[x] = funct
+			index, isIndex := x.(*expr.Index)
+			if !isIndex {
+				break
+			}
+			x = index.Left
+		}
+	}
+
 	var params, results []tipe.Type
 	if funct.Params != nil {
 		params = funct.Params.Elems
@@ -1916,16 +2166,6 @@ func (c *Checker) exprPartialCall(e *expr.Call) partial {
 		p.typ = funct.Results
 	}
 
-	// When we have exactly one argument, the Go spec allows this
-	// to be treated as multiple arguments in a few cases, such as
-	// when calling f(g()) and g returns multiple values. Handle this.
-	// TODO: also handle the comma-ok cases.
-	unpacked, ok := c.unpackExprs(hintNone, e.Args...)
-	if !ok {
-		p.mode = modeInvalid
-		return p
-	}
-
 	// If we have f([a, b,] c...), check whether that is permissible.
 	if e.Ellipsis {
 		if !funct.Variadic {
@@ -2005,12 +2245,20 @@ func (c *Checker) exprPartialCall(e *expr.Call) partial {
 
 		// Typecheck the argument against the declared type of the
 		// matching function parameter.
+		argType := pi.typ
+		if !c.assignable(typ, pi.typ) && c.errorNotImplements(pi.typ, typ, "argument to "+format.Expr(e.Func)) {
+			p.mode = modeInvalid
+			return p
+		}
 		c.convert(&pi, typ)
 		if pi.mode == modeInvalid {
 			p.mode = modeInvalid
 			c.errorfmt("cannot use type %s as type %s in argument %d to function", pi.typ, typ, i)
 			return p
 		}
+		if pi.mode != modeUnpacked {
+			c.markConv(pi.expr, argType, typ)
+		}
 	}
 
 	// Check if we have too few arguments
@@ -2038,7 +2286,13 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 		}
 		if p.mode != modeInvalid {
 			if _, exists := c.types[p.expr]; !exists {
-				c.types[p.expr] = p.typ
+				typ := p.typ
+				if p.mode == modeVar && typ == tipe.UntypedBool {
+					// Non-constant untyped booleans, the results
+					// of comparisons, are bool values at run time.
+					typ = tipe.Bool
+				}
+				c.types[p.expr] = typ
 			}
 		}
 	}()
@@ -2066,10 +2320,21 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 			if v, ok := obj.Decl.(constant.Value); ok {
 				p.val = v
 			}
+			if obj == Universe.Objs["iota"] {
+				if c.iota == nil {
+					p.mode = modeInvalid
+					c.errorfmt("cannot use iota outside constant declaration")
+					return p
+				}
+				p.val = c.iota
+			}
 		case ObjType:
 			p.mode = modeTypeExpr
 		}
 		p.typ = obj.Type
+		if Universe.Objs[e.Name] != obj {
+			obj.Used = true // Universe is shared by all checkers
+		}
 		c.idents[e] = obj
 		return p
 	case *expr.BasicLiteral:
@@ -2090,11 +2355,11 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 		case string:
 			p.mode = modeConst
 			p.typ = tipe.UntypedString
-			p.val = constant.MakeFromLiteral(v, gotoken.STRING, 0)
+			p.val = constant.MakeString(v)
 		case rune:
 			p.mode = modeConst
 			p.typ = tipe.UntypedRune
-			p.val = constant.MakeFromLiteral(string(v), gotoken.CHAR, 0)
+			p.val = constant.MakeInt64(int64(v))
 		case bool:
 			p.mode = modeConst
 			p.typ = tipe.UntypedBool
@@ -2106,6 +2371,7 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 		defer c.popScope()
 		c.cur.foundInParent = make(map[string]bool)
 		c.cur.foundMdikInParent = make(map[*tipe.Named]bool)
+		c.declareTypeParams(e.Type.TypeParams)
 		if e.Type.Params != nil {
 			for i, t := range e.Type.Params.Elems {
 				t, _ = c.resolve(t)
@@ -2142,6 +2408,7 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 				}
 			}
 		}
+		c.checkLabels(e.Body.(*stmt.Block), false)
 		c.stmt(e.Body.(*stmt.Block), e.Type.Results, retNames)
 		for _, pname := range e.ParamNames {
 			delete(c.cur.foundInParent, pname)
@@ -2293,6 +2560,9 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 		}
 		return p
 
+	case *expr.TableIndex:
+		return c.exprTableIndex(e)
+
 	case *expr.Type:
 		if t, resolved := c.resolve(e.Type); resolved {
 			e.Type = t
@@ -2332,7 +2602,11 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 			p.typ = t
 			return p
 		}
-		c.errorfmt("%s does not implement %s", t, leftTyp)
+		if reason := c.notImplements(t, leftTyp); reason != "" {
+			c.errorfmt("impossible type assertion: %s", reason)
+		} else {
+			c.errorfmt("%s does not implement %s", t, leftTyp)
+		}
 		p.mode = modeInvalid
 		return p
 
@@ -2393,13 +2667,27 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 		if right.mode == modeInvalid {
 			return right
 		}
+		if isElemwiseOp(e.Op) {
+			_, lok := elemType(left.typ)
+			_, rok := elemType(right.typ)
+			if lok || rok {
+				return c.exprElemwise(e, left, right)
+			}
+		}
 		ltOrig, rtOrig := left.typ, right.typ
 		switch e.Op {
 		case token.TwoGreater, token.TwoLess:
 			// constraints are handled later
 		default:
-			c.constrainUntyped(&left, right.typ)
-			c.constrainUntyped(&right, left.typ)
+			// Of two untyped operands, the one of the smaller
+			// kind is promoted to the kind of the other.
+			if untypedRank(left.typ) > untypedRank(right.typ) {
+				c.constrainUntyped(&right, left.typ)
+				c.constrainUntyped(&left, right.typ)
+			} else {
+				c.constrainUntyped(&left, right.typ)
+				c.constrainUntyped(&right, left.typ)
+			}
 		}
 		left.expr = e
 
@@ -2434,14 +2722,30 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 					return left
 				}
 			}
-			left.typ = tipe.Bool
+			// Comparisons yield an untyped boolean value.
+			left.typ = tipe.UntypedBool
+			if left.mode == modeConst && right.mode == modeConst && left.val != nil && right.val != nil {
+				left.val = constant.MakeBool(constant.Compare(left.val, convGoOp(e.Op), right.val))
+			} else {
+				left.mode = modeVar
+				left.val = nil
+			}
 			return left
 		}
 
-		// TODO check for division by zero
+		switch e.Op {
+		case token.Div, token.Rem:
+			if right.mode == modeConst && isZero(right.val) {
+				if left.mode == modeConst || isInteger(left.typ) {
+					c.errorfmt("invalid operation: division by zero")
+					left.mode = modeInvalid
+					return left
+				}
+			}
+		}
 		if left.mode == modeConst && right.mode == modeConst {
 			switch e.Op {
-			case token.TwoLess, token.Greater:
+			case token.TwoLess, token.TwoGreater:
 				rhs, ok := big.NewInt(0).SetString(right.val.ExactString(), 0)
 				if !ok {
 					c.errorfmt("constant %s is not an integer", right.val.ExactString())
@@ -2449,11 +2753,24 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 					return left
 				}
 				left.val = constant.Shift(left.val, convGoOp(e.Op), uint(rhs.Uint64()))
+			case token.Div:
+				op := gotoken.QUO
+				if isInteger(left.typ) && isInteger(right.typ) {
+					op = gotoken.QUO_ASSIGN // truncated integer division
+				}
+				left.val = constant.BinaryOp(left.val, op, right.val)
 			default:
 				left.val = constant.BinaryOp(left.val, convGoOp(e.Op), right.val)
 			}
-			// TODO check rounding
-			// TODO check for comparison, result is untyped bool
+			if t, isBasic := tipe.Unalias(tipe.Underlying(left.typ)).(tipe.Basic); isBasic && isTyped(t) {
+				v := round(left.val, t)
+				if v == nil {
+					c.errorfmt("%s", roundError(left.val, t))
+					left.mode = modeInvalid
+					return left
+				}
+				left.val = v
+			}
 			return left
 		}
 
@@ -2521,6 +2838,17 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 				left.mode = modeInvalid
 				return left
 			}
+			if tp, isParam := left.typ.(*tipe.TypeParam); isParam {
+				ok := tp.AllTerms(tipe.IsNumeric)
+				if e.Op == token.Add {
+					ok = tp.AllTerms(func(t tipe.Type) bool { return tipe.IsNumeric(t) || isString(t) })
+				}
+				if !ok {
+					c.errorfmt("invalid operation: operator %s not defined on %s (type %s constrained by %s)", e.Op, format.Expr(e.Left), tp.Name, format.Type(tp.Constraint))
+					left.mode = modeInvalid
+					return left
+				}
+			}
 		}
 		return left
 	case *expr.Call:
@@ -2552,56 +2880,38 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 			return left
 		}
 
-		methodNames, methods := c.memory.Methods(left.typ)
-		for i, name := range methodNames {
-			if name == right {
-				p.mode = modeVar // modeFunc?
-				p.typ = methods[i]
-				return
-			}
-		}
-
 		lt := tipe.Underlying(left.typ)
 		if t, isPtr := lt.(*tipe.Pointer); isPtr {
 			lt = tipe.Underlying(t.Elem)
 		}
-		var found = false
-		var getField func(typ tipe.Type)
-		getField = func(typ tipe.Type) {
-			if found {
-				return
+		if tp, isParam := lt.(*tipe.TypeParam); isParam {
+			// Methods of a type parameter come from its constraint.
+			if m := tipe.Underlying(tp.Constraint).(*tipe.Interface).Methods[right]; m != nil {
+				p.mode = modeVar
+				p.typ = m
+				return p
 			}
-			switch typ := typ.(type) {
-			case *tipe.Struct:
-				for _, sf := range typ.Fields {
-					if sf.Name == right {
-						p.mode = modeVar
-						p.typ = sf.Type
-						found = true
-						return
-					}
-				}
-				// try deeper depths after the shallowest ones have been exhausted.
-				for _, sf := range typ.Fields {
-					if !sf.Embedded {
-						continue
-					}
-					typ := tipe.Underlying(sf.Type)
-					if t, isPtr := typ.(*tipe.Pointer); isPtr {
-						typ = tipe.Underlying(t.Elem)
-					}
-					getField(tipe.Underlying(typ))
-					if found {
-						return
-					}
-				}
+			p.mode = modeInvalid
+			c.errorfmt("%s undefined (type %s has no method %s)", format.Expr(e), left.typ, right)
+			return p
+		}
+		if _, isPkg := lt.(*tipe.Package); !isPkg {
+			mt, _, _, ambiguous := tipe.LookupFieldOrMethod(left.typ, right)
+			switch {
+			case ambiguous:
 				p.mode = modeInvalid
-				c.errorfmt("%s undefined (type %s has no field or method %s)", e, typ, right)
+				c.errorfmt("ambiguous selector %s", format.Expr(e))
+				return p
+			case mt != nil:
+				p.mode = modeVar // modeFunc?
+				p.typ = mt
+				return p
 			}
 		}
 		switch lt := lt.(type) {
 		case *tipe.Struct:
-			getField(lt)
+			p.mode = modeInvalid
+			c.errorfmt("%s undefined (type %s has no field or method %s)", format.Expr(e), left.typ, right)
 			return p
 		case *tipe.Package:
 			for name, t := range lt.Exports {
@@ -2610,9 +2920,17 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 					if lt.GoPkg != nil {
 						s := lt.GoPkg.(*gotypes.Package).Scope()
 						obj := s.Lookup(name)
-						if _, isAType := obj.(*gotypes.TypeName); isAType {
+						switch obj := obj.(type) {
+						case *gotypes.TypeName:
 							p.mode = modeTypeExpr
 							return p
+						case *gotypes.Const:
+							// The exact value, which an untyped
+							// constant may need, as in
+							// uint64(math.MaxUint64).
+							p.mode = modeConst
+							p.val = obj.Val()
+							return p
 						}
 					}
 					p.mode = modeVar // TODO modeFunc?
@@ -2631,7 +2949,10 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 		if left.mode == modeInvalid {
 			return left
 		}
-		lt := tipe.Underlying(left.typ)
+		if tipe.IsGeneric(left.typ) {
+			return c.exprInstantiate(e, left)
+		}
+		lt := coreType(left.typ)
 		switch lt := lt.(type) {
 		case *tipe.Map:
 			if len(e.Indicies) != 1 {
@@ -2720,9 +3041,7 @@ func (c *Checker) exprPartial(e expr.Expr, hint typeHint) (p partial) {
 			}
 			return p
 		case *tipe.Table:
-			p.mode = modeInvalid
-			c.errorfmt("TODO table slicing support")
-			return p
+			return c.indexTable(e, left.typ, lt)
 		default:
 			p.mode = modeInvalid
 			c.errorfmt("TODO index %T", lt)
@@ -2804,28 +3123,30 @@ func (c *Checker) checkStructLiteral(e *expr.CompLiteral, t *tipe.Struct, p part
 				p.mode = modeInvalid
 				return p
 			}
+			c.markConv(e.Values[i], elemsp[i].typ, sf.Type)
 		}
 	} else {
-		namedp := make(map[string]partial)
-		for i, elemp := range elemsp {
+		namedp := make(map[string]int)
+		for i := range elemsp {
 			ident, ok := e.Keys[i].(*expr.Ident)
 			if !ok {
 				c.errorfmt("invalid field name %s in struct initializer", e.Keys[i])
 				p.mode = modeInvalid
 				return p
 			}
-			namedp[ident.Name] = elemp
+			namedp[ident.Name] = i
 		}
 		for _, sf := range t.Fields {
-			elemp, found := namedp[sf.Name]
+			i, found := namedp[sf.Name]
 			if !found {
 				continue
 			}
-			c.assign(&elemp, sf.Type)
-			if elemp.mode == modeInvalid {
+			c.assign(&elemsp[i], sf.Type)
+			if elemsp[i].mode == modeInvalid {
 				p.mode = modeInvalid
 				return p
 			}
+			c.markConv(e.Values[i], elemsp[i].typ, sf.Type)
 		}
 		//panic("TODO: named CompLiteral")
 	}
@@ -2848,6 +3169,7 @@ func (c *Checker) checkMapLiteral(e expr.Expr, keys, vals []expr.Expr, t *tipe.M
 			p.mode = modeInvalid
 			return p
 		}
+		c.markConv(k, kp.typ, t.Key)
 	}
 	for _, v := range vals {
 		vp := c.expr(v)
@@ -2860,6 +3182,7 @@ func (c *Checker) checkMapLiteral(e expr.Expr, keys, vals []expr.Expr, t *tipe.M
 			p.mode = modeInvalid
 			return p
 		}
+		c.markConv(v, vp.typ, t.Value)
 	}
 	p.expr = e
 	return p
@@ -2889,6 +3212,7 @@ func (c *Checker) checkArrayLiteral(e expr.Expr, keys, vals []expr.Expr, t *tipe
 			p.mode = modeInvalid
 			return p
 		}
+		c.markConv(v, vp.typ, t.Elem)
 	}
 	p.expr = e
 	return p
@@ -2918,6 +3242,7 @@ func (c *Checker) checkSliceLiteral(e expr.Expr, keys, vals []expr.Expr, t *tipe
 			p.mode = modeInvalid
 			return p
 		}
+		c.markConv(v, vp.typ, t.Elem)
 	}
 	p.expr = e
 	return p
@@ -3020,7 +3345,8 @@ func (c *Checker) unpackExprs(hint typeHint, exprs ...expr.Expr) (partials []par
 		p := c.exprPartial(expr, hint)
 
 		// Handle single expressions that are multi-valued.
-		// TODO: handle comma-ok cases (map index, type assert, chan recv)
+		// Comma-ok forms (map index, type assert, chan recv) are
+		// expanded by the caller with checkCommaOK, not here.
 		if tup, ok := p.typ.(*tipe.Tuple); ok {
 			// Treat each entry as the same underlying
 			// expression, but update the types
@@ -3069,30 +3395,138 @@ func (c *Checker) assign(p *partial, t tipe.Type) {
 		case *tipe.Interface:
 			// make sure p.typ implements all methods of iface.
 			if c.typeAssert(iface, p.typ) {
+				c.markConv(p.expr, p.typ, t)
 				return
 			}
-			// TODO: explain why p.typ does not implement t
+			if !c.errorNotImplements(p.typ, t, "assignment") {
+				c.errorfmt("cannot assign %s to %s", p.typ, t)
+			}
+			p.mode = modeInvalid
+			return
 		}
 		c.errorfmt("cannot assign %s to %s", p.typ, t)
 		p.mode = modeInvalid
 	}
 }
 
+// markConv records an implicit conversion of the value of e, of
+// type src, to dst if dst is an interface type and src is not.
+func (c *Checker) markConv(e expr.Expr, src, dst tipe.Type) {
+	if e == nil || isUntyped(src) {
+		return
+	}
+	if _, isTuple := c.types[e].(*tipe.Tuple); isTuple {
+		// The conversion applies to one value of e, so it is
+		// made where the tuple is unpacked.
+		return
+	}
+	if _, isIface := tipe.Underlying(dst).(*tipe.Interface); !isIface {
+		return
+	}
+	if _, isIface := tipe.Underlying(src).(*tipe.Interface); isIface {
+		return
+	}
+	c.convs[e] = dst
+}
+
+// missingMethod returns the name of a method of the interface iface
+// that t does not have. If t has a method of that name with a
+// different type, wrongType is true. If the method has a pointer
+// receiver, and so is not in the method set of t, ptrRecv is true.
+func (c *Checker) missingMethod(t tipe.Type, iface *tipe.Interface) (name string, wrongType, ptrRecv bool) {
+	names := make([]string, 0, len(iface.Methods))
+	for name := range iface.Methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var mt tipe.Type
+		if tiface, isIface := tipe.Underlying(t).(*tipe.Interface); isIface {
+			if m := tiface.Methods[name]; m != nil {
+				mt = m
+			}
+		} else if m, _, isMethod, _ := tipe.LookupFieldOrMethod(t, name); isMethod {
+			mt = m
+		}
+		if mt == nil {
+			return name, false, false
+		}
+		if !tipe.Equal(mt, iface.Methods[name]) {
+			return name, true, false
+		}
+		if tipe.PointerMethod(t, name) {
+			return name, false, true
+		}
+	}
+	return "", false, false
+}
+
+// notImplements explains why t does not implement the interface type
+// iface, for example "*T does not implement io.Reader (missing Read
+// method)".
+func (c *Checker) notImplements(t, iface tipe.Type) string {
+	it, _ := tipe.Underlying(iface).(*tipe.Interface)
+	if it == nil {
+		return ""
+	}
+	name, wrongType, ptrRecv := c.missingMethod(t, it)
+	if name == "" {
+		return ""
+	}
+	reason := "missing " + name + " method"
+	switch {
+	case wrongType:
+		reason = "wrong type for " + name + " method"
+	case ptrRecv:
+		reason = "method " + name + " has pointer receiver"
+	}
+	return fmt.Sprintf("%s does not implement %s (%s)", c.typeString(t), c.typeString(iface), reason)
+}
+
+// errorNotImplements reports an error if t does not implement the
+// interface type iface, so a value of type t cannot be used as one in
+// context, such as "assignment". It reports whether it did so.
+func (c *Checker) errorNotImplements(t, iface tipe.Type, context string) bool {
+	reason := c.notImplements(t, iface)
+	if reason == "" {
+		return false
+	}
+	c.errorfmt("cannot use %s as %s in %s: %s", c.typeString(t), c.typeString(iface), context, reason)
+	return true
+}
+
+// typeString formats t for an error message. Named types from Go
+// packages are qualified with their package name, as in io.Reader.
+func (c *Checker) typeString(t tipe.Type) string {
+	prefix := ""
+	if ptr, isPtr := t.(*tipe.Pointer); isPtr {
+		prefix = "*"
+		t = ptr.Elem
+	}
+	if n, isNamed := t.(*tipe.Named); isNamed && n.PkgName != "" {
+		if pkg := c.pkgs[n.PkgPath]; pkg != nil && pkg.Type.GoPkg != nil {
+			return prefix + n.PkgName + "." + n.Name
+		}
+	}
+	return prefix + format.Type(t)
+}
+
 func (c *Checker) convert(p *partial, t tipe.Type) {
 	//fmt.Printf("Checker.convert(p=%#+v, t=%s)\n", p, t)
 	_, tIsConst := t.(tipe.Basic)
 	if p.mode == modeConst && tIsConst {
 		// TODO or integer -> string conversion
 		if round(p.val, t.(tipe.Basic)) == nil {
-			// p.val does not fit in t
-			c.errorfmt("constant %s does not fit in %s", p.val, t)
+			c.errorfmt("%s", roundError(p.val, t.(tipe.Basic)))
 			p.mode = modeInvalid
 			return
 		}
 	}
 
 	if !c.convertible(t, p.typ) {
-		c.errorfmt("cannot convert %s to %s", p.typ, t)
+		if !c.errorNotImplements(p.typ, t, "conversion") {
+			c.errorfmt("cannot convert %s to %s", p.typ, t)
+		}
 		p.mode = modeInvalid
 		return
 	}
@@ -3159,6 +3593,53 @@ func isString(t tipe.Type) bool {
 	return t == tipe.String || t == tipe.UntypedString
 }
 
+func isInteger(t tipe.Type) bool {
+	switch tipe.Underlying(t) {
+	case tipe.Integer,
+		tipe.Int, tipe.Int8, tipe.Int16, tipe.Int32, tipe.Int64,
+		tipe.Uint, tipe.Uint8, tipe.Uint16, tipe.Uint32, tipe.Uint64, tipe.Uintptr,
+		tipe.UntypedInteger, tipe.UntypedRune:
+		return true
+	}
+	return false
+}
+
+func isNumeric(t tipe.Type) bool {
+	if isInteger(t) {
+		return true
+	}
+	switch tipe.Underlying(t) {
+	case tipe.Num, tipe.Float, tipe.Complex,
+		tipe.Float32, tipe.Float64, tipe.Complex64, tipe.Complex128,
+		tipe.UntypedFloat, tipe.UntypedComplex:
+		return true
+	}
+	return false
+}
+
+// rangeFuncParams reports whether t is a range-over-func iterator,
+// func(yield func(...) bool), and returns the parameter types of
+// its yield function. There are at most two.
+func rangeFuncParams(t *tipe.Func) (params []tipe.Type, ok bool) {
+	if t.Params == nil || len(t.Params.Elems) != 1 || (t.Results != nil && len(t.Results.Elems) > 0) {
+		return nil, false
+	}
+	yield, isFunc := tipe.Underlying(t.Params.Elems[0]).(*tipe.Func)
+	if !isFunc || yield.Variadic || yield.Results == nil || len(yield.Results.Elems) != 1 {
+		return nil, false
+	}
+	if tipe.Underlying(yield.Results.Elems[0]) != tipe.Bool {
+		return nil, false
+	}
+	if yield.Params != nil {
+		params = yield.Params.Elems
+	}
+	if len(params) > 2 {
+		return nil, false
+	}
+	return params, true
+}
+
 func (c *Checker) convertible(dst, src tipe.Type) bool {
 	if c.assignable(dst, src) {
 		return true
@@ -3195,10 +3676,11 @@ func (c *Checker) constrainUntyped(p *partial, t tipe.Type) {
 	// catch invalid constraints
 	if isUntyped(t) {
 		switch {
-		case t == tipe.UntypedFloat && p.typ == tipe.UntypedInteger:
-			// promote untyped int to float
-		case t == tipe.UntypedComplex && (p.typ == tipe.UntypedInteger || p.typ == tipe.UntypedFloat):
-			// promote untyped int or float to complex
+		case t == p.typ:
+			return
+		case untypedRank(p.typ) > 0 && untypedRank(p.typ) < untypedRank(t):
+			// promote untyped int to rune, float or complex,
+			// and so on up
 		case t == tipe.Num && (p.typ == tipe.UntypedInteger || p.typ == tipe.UntypedFloat):
 			// promote untyped int or float to num type parameter
 		case t != p.typ:
@@ -3209,12 +3691,15 @@ func (c *Checker) constrainUntyped(p *partial, t tipe.Type) {
 		case tipe.Basic:
 			switch p.mode {
 			case modeConst:
-				p.val = round(p.val, t)
-				if p.val == nil {
-					c.errorfmt("cannot convert const %s to %s", p.typ, t)
-					// TODO more details about why
+				v := round(p.val, t)
+				if v == nil {
+					c.errorfmt("cannot convert const %s to %s: %s", p.typ, t, roundError(p.val, t))
 				}
+				p.val = v
 			case modeVar:
+				if p.typ == tipe.UntypedBool && t == tipe.Bool {
+					break
+				}
 				panic(fmt.Sprintf("TODO coerce var to basic: t=%s, p.typ=%s", t, format.Type(p.typ)))
 			}
 		}
@@ -3248,6 +3733,11 @@ func (c *Checker) constrainExprType(e expr.Expr, t tipe.Type) {
 		}
 		c.constrainExprType(e.Left, t)
 		c.constrainExprType(e.Right, t)
+	case *expr.Unary:
+		switch e.Op {
+		case token.Not, token.LeftParen:
+			c.constrainExprType(e.Expr, t)
+		}
 	}
 
 	c.types[e] = t
@@ -3273,8 +3763,8 @@ func (c *Checker) typeAssert(iface *tipe.Interface, t tipe.Type) bool {
 		}
 	} else {
 		for name, method := range iface.Methods {
-			mt := findMember(t, name)
-			if !tipe.Equal(method, mt) {
+			mt, _, isMethod, _ := tipe.LookupFieldOrMethod(t, name)
+			if !isMethod || !tipe.Equal(method, mt) {
 				return false
 			}
 		}
@@ -3283,41 +3773,6 @@ func (c *Checker) typeAssert(iface *tipe.Interface, t tipe.Type) bool {
 	return true
 }
 
-// findMember finds the field or method with name in type t.
-//
-// TODO: there is a lot to do here re: embedding. We have to think
-// if we are enumerating the order correctly, worry about infinite
-// recursion, and think about the pkg the field belongs to.
-func findMember(t tipe.Type, name string) (mt tipe.Type) {
-	if tp, isPointer := tipe.Underlying(t).(*tipe.Pointer); isPointer {
-		t = tp.Elem
-	}
-
-	for t != nil {
-		if methodik, isNamed := t.(*tipe.Named); isNamed {
-			for i, mname := range methodik.MethodNames {
-				if mname == name {
-					return methodik.Methods[i]
-				}
-			}
-			t = methodik.Type
-		}
-
-		if st, isStruct := t.(*tipe.Struct); isStruct {
-			for _, sf := range st.Fields {
-				if sf.Name == name {
-					return sf.Type
-				}
-				// TODO: if the field is an embedding,
-				// collect it onto the list of types
-				// to analyze.
-			}
-		}
-		return nil
-	}
-	panic("unreachable")
-}
-
 func (c *Checker) errorfmt(formatstr string, args ...interface{}) {
 	for i, arg := range args {
 		switch v := arg.(type) {
@@ -3362,6 +3817,17 @@ func (c *Checker) Ident(e *expr.Ident) *Obj {
 	return id
 }
 
+// ImplicitConv reports the interface type that the value of e is
+// implicitly converted to, as when a concrete value is assigned to
+// an interface variable, passed as an interface argument or returned
+// as an interface result. It is nil if the value is not converted.
+func (c *Checker) ImplicitConv(e expr.Expr) tipe.Type {
+	c.mu.Lock()
+	t := c.convs[e]
+	c.mu.Unlock()
+	return t
+}
+
 // NewScope make a copy of Checker with a new, blank current scope.
 // The two checkers share all type checked data.
 func (c *Checker) NewScope() *Checker {
@@ -3404,9 +3870,15 @@ func convGoOp(op token.Token) gotoken.Token {
 	case token.Mul:
 		return gotoken.MUL
 	case token.Div:
-		return gotoken.QUO // TODO: QUO_ASSIGN for int div
+		return gotoken.QUO
 	case token.Rem:
 		return gotoken.REM
+	case token.Ref:
+		return gotoken.AND
+	case token.Pipe:
+		return gotoken.OR
+	case token.RefPow:
+		return gotoken.AND_NOT
 	case token.Pow:
 		panic("TODO token.Pow")
 		return gotoken.REM
@@ -3418,6 +3890,18 @@ func convGoOp(op token.Token) gotoken.Token {
 		return gotoken.SHL
 	case token.TwoGreater:
 		return gotoken.SHR
+	case token.Equal:
+		return gotoken.EQL
+	case token.NotEqual:
+		return gotoken.NEQ
+	case token.Less:
+		return gotoken.LSS
+	case token.LessEqual:
+		return gotoken.LEQ
+	case token.Greater:
+		return gotoken.GTR
+	case token.GreaterEqual:
+		return gotoken.GEQ
 	default:
 		panic(fmt.Sprintf("typecheck: bad op: %s", op))
 	}
@@ -3531,38 +4015,60 @@ func round(v constant.Value, t tipe.Basic) constant.Value {
 			}
 
 		case tipe.Float32:
-			r, _ := constant.Float32Val(v)
-			return constant.MakeFloat64(float64(r))
+			return roundFloat32(v)
 		case tipe.Float64:
-			r, _ := constant.Float64Val(v)
-			return constant.MakeFloat64(float64(r))
+			return roundFloat64(v)
 		case tipe.Complex64:
-			re, _ := constant.Float32Val(v)
-			return constant.ToComplex(constant.MakeFloat64(float64(re)))
+			if re := roundFloat32(v); re != nil {
+				return constant.ToComplex(re)
+			}
+			return nil
 		case tipe.Complex128:
-			re, _ := constant.Float64Val(v)
-			return constant.ToComplex(constant.MakeFloat64(float64(re)))
+			if re := roundFloat64(v); re != nil {
+				return constant.ToComplex(re)
+			}
+			return nil
 		}
 	case constant.Float:
+		if isInteger(t) {
+			// Only floats with no fractional part convert
+			// to integers.
+			i := constant.ToInt(v)
+			if i.Kind() != constant.Int {
+				return nil
+			}
+			return round(i, t)
+		}
 		switch t {
 		case tipe.Float, tipe.UntypedFloat, tipe.UntypedComplex:
 			return v
 		case tipe.Float32:
-			r, _ := constant.Float32Val(v)
-			return constant.MakeFloat64(float64(r))
+			return roundFloat32(v)
 		case tipe.Float64:
-			r, _ := constant.Float64Val(v)
-			return constant.MakeFloat64(float64(r))
+			return roundFloat64(v)
 		case tipe.Complex64:
-			re, _ := constant.Float32Val(v)
-			return constant.ToComplex(constant.MakeFloat64(float64(re)))
+			if re := roundFloat32(v); re != nil {
+				return constant.ToComplex(re)
+			}
+			return nil
 		case tipe.Complex128:
-			re, _ := constant.Float64Val(v)
-			return constant.ToComplex(constant.MakeFloat64(float64(re)))
+			if re := roundFloat64(v); re != nil {
+				return constant.ToComplex(re)
+			}
+			return nil
 		case tipe.Num:
 			return v
 		}
 	case constant.Complex:
+		if constant.Sign(constant.Imag(v)) == 0 {
+			switch t {
+			case tipe.UntypedComplex, tipe.Complex, tipe.Complex64, tipe.Complex128:
+			default:
+				// A complex constant with no imaginary
+				// part is representable by real types.
+				return round(constant.Real(v), t)
+			}
+		}
 		switch t {
 		case tipe.UntypedComplex, tipe.Complex:
 			return v
@@ -3588,9 +4094,57 @@ func round(v constant.Value, t tipe.Basic) constant.Value {
 	return nil
 }
 
+// roundFloat32 rounds v to a float32, or returns nil on overflow.
+func roundFloat32(v constant.Value) constant.Value {
+	r, _ := constant.Float32Val(v)
+	if math.IsInf(float64(r), 0) {
+		return nil
+	}
+	return constant.MakeFloat64(float64(r))
+}
+
+// roundFloat64 rounds v to a float64, or returns nil on overflow.
+func roundFloat64(v constant.Value) constant.Value {
+	r, _ := constant.Float64Val(v)
+	if math.IsInf(r, 0) {
+		return nil
+	}
+	return constant.MakeFloat64(r)
+}
+
+// isZero reports whether v is a numeric constant equal to zero.
+func isZero(v constant.Value) bool {
+	if v == nil {
+		return false
+	}
+	switch v.Kind() {
+	case constant.Int, constant.Float, constant.Complex:
+		return constant.Sign(v) == 0
+	}
+	return false
+}
+
+// roundError explains why the constant v cannot be represented by
+// a value of type t.
+func roundError(v constant.Value, t tipe.Basic) string {
+	switch v.Kind() {
+	case constant.Float, constant.Complex:
+		if isInteger(t) && constant.ToInt(v).Kind() != constant.Int {
+			return fmt.Sprintf("constant %s truncated to integer", v)
+		}
+		fallthrough
+	case constant.Int:
+		if isNumeric(t) {
+			return fmt.Sprintf("constant %s overflows %s", v, t)
+		}
+	}
+	return fmt.Sprintf("constant %s does not fit in %s", v, t)
+}
+
 func (c *Checker) Add(s stmt.Stmt) tipe.Type {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.checkLabels(&stmt.Block{Stmts: []stmt.Stmt{s}}, true)
 	return c.stmt(s, nil, nil)
 }
 
@@ -3600,6 +4154,71 @@ func (c *Checker) Lookup(name string) *Obj {
 	return c.cur.LookupRec(name)
 }
 
+// Define declares name as a variable of type t in the current scope.
+// Unlike a variable declared by a statement, it is not a global of
+// the package. It is used to make the values of a host Go program
+// visible to the Neugram program it runs.
+func (c *Checker) Define(name string, t tipe.Type) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cur.Objs[name] = &Obj{
+		Name: name,
+		Kind: ObjVar,
+		Type: t,
+	}
+}
+
+// GoNamed reports the type of the exported named type name of the
+// Go package pkgPath, as an import of the package would give it.
+// It reports nil if the package cannot be imported or has no such
+// type. It lets host Go values share the types of imported values.
+func (c *Checker) GoNamed(pkgPath, name string) tipe.Type {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pkg, err := c.goPkg(pkgPath)
+	if err != nil {
+		return nil
+	}
+	obj := pkg.GlobalNames[name]
+	if obj == nil || obj.Kind != ObjType {
+		return nil
+	}
+	return obj.Type
+}
+
+// DefinePackage declares name as the package pkg in the current
+// scope, with a variable for each of its exports. Like Define, it
+// is used for the values of a host Go program.
+func (c *Checker) DefinePackage(name string, pkg *tipe.Package) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := &Package{
+		Path:        pkg.Path,
+		Type:        pkg,
+		GlobalNames: make(map[string]*Obj),
+	}
+	var members []string
+	for member := range pkg.Exports {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	for _, member := range members {
+		obj := &Obj{
+			Name: member,
+			Kind: ObjVar,
+			Type: pkg.Exports[member],
+		}
+		p.Globals = append(p.Globals, obj)
+		p.GlobalNames[member] = obj
+	}
+	c.cur.Objs[name] = &Obj{
+		Name: name,
+		Kind: ObjPkg,
+		Type: pkg,
+		Decl: p,
+	}
+}
+
 func (c *Checker) addObj(obj *Obj) {
 	c.cur.Objs[obj.Name] = obj
 
@@ -3642,6 +4261,9 @@ func (s *Scope) LookupRec(name string) *Obj {
 	if s.foundInParent != nil && (o.Kind == ObjVar || o.Kind == ObjPkg) {
 		s.foundInParent[name] = true
 	}
+	if s.foundInParent != nil && o.Kind == ObjConst && Universe.Objs[name] != o {
+		s.foundInParent[name] = true
+	}
 	if s.foundMdikInParent != nil && o.Kind == ObjType {
 		if mdik, ok := o.Type.(*tipe.Named); ok {
 			s.foundMdikInParent[mdik] = true
@@ -3708,7 +4330,7 @@ type Obj struct {
 	Kind ObjKind
 	Type tipe.Type
 	Decl interface{} // *expr.FuncLiteral, *stmt.MethodikDecl, constant.Value, *stmt.TypeDecl, *Package
-	Used bool
+	Used bool        // referred to by an identifier or qualified type
 }
 
 type Package struct {
@@ -3717,13 +4339,31 @@ type Package struct {
 	Type        *tipe.Package
 	Globals     []*Obj
 	GlobalNames map[string]*Obj
-	Syntax      *syntax.File
+	Syntax      *syntax.File   // the first of Files
+	Files       []*syntax.File // the files of a Neugram package
 }
 
 func isTyped(t tipe.Type) bool {
 	return t != tipe.Invalid && !isUntyped(t)
 }
 
+// untypedRank orders the kinds of untyped numeric constants:
+// an operation on two of them has the kind of the larger.
+// It reports 0 for other types.
+func untypedRank(t tipe.Type) int {
+	switch t {
+	case tipe.UntypedInteger:
+		return 1
+	case tipe.UntypedRune:
+		return 2
+	case tipe.UntypedFloat:
+		return 3
+	case tipe.UntypedComplex:
+		return 4
+	}
+	return 0
+}
+
 func isUntyped(t tipe.Type) bool {
 	switch t {
 	case tipe.UntypedNil, tipe.UntypedBool, tipe.UntypedString, tipe.UntypedRune,
@@ -3735,6 +4375,8 @@ func isUntyped(t tipe.Type) bool {
 
 func isComparable(t tipe.Type) bool {
 	switch t := tipe.Underlying(t).(type) {
+	case *tipe.TypeParam:
+		return tipe.Unalias(t.Constraint) == tipe.Comparable || t.AllTerms(isComparable)
 	case tipe.Basic:
 		return t != tipe.Invalid && t != tipe.UntypedNil
 	case *tipe.Chan, *tipe.Interface, *tipe.Pointer:
@@ -3753,6 +4395,9 @@ func isComparable(t tipe.Type) bool {
 }
 
 func isOrdered(t tipe.Type) bool {
+	if tp, isParam := t.(*tipe.TypeParam); isParam {
+		return tp.AllTerms(isOrdered)
+	}
 	switch tipe.Underlying(t) {
 	case tipe.Num, tipe.Byte, tipe.Rune, tipe.Integer, tipe.Float, tipe.Complex, tipe.String,
 		tipe.Int, tipe.Int8, tipe.Int16, tipe.Int32, tipe.Int64,
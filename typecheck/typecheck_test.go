@@ -5,10 +5,14 @@
 package typecheck
 
 import (
+	"go/constant"
+	gotoken "go/token"
+	"strings"
 	"testing"
 
 	"neugram.io/ng/format"
 	"neugram.io/ng/parser"
+	"neugram.io/ng/syntax/expr"
 	"neugram.io/ng/syntax/stmt"
 	"neugram.io/ng/syntax/tipe"
 )
@@ -100,6 +104,41 @@ var typeTests = []typeTest{
 		},
 		[]identType{{"a", &tipe.Table{tipe.Int64}}},
 	},
+	{
+		[]string{
+			`t := [|]float64{{|"x", "y"|}, {1, 2}, {3, 4}}`,
+			`e := t[1|"y"]`,
+			`row := t[0]`,
+			`rows := t[1:]`,
+			`col := t[:|0]`,
+			`n := len(t)`,
+			`for i, r := range t { row = r; n = i }`,
+		},
+		[]identType{
+			{"e", tipe.Float64},
+			{"row", &tipe.Slice{Elem: tipe.Float64}},
+			{"rows", &tipe.Table{Type: tipe.Float64}},
+			{"col", &tipe.Table{Type: tipe.Float64}},
+			{"n", tipe.Int},
+		},
+	},
+	{
+		[]string{
+			`s := []float64{1, 2}`,
+			`a := 2*s + s`,
+			`v := [2]int{1, 2}`,
+			`b := v - 1`,
+			`t := [|]int{{1, 2}, {3, 4}}`,
+			`c := t / 2`,
+			`d := matmul(t, t)`,
+		},
+		[]identType{
+			{"a", &tipe.Slice{Elem: tipe.Float64}},
+			{"b", &tipe.Array{Len: 2, Elem: tipe.Int}},
+			{"c", &tipe.Table{Type: tipe.Int}},
+			{"d", &tipe.Table{Type: tipe.Int}},
+		},
+	},
 	{
 		[]string{
 			`methodik A struct{ X int64 } {
@@ -187,6 +226,76 @@ var typeTests = []typeTest{
 			{"m", tipe.Int64},
 		},
 	},
+	{
+		[]string{
+			`var i int`,
+			`var r rune`,
+			`var n int64`,
+			`var s string`,
+			`for j, c := range "héllo" { i, r = j, c }`,
+			`for j := range int64(3) { n = j }`,
+			`for _, v := range &[2]string{"a", "b"} { s = v }`,
+			`seq := func(yield func(string, int64) bool) {}`,
+			`for k, v := range seq { s, n = k, v }`,
+		},
+		[]identType{
+			{"i", tipe.Int},
+			{"r", tipe.Rune},
+			{"n", tipe.Int64},
+		},
+	},
+	{
+		[]string{
+			`type M map[string]int16`,
+			`m := M{}`,
+			`v, ok := m["a"]`,
+			`var b, bok = interface{}(1).(bool)`,
+			`c := make(chan float32)`,
+			`var f float32`,
+			`var fok bool`,
+			`f, fok = <-c`,
+		},
+		[]identType{
+			{"v", tipe.Int16},
+			{"ok", tipe.Bool},
+			{"b", tipe.Bool},
+			{"bok", tipe.Bool},
+			{"fok", tipe.Bool},
+		},
+	},
+	{
+		[]string{
+			`type A struct { X int8; Y string }`,
+			`type B struct { Y int16 }`,
+			`type C struct { A; *B; Z bool }`,
+			`type D struct { C; X float32 }`,
+			`d := D{}`,
+			`x := d.X`,
+			`z := d.Z`,
+			`ax := d.C.X`,
+			`by := d.C.B.Y`,
+		},
+		[]identType{
+			{"x", tipe.Float32},
+			{"z", tipe.Bool},
+			{"ax", tipe.Int8},
+			{"by", tipe.Int16},
+		},
+	},
+	{
+		[]string{
+			"func Map[T, U any](xs []T, f func(T) U) []U { return nil }",
+			`strs := Map([]int{1}, func(i int) string { return "" })`,
+			"func Max[T int | float64](a, b T) T { return a }",
+			"m := Max(1.5, 2)",
+			"i := Max[int](1, 2)",
+		},
+		[]identType{
+			{"strs", &tipe.Slice{Elem: tipe.String}},
+			{"m", tipe.Float64},
+			{"i", tipe.Int},
+		},
+	},
 }
 
 func TestBasic(t *testing.T) {
@@ -225,3 +334,285 @@ func TestBasic(t *testing.T) {
 		}
 	}
 }
+
+func TestConstGroup(t *testing.T) {
+	src := []string{
+		`type Weekday int`,
+		"const (\n\tSunday Weekday = iota\n\tMonday\n\t_\n\tWednesday\n)",
+		"const (\n\t_ = iota\n\tKB = 1 << (10 * iota)\n\tMB\n)",
+		`const half = 7 / 2`,
+		"const (\n\tA = iota * 1.5\n\tB\n\tC\n)",
+		"const (\n\tX float64 = 0.5 + iota\n\tY\n)",
+	}
+	c := New("")
+	for _, str := range src {
+		s, err := parser.ParseStmt([]byte(str))
+		if err != nil {
+			t.Fatalf("parser.ParseStmt(%q): %v", str, err)
+		}
+		c.Add(s)
+		if errs := c.Errs(); len(errs) > 0 {
+			t.Fatalf("Add(%q): %v", str, errs[0])
+		}
+	}
+	weekday := c.cur.Objs["Weekday"].Type
+	tests := []struct {
+		name string
+		t    tipe.Type
+		val  int64
+	}{
+		{"Sunday", weekday, 0},
+		{"Monday", weekday, 1},
+		{"Wednesday", weekday, 3},
+		{"KB", tipe.UntypedInteger, 1 << 10},
+		{"MB", tipe.UntypedInteger, 1 << 20},
+		{"half", tipe.UntypedInteger, 3},
+	}
+	for _, test := range tests {
+		obj := c.cur.Objs[test.name]
+		if obj == nil {
+			t.Errorf("%s is missing", test.name)
+			continue
+		}
+		if obj.Type != test.t {
+			t.Errorf("%s has type %s, want %s", test.name, format.Type(obj.Type), format.Type(test.t))
+		}
+		if v, _ := obj.Decl.(constant.Value); v == nil || v.String() != constant.MakeInt64(test.val).String() {
+			t.Errorf("%s=%v, want %d", test.name, obj.Decl, test.val)
+		}
+	}
+
+	floatTests := []struct {
+		name string
+		t    tipe.Type
+		val  float64
+	}{
+		{"A", tipe.UntypedFloat, 0},
+		{"B", tipe.UntypedFloat, 1.5},
+		{"C", tipe.UntypedFloat, 3},
+		{"X", tipe.Float64, 0.5},
+		{"Y", tipe.Float64, 1.5},
+	}
+	for _, test := range floatTests {
+		obj := c.cur.Objs[test.name]
+		if obj == nil {
+			t.Errorf("%s is missing", test.name)
+			continue
+		}
+		if obj.Type != test.t {
+			t.Errorf("%s has type %s, want %s", test.name, format.Type(obj.Type), format.Type(test.t))
+		}
+		if v, _ := obj.Decl.(constant.Value); v == nil || !constant.Compare(v, gotoken.EQL, constant.MakeFloat64(test.val)) {
+			t.Errorf("%s=%v, want %v", test.name, obj.Decl, test.val)
+		}
+	}
+}
+
+func TestConstExpr(t *testing.T) {
+	src := []string{
+		`type B bool`,
+		`const lt = 1 < 2`,
+		`const gt = 3 > 2`,
+		`const sh = 1 << 100 >> 98`,
+		`const c int8 = 100`,
+		`const d = c + 27`,
+		`const f float32 = 0.5`,
+		`const i = int(2.0)`,
+		`x, y := 1, 2`,
+		`var b B = x < y`,
+		`e := x == y`,
+		`const g1 = 1.5 * 2`,
+		`const g2 = 2 * 1.5`,
+		`const h = 1.5`,
+		`var j int = h * 2`,
+		`const r = 'a' + 1`,
+		`const rf = 0.5 * 'b'`,
+	}
+	c := New("")
+	for _, str := range src {
+		s, err := parser.ParseStmt([]byte(str))
+		if err != nil {
+			t.Fatalf("parser.ParseStmt(%q): %v", str, err)
+		}
+		c.Add(s)
+		if errs := c.Errs(); len(errs) > 0 {
+			t.Fatalf("Add(%q): %v", str, errs[0])
+		}
+	}
+	tests := []struct {
+		name string
+		t    tipe.Type
+		val  constant.Value
+	}{
+		{"lt", tipe.UntypedBool, constant.MakeBool(true)},
+		{"gt", tipe.UntypedBool, constant.MakeBool(true)},
+		{"sh", tipe.UntypedInteger, constant.MakeInt64(4)},
+		{"d", tipe.Int8, constant.MakeInt64(127)},
+		{"f", tipe.Float32, constant.MakeFloat64(0.5)},
+		{"i", tipe.Int, constant.MakeInt64(2)},
+		{"b", c.cur.Objs["B"].Type, nil},
+		{"e", tipe.Bool, nil},
+		{"g1", tipe.UntypedFloat, constant.MakeFloat64(3)},
+		{"g2", tipe.UntypedFloat, constant.MakeFloat64(3)},
+		{"j", tipe.Int, nil},
+		{"r", tipe.UntypedRune, constant.MakeInt64('b')},
+		{"rf", tipe.UntypedFloat, constant.MakeFloat64(49)},
+	}
+	for _, test := range tests {
+		obj := c.cur.Objs[test.name]
+		if obj == nil {
+			t.Errorf("%s is missing", test.name)
+			continue
+		}
+		if obj.Type != test.t {
+			t.Errorf("%s has type %s, want %s", test.name, format.Type(obj.Type), format.Type(test.t))
+		}
+		if test.val == nil {
+			continue
+		}
+		if v, _ := obj.Decl.(constant.Value); v == nil || !constant.Compare(v, gotoken.EQL, test.val) {
+			t.Errorf("%s=%v, want %v", test.name, obj.Decl, test.val)
+		}
+	}
+
+	errTests := []struct {
+		src, err string
+	}{
+		{`const z1 = 1 / 0`, "division by zero"},
+		{`z2 := x % 0`, "division by zero"},
+		{`z3 := 1.5 / 0.0`, "division by zero"},
+		{`var z4 int8 = 300`, "constant 300 overflows int8"},
+		{`var z5 uint = -1`, "constant -1 overflows uint"},
+		{`const z6 = c * 2`, "constant 200 overflows int8"},
+		{`var z7 float32 = 1e40`, "constant 1e+40 overflows float32"},
+		{`z8 := int(1.5)`, "constant 1.5 truncated to integer"},
+		{`var z9 int = 2.5`, "constant 2.5 truncated to integer"},
+		{`var z10 bool = b`, "cannot use b (type B) as type bool"},
+	}
+	for _, test := range errTests {
+		s, err := parser.ParseStmt([]byte(test.src))
+		if err != nil {
+			t.Fatalf("parser.ParseStmt(%q): %v", test.src, err)
+		}
+		c.Add(s)
+		errs := c.Errs()
+		if len(errs) == 0 {
+			t.Errorf("Add(%q): missing error %q", test.src, test.err)
+			continue
+		}
+		if got := errs[0].Error(); !strings.Contains(got, test.err) {
+			t.Errorf("Add(%q): error %q does not contain %q", test.src, got, test.err)
+		}
+	}
+}
+
+func TestImplicitConv(t *testing.T) {
+	src := []string{
+		`type S interface { M() int }`,
+		`methodik T struct{} { func (t) M() int { return 1 } }`,
+		`func f(s S) S { return &T{} }`,
+		`var s S = &T{}`,
+		`s = &T{}`,
+		`f(&T{})`,
+		`s = s`,
+		`t := T{}`,
+		`type H struct { S S }`,
+		`h := H{&T{}}`,
+		`hk := H{S: &T{}}`,
+		`ss := []S{&T{}}`,
+		`sa := [1]S{&T{}}`,
+		`sm := map[S]S{&T{}: &T{}}`,
+		`hc := H{nil}`,
+	}
+	c := New("")
+	var stmts []stmt.Stmt
+	for _, str := range src {
+		s, err := parser.ParseStmt([]byte(str))
+		if err != nil {
+			t.Fatalf("parser.ParseStmt(%q): %v", str, err)
+		}
+		c.Add(s)
+		if errs := c.Errs(); len(errs) > 0 {
+			t.Fatalf("Add(%q): %v", str, errs[0])
+		}
+		stmts = append(stmts, s)
+	}
+	sType := c.cur.Objs["S"].Type
+
+	fn := stmts[2].(*stmt.Simple).Expr.(*expr.FuncLiteral)
+	ret := fn.Body.(*stmt.Block).Stmts[0].(*stmt.Return)
+	conversions := []struct {
+		name string
+		e    expr.Expr
+		want tipe.Type
+	}{
+		{"return", ret.Exprs[0], sType},
+		{"var", stmts[3].(*stmt.Var).Values[0], sType},
+		{"assign", stmts[4].(*stmt.Assign).Right[0], sType},
+		{"argument", stmts[5].(*stmt.Simple).Expr.(*expr.Call).Args[0], sType},
+		{"interface to interface", stmts[6].(*stmt.Assign).Right[0], nil},
+		{"inferred", stmts[7].(*stmt.Assign).Right[0], nil},
+		{"struct field", stmts[9].(*stmt.Assign).Right[0].(*expr.CompLiteral).Values[0], sType},
+		{"keyed struct field", stmts[10].(*stmt.Assign).Right[0].(*expr.CompLiteral).Values[0], sType},
+		{"slice element", stmts[11].(*stmt.Assign).Right[0].(*expr.SliceLiteral).Values[0], sType},
+		{"array element", stmts[12].(*stmt.Assign).Right[0].(*expr.ArrayLiteral).Values[0], sType},
+		{"map key", stmts[13].(*stmt.Assign).Right[0].(*expr.MapLiteral).Keys[0], sType},
+		{"map value", stmts[13].(*stmt.Assign).Right[0].(*expr.MapLiteral).Values[0], sType},
+		{"nil field", stmts[14].(*stmt.Assign).Right[0].(*expr.CompLiteral).Values[0], nil},
+	}
+	for _, test := range conversions {
+		if got := c.ImplicitConv(test.e); got != test.want {
+			t.Errorf("%s: ImplicitConv(%s)=%v, want %v", test.name, format.Expr(test.e), got, test.want)
+		}
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	src := []string{
+		"type Pair[K comparable, V any] struct { Key K; Val V }",
+		`p := Pair[string, int]{Key: "a", Val: 1}`,
+		"var q Pair[string, int]",
+		"func Max[T int | float64](a, b T) T { return a }",
+	}
+	c := New("")
+	for _, str := range src {
+		s, err := parser.ParseStmt([]byte(str))
+		if err != nil {
+			t.Fatalf("parser.ParseStmt(%q): %v", str, err)
+		}
+		c.Add(s)
+		if errs := c.Errs(); len(errs) > 0 {
+			t.Fatalf("Add(%q): %v", str, errs[0])
+		}
+	}
+	p, q := c.cur.Objs["p"].Type, c.cur.Objs["q"].Type
+	if p != q {
+		t.Errorf("Pair[string, int] instantiated twice: %s, %s", format.Type(p), format.Type(q))
+	}
+	if got, want := p.(*tipe.Named).Type.(*tipe.Struct).Fields[1].Type, tipe.Int; got != want {
+		t.Errorf("p.Val has type %s, want %s", format.Type(got), format.Type(want))
+	}
+
+	errTests := []struct {
+		src, err string
+	}{
+		{`Max("a", "b")`, "string does not satisfy int | float64"},
+		{"f := Max", "cannot use generic function Max without instantiation"},
+		{"var r Pair[[]int, int]", "[]int does not satisfy comparable"},
+	}
+	for _, test := range errTests {
+		s, err := parser.ParseStmt([]byte(test.src))
+		if err != nil {
+			t.Fatalf("parser.ParseStmt(%q): %v", test.src, err)
+		}
+		c.Add(s)
+		errs := c.Errs()
+		if len(errs) == 0 {
+			t.Errorf("Add(%q): missing error %q", test.src, test.err)
+			continue
+		}
+		if got := errs[0].Error(); !strings.Contains(got, test.err) {
+			t.Errorf("Add(%q): error %q does not contain %q", test.src, got, test.err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2018 The Neugram Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"neugram.io/ng/ngvet"
+)
+
+const vetUsage = `usage: ng vet [-json] file.ng ...
+
+Vet reports suspicious constructs in Neugram programs: unused
+variables and imports, shadowed declarations, ignored $$ errors,
+unreachable code, printf mistakes, copied sync values, and loop
+variables captured by goroutines. Problems are printed as
+file:line:col: message. The exit status is 1 if any are found.
+`
+
+func cmdVet(args []string) {
+	flags := flag.NewFlagSet("vet", flag.ExitOnError)
+	flagJSON := flags.Bool("json", false, "print diagnostics as a JSON array")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, vetUsage)
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+	}
+
+	diags := []ngvet.Diagnostic{}
+	for _, filename := range flags.Args() {
+		d, err := ngvet.Check(filename)
+		if err != nil {
+			exitf("vet: %s: %v", filename, err)
+		}
+		diags = append(diags, d...)
+	}
+	if *flagJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(diags); err != nil {
+			exitf("vet: %v", err)
+		}
+	} else {
+		for _, d := range diags {
+			fmt.Fprintln(os.Stderr, d)
+		}
+	}
+	if len(diags) > 0 {
+		os.Exit(1)
+	}
+}